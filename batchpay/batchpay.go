@@ -0,0 +1,349 @@
+// Package batchpay pays a batch of payouts read from a CSV file.
+//
+// Payouts are first previewed: each destination is parsed, its amount
+// checked against the swap limits and its payment prepared, and the plan
+// summarizes the amounts, fees and problems found. The plan is only
+// executed with its confirmation code, which changes whenever the previewed
+// amounts or fees change. A plan can be passed around as JSON between its
+// preview and its execution, the payouts being prepared again if needed.
+// Results are written back as CSV.
+package batchpay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/idempotency"
+	"github.com/breez/breez-sdk-liquid-go/internal/payflow"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+	"github.com/breez/breez-sdk-liquid-go/limitcheck"
+)
+
+// DefaultMaxPlanAge is how long a plan can be executed after its preview,
+// used when Options.MaxPlanAge is not set. Prepared fees expire.
+const DefaultMaxPlanAge = 10 * time.Minute
+
+var (
+	// ErrConfirmation is returned when executing a plan with a wrong
+	// confirmation code.
+	ErrConfirmation = errors.New("batchpay: confirmation code does not match the plan")
+	// ErrPlanExpired is returned when executing a plan older than
+	// Options.MaxPlanAge.
+	ErrPlanExpired = errors.New("batchpay: plan expired, preview it again")
+	// ErrInsufficientBalance is returned when executing a plan whose total
+	// exceeds the balance.
+	ErrInsufficientBalance = errors.New("batchpay: insufficient balance")
+	// ErrFeesChanged is returned for a payout prepared again on execution
+	// whose amount or fees exceed the previewed ones.
+	ErrFeesChanged = errors.New("batchpay: amount or fees changed since the preview")
+)
+
+// Options configures a Batcher.
+type Options struct {
+	// Executor, if set, sends each payout at most once, keyed by the batch
+	// id and the row id, so that a batch interrupted by a crash can be
+	// previewed and executed again.
+	Executor *idempotency.Executor
+	// MaxPlanAge is how long a plan can be executed after its preview.
+	// Defaults to DefaultMaxPlanAge.
+	MaxPlanAge time.Duration
+	// StopOnError stops the execution at the first failed payout. The
+	// remaining payouts are reported as skipped.
+	StopOnError bool
+	// OnProgress, if set, is called after each payout.
+	OnProgress func(Progress)
+}
+
+// Entry is the preview of a payout.
+type Entry struct {
+	Row       Row      `json:"row"`
+	Kind      string   `json:"kind,omitempty"`
+	AmountSat uint64   `json:"amount_sat"`
+	FeesSat   uint64   `json:"fees_sat"`
+	Valid     bool     `json:"valid"`
+	Errors    []string `json:"errors,omitempty"`
+
+	// prepared is lost when the plan goes through JSON, in which case the
+	// payout is prepared again on execution.
+	prepared *payflow.Prepared
+}
+
+// Summary totals a plan.
+type Summary struct {
+	Rows           int    `json:"rows"`
+	Valid          int    `json:"valid"`
+	Invalid        int    `json:"invalid"`
+	TotalAmountSat uint64 `json:"total_amount_sat"`
+	TotalFeesSat   uint64 `json:"total_fees_sat"`
+	BalanceSat     uint64 `json:"balance_sat"`
+	// ShortfallSat is how much the valid payouts and their fees exceed
+	// the balance.
+	ShortfallSat uint64 `json:"shortfall_sat"`
+}
+
+// Plan is the preview of a batch.
+type Plan struct {
+	// BatchId identifies the batch, e.g. the name of the input file.
+	BatchId string `json:"batch_id"`
+	// Code confirms the execution of the plan.
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   []Entry   `json:"entries"`
+	Summary   Summary   `json:"summary"`
+}
+
+// Status of an executed payout.
+type Status string
+
+const (
+	// StatusPaid payouts completed.
+	StatusPaid Status = "paid"
+	// StatusPending payouts were sent and have not completed yet.
+	StatusPending Status = "pending"
+	// StatusFailed payouts returned an error.
+	StatusFailed Status = "failed"
+	// StatusSkipped payouts were invalid in the preview, or not attempted
+	// because of StopOnError.
+	StatusSkipped Status = "skipped"
+)
+
+// Result is the outcome of a payout.
+type Result struct {
+	Row          Row    `json:"row"`
+	Kind         string `json:"kind,omitempty"`
+	AmountSat    uint64 `json:"amount_sat"`
+	FeesSat      uint64 `json:"fees_sat"`
+	Status       Status `json:"status"`
+	PaymentState string `json:"payment_state,omitempty"`
+	PaymentId    string `json:"payment_id,omitempty"`
+	TxId         string `json:"tx_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Progress is reported after each payout.
+type Progress struct {
+	Done   int    `json:"done"`
+	Total  int    `json:"total"`
+	Result Result `json:"result"`
+}
+
+// Batcher previews and executes batches.
+type Batcher struct {
+	sdk    breez_sdk_liquid.BindingLiquidSdkInterface
+	opts   Options
+	limits *limitcheck.Validator
+}
+
+// New creates a Batcher for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Batcher {
+	if opts.MaxPlanAge <= 0 {
+		opts.MaxPlanAge = DefaultMaxPlanAge
+	}
+	return &Batcher{sdk: sdk, opts: opts, limits: limitcheck.New(sdk, limitcheck.Options{})}
+}
+
+// Preview parses, validates and prepares every payout of a batch. batchId
+// identifies the batch and must be the same when the batch is previewed
+// again after an interrupted execution. It is required with an Executor.
+func (b *Batcher) Preview(batchId string, rows []Row) (*Plan, error) {
+	if batchId == "" && b.opts.Executor != nil {
+		return nil, errors.New("batchpay: batch id is required")
+	}
+	info, err := b.sdk.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{BatchId: batchId, CreatedAt: time.Now().UTC()}
+	invoices := map[string]string{}
+	for _, row := range rows {
+		entry := b.preview(row)
+		// Paying the same invoice twice fails, the second row is invalid.
+		if entry.Kind == payflow.KindBolt11 {
+			if prev, ok := invoices[row.Destination]; ok {
+				entry.Valid = false
+				entry.Errors = append(entry.Errors, fmt.Sprintf("invoice already paid by row %s", prev))
+			} else {
+				invoices[row.Destination] = row.Id
+			}
+		}
+		plan.Entries = append(plan.Entries, entry)
+	}
+
+	s := &plan.Summary
+	s.Rows = len(plan.Entries)
+	s.BalanceSat = info.WalletInfo.BalanceSat
+	for _, e := range plan.Entries {
+		if !e.Valid {
+			s.Invalid++
+			continue
+		}
+		s.Valid++
+		s.TotalAmountSat += e.AmountSat
+		s.TotalFeesSat += e.FeesSat
+	}
+	if total := s.TotalAmountSat + s.TotalFeesSat; total > s.BalanceSat {
+		s.ShortfallSat = total - s.BalanceSat
+	}
+	plan.Code = plan.code()
+	return plan, nil
+}
+
+func (b *Batcher) preview(row Row) Entry {
+	entry := Entry{Row: row, AmountSat: row.AmountSat}
+	input, sdkErr := b.sdk.Parse(row.Destination)
+	if sdkErr != nil {
+		entry.Errors = append(entry.Errors, "invalid destination: "+sdkErr.Error())
+		return entry
+	}
+	entry.Kind = payflow.Kind(input)
+	if entry.Kind == "" {
+		entry.Errors = append(entry.Errors, payflow.ErrUnsupported.Error())
+		return entry
+	}
+
+	if amount := inputAmountSat(input, row.AmountSat); amount > 0 {
+		result, err := b.limits.CheckSend(input, amount)
+		if err != nil {
+			entry.Errors = append(entry.Errors, "fetching limits: "+err.Error())
+			return entry
+		}
+		for _, problem := range result.Problems {
+			entry.Errors = append(entry.Errors, problem.Message)
+		}
+		if !result.Valid {
+			return entry
+		}
+	}
+
+	prepared, err := payflow.Prepare(b.sdk, row.Destination, input, row.AmountSat, row.Comment)
+	if err != nil {
+		entry.Errors = append(entry.Errors, err.Error())
+		return entry
+	}
+	entry.prepared = &prepared
+	entry.AmountSat, entry.FeesSat = prepared.AmountSat, prepared.FeesSat
+	entry.Valid = true
+	return entry
+}
+
+// inputAmountSat returns the amount to pay to a parsed input, zero if it
+// is only known once prepared.
+func inputAmountSat(input breez_sdk_liquid.InputType, amountSat uint64) uint64 {
+	if bolt11, ok := input.(breez_sdk_liquid.InputTypeBolt11); ok && bolt11.Invoice.AmountMsat != nil {
+		return *bolt11.Invoice.AmountMsat / 1000
+	}
+	if amountSat > 0 {
+		return amountSat
+	}
+	switch input := input.(type) {
+	case breez_sdk_liquid.InputTypeBitcoinAddress:
+		return sdkutil.Uint64Value(input.Address.AmountSat)
+	case breez_sdk_liquid.InputTypeLiquidAddress:
+		return sdkutil.Uint64Value(input.Address.AmountSat)
+	}
+	return 0
+}
+
+// code hashes the previewed payouts.
+func (p *Plan) code() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", p.BatchId)
+	for _, e := range p.Entries {
+		fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d\x00%t\n", e.Row.Id, e.Row.Destination, e.AmountSat, e.FeesSat, e.Valid)
+	}
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil))[:8])
+}
+
+// Execute pays the valid payouts of the plan, in order. confirmation must
+// be the plan's code. It returns a result for every entry, and ctx.Err()
+// if cancelled, the remaining payouts being skipped.
+func (b *Batcher) Execute(ctx context.Context, plan *Plan, confirmation string) ([]Result, error) {
+	// The code is computed again in case the plan was changed since.
+	if !strings.EqualFold(strings.TrimSpace(confirmation), plan.Code) || plan.Code != plan.code() {
+		return nil, ErrConfirmation
+	}
+	if time.Since(plan.CreatedAt) > b.opts.MaxPlanAge {
+		return nil, ErrPlanExpired
+	}
+	if plan.Summary.ShortfallSat > 0 {
+		return nil, fmt.Errorf("%w: short of %d sat", ErrInsufficientBalance, plan.Summary.ShortfallSat)
+	}
+
+	results := make([]Result, len(plan.Entries))
+	stop := false
+	var err error
+	for i, e := range plan.Entries {
+		r := Result{Row: e.Row, Kind: e.Kind, AmountSat: e.AmountSat, FeesSat: e.FeesSat, Status: StatusSkipped}
+		switch {
+		case !e.Valid:
+			r.Error = strings.Join(e.Errors, "; ")
+		case stop:
+		case ctx.Err() != nil:
+			err, stop = ctx.Err(), true
+		default:
+			b.pay(plan, e, &r)
+			stop = r.Status == StatusFailed && b.opts.StopOnError
+		}
+		results[i] = r
+		if b.opts.OnProgress != nil {
+			b.opts.OnProgress(Progress{Done: i + 1, Total: len(plan.Entries), Result: r})
+		}
+	}
+	return results, err
+}
+
+func (b *Batcher) pay(plan *Plan, e Entry, r *Result) {
+	prepared, err := b.prepared(e)
+	if err != nil {
+		r.Status, r.Error = StatusFailed, err.Error()
+		return
+	}
+	var payment breez_sdk_liquid.Payment
+	if b.opts.Executor != nil {
+		payment, err = prepared.ExecuteOnce(b.opts.Executor, "batchpay/"+plan.BatchId+"/"+e.Row.Id)
+	} else {
+		payment, err = prepared.Execute(b.sdk)
+	}
+	if err != nil {
+		r.Status, r.Error = StatusFailed, err.Error()
+		return
+	}
+	r.Status = StatusPending
+	switch payment.Status {
+	case breez_sdk_liquid.PaymentStateComplete:
+		r.Status = StatusPaid
+	case breez_sdk_liquid.PaymentStateFailed, breez_sdk_liquid.PaymentStateTimedOut:
+		r.Status = StatusFailed
+	}
+	r.PaymentState = sdkutil.PaymentStateName(payment.Status)
+	r.PaymentId = sdkutil.PaymentId(payment)
+	r.TxId = sdkutil.StringValue(payment.TxId)
+	r.FeesSat = payment.FeesSat
+}
+
+// prepared returns the prepared payment of an entry, preparing it again if
+// the plan was decoded from JSON.
+func (b *Batcher) prepared(e Entry) (payflow.Prepared, error) {
+	if e.prepared != nil {
+		return *e.prepared, nil
+	}
+	input, sdkErr := b.sdk.Parse(e.Row.Destination)
+	if sdkErr != nil {
+		return payflow.Prepared{}, sdkErr.AsError()
+	}
+	prepared, err := payflow.Prepare(b.sdk, e.Row.Destination, input, e.Row.AmountSat, e.Row.Comment)
+	if err != nil {
+		return payflow.Prepared{}, err
+	}
+	if prepared.AmountSat != e.AmountSat || prepared.FeesSat > e.FeesSat {
+		return payflow.Prepared{}, fmt.Errorf("%w: %d sat with %d sat fees, previewed %d sat with %d sat fees",
+			ErrFeesChanged, prepared.AmountSat, prepared.FeesSat, e.AmountSat, e.FeesSat)
+	}
+	return prepared, nil
+}
@@ -0,0 +1,154 @@
+package batchpay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/idempotency"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+var rows = []Row{
+	{Line: 2, Id: "alice", Destination: "lq1alice", AmountSat: 1000},
+	{Line: 3, Id: "bob", Destination: "lq1bob", AmountSat: 2000},
+}
+
+func newBatcher(t *testing.T, sdk *sdktest.Fake) *Batcher {
+	t.Helper()
+	exec, err := idempotency.New(sdk, idempotency.Options{StatePath: filepath.Join(t.TempDir(), "keys.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(sdk, Options{Executor: exec})
+}
+
+func execute(t *testing.T, b *Batcher, plan *Plan) []Result {
+	t.Helper()
+	results, err := b.Execute(context.Background(), plan, plan.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestPreview(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 3000, FeesSat: 10}
+	b := newBatcher(t, sdk)
+	if _, err := b.Preview("", rows); err == nil {
+		t.Fatal("previewed without a batch id")
+	}
+	plan, err := b.Preview("payouts.csv", append(rows, Row{Line: 4, Id: "carol", Destination: "nope"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Summary{Rows: 3, Valid: 2, Invalid: 1, TotalAmountSat: 3000, TotalFeesSat: 20, BalanceSat: 3000, ShortfallSat: 20}
+	if plan.Summary != want {
+		t.Fatalf("summary %+v, want %+v", plan.Summary, want)
+	}
+	if _, err := b.Execute(context.Background(), plan, plan.Code); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("got %v, want ErrInsufficientBalance", err)
+	}
+}
+
+func TestConfirmation(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 10000, FeesSat: 10}
+	b := newBatcher(t, sdk)
+	plan, err := b.Preview("payouts.csv", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Execute(context.Background(), plan, "WRONG"); !errors.Is(err, ErrConfirmation) {
+		t.Fatalf("got %v, want ErrConfirmation", err)
+	}
+	plan.Entries[1].AmountSat = 5000
+	if _, err := b.Execute(context.Background(), plan, plan.Code); !errors.Is(err, ErrConfirmation) {
+		t.Fatalf("changed plan: got %v, want ErrConfirmation", err)
+	}
+	if len(sdk.Payments) != 0 {
+		t.Fatalf("%d payments made", len(sdk.Payments))
+	}
+}
+
+func TestKeyedByBatchAndRow(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 10000, FeesSat: 10}
+	b := newBatcher(t, sdk)
+	plan, err := b.Preview("payouts.csv", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range execute(t, b, plan) {
+		if r.Status != StatusPaid {
+			t.Fatalf("result %+v", r)
+		}
+	}
+	for _, key := range []string{"batchpay/payouts.csv/alice", "batchpay/payouts.csv/bob"} {
+		if r, ok := b.opts.Executor.Record(key); !ok || r.Status != idempotency.StatusSucceeded {
+			t.Fatalf("record of %s: %+v", key, r)
+		}
+	}
+
+	// The same batch previewed again, e.g. after a crash, pays nothing
+	// twice, even though the fees changed the confirmation code.
+	sdk.FeesSat = 12
+	plan, err = b.Preview("payouts.csv", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := execute(t, b, plan)
+	if len(sdk.Payments) != 2 {
+		t.Fatalf("%d payments, want 2", len(sdk.Payments))
+	}
+	if results[0].PaymentId != "tx1" || results[1].PaymentId != "tx2" {
+		t.Fatalf("replayed %+v", results)
+	}
+
+	// Another batch pays again.
+	plan, err = b.Preview("payouts-2.csv", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	execute(t, b, plan)
+	if len(sdk.Payments) != 4 {
+		t.Fatalf("%d payments, want 4", len(sdk.Payments))
+	}
+}
+
+func TestPlanThroughJson(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 10000, FeesSat: 10}
+	b := newBatcher(t, sdk)
+	plan, err := b.Preview("payouts.csv", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Plan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// The fees of bob's payout rose since the preview.
+	prepares := sdk.Prepares
+	sdk.FeesSat = 11
+	decoded.Entries[0].FeesSat = 11
+	decoded.Code = decoded.code()
+	results := execute(t, b, &decoded)
+	if sdk.Prepares != prepares+2 {
+		t.Fatalf("%d prepares on execution, want 2", sdk.Prepares-prepares)
+	}
+	if results[0].Status != StatusPaid {
+		t.Fatalf("alice: %+v", results[0])
+	}
+	if results[1].Status != StatusFailed || !strings.Contains(results[1].Error, ErrFeesChanged.Error()) {
+		t.Fatalf("bob: %+v, want the fees changed", results[1])
+	}
+	if len(sdk.Payments) != 1 {
+		t.Fatalf("%d payments, want 1", len(sdk.Payments))
+	}
+}
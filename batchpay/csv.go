@@ -0,0 +1,162 @@
+package batchpay
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Row is a payout read from the input CSV.
+type Row struct {
+	// Line is the line number of the row in the input, starting at 2 for
+	// the first row after the header.
+	Line int `json:"line"`
+	// Id identifies the payout. Defaults to the line number.
+	Id          string `json:"id"`
+	Destination string `json:"destination"`
+	// AmountSat is zero when the destination sets the amount.
+	AmountSat uint64 `json:"amount_sat,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// Input columns. Only destination is required; other columns are ignored.
+const (
+	ColumnId          = "id"
+	ColumnDestination = "destination"
+	ColumnAmountSat   = "amount_sat"
+	ColumnComment     = "comment"
+)
+
+// ReadCSV reads payouts from a CSV file with a header row naming the
+// columns, e.g. "id,destination,amount_sat,comment".
+func ReadCSV(r io.Reader) ([]Row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("batchpay: empty csv")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns[ColumnDestination]; !ok {
+		return nil, fmt.Errorf("batchpay: missing %q column", ColumnDestination)
+	}
+	field := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []Row
+	ids := map[string]int{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+		row := Row{
+			Line:        line,
+			Id:          field(record, ColumnId),
+			Destination: field(record, ColumnDestination),
+			Comment:     field(record, ColumnComment),
+		}
+		if row.Id == "" {
+			row.Id = strconv.Itoa(line)
+		}
+		if prev, ok := ids[row.Id]; ok {
+			return nil, fmt.Errorf("batchpay: line %d: id %q already used on line %d", line, row.Id, prev)
+		}
+		ids[row.Id] = line
+		if row.Destination == "" {
+			return nil, fmt.Errorf("batchpay: line %d: missing destination", line)
+		}
+		if amount := field(record, ColumnAmountSat); amount != "" {
+			row.AmountSat, err = strconv.ParseUint(strings.ReplaceAll(amount, "_", ""), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("batchpay: line %d: invalid amount %q", line, amount)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+var previewHeader = []string{"line", "id", "destination", "kind", "amount_sat", "fees_sat", "valid", "errors"}
+
+// WriteCSV writes the preview of the plan, one row per payout.
+func (p *Plan) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(previewHeader); err != nil {
+		return err
+	}
+	for _, e := range p.Entries {
+		if err := cw.Write([]string{
+			strconv.Itoa(e.Row.Line),
+			e.Row.Id,
+			e.Row.Destination,
+			e.Kind,
+			strconv.FormatUint(e.AmountSat, 10),
+			strconv.FormatUint(e.FeesSat, 10),
+			strconv.FormatBool(e.Valid),
+			strings.Join(e.Errors, "; "),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var resultsHeader = []string{"line", "id", "destination", "kind", "amount_sat", "fees_sat", "status", "payment_state", "payment_id", "tx_id", "error"}
+
+// WriteResultsCSV writes the results of an execution, one row per payout.
+func WriteResultsCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(resultsHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := cw.Write([]string{
+			strconv.Itoa(r.Row.Line),
+			r.Row.Id,
+			r.Row.Destination,
+			r.Kind,
+			strconv.FormatUint(r.AmountSat, 10),
+			strconv.FormatUint(r.FeesSat, 10),
+			string(r.Status),
+			r.PaymentState,
+			r.PaymentId,
+			r.TxId,
+			r.Error,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,482 @@
+// Package checkout manages merchant checkouts: payment requests for an
+// order, in sat or in a fiat currency, payable by Lightning invoice, Liquid
+// address or Bitcoin address.
+//
+// Create prepares and creates a receive for each method and persists the
+// checkout. While Run is running, the payments received are correlated to
+// the checkouts by invoice, payment hash or address, and each checkout moves
+// from pending to processing, paid or underpaid, or expires once its TTL has
+// passed or the swaps of all its offers reached their expiration
+// blockheight. Options.OnUpdate is called on every change.
+package checkout
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultTtl          = time.Hour
+	DefaultPollInterval = 30 * time.Second
+	// DefaultRetention is how long checkouts are kept after they expire.
+	DefaultRetention = 30 * 24 * time.Hour
+	// LateGrace is how long after they expire checkouts are still polled
+	// for late payments.
+	LateGrace = 24 * time.Hour
+)
+
+var (
+	// ErrNotFound is returned for unknown checkouts.
+	ErrNotFound = errors.New("checkout: not found")
+	// ErrConflict is returned when creating a checkout for an existing
+	// order with a different amount.
+	ErrConflict = errors.New("checkout: order already has a checkout for a different amount")
+	// ErrNoMethod is returned when none of the requested methods could be
+	// offered. The errors of each method are wrapped in a MethodsError.
+	ErrNoMethod = errors.New("checkout: no payment method available")
+)
+
+// MethodsError reports why each method of a checkout could not be offered.
+type MethodsError struct {
+	Errors map[Method]error
+}
+
+func (e *MethodsError) Error() string {
+	var parts []string
+	for _, m := range []Method{MethodBolt11, MethodLiquid, MethodBitcoin} {
+		if err, ok := e.Errors[m]; ok {
+			parts = append(parts, fmt.Sprintf("%s: %v", m, err))
+		}
+	}
+	return ErrNoMethod.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e *MethodsError) Unwrap() error {
+	return ErrNoMethod
+}
+
+// Options configures a Manager.
+type Options struct {
+	// StatePath is the file the checkouts are persisted to. Required.
+	StatePath string
+	// Ttl is how long checkouts can be paid, unless set by the request.
+	// Defaults to DefaultTtl.
+	Ttl time.Duration
+	// ToleranceSat is how much less than the amount a checkout accepts as
+	// paid, to absorb rounding by wallets.
+	ToleranceSat uint64
+	// PollInterval between checks for expiries and missed payments.
+	// Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// Retention is how long checkouts are kept after they expire. Defaults
+	// to DefaultRetention.
+	Retention time.Duration
+	// OnUpdate, if set, is called whenever a checkout changes.
+	OnUpdate func(Session)
+}
+
+// Manager creates and tracks checkouts.
+type Manager struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	trigger chan struct{}
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// New creates a Manager and loads its persisted checkouts.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Manager, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("checkout: StatePath is required")
+	}
+	if opts.Ttl <= 0 {
+		opts.Ttl = DefaultTtl
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	m := &Manager{sdk: sdk, opts: opts, trigger: make(chan struct{}, 1), sessions: map[string]*Session{}}
+	if err := filestore.Load(opts.StatePath, &m.sessions); err != nil {
+		return nil, fmt.Errorf("checkout: loading state: %w", err)
+	}
+	return m, nil
+}
+
+// Create creates the checkout of an order, or returns its existing
+// checkout.
+func (m *Manager) Create(req Request) (Session, error) {
+	if req.OrderId == "" {
+		return Session{}, errors.New("checkout: order id is required")
+	}
+	methods := req.Methods
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+	for _, method := range methods {
+		if _, ok := method.paymentMethod(); !ok {
+			return Session{}, fmt.Errorf("checkout: unknown method %q", method)
+		}
+	}
+	if existing, ok := m.byOrder(req.OrderId); ok {
+		if !sameAmount(existing, req) {
+			return Session{}, ErrConflict
+		}
+		return existing, nil
+	}
+	amountSat, fiat, err := m.amount(req)
+	if err != nil {
+		return Session{}, err
+	}
+
+	id, err := newId()
+	if err != nil {
+		return Session{}, err
+	}
+	ttl := req.Ttl
+	if ttl <= 0 {
+		ttl = m.opts.Ttl
+	}
+	now := time.Now().UTC()
+	s := &Session{
+		Id:        id,
+		OrderId:   req.OrderId,
+		AmountSat: amountSat,
+		Fiat:      fiat,
+		Metadata:  copyMetadata(req.Metadata),
+		Status:    StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	unavailable := map[Method]error{}
+	for _, method := range methods {
+		offer, err := m.offer(method, amountSat, req.Description)
+		if err != nil {
+			unavailable[method] = err
+			continue
+		}
+		s.Offers = append(s.Offers, offer)
+	}
+	if len(s.Offers) == 0 {
+		return Session{}, &MethodsError{Errors: unavailable}
+	}
+	for method, err := range unavailable {
+		if s.Unavailable == nil {
+			s.Unavailable = map[Method]string{}
+		}
+		s.Unavailable[method] = err.Error()
+	}
+
+	m.mu.Lock()
+	for _, existing := range m.sessions {
+		if existing.OrderId == req.OrderId {
+			// Created concurrently: the receives just created are left
+			// unused.
+			session := existing.clone()
+			m.mu.Unlock()
+			return session, nil
+		}
+	}
+	m.sessions[s.Id] = s
+	if err := m.saveLocked(); err != nil {
+		delete(m.sessions, s.Id)
+		m.mu.Unlock()
+		return Session{}, err
+	}
+	session := s.clone()
+	m.mu.Unlock()
+	m.notify(session)
+	return session, nil
+}
+
+// sameAmount reports whether a request for the order of s asks for the
+// same amount.
+func sameAmount(s Session, req Request) bool {
+	if req.Currency == "" {
+		return s.Fiat == nil && s.AmountSat == req.AmountSat
+	}
+	return s.Fiat != nil && s.Fiat.Amount == req.FiatAmount && strings.EqualFold(s.Fiat.Currency, req.Currency)
+}
+
+// amount returns the amount due by a request in sat.
+func (m *Manager) amount(req Request) (uint64, *FiatAmount, error) {
+	if req.Currency == "" {
+		if req.AmountSat == 0 || req.FiatAmount != 0 {
+			return 0, nil, errors.New("checkout: amount_sat, or fiat_amount and currency, are required")
+		}
+		return req.AmountSat, nil, nil
+	}
+	if req.AmountSat != 0 {
+		return 0, nil, errors.New("checkout: amount_sat and currency are exclusive")
+	}
+	if req.FiatAmount <= 0 || math.IsInf(req.FiatAmount, 0) || math.IsNaN(req.FiatAmount) {
+		return 0, nil, errors.New("checkout: fiat_amount must be positive")
+	}
+	rates, sdkErr := m.sdk.FetchFiatRates()
+	if sdkErr != nil {
+		return 0, nil, sdkErr
+	}
+	for _, rate := range rates {
+		if strings.EqualFold(rate.Coin, req.Currency) && rate.Value > 0 {
+			sats := uint64(math.Round(req.FiatAmount / rate.Value * 1e8))
+			if sats == 0 {
+				return 0, nil, errors.New("checkout: amount too small")
+			}
+			return sats, &FiatAmount{Amount: req.FiatAmount, Currency: strings.ToUpper(req.Currency), Rate: rate.Value}, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("checkout: unknown currency %q", req.Currency)
+}
+
+// offer prepares and creates the receive of a method.
+func (m *Manager) offer(method Method, amountSat uint64, description string) (Offer, error) {
+	paymentMethod, _ := method.paymentMethod()
+	var amount breez_sdk_liquid.ReceiveAmount = breez_sdk_liquid.ReceiveAmountBitcoin{PayerAmountSat: amountSat}
+	prepared, payErr := m.sdk.PrepareReceivePayment(breez_sdk_liquid.PrepareReceiveRequest{PaymentMethod: paymentMethod, Amount: &amount})
+	if payErr != nil {
+		return Offer{}, payErr
+	}
+	req := breez_sdk_liquid.ReceivePaymentRequest{PrepareResponse: prepared}
+	if description != "" {
+		req.Description = &description
+	}
+	res, payErr := m.sdk.ReceivePayment(req)
+	if payErr != nil {
+		return Offer{}, payErr
+	}
+	offer := Offer{
+		Method:                       method,
+		Destination:                  res.Destination,
+		FeesSat:                      prepared.FeesSat,
+		LiquidExpirationBlockheight:  res.LiquidExpirationBlockheight,
+		BitcoinExpirationBlockheight: res.BitcoinExpirationBlockheight,
+	}
+	if method == MethodBolt11 {
+		invoice, payErr := breez_sdk_liquid.ParseInvoice(res.Destination)
+		if payErr != nil {
+			return Offer{}, payErr
+		}
+		offer.PaymentHash = invoice.PaymentHash
+		offer.ExpiresAt = time.Unix(int64(invoice.Timestamp+invoice.Expiry), 0).UTC()
+	}
+	return offer, nil
+}
+
+// Get returns a checkout.
+func (m *Manager) Get(id string) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	return s.clone(), true
+}
+
+// GetByOrder returns the checkout of an order.
+func (m *Manager) GetByOrder(orderId string) (Session, bool) {
+	return m.byOrder(orderId)
+}
+
+func (m *Manager) byOrder(orderId string) (Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.sessions {
+		if s.OrderId == orderId {
+			return s.clone(), true
+		}
+	}
+	return Session{}, false
+}
+
+// List returns the checkouts in the given statuses, or all checkouts if
+// none is given, ordered by creation time.
+func (m *Manager) List(statuses ...Status) []Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []Session
+	for _, s := range m.sessions {
+		if len(statuses) == 0 || hasStatus(statuses, s.Status) {
+			sessions = append(sessions, s.clone())
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+		}
+		return sessions[i].Id < sessions[j].Id
+	})
+	return sessions
+}
+
+// Trigger makes Run check the checkouts immediately.
+func (m *Manager) Trigger() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run tracks the payments and expiries of the checkouts until ctx is done.
+func (m *Manager) Run(ctx context.Context) error {
+	listenerId, sdkErr := m.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if p, ok := sdkutil.EventPayment(e); ok && p.PaymentType == breez_sdk_liquid.PaymentTypeReceive {
+			m.apply([]breez_sdk_liquid.Payment{p}, nil)
+		}
+	}))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	defer func() { _ = m.sdk.RemoveEventListener(listenerId) }()
+
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		m.refresh()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-m.trigger:
+		}
+	}
+}
+
+// refresh correlates the payments received since the oldest tracked
+// checkout, which catches up on the events missed while not running, and
+// checks the expiries against the current tips.
+func (m *Manager) refresh() {
+	m.prune()
+	oldest, ok := m.oldestTracked()
+	if !ok {
+		return
+	}
+	info, sdkErr := m.sdk.GetInfo()
+	if sdkErr != nil {
+		return
+	}
+	from := oldest.Unix()
+	payments, err := sdkutil.ListAllPayments(m.sdk, breez_sdk_liquid.ListPaymentsRequest{
+		Filters:       &[]breez_sdk_liquid.PaymentType{breez_sdk_liquid.PaymentTypeReceive},
+		FromTimestamp: &from,
+	})
+	if err != nil {
+		return
+	}
+	m.apply(payments, &info.BlockchainInfo)
+}
+
+// oldestTracked returns the creation time of the oldest checkout still
+// polled: not paid, and either with a payment in progress or expired for
+// less than LateGrace.
+func (m *Manager) oldestTracked() (time.Time, bool) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var oldest time.Time
+	for _, s := range m.sessions {
+		if s.Status == StatusPaid || s.Status != StatusProcessing && !now.Before(s.ExpiresAt.Add(LateGrace)) {
+			continue
+		}
+		if oldest.IsZero() || s.CreatedAt.Before(oldest) {
+			oldest = s.CreatedAt
+		}
+	}
+	return oldest, !oldest.IsZero()
+}
+
+// apply records the payments of the checkouts and updates their statuses.
+// The expiration blockheights of the swaps are checked if tips is given.
+func (m *Manager) apply(payments []breez_sdk_liquid.Payment, tips *breez_sdk_liquid.BlockchainInfo) {
+	now := time.Now().UTC()
+	var updated []Session
+	m.mu.Lock()
+	for _, s := range m.sessions {
+		changed := false
+		for _, p := range payments {
+			if method, ok := s.matches(p); ok && s.record(method, p) {
+				changed = true
+			}
+		}
+		if s.expireOffers(now, tips) {
+			changed = true
+		}
+		if s.updateStatus(now, m.opts.ToleranceSat) {
+			changed = true
+		}
+		if changed {
+			s.UpdatedAt = now
+			updated = append(updated, s.clone())
+		}
+	}
+	if len(updated) > 0 {
+		_ = m.saveLocked()
+	}
+	m.mu.Unlock()
+	for _, s := range updated {
+		m.notify(s)
+	}
+}
+
+// prune deletes the checkouts whose TTL passed more than Options.Retention
+// ago.
+func (m *Manager) prune() {
+	cutoff := time.Now().Add(-m.opts.Retention)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pruned := false
+	for id, s := range m.sessions {
+		if s.ExpiresAt.Before(cutoff) {
+			delete(m.sessions, id)
+			pruned = true
+		}
+	}
+	if pruned {
+		_ = m.saveLocked()
+	}
+}
+
+func (m *Manager) saveLocked() error {
+	return filestore.Save(m.opts.StatePath, m.sessions)
+}
+
+func (m *Manager) notify(s Session) {
+	if m.opts.OnUpdate != nil {
+		m.opts.OnUpdate(s)
+	}
+}
+
+func hasStatus(statuses []Status, s Status) bool {
+	for _, status := range statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
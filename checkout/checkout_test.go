@@ -0,0 +1,117 @@
+package checkout
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk receives to Liquid addresses, failing the other methods with
+// receiveErr.
+type fakeSdk struct {
+	receiveErr *breez_sdk_liquid.PaymentError
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		switch call.Method {
+		case "PrepareReceivePayment":
+			req := call.Request.(breez_sdk_liquid.PrepareReceiveRequest)
+			if req.PaymentMethod != breez_sdk_liquid.PaymentMethodLiquidAddress {
+				return nil, f.receiveErr
+			}
+			return breez_sdk_liquid.PrepareReceiveResponse{PaymentMethod: req.PaymentMethod, Amount: req.Amount}, nil
+		case "ReceivePayment":
+			return breez_sdk_liquid.ReceivePaymentResponse{Destination: "liquidnetwork:lq1?amount=0.00001"}, nil
+		}
+		return nil, nil
+	})
+}
+
+func newManager(t *testing.T, f *fakeSdk, onUpdate func(Session)) *Manager {
+	t.Helper()
+	m, err := New(f.sdk(), Options{StatePath: filepath.Join(t.TempDir(), "checkouts.json"), OnUpdate: onUpdate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func received(amountSat uint64) breez_sdk_liquid.Payment {
+	txId := "tx1"
+	return breez_sdk_liquid.Payment{
+		TxId:        &txId,
+		AmountSat:   amountSat,
+		PaymentType: breez_sdk_liquid.PaymentTypeReceive,
+		Status:      breez_sdk_liquid.PaymentStateComplete,
+		Details:     breez_sdk_liquid.PaymentDetailsLiquid{Destination: "lq1"},
+	}
+}
+
+func TestCheckoutPaid(t *testing.T) {
+	var updates []Session
+	m := newManager(t, &fakeSdk{receiveErr: breez_sdk_liquid.NewPaymentErrorAmountOutOfRange()}, func(s Session) {
+		updates = append(updates, s)
+	})
+	metadata := map[string]string{"customer": "c1"}
+	s, err := m.Create(Request{OrderId: "o1", AmountSat: 1000, Metadata: metadata})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Offers) != 1 || s.Offers[0].Method != MethodLiquid || s.Unavailable[MethodBolt11] == "" {
+		t.Fatalf("created %+v", s)
+	}
+
+	// The checkouts returned are copies.
+	metadata["customer"] = "c2"
+	s.Metadata["customer"] = "c3"
+	s.Unavailable[MethodBolt11] = ""
+	if got, _ := m.Get(s.Id); got.Metadata["customer"] != "c1" || got.Unavailable[MethodBolt11] == "" {
+		t.Fatalf("stored checkout changed: %+v", got)
+	}
+
+	m.apply([]breez_sdk_liquid.Payment{received(1000)}, nil)
+	// The same payment again changes nothing.
+	m.apply([]breez_sdk_liquid.Payment{received(1000)}, nil)
+	if len(updates) != 2 || updates[1].Status != StatusPaid || updates[1].PaidSat != 1000 {
+		t.Fatalf("updates %+v", updates)
+	}
+}
+
+func TestCheckoutFailures(t *testing.T) {
+	m := newManager(t, &fakeSdk{receiveErr: breez_sdk_liquid.NewPaymentErrorAmountOutOfRange()}, nil)
+	_, err := m.Create(Request{OrderId: "o1", AmountSat: 1000, Methods: []Method{MethodBolt11}})
+	var methodsErr *MethodsError
+	if !errors.Is(err, ErrNoMethod) || !errors.As(err, &methodsErr) || methodsErr.Errors[MethodBolt11] == nil {
+		t.Fatalf("got %v, want ErrNoMethod", err)
+	}
+
+	if _, err := m.Create(Request{OrderId: "o2", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create(Request{OrderId: "o2", AmountSat: 2000}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("got %v, want ErrConflict", err)
+	}
+}
+
+func TestRequestTtlJSON(t *testing.T) {
+	data, err := json.Marshal(Request{OrderId: "o1", Ttl: 90 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"order_id":"o1","ttl_sec":90}` {
+		t.Fatalf("encoded %s", data)
+	}
+	var req Request
+	if err := json.Unmarshal([]byte(`{"order_id":"o1","amount_sat":1000,"ttl_sec":600}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.OrderId != "o1" || req.AmountSat != 1000 || req.Ttl != 10*time.Minute {
+		t.Fatalf("decoded %+v", req)
+	}
+}
@@ -0,0 +1,148 @@
+package checkout
+
+import (
+	"strings"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// matches returns the method of the offer a received payment pays, if any.
+func (s *Session) matches(p breez_sdk_liquid.Payment) (Method, bool) {
+	if p.PaymentType != breez_sdk_liquid.PaymentTypeReceive {
+		return "", false
+	}
+	for _, offer := range s.Offers {
+		switch d := p.Details.(type) {
+		case breez_sdk_liquid.PaymentDetailsLightning:
+			if offer.Method != MethodBolt11 {
+				continue
+			}
+			if d.Invoice != nil && *d.Invoice == offer.Destination ||
+				d.PaymentHash != nil && offer.PaymentHash != "" && *d.PaymentHash == offer.PaymentHash {
+				return MethodBolt11, true
+			}
+		case breez_sdk_liquid.PaymentDetailsLiquid:
+			if offer.Method != MethodLiquid {
+				continue
+			}
+			addr := sdkutil.Address(offer.Destination)
+			if sdkutil.Address(d.Destination) == addr || sdkutil.Address(sdkutil.StringValue(p.Destination)) == addr {
+				return MethodLiquid, true
+			}
+		case breez_sdk_liquid.PaymentDetailsBitcoin:
+			if offer.Method != MethodBitcoin {
+				continue
+			}
+			// Bitcoin bech32 addresses may be uppercased in URIs.
+			if strings.EqualFold(d.BitcoinAddress, sdkutil.Address(offer.Destination)) {
+				return MethodBitcoin, true
+			}
+		}
+	}
+	return "", false
+}
+
+// record adds or updates a payment of the checkout, and reports whether it
+// changed.
+func (s *Session) record(method Method, p breez_sdk_liquid.Payment) bool {
+	payment := Payment{
+		PaymentId:      sdkutil.PaymentId(p),
+		Method:         method,
+		PayerAmountSat: p.AmountSat + p.FeesSat,
+		FeesSat:        p.FeesSat,
+		State:          sdkutil.PaymentStateName(p.Status),
+		TxId:           sdkutil.StringValue(p.TxId),
+		ReceivedAt:     time.Unix(int64(p.Timestamp), 0).UTC(),
+	}
+	for i := range s.Payments {
+		if s.Payments[i].PaymentId == payment.PaymentId {
+			if s.Payments[i] == payment {
+				return false
+			}
+			s.Payments[i] = payment
+			return true
+		}
+	}
+	s.Payments = append(s.Payments, payment)
+	return true
+}
+
+// expireOffers marks the offers which can no longer be paid: invoices past
+// their expiry and, if tips is given, swaps past their expiration
+// blockheight. It reports whether any offer expired.
+func (s *Session) expireOffers(now time.Time, tips *breez_sdk_liquid.BlockchainInfo) bool {
+	expired := false
+	for i := range s.Offers {
+		o := &s.Offers[i]
+		if o.Expired {
+			continue
+		}
+		switch {
+		case !o.ExpiresAt.IsZero() && !now.Before(o.ExpiresAt):
+			o.Expired = true
+		case tips != nil && o.LiquidExpirationBlockheight != nil && tips.LiquidTip >= *o.LiquidExpirationBlockheight:
+			o.Expired = true
+		case tips != nil && o.BitcoinExpirationBlockheight != nil && tips.BitcoinTip >= *o.BitcoinExpirationBlockheight:
+			o.Expired = true
+		}
+		expired = expired || o.Expired
+	}
+	return expired
+}
+
+// expired reports whether the checkout can no longer be paid: its TTL
+// passed or all its offers expired.
+func (s *Session) expired(now time.Time) bool {
+	if !now.Before(s.ExpiresAt) {
+		return true
+	}
+	for _, o := range s.Offers {
+		if !o.Expired {
+			return false
+		}
+	}
+	return true
+}
+
+// updateStatus derives the status of the checkout from its payments, and
+// reports whether it changed.
+func (s *Session) updateStatus(now time.Time, toleranceSat uint64) bool {
+	status, paidSat, paidAt := s.Status, s.PaidSat, s.PaidAt
+	var paid, complete uint64
+	for _, p := range s.Payments {
+		switch p.State {
+		case sdkutil.PaymentStateName(breez_sdk_liquid.PaymentStateComplete):
+			complete += p.PayerAmountSat
+			paid += p.PayerAmountSat
+		case sdkutil.PaymentStateName(breez_sdk_liquid.PaymentStateCreated),
+			sdkutil.PaymentStateName(breez_sdk_liquid.PaymentStatePending),
+			sdkutil.PaymentStateName(breez_sdk_liquid.PaymentStateWaitingFeeAcceptance):
+			paid += p.PayerAmountSat
+		}
+	}
+	required := s.AmountSat
+	if toleranceSat >= required {
+		required = 1
+	} else {
+		required -= toleranceSat
+	}
+	s.PaidSat = paid
+	switch {
+	case complete >= required:
+		s.Status = StatusPaid
+		if s.PaidAt.IsZero() {
+			s.PaidAt = now
+		}
+	case paid >= required:
+		s.Status = StatusProcessing
+	case paid > 0:
+		s.Status = StatusUnderpaid
+	case s.expired(now):
+		s.Status = StatusExpired
+	default:
+		s.Status = StatusPending
+	}
+	return s.Status != status || s.PaidSat != paidSat || !s.PaidAt.Equal(paidAt)
+}
@@ -0,0 +1,199 @@
+package checkout
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// Method is a way of paying a checkout.
+type Method string
+
+const (
+	// MethodBolt11 is a Lightning invoice, paid through a swap.
+	MethodBolt11 Method = "bolt11"
+	// MethodLiquid is a Liquid address, paid directly.
+	MethodLiquid Method = "liquid"
+	// MethodBitcoin is a Bitcoin address, paid through a chain swap.
+	MethodBitcoin Method = "bitcoin"
+)
+
+// DefaultMethods are the methods of a checkout created without any.
+var DefaultMethods = []Method{MethodBolt11, MethodLiquid}
+
+func (m Method) paymentMethod() (breez_sdk_liquid.PaymentMethod, bool) {
+	switch m {
+	case MethodBolt11:
+		return breez_sdk_liquid.PaymentMethodBolt11Invoice, true
+	case MethodLiquid:
+		return breez_sdk_liquid.PaymentMethodLiquidAddress, true
+	case MethodBitcoin:
+		return breez_sdk_liquid.PaymentMethodBitcoinAddress, true
+	default:
+		return 0, false
+	}
+}
+
+// Status of a checkout.
+type Status string
+
+const (
+	// StatusPending checkouts wait for a payment.
+	StatusPending Status = "pending"
+	// StatusProcessing checkouts received payments covering the amount
+	// which are not complete yet, such as unconfirmed transactions.
+	StatusProcessing Status = "processing"
+	// StatusPaid checkouts received complete payments covering the amount.
+	StatusPaid Status = "paid"
+	// StatusUnderpaid checkouts received less than the amount. A checkout
+	// paid by a Liquid or Bitcoin address can still be topped up.
+	StatusUnderpaid Status = "underpaid"
+	// StatusExpired checkouts expired without payment. A payment received
+	// later, by an address still being watched, updates the checkout.
+	StatusExpired Status = "expired"
+)
+
+// Request is a checkout to create.
+type Request struct {
+	// OrderId identifies the order. Creating a checkout for an existing
+	// order returns the existing checkout. Required.
+	OrderId string `json:"order_id"`
+	// AmountSat is the amount due in sat. Alternatively, set FiatAmount
+	// and Currency.
+	AmountSat uint64 `json:"amount_sat,omitempty"`
+	// FiatAmount is the amount due in Currency, such as "USD", converted
+	// with the current rate.
+	FiatAmount float64 `json:"fiat_amount,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+	// Methods are the ways the checkout can be paid. Defaults to
+	// DefaultMethods.
+	Methods []Method `json:"methods,omitempty"`
+	// Description is shown to the payer, in the invoice for instance.
+	Description string `json:"description,omitempty"`
+	// Ttl is how long the checkout can be paid. Defaults to Options.Ttl.
+	// It is encoded in JSON as ttl_sec, in whole seconds.
+	Ttl time.Duration `json:"-"`
+	// Metadata is kept with the checkout for the merchant.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// request has the fields of Request without its JSON methods.
+type request Request
+
+type requestJSON struct {
+	request
+	TtlSec uint64 `json:"ttl_sec,omitempty"`
+}
+
+// MarshalJSON encodes Ttl in seconds.
+func (r Request) MarshalJSON() ([]byte, error) {
+	v := requestJSON{request: request(r)}
+	if r.Ttl > 0 {
+		v.TtlSec = uint64(r.Ttl / time.Second)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes Ttl from seconds.
+func (r *Request) UnmarshalJSON(data []byte) error {
+	var v requestJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Request(v.request)
+	r.Ttl = time.Duration(v.TtlSec) * time.Second
+	return nil
+}
+
+// Offer is the destination of a method of a checkout.
+type Offer struct {
+	Method Method `json:"method"`
+	// Destination is the invoice, or the address or BIP21 URI, to show to
+	// the payer.
+	Destination string `json:"destination"`
+	// FeesSat are the fees deducted from the amount received.
+	FeesSat uint64 `json:"fees_sat"`
+	// ExpiresAt is the expiry of an invoice.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// LiquidExpirationBlockheight and BitcoinExpirationBlockheight are the
+	// heights at which the swap behind the offer expires.
+	LiquidExpirationBlockheight  *uint32 `json:"liquid_expiration_blockheight,omitempty"`
+	BitcoinExpirationBlockheight *uint32 `json:"bitcoin_expiration_blockheight,omitempty"`
+	// PaymentHash is the payment hash of an invoice.
+	PaymentHash string `json:"payment_hash,omitempty"`
+	// Expired is set once the offer can no longer be paid.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// Payment is a payment received for a checkout.
+type Payment struct {
+	PaymentId string `json:"payment_id"`
+	Method    Method `json:"method"`
+	// PayerAmountSat is the amount sent by the payer, fees included.
+	PayerAmountSat uint64    `json:"payer_amount_sat"`
+	FeesSat        uint64    `json:"fees_sat"`
+	State          string    `json:"state"`
+	TxId           string    `json:"tx_id,omitempty"`
+	ReceivedAt     time.Time `json:"received_at"`
+}
+
+// Session is a checkout and its progress.
+type Session struct {
+	Id        string            `json:"id"`
+	OrderId   string            `json:"order_id"`
+	AmountSat uint64            `json:"amount_sat"`
+	Fiat      *FiatAmount       `json:"fiat,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Status    Status            `json:"status"`
+	Offers    []Offer           `json:"offers"`
+	// Unavailable are the errors of the requested methods which could not
+	// be offered, such as an amount outside of their limits.
+	Unavailable map[Method]string `json:"unavailable,omitempty"`
+	Payments    []Payment         `json:"payments,omitempty"`
+	// PaidSat is the amount of the payments which did not fail, as sent
+	// by the payers.
+	PaidSat   uint64    `json:"paid_sat"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	PaidAt    time.Time `json:"paid_at,omitempty"`
+}
+
+// FiatAmount is the fiat amount of a checkout and the rate it was
+// converted at.
+type FiatAmount struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	// Rate is the price of a bitcoin in Currency.
+	Rate float64 `json:"rate"`
+}
+
+func (s *Session) clone() Session {
+	c := *s
+	if s.Fiat != nil {
+		fiat := *s.Fiat
+		c.Fiat = &fiat
+	}
+	c.Metadata = copyMetadata(s.Metadata)
+	if s.Unavailable != nil {
+		c.Unavailable = make(map[Method]string, len(s.Unavailable))
+		for k, v := range s.Unavailable {
+			c.Unavailable[k] = v
+		}
+	}
+	c.Offers = append([]Offer(nil), s.Offers...)
+	c.Payments = append([]Payment(nil), s.Payments...)
+	return c
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
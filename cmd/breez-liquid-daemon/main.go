@@ -0,0 +1,77 @@
+// Command breez-liquid-daemon serves a connected SDK over JSON-RPC 2.0 on a
+// Unix socket, see package jsonrpc.
+//
+// Usage:
+//
+//	BREEZ_MNEMONIC="..." breez-liquid-daemon -socket /run/breez.sock -nwc
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkconnect"
+	"github.com/breez/breez-sdk-liquid-go/jsonrpc"
+)
+
+func main() {
+	var (
+		conn      sdkconnect.Flags
+		socket    = flag.String("socket", "", "Unix socket to listen on, defaults to breez.sock in the data directory")
+		nwc       = flag.Bool("nwc", false, "enable the NWC service and its nwc. methods")
+		nwcRelays = flag.String("nwc-relays", "", "comma-separated NWC relay URLs, defaults to the SDK's")
+	)
+	conn.Register(flag.CommandLine)
+	flag.Parse()
+	if *socket == "" {
+		*socket = filepath.Join(conn.DataDir, "breez.sock")
+	}
+
+	sdk, err := conn.Connect()
+	if err != nil {
+		log.Fatalf("connecting: %v", err)
+	}
+	defer func() {
+		if err := sdk.Disconnect(); err != nil {
+			log.Printf("disconnecting: %v", err)
+		}
+	}()
+
+	var opts jsonrpc.Options
+	if *nwc {
+		var config breez_sdk_liquid.NwcConfig
+		if *nwcRelays != "" {
+			relays := strings.Split(*nwcRelays, ",")
+			config.RelayUrls = &relays
+		}
+		service, sdkErr := sdk.UseNwcPlugin(config)
+		if sdkErr != nil {
+			log.Printf("starting NWC: %v", sdkErr)
+			return
+		}
+		defer service.Stop()
+		opts.Nwc = service
+	}
+
+	l, err := jsonrpc.Listen(*socket)
+	if err != nil {
+		log.Printf("listening: %v", err)
+		return
+	}
+	defer os.Remove(*socket)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("listening on %s", *socket)
+	if err := jsonrpc.New(sdk, opts).Serve(ctx, l); err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("serving: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Command breez-liquid-lnbits serves the LNbits wallet API of package lnbits
+// over a connected SDK.
+//
+// Usage:
+//
+//	BREEZ_MNEMONIC="..." LNBITS_ADMIN_KEY=... LNBITS_INVOICE_KEY=... breez-liquid-lnbits -listen :5000
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkconnect"
+	"github.com/breez/breez-sdk-liquid-go/lnbits"
+)
+
+func main() {
+	var (
+		conn         sdkconnect.Flags
+		listen       = flag.String("listen", "127.0.0.1:5000", "address to listen on")
+		adminKey     = flag.String("admin-key", os.Getenv("LNBITS_ADMIN_KEY"), "admin API key, defaults to $LNBITS_ADMIN_KEY")
+		invoiceKey   = flag.String("invoice-key", os.Getenv("LNBITS_INVOICE_KEY"), "invoice API key, defaults to $LNBITS_INVOICE_KEY")
+		walletName   = flag.String("wallet-name", lnbits.DefaultWalletName, "wallet name reported to clients")
+		privateHooks = flag.Bool("allow-private-webhooks", false, "allow invoice webhooks on loopback, link-local and private addresses")
+	)
+	conn.Register(flag.CommandLine)
+	flag.Parse()
+
+	sdk, err := conn.Connect()
+	if err != nil {
+		log.Fatalf("connecting: %v", err)
+	}
+	defer func() {
+		if err := sdk.Disconnect(); err != nil {
+			log.Printf("disconnecting: %v", err)
+		}
+	}()
+
+	shim, err := lnbits.New(sdk, lnbits.Options{
+		AdminKey:             *adminKey,
+		InvoiceKey:           *invoiceKey,
+		WalletName:           *walletName,
+		AllowPrivateWebhooks: *privateHooks,
+	})
+	if err != nil {
+		log.Printf("%v", err)
+		return
+	}
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           shim,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := shim.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("webhooks: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	log.Printf("listening on %s", *listen)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("serving: %v", err)
+	}
+}
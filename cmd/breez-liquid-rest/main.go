@@ -0,0 +1,64 @@
+// Command breez-liquid-rest serves the REST API of package restapi over a
+// connected SDK.
+//
+// Usage:
+//
+//	BREEZ_MNEMONIC="..." breez-liquid-rest -listen :8080 -token secret
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkconnect"
+	"github.com/breez/breez-sdk-liquid-go/restapi"
+)
+
+func main() {
+	var (
+		conn   sdkconnect.Flags
+		listen = flag.String("listen", "127.0.0.1:8080", "address to listen on")
+		token  = flag.String("token", os.Getenv("BREEZ_REST_TOKEN"), "bearer token required from clients, defaults to $BREEZ_REST_TOKEN")
+	)
+	conn.Register(flag.CommandLine)
+	flag.Parse()
+
+	sdk, err := conn.Connect()
+	if err != nil {
+		log.Fatalf("connecting: %v", err)
+	}
+	defer func() {
+		if err := sdk.Disconnect(); err != nil {
+			log.Printf("disconnecting: %v", err)
+		}
+	}()
+
+	srv := &http.Server{
+		Addr:              *listen,
+		Handler:           restapi.New(sdk, restapi.Options{Token: *token}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+
+	if *token == "" {
+		log.Printf("warning: no -token set, the API is unauthenticated")
+	}
+	log.Printf("listening on %s", *listen)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("serving: %v", err)
+	}
+}
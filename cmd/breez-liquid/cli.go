@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+)
+
+// errCancelled is returned when the user declines a confirmation.
+var errCancelled = errors.New("cancelled")
+
+// cli runs commands against a connected SDK.
+type cli struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	nwc  breez_sdk_liquid.BindingNwcServiceInterface
+	json bool
+	out  io.Writer
+	in   *bufio.Reader
+}
+
+// close stops the NWC service, if started, and disconnects the SDK.
+func (c *cli) close() {
+	if c.nwc != nil {
+		c.nwc.Stop()
+	}
+	if err := c.sdk.Disconnect(); err != nil {
+		fmt.Fprintf(os.Stderr, "disconnecting: %v\n", err)
+	}
+}
+
+// run runs the command named by args[0].
+func (c *cli) run(args []string) error {
+	if args[0] == "help" {
+		return help(args[1:])
+	}
+	cmd, ok := lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q, run help for the list", args[0])
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	err := cmd.run(c, fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+// parseArgs parses the flags of a command and checks that it is given
+// between min and max arguments.
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if n := fs.NArg(); n < min || n > max {
+		fs.Usage()
+		return fmt.Errorf("%s: wrong number of arguments", fs.Name())
+	}
+	return nil
+}
+
+// nwcService returns the NWC service, starting it on first use. It does
+// not listen to relay events: connections are only managed.
+func (c *cli) nwcService() (breez_sdk_liquid.BindingNwcServiceInterface, error) {
+	if c.nwc == nil {
+		listen := false
+		service, sdkErr := c.sdk.UseNwcPlugin(breez_sdk_liquid.NwcConfig{ListenToEvents: &listen})
+		if sdkErr != nil {
+			return nil, sdkErr
+		}
+		c.nwc = service
+	}
+	return c.nwc, nil
+}
+
+// confirm asks a yes/no question on the standard error, returning true
+// without asking if yes is set.
+func (c *cli) confirm(question string, yes bool) bool {
+	if yes {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	line, _ := c.in.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// print prints the result of a command: its JSON encoding with -json, else
+// the output of human, or a generic listing of the value if human is nil.
+func (c *cli) print(v any, human func(w io.Writer)) {
+	if c.json {
+		data, err := json.MarshalIndent(sdkjson.Encode(v), "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "encoding: %v\n", err)
+			return
+		}
+		fmt.Fprintf(c.out, "%s\n", data)
+		return
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	if human != nil {
+		human(tw)
+	} else {
+		printValue(tw, sdkjson.Encode(v), "")
+	}
+	tw.Flush()
+}
+
+// done reports the success of a command without result.
+func (c *cli) done(msg string) {
+	c.print(struct{}{}, func(w io.Writer) { fmt.Fprintln(w, msg) })
+}
+
+// printError prints err, as {"error": {...}} with -json.
+func (c *cli) printError(err error) {
+	if c.json {
+		data, _ := json.Marshal(map[string]sdkjson.Error{"error": sdkjson.EncodeError(err)})
+		fmt.Fprintf(c.out, "%s\n", data)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "error: %v\n", err)
+}
+
+// printValue lists a value encoded by sdkjson, one member per line.
+func printValue(w io.Writer, v any, indent string) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if tag, ok := v[sdkjson.TypeKey]; ok {
+			fmt.Fprintf(w, "%s%s:\t%v\n", indent, sdkjson.TypeKey, tag)
+		}
+		for _, key := range keys {
+			if key == sdkjson.TypeKey || v[key] == nil {
+				continue
+			}
+			switch member := v[key].(type) {
+			case map[string]any, []any:
+				fmt.Fprintf(w, "%s%s:\n", indent, key)
+				printValue(w, member, indent+"  ")
+			default:
+				fmt.Fprintf(w, "%s%s:\t%v\n", indent, key, member)
+			}
+		}
+	case []any:
+		if len(v) == 0 {
+			fmt.Fprintf(w, "%s(none)\n", indent)
+		}
+		for i, item := range v {
+			switch item.(type) {
+			case map[string]any, []any:
+				fmt.Fprintf(w, "%s[%d]\n", indent, i)
+				printValue(w, item, indent+"  ")
+			default:
+				fmt.Fprintf(w, "%s- %v\n", indent, item)
+			}
+		}
+	default:
+		fmt.Fprintf(w, "%s%v\n", indent, v)
+	}
+}
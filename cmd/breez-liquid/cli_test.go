@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+func newCli(sdk *sdktest.Fake, input string) (*cli, *bytes.Buffer) {
+	var out bytes.Buffer
+	return &cli{sdk: sdk, json: true, out: &out, in: bufio.NewReader(strings.NewReader(input))}, &out
+}
+
+func TestSendJSON(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 5000, FeesSat: 10}
+	c, out := newCli(sdk, "")
+	if err := c.run([]string{"info"}); err != nil {
+		t.Fatal(err)
+	}
+	var info struct {
+		WalletInfo struct {
+			BalanceSat uint64 `json:"balance_sat"`
+		} `json:"wallet_info"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil || info.WalletInfo.BalanceSat != 5000 {
+		t.Fatalf("info: %s", out)
+	}
+
+	out.Reset()
+	if err := c.run([]string{"send", "-amount", "1000", "-yes", "lq1"}); err != nil {
+		t.Fatal(err)
+	}
+	var payment struct {
+		AmountSat uint64 `json:"amount_sat"`
+		TxId      string `json:"tx_id"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &payment); err != nil || payment.AmountSat != 1000 || sdk.Sends != 1 {
+		t.Fatalf("payment: %s after %d sends", out, sdk.Sends)
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 5000}
+	c, out := newCli(sdk, "n\n")
+	// Declined.
+	if err := c.run([]string{"send", "-amount", "1000", "lq1"}); !errors.Is(err, errCancelled) || sdk.Sends != 0 {
+		t.Fatalf("got %v after %d sends", err, sdk.Sends)
+	}
+	if err := c.run([]string{"send", "lq1", "extra"}); err == nil {
+		t.Fatal("extra argument accepted")
+	}
+	if err := c.run([]string{"unknown"}); err == nil {
+		t.Fatal("unknown command accepted")
+	}
+
+	out.Reset()
+	err := c.run([]string{"send", "-yes", "not-an-address"})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	c.printError(err)
+	var res struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	if jsonErr := json.Unmarshal(out.Bytes(), &res); jsonErr != nil || res.Error.Type == "" {
+		t.Fatalf("error printed as %s", out)
+	}
+}
@@ -0,0 +1,672 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/payflow"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// command is a subcommand. run defines its flags on fs, parses args and
+// runs it.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(c *cli, fs *flag.FlagSet, args []string) error
+}
+
+var commands = []command{
+	{"info", "", "Show the wallet balance and the blockchain tips.", (*cli).info},
+	{"sync", "", "Sync the wallet with the swapper and the chains.", (*cli).sync},
+	{"parse", "<input>", "Parse an invoice, offer, address, LNURL or BIP353 address.", (*cli).parse},
+	{"send", "[-amount sat] [-comment text] [-yes] <destination>", "Pay any destination accepted by parse.", (*cli).send},
+	{"receive", "[-method bolt11|bolt12|liquid|bitcoin] [-amount sat] [-description text] [-yes]", "Create an invoice, offer or address to receive a payment.", (*cli).receive},
+	{"payments", "[-type send|receive] [-state list] [-from time] [-to time] [-offset n] [-limit n] [-asc]", "List payments.", (*cli).payments},
+	{"payment", "[-swap] <payment hash or swap id>", "Show a payment.", (*cli).payment},
+	{"limits", "", "Show the Lightning and onchain swap limits.", (*cli).limits},
+	{"fees", "", "Show the recommended Bitcoin fee rates.", (*cli).fees},
+	{"refundables", "", "List the failed Bitcoin swaps which can be refunded.", (*cli).refundables},
+	{"refund", "[-fee-rate sat/vbyte] [-yes] <swap address> <refund address>", "Refund a failed Bitcoin swap.", (*cli).refund},
+	{"rescan", "", "Rescan the onchain swaps for refundable funds.", (*cli).rescan},
+	{"lnurl-pay", "-amount sat [-comment text] [-yes] <lnurl>", "Pay an LNURL-pay service or Lightning address.", (*cli).lnurlPay},
+	{"lnurl-withdraw", "[-amount sat] [-description text] <lnurl>", "Withdraw from an LNURL-withdraw service.", (*cli).lnurlWithdraw},
+	{"lnurl-auth", "[-yes] <lnurl>", "Log in to an LNURL-auth service.", (*cli).lnurlAuth},
+	{"sign", "<message>", "Sign a message with the wallet key.", (*cli).sign},
+	{"check-message", "<message> <pubkey> <signature>", "Check a message signature.", (*cli).checkMessage},
+	{"fiat-rates", "", "Show the fiat exchange rates.", (*cli).fiatRates},
+	{"fiat-currencies", "", "List the fiat currencies.", (*cli).fiatCurrencies},
+	{"backup", "[-path file]", "Back up the wallet.", (*cli).backup},
+	{"restore", "[-path file]", "Restore the wallet from a backup.", (*cli).restore},
+	{"nwc", "list | add | edit | remove | payments ...", "Manage Nostr Wallet Connect connections.", (*cli).nwcCommand},
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "commands:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(tw, "  help\tShow the usage of a command.\n")
+	tw.Flush()
+}
+
+// help prints the list of commands or the usage of one.
+func help(args []string) error {
+	if len(args) == 0 {
+		printCommands(os.Stdout)
+		return nil
+	}
+	cmd, ok := lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	fmt.Printf("usage: %s %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+	return nil
+}
+
+func (c *cli) info(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	info, sdkErr := c.sdk.GetInfo()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	c.print(info, func(w io.Writer) {
+		fmt.Fprintf(w, "Balance:\t%d sat\n", info.WalletInfo.BalanceSat)
+		fmt.Fprintf(w, "Pending send:\t%d sat\n", info.WalletInfo.PendingSendSat)
+		fmt.Fprintf(w, "Pending receive:\t%d sat\n", info.WalletInfo.PendingReceiveSat)
+		for _, asset := range info.WalletInfo.AssetBalances {
+			fmt.Fprintf(w, "Asset %s:\t%d\n", assetName(asset), asset.BalanceSat)
+		}
+		fmt.Fprintf(w, "Fingerprint:\t%s\n", info.WalletInfo.Fingerprint)
+		fmt.Fprintf(w, "Pubkey:\t%s\n", info.WalletInfo.Pubkey)
+		fmt.Fprintf(w, "Liquid tip:\t%d\n", info.BlockchainInfo.LiquidTip)
+		fmt.Fprintf(w, "Bitcoin tip:\t%d\n", info.BlockchainInfo.BitcoinTip)
+	})
+	return nil
+}
+
+func assetName(asset breez_sdk_liquid.AssetBalance) string {
+	if asset.Ticker != nil {
+		return *asset.Ticker
+	}
+	return asset.AssetId
+}
+
+func (c *cli) sync(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := c.sdk.Sync().AsError(); err != nil {
+		return err
+	}
+	c.done("Synced.")
+	return nil
+}
+
+func (c *cli) parse(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	input, sdkErr := c.sdk.Parse(fs.Arg(0))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	c.print(input, nil)
+	return nil
+}
+
+func (c *cli) send(fs *flag.FlagSet, args []string) error {
+	amount := fs.Uint64("amount", 0, "amount in sat, required if the destination sets none")
+	comment := fs.String("comment", "", "comment sent to LNURL services")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	input, sdkErr := c.sdk.Parse(fs.Arg(0))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	return c.pay(fs.Arg(0), input, *amount, *comment, *yes)
+}
+
+func (c *cli) lnurlPay(fs *flag.FlagSet, args []string) error {
+	amount := fs.Uint64("amount", 0, "amount in sat")
+	comment := fs.String("comment", "", "comment sent to the service")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	input, sdkErr := c.sdk.Parse(fs.Arg(0))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	if payflow.Kind(input) != payflow.KindLnurlPay {
+		return fmt.Errorf("not an LNURL-pay: %s", sdkjson.VariantTag(input))
+	}
+	return c.pay(fs.Arg(0), input, *amount, *comment, *yes)
+}
+
+// pay prepares a payment, asks for its confirmation and sends it.
+func (c *cli) pay(destination string, input breez_sdk_liquid.InputType, amountSat uint64, comment string, yes bool) error {
+	p, err := payflow.Prepare(c.sdk, destination, input, amountSat, comment)
+	if err != nil {
+		return err
+	}
+	question := fmt.Sprintf("Pay %d sat to this %s with %d sat fees?", p.AmountSat, p.Kind, p.FeesSat)
+	if !c.confirm(question, yes) {
+		return errCancelled
+	}
+	payment, err := p.Execute(c.sdk)
+	if err != nil {
+		return err
+	}
+	c.print(payment, func(w io.Writer) { printPayment(w, payment) })
+	return nil
+}
+
+// parseMethod parses the name of a receive method.
+func parseMethod(name string) (breez_sdk_liquid.PaymentMethod, error) {
+	switch name {
+	case "bolt11", "lightning":
+		return breez_sdk_liquid.PaymentMethodBolt11Invoice, nil
+	case "bolt12":
+		return breez_sdk_liquid.PaymentMethodBolt12Offer, nil
+	case "liquid":
+		return breez_sdk_liquid.PaymentMethodLiquidAddress, nil
+	case "bitcoin":
+		return breez_sdk_liquid.PaymentMethodBitcoinAddress, nil
+	default:
+		return 0, fmt.Errorf("unknown method %q, expected bolt11, bolt12, liquid or bitcoin", name)
+	}
+}
+
+func (c *cli) receive(fs *flag.FlagSet, args []string) error {
+	methodName := fs.String("method", "bolt11", "bolt11, bolt12, liquid or bitcoin")
+	amount := fs.Uint64("amount", 0, "amount paid by the payer in sat, optional except for bolt11")
+	description := fs.String("description", "", "invoice description")
+	yes := fs.Bool("yes", false, "do not ask to confirm the fees")
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	method, err := parseMethod(*methodName)
+	if err != nil {
+		return err
+	}
+	req := breez_sdk_liquid.PrepareReceiveRequest{PaymentMethod: method}
+	if *amount > 0 {
+		var a breez_sdk_liquid.ReceiveAmount = breez_sdk_liquid.ReceiveAmountBitcoin{PayerAmountSat: *amount}
+		req.Amount = &a
+	}
+	prepared, sdkErr := c.sdk.PrepareReceivePayment(req)
+	if sdkErr != nil {
+		return sdkErr
+	}
+	if prepared.FeesSat > 0 && !c.confirm(fmt.Sprintf("Receiving costs %d sat in fees. Continue?", prepared.FeesSat), *yes) {
+		return errCancelled
+	}
+	receiveReq := breez_sdk_liquid.ReceivePaymentRequest{PrepareResponse: prepared}
+	if *description != "" {
+		receiveReq.Description = description
+	}
+	res, sdkErr := c.sdk.ReceivePayment(receiveReq)
+	if sdkErr != nil {
+		return sdkErr
+	}
+	c.print(res, func(w io.Writer) {
+		fmt.Fprintf(w, "Destination:\t%s\n", res.Destination)
+		fmt.Fprintf(w, "Fees:\t%d sat\n", prepared.FeesSat)
+		if prepared.MinPayerAmountSat != nil && prepared.MaxPayerAmountSat != nil {
+			fmt.Fprintf(w, "Payer amount:\t%d to %d sat\n", *prepared.MinPayerAmountSat, *prepared.MaxPayerAmountSat)
+		}
+		if res.LiquidExpirationBlockheight != nil {
+			fmt.Fprintf(w, "Expires at Liquid block:\t%d\n", *res.LiquidExpirationBlockheight)
+		}
+		if res.BitcoinExpirationBlockheight != nil {
+			fmt.Fprintf(w, "Expires at Bitcoin block:\t%d\n", *res.BitcoinExpirationBlockheight)
+		}
+	})
+	return nil
+}
+
+func (c *cli) payments(fs *flag.FlagSet, args []string) error {
+	paymentType := fs.String("type", "", "send or receive")
+	states := fs.String("state", "", "comma-separated states, such as pending,complete")
+	from := fs.String("from", "", "earliest payment time, RFC 3339, date or Unix time")
+	to := fs.String("to", "", "latest payment time, RFC 3339, date or Unix time")
+	offset := fs.Uint("offset", 0, "number of payments to skip")
+	limit := fs.Uint("limit", 0, "maximum number of payments")
+	asc := fs.Bool("asc", false, "oldest first")
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var req breez_sdk_liquid.ListPaymentsRequest
+	if *paymentType != "" {
+		var types []breez_sdk_liquid.PaymentType
+		if err := sdkjson.Decode([]any{*paymentType}, &types); err != nil {
+			return fmt.Errorf("-type: %w", err)
+		}
+		req.Filters = &types
+	}
+	if *states != "" {
+		var list []any
+		for _, s := range strings.Split(*states, ",") {
+			list = append(list, strings.TrimSpace(s))
+		}
+		var values []breez_sdk_liquid.PaymentState
+		if err := sdkjson.Decode(list, &values); err != nil {
+			return fmt.Errorf("-state: %w", err)
+		}
+		req.States = &values
+	}
+	var err error
+	if req.FromTimestamp, err = parseTime(*from, "-from"); err != nil {
+		return err
+	}
+	if req.ToTimestamp, err = parseTime(*to, "-to"); err != nil {
+		return err
+	}
+	if *offset > 0 {
+		n := uint32(*offset)
+		req.Offset = &n
+	}
+	if *limit > 0 {
+		n := uint32(*limit)
+		req.Limit = &n
+	}
+	if *asc {
+		req.SortAscending = asc
+	}
+	payments, sdkErr := c.sdk.ListPayments(req)
+	if sdkErr != nil {
+		return sdkErr
+	}
+	if payments == nil {
+		payments = []breez_sdk_liquid.Payment{}
+	}
+	c.print(payments, func(w io.Writer) {
+		if len(payments) == 0 {
+			fmt.Fprintln(w, "No payments.")
+			return
+		}
+		fmt.Fprintln(w, "TIME\tTYPE\tSTATUS\tAMOUNT\tFEES\tMETHOD\tID")
+		for _, p := range payments {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+				formatTime(p.Timestamp), sdkutil.PaymentTypeName(p.PaymentType), sdkutil.PaymentStateName(p.Status),
+				p.AmountSat, p.FeesSat, sdkutil.PaymentDetailsName(p.Details), sdkutil.PaymentId(p))
+		}
+	})
+	return nil
+}
+
+// parseTime parses a time given as RFC 3339, a date or Unix time.
+func parseTime(v, name string) (*int64, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return &n, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			n := t.Unix()
+			return &n, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: expected RFC 3339 time, date or Unix time", name)
+}
+
+func formatTime(unix uint32) string {
+	return time.Unix(int64(unix), 0).Format("2006-01-02 15:04")
+}
+
+func (c *cli) payment(fs *flag.FlagSet, args []string) error {
+	bySwap := fs.Bool("swap", false, "look the payment up by swap id")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	var req breez_sdk_liquid.GetPaymentRequest = breez_sdk_liquid.GetPaymentRequestPaymentHash{PaymentHash: fs.Arg(0)}
+	if *bySwap {
+		req = breez_sdk_liquid.GetPaymentRequestSwapId{SwapId: fs.Arg(0)}
+	}
+	payment, sdkErr := c.sdk.GetPayment(req)
+	if sdkErr != nil {
+		return sdkErr
+	}
+	if payment == nil {
+		return fmt.Errorf("payment not found")
+	}
+	c.print(*payment, func(w io.Writer) { printPayment(w, *payment) })
+	return nil
+}
+
+func printPayment(w io.Writer, p breez_sdk_liquid.Payment) {
+	fmt.Fprintf(w, "Type:\t%s\n", sdkutil.PaymentTypeName(p.PaymentType))
+	fmt.Fprintf(w, "Status:\t%s\n", sdkutil.PaymentStateName(p.Status))
+	fmt.Fprintf(w, "Amount:\t%d sat\n", p.AmountSat)
+	fmt.Fprintf(w, "Fees:\t%d sat\n", p.FeesSat)
+	fmt.Fprintf(w, "Time:\t%s\n", formatTime(p.Timestamp))
+	if p.Destination != nil {
+		fmt.Fprintf(w, "Destination:\t%s\n", *p.Destination)
+	}
+	if p.TxId != nil {
+		fmt.Fprintf(w, "Transaction:\t%s\n", *p.TxId)
+	}
+	if swapId := sdkutil.SwapId(p); swapId != "" {
+		fmt.Fprintf(w, "Swap:\t%s\n", swapId)
+	}
+	if hash := sdkutil.PaymentHash(p); hash != "" {
+		fmt.Fprintf(w, "Payment hash:\t%s\n", hash)
+	}
+	fmt.Fprintln(w, "Details:")
+	printValue(w, sdkjson.Encode(p.Details), "  ")
+}
+
+func (c *cli) limits(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	lightning, sdkErr := c.sdk.FetchLightningLimits()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	onchain, sdkErr := c.sdk.FetchOnchainLimits()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	res := struct {
+		Lightning breez_sdk_liquid.LightningPaymentLimitsResponse
+		Onchain   breez_sdk_liquid.OnchainPaymentLimitsResponse
+	}{lightning, onchain}
+	c.print(res, func(w io.Writer) {
+		fmt.Fprintln(w, "\tMIN\tMAX\tMAX ZERO-CONF")
+		row := func(name string, l breez_sdk_liquid.Limits) {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", name, l.MinSat, l.MaxSat, l.MaxZeroConfSat)
+		}
+		row("Lightning send", lightning.Send)
+		row("Lightning receive", lightning.Receive)
+		row("Onchain send", onchain.Send)
+		row("Onchain receive", onchain.Receive)
+	})
+	return nil
+}
+
+func (c *cli) fees(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	fees, sdkErr := c.sdk.RecommendedFees()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	c.print(fees, func(w io.Writer) {
+		fmt.Fprintf(w, "Fastest:\t%d sat/vbyte\n", fees.FastestFee)
+		fmt.Fprintf(w, "Half hour:\t%d sat/vbyte\n", fees.HalfHourFee)
+		fmt.Fprintf(w, "Hour:\t%d sat/vbyte\n", fees.HourFee)
+		fmt.Fprintf(w, "Economy:\t%d sat/vbyte\n", fees.EconomyFee)
+		fmt.Fprintf(w, "Minimum:\t%d sat/vbyte\n", fees.MinimumFee)
+	})
+	return nil
+}
+
+func (c *cli) refundables(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	swaps, sdkErr := c.sdk.ListRefundables()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	if swaps == nil {
+		swaps = []breez_sdk_liquid.RefundableSwap{}
+	}
+	c.print(swaps, func(w io.Writer) {
+		if len(swaps) == 0 {
+			fmt.Fprintln(w, "No refundable swaps.")
+			return
+		}
+		fmt.Fprintln(w, "TIME\tAMOUNT\tSWAP ADDRESS\tLAST REFUND TX")
+		for _, s := range swaps {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", formatTime(s.Timestamp), s.AmountSat, s.SwapAddress, sdkutil.StringValue(s.LastRefundTxId))
+		}
+	})
+	return nil
+}
+
+func (c *cli) refund(fs *flag.FlagSet, args []string) error {
+	feeRate := fs.Uint("fee-rate", 0, "fee rate in sat/vbyte, defaults to the recommended hour fee")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	rate := uint32(*feeRate)
+	if rate == 0 {
+		fees, sdkErr := c.sdk.RecommendedFees()
+		if sdkErr != nil {
+			return sdkErr
+		}
+		rate = uint32(fees.HourFee)
+	}
+	prepareReq := breez_sdk_liquid.PrepareRefundRequest{SwapAddress: fs.Arg(0), RefundAddress: fs.Arg(1), FeeRateSatPerVbyte: rate}
+	prepared, sdkErr := c.sdk.PrepareRefund(prepareReq)
+	if sdkErr != nil {
+		return sdkErr
+	}
+	if !c.confirm(fmt.Sprintf("Refund to %s with %d sat fees (%d sat/vbyte)?", fs.Arg(1), prepared.TxFeeSat, rate), *yes) {
+		return errCancelled
+	}
+	res, payErr := c.sdk.Refund(breez_sdk_liquid.RefundRequest(prepareReq))
+	if payErr != nil {
+		return payErr
+	}
+	c.print(res, func(w io.Writer) { fmt.Fprintf(w, "Refund transaction:\t%s\n", res.RefundTxId) })
+	return nil
+}
+
+func (c *cli) rescan(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if err := c.sdk.RescanOnchainSwaps().AsError(); err != nil {
+		return err
+	}
+	c.done("Rescanned.")
+	return nil
+}
+
+func (c *cli) lnurlWithdraw(fs *flag.FlagSet, args []string) error {
+	amount := fs.Uint64("amount", 0, "amount in sat, defaults to the maximum withdrawable")
+	description := fs.String("description", "", "invoice description, defaults to the service's")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	input, sdkErr := c.sdk.Parse(fs.Arg(0))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	withdraw, ok := input.(breez_sdk_liquid.InputTypeLnUrlWithdraw)
+	if !ok {
+		return fmt.Errorf("not an LNURL-withdraw: %s", sdkjson.VariantTag(input))
+	}
+	amountMsat := *amount * 1000
+	if amountMsat == 0 {
+		amountMsat = withdraw.Data.MaxWithdrawable
+	}
+	if amountMsat < withdraw.Data.MinWithdrawable || amountMsat > withdraw.Data.MaxWithdrawable {
+		return fmt.Errorf("amount out of range: %d to %d sat", withdraw.Data.MinWithdrawable/1000, withdraw.Data.MaxWithdrawable/1000)
+	}
+	req := breez_sdk_liquid.LnUrlWithdrawRequest{Data: withdraw.Data, AmountMsat: amountMsat}
+	if *description != "" {
+		req.Description = description
+	}
+	res, withdrawErr := c.sdk.LnurlWithdraw(req)
+	if withdrawErr != nil {
+		return withdrawErr
+	}
+	c.print(res, func(w io.Writer) {
+		switch res := res.(type) {
+		case breez_sdk_liquid.LnUrlWithdrawResultOk:
+			fmt.Fprintf(w, "Withdrawal requested, invoice:\t%s\n", res.Data.Invoice.Bolt11)
+		case breez_sdk_liquid.LnUrlWithdrawResultTimeout:
+			fmt.Fprintf(w, "Withdrawal pending, invoice:\t%s\n", res.Data.Invoice.Bolt11)
+		case breez_sdk_liquid.LnUrlWithdrawResultErrorStatus:
+			fmt.Fprintf(w, "Withdrawal failed:\t%s\n", res.Data.Reason)
+		}
+	})
+	return nil
+}
+
+func (c *cli) lnurlAuth(fs *flag.FlagSet, args []string) error {
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	input, sdkErr := c.sdk.Parse(fs.Arg(0))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	auth, ok := input.(breez_sdk_liquid.InputTypeLnUrlAuth)
+	if !ok {
+		return fmt.Errorf("not an LNURL-auth: %s", sdkjson.VariantTag(input))
+	}
+	action := "login"
+	if auth.Data.Action != nil {
+		action = *auth.Data.Action
+	}
+	if !c.confirm(fmt.Sprintf("Authenticate (%s) to %s?", action, auth.Data.Domain), *yes) {
+		return errCancelled
+	}
+	res, authErr := c.sdk.LnurlAuth(auth.Data)
+	if authErr != nil {
+		return authErr
+	}
+	c.print(res, func(w io.Writer) {
+		switch res := res.(type) {
+		case breez_sdk_liquid.LnUrlCallbackStatusOk:
+			fmt.Fprintf(w, "Authenticated to %s.\n", auth.Data.Domain)
+		case breez_sdk_liquid.LnUrlCallbackStatusErrorStatus:
+			fmt.Fprintf(w, "Authentication failed:\t%s\n", res.Data.Reason)
+		}
+	})
+	return nil
+}
+
+func (c *cli) sign(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	res, sdkErr := c.sdk.SignMessage(breez_sdk_liquid.SignMessageRequest{Message: fs.Arg(0)})
+	if sdkErr != nil {
+		return sdkErr
+	}
+	c.print(res, func(w io.Writer) { fmt.Fprintln(w, res.Signature) })
+	return nil
+}
+
+func (c *cli) checkMessage(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 3, 3); err != nil {
+		return err
+	}
+	res, sdkErr := c.sdk.CheckMessage(breez_sdk_liquid.CheckMessageRequest{Message: fs.Arg(0), Pubkey: fs.Arg(1), Signature: fs.Arg(2)})
+	if sdkErr != nil {
+		return sdkErr
+	}
+	c.print(res, func(w io.Writer) {
+		if res.IsValid {
+			fmt.Fprintln(w, "Valid signature.")
+		} else {
+			fmt.Fprintln(w, "Invalid signature.")
+		}
+	})
+	return nil
+}
+
+func (c *cli) fiatRates(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	rates, sdkErr := c.sdk.FetchFiatRates()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Coin < rates[j].Coin })
+	c.print(rates, func(w io.Writer) {
+		fmt.Fprintln(w, "CURRENCY\tBTC PRICE")
+		for _, r := range rates {
+			fmt.Fprintf(w, "%s\t%.2f\n", r.Coin, r.Value)
+		}
+	})
+	return nil
+}
+
+func (c *cli) fiatCurrencies(fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	currencies, sdkErr := c.sdk.ListFiatCurrencies()
+	if sdkErr != nil {
+		return sdkErr
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Id < currencies[j].Id })
+	c.print(currencies, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME")
+		for _, cur := range currencies {
+			fmt.Fprintf(w, "%s\t%s\n", cur.Id, cur.Info.Name)
+		}
+	})
+	return nil
+}
+
+func (c *cli) backup(fs *flag.FlagSet, args []string) error {
+	path := fs.String("path", "", "backup file, defaults to the SDK's")
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	var req breez_sdk_liquid.BackupRequest
+	if *path != "" {
+		req.BackupPath = path
+	}
+	if err := c.sdk.Backup(req).AsError(); err != nil {
+		return err
+	}
+	c.done("Backed up.")
+	return nil
+}
+
+func (c *cli) restore(fs *flag.FlagSet, args []string) error {
+	path := fs.String("path", "", "backup file, defaults to the SDK's")
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	var req breez_sdk_liquid.RestoreRequest
+	if *path != "" {
+		req.BackupPath = path
+	}
+	if err := c.sdk.Restore(req).AsError(); err != nil {
+		return err
+	}
+	c.done("Restored.")
+	return nil
+}
@@ -0,0 +1,72 @@
+// Command breez-liquid is a command-line wallet covering the SDK API:
+// sending to any destination, receiving, payment history, refunds, LNURL,
+// message signing, fiat rates, backups and NWC connections.
+//
+// Usage:
+//
+//	breez-liquid [flags] command [command flags] [args]
+//	breez-liquid [flags]
+//
+// Without a command, commands are read interactively from the standard
+// input. Run "breez-liquid help" for the list of commands. With -json,
+// results are printed as JSON documents encoded like the REST API's, and
+// errors as {"error": {...}}.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkconnect"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var (
+		conn    sdkconnect.Flags
+		jsonOut = flag.Bool("json", false, "print results as JSON")
+	)
+	conn.Register(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: breez-liquid [flags] [command [args]]\n\nflags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr)
+		printCommands(os.Stderr)
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "help" {
+		return exitCode(help(args[1:]))
+	}
+
+	sdk, err := conn.Connect()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "connecting: %v\n", err)
+		return 1
+	}
+	c := &cli{sdk: sdk, json: *jsonOut, out: os.Stdout, in: bufio.NewReader(os.Stdin)}
+	defer c.close()
+
+	if len(args) == 0 {
+		return exitCode(c.repl())
+	}
+	if err := c.run(args); err != nil {
+		c.printError(err)
+		return 1
+	}
+	return 0
+}
+
+func exitCode(err error) int {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+const nwcUsage = `usage:
+  nwc list
+  nwc add [-receive-only] [-expiry-mins n] [-budget-sat n] [-renewal-mins n] <name>
+  nwc edit [-receive-only=true|false] [-expiry-mins n] [-remove-expiry] [-budget-sat n] [-renewal-mins n] [-remove-budget] <name>
+  nwc remove [-yes] <name>
+  nwc payments <name>`
+
+// nwcCommand runs the nwc subcommand named by args[0].
+func (c *cli) nwcCommand(fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("nwc: missing subcommand\n%s", nwcUsage)
+	}
+	nwc, err := c.nwcService()
+	if err != nil {
+		return err
+	}
+	sub := flag.NewFlagSet("nwc "+args[0], flag.ContinueOnError)
+	sub.Usage = func() { fmt.Fprintln(sub.Output(), nwcUsage) }
+	switch args[0] {
+	case "list":
+		return c.nwcList(nwc, sub, args[1:])
+	case "add":
+		return c.nwcAdd(nwc, sub, args[1:])
+	case "edit":
+		return c.nwcEdit(nwc, sub, args[1:])
+	case "remove":
+		return c.nwcRemove(nwc, sub, args[1:])
+	case "payments":
+		return c.nwcPayments(nwc, sub, args[1:])
+	default:
+		return fmt.Errorf("nwc: unknown subcommand %q\n%s", args[0], nwcUsage)
+	}
+}
+
+func (c *cli) nwcList(nwc breez_sdk_liquid.BindingNwcServiceInterface, fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+	connections, nwcErr := nwc.ListConnections()
+	if nwcErr != nil {
+		return nwcErr
+	}
+	if connections == nil {
+		connections = map[string]breez_sdk_liquid.NwcConnection{}
+	}
+	names := make([]string, 0, len(connections))
+	for name := range connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c.print(connections, func(w io.Writer) {
+		if len(names) == 0 {
+			fmt.Fprintln(w, "No connections.")
+			return
+		}
+		fmt.Fprintln(w, "NAME\tCREATED\tRECEIVE ONLY\tPAID\tBUDGET\tEXPIRES")
+		for _, name := range names {
+			conn := connections[name]
+			fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\n", name, formatTime(conn.CreatedAt), conn.ReceiveOnly, conn.PaidAmountSat, budget(conn), expiry(conn))
+		}
+	})
+	return nil
+}
+
+func budget(conn breez_sdk_liquid.NwcConnection) string {
+	if conn.PeriodicBudget == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", conn.PeriodicBudget.UsedBudgetSat, conn.PeriodicBudget.MaxBudgetSat)
+}
+
+func expiry(conn breez_sdk_liquid.NwcConnection) string {
+	if conn.ExpiresAt == nil {
+		return "never"
+	}
+	return formatTime(*conn.ExpiresAt)
+}
+
+// budgetFlags are the flags setting a connection's periodic budget.
+type budgetFlags struct {
+	expiryMins  uint
+	budgetSat   uint64
+	renewalMins uint
+}
+
+func (b *budgetFlags) register(fs *flag.FlagSet) {
+	fs.UintVar(&b.expiryMins, "expiry-mins", 0, "minutes until the connection expires")
+	fs.Uint64Var(&b.budgetSat, "budget-sat", 0, "maximum amount spent per budget period")
+	fs.UintVar(&b.renewalMins, "renewal-mins", 0, "length of the budget period in minutes, unset for a budget never renewed")
+}
+
+func (b *budgetFlags) expiry() *uint32 {
+	if b.expiryMins == 0 {
+		return nil
+	}
+	mins := uint32(b.expiryMins)
+	return &mins
+}
+
+func (b *budgetFlags) budget() *breez_sdk_liquid.PeriodicBudgetRequest {
+	if b.budgetSat == 0 {
+		return nil
+	}
+	req := &breez_sdk_liquid.PeriodicBudgetRequest{MaxBudgetSat: b.budgetSat}
+	if b.renewalMins > 0 {
+		mins := uint32(b.renewalMins)
+		req.RenewalTimeMins = &mins
+	}
+	return req
+}
+
+func (c *cli) nwcAdd(nwc breez_sdk_liquid.BindingNwcServiceInterface, fs *flag.FlagSet, args []string) error {
+	var b budgetFlags
+	b.register(fs)
+	receiveOnly := fs.Bool("receive-only", false, "only allow receiving")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	req := breez_sdk_liquid.AddConnectionRequest{
+		Name:              fs.Arg(0),
+		ExpiryTimeMins:    b.expiry(),
+		PeriodicBudgetReq: b.budget(),
+	}
+	if *receiveOnly {
+		req.ReceiveOnly = receiveOnly
+	}
+	res, nwcErr := nwc.AddConnection(req)
+	if nwcErr != nil {
+		return nwcErr
+	}
+	c.print(res, func(w io.Writer) { printConnection(w, fs.Arg(0), res.Connection) })
+	return nil
+}
+
+func (c *cli) nwcEdit(nwc breez_sdk_liquid.BindingNwcServiceInterface, fs *flag.FlagSet, args []string) error {
+	var b budgetFlags
+	b.register(fs)
+	receiveOnly := fs.Bool("receive-only", false, "only allow receiving")
+	removeExpiry := fs.Bool("remove-expiry", false, "remove the expiry")
+	removeBudget := fs.Bool("remove-budget", false, "remove the periodic budget")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	req := breez_sdk_liquid.EditConnectionRequest{
+		Name:              fs.Arg(0),
+		ExpiryTimeMins:    b.expiry(),
+		PeriodicBudgetReq: b.budget(),
+	}
+	// Only send the flags which were given.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "receive-only":
+			req.ReceiveOnly = receiveOnly
+		case "remove-expiry":
+			req.RemoveExpiry = removeExpiry
+		case "remove-budget":
+			req.RemovePeriodicBudget = removeBudget
+		}
+	})
+	res, nwcErr := nwc.EditConnection(req)
+	if nwcErr != nil {
+		return nwcErr
+	}
+	c.print(res, func(w io.Writer) { printConnection(w, fs.Arg(0), res.Connection) })
+	return nil
+}
+
+func printConnection(w io.Writer, name string, conn breez_sdk_liquid.NwcConnection) {
+	fmt.Fprintf(w, "Name:\t%s\n", name)
+	fmt.Fprintf(w, "Connection string:\t%s\n", conn.ConnectionString)
+	fmt.Fprintf(w, "Receive only:\t%t\n", conn.ReceiveOnly)
+	fmt.Fprintf(w, "Budget:\t%s\n", budget(conn))
+	fmt.Fprintf(w, "Expires:\t%s\n", expiry(conn))
+}
+
+func (c *cli) nwcRemove(nwc breez_sdk_liquid.BindingNwcServiceInterface, fs *flag.FlagSet, args []string) error {
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if !c.confirm(fmt.Sprintf("Remove connection %q?", fs.Arg(0)), *yes) {
+		return errCancelled
+	}
+	if err := nwc.RemoveConnection(fs.Arg(0)).AsError(); err != nil {
+		return err
+	}
+	c.done("Removed.")
+	return nil
+}
+
+func (c *cli) nwcPayments(nwc breez_sdk_liquid.BindingNwcServiceInterface, fs *flag.FlagSet, args []string) error {
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	payments, nwcErr := nwc.ListConnectionPayments(fs.Arg(0))
+	if nwcErr != nil {
+		return nwcErr
+	}
+	if payments == nil {
+		payments = []breez_sdk_liquid.Payment{}
+	}
+	c.print(payments, func(w io.Writer) {
+		if len(payments) == 0 {
+			fmt.Fprintln(w, "No payments.")
+			return
+		}
+		fmt.Fprintln(w, "TIME\tTYPE\tSTATUS\tAMOUNT\tFEES\tID")
+		for _, p := range payments {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\n", formatTime(p.Timestamp), sdkutil.PaymentTypeName(p.PaymentType),
+				sdkutil.PaymentStateName(p.Status), p.AmountSat, p.FeesSat, sdkutil.PaymentId(p))
+		}
+	})
+	return nil
+}
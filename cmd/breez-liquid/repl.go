@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+const prompt = "breez> "
+
+// repl reads commands from the standard input until "exit" or its end.
+// Payment events are reported as they happen.
+func (c *cli) repl() error {
+	listenerId, sdkErr := c.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if payment, ok := sdkutil.EventPayment(e); ok {
+			fmt.Fprintf(os.Stderr, "\n[%s] %s %d sat %s\n%s", sdkutil.EventName(e), sdkutil.PaymentTypeName(payment.PaymentType),
+				payment.AmountSat, sdkutil.PaymentId(payment), prompt)
+		}
+	}))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	defer func() { _ = c.sdk.RemoveEventListener(listenerId) }()
+
+	fmt.Fprintln(os.Stderr, `Type "help" for the list of commands, "exit" to quit.`)
+	for {
+		fmt.Fprint(os.Stderr, prompt)
+		line, err := c.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(os.Stderr)
+				return nil
+			}
+			return err
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			c.printError(err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+		if err := c.run(args); err != nil {
+			c.printError(err)
+		}
+	}
+}
+
+// splitArgs splits a command line into arguments separated by spaces.
+// Single and double quotes group arguments and a backslash escapes the
+// next character outside single quotes.
+func splitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,221 @@
+package eventhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AdminHandler returns the handler of the admin API:
+//
+//	GET    /subscriptions                 subscriptions, without secrets
+//	POST   /subscriptions                 adds a subscription, returned with its secret
+//	GET    /subscriptions/{id}            a subscription, without its secret
+//	PUT    /subscriptions/{id}            updates a subscription
+//	DELETE /subscriptions/{id}            removes a subscription and its deliveries
+//	GET    /deliveries                    deliveries, ?state=, ?subscription= and ?event=
+//	GET    /deliveries/{id}               a delivery
+//	POST   /deliveries/{id}/redeliver     attempts a delivery again
+//	GET    /dead-letters                  dead-lettered deliveries
+//	DELETE /dead-letters                  purges them, ?before= an RFC 3339 time
+//	GET    /stats                         number of deliveries in each state
+//
+// Mount it with http.StripPrefix under a path of your choice. Errors are
+// reported as {"error": message}.
+func (d *Dispatcher) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subscriptions", d.serveSubscriptions)
+	mux.HandleFunc("/subscriptions/", d.serveSubscription)
+	mux.HandleFunc("/deliveries", d.serveDeliveries)
+	mux.HandleFunc("/deliveries/", d.serveDelivery)
+	mux.HandleFunc("/dead-letters", d.serveDeadLetters)
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, d.Stats())
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.opts.AdminToken != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(d.opts.AdminToken)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "invalid token")
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (d *Dispatcher) serveSubscriptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		subs := d.Subscriptions()
+		for i := range subs {
+			subs[i].Secret = ""
+		}
+		writeJSON(w, http.StatusOK, subs)
+	case http.MethodPost:
+		var sub Subscription
+		if !readJSON(w, r, &sub) {
+			return
+		}
+		sub, err := d.AddSubscription(sub)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, sub)
+	default:
+		allow(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (d *Dispatcher) serveSubscription(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/subscriptions/")
+	switch r.Method {
+	case http.MethodGet:
+		sub, ok := d.Subscription(id)
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrNotFound.Error())
+			return
+		}
+		sub.Secret = ""
+		writeJSON(w, http.StatusOK, sub)
+	case http.MethodPut:
+		var sub Subscription
+		if !readJSON(w, r, &sub) {
+			return
+		}
+		sub.Id = id
+		sub, err := d.UpdateSubscription(sub)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			sub.Secret = ""
+			writeJSON(w, http.StatusOK, sub)
+		}
+	case http.MethodDelete:
+		if err := d.RemoveSubscription(id); err != nil {
+			writeError(w, statusOf(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		allow(w, r, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+func (d *Dispatcher) serveDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	q := r.URL.Query()
+	writeJSON(w, http.StatusOK, d.Deliveries(Filter{
+		State:          State(q.Get("state")),
+		SubscriptionId: q.Get("subscription"),
+		EventType:      q.Get("event"),
+	}))
+}
+
+func (d *Dispatcher) serveDelivery(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/deliveries/")
+	if strings.HasSuffix(id, "/redeliver") {
+		id = strings.TrimSuffix(id, "/redeliver")
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		dl, err := d.Redeliver(id)
+		if err != nil {
+			writeError(w, statusOf(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusAccepted, dl)
+		return
+	}
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	dl, ok := d.Delivery(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrNotFound.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, dl)
+}
+
+func (d *Dispatcher) serveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, d.DeadLetters())
+	case http.MethodDelete:
+		before := time.Now()
+		if s := r.URL.Query().Get("before"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "before must be an RFC 3339 time")
+				return
+			}
+			before = t
+		}
+		n, err := d.Purge(before)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"purged": n})
+	default:
+		allow(w, r, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// allow reports whether r uses one of methods, answering 405 otherwise.
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, r.Method+" not allowed")
+	return false
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidState):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
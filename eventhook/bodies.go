@@ -0,0 +1,127 @@
+package eventhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+)
+
+// bodyRecord is a line of the payloads file.
+type bodyRecord struct {
+	EventId string          `json:"event_id"`
+	Body    json.RawMessage `json:"body"`
+}
+
+// bodies are the payloads of the events, by event id. They are appended to
+// a file of JSON lines as the events are recorded, and the file is
+// rewritten only when compacted.
+type bodies struct {
+	path   string
+	bodies map[string]json.RawMessage
+}
+
+// loadBodies reads the payloads file, truncating a trailing partial line
+// left by an interrupted append.
+func loadBodies(path string) (*bodies, error) {
+	b := &bodies{path: path, bodies: map[string]json.RawMessage{}}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && len(line) == 0 {
+			break
+		}
+		var rec bodyRecord
+		if err != nil || json.Unmarshal(line, &rec) != nil {
+			break
+		}
+		offset += int64(len(line))
+		b.bodies[rec.EventId] = rec.Body
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if offset != info.Size() {
+		if err := f.Truncate(offset); err != nil {
+			return nil, err
+		}
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *bodies) get(eventId string) json.RawMessage {
+	return b.bodies[eventId]
+}
+
+// add appends the payload of an event, unless already saved.
+func (b *bodies) add(eventId string, body json.RawMessage) error {
+	if _, ok := b.bodies[eventId]; ok {
+		return nil
+	}
+	line, err := json.Marshal(bodyRecord{EventId: eventId, Body: body})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(b.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	b.bodies[eventId] = body
+	return nil
+}
+
+// compact deletes the payloads of the events not in used and rewrites the
+// file if any was deleted.
+func (b *bodies) compact(used map[string]bool) error {
+	pruned := false
+	for eventId := range b.bodies {
+		if !used[eventId] {
+			delete(b.bodies, eventId)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	var data []byte
+	for eventId, body := range b.bodies {
+		line, err := json.Marshal(bodyRecord{EventId: eventId, Body: body})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	return filestore.WriteFile(b.path, data)
+}
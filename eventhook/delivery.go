@@ -0,0 +1,144 @@
+package eventhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// State of a delivery.
+type State string
+
+const (
+	// StatePending deliveries are attempted once NextAttemptAt is reached.
+	StatePending State = "pending"
+	// StateSucceeded deliveries were acknowledged with a 2xx status.
+	StateSucceeded State = "succeeded"
+	// StateDead deliveries failed permanently or exhausted their attempts.
+	// They stay in the dead-letter store until redelivered or purged.
+	StateDead State = "dead"
+)
+
+// RetryPolicy decides how failed deliveries are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of failed attempts after which a delivery
+	// is dead-lettered.
+	MaxAttempts int `json:"max_attempts"`
+	// InitialBackoff is the delay before the first retry. It doubles on
+	// each retry, up to MaxBackoff.
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+}
+
+// DefaultRetryPolicy is used when Options.Retry is not set.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    8,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     6 * time.Hour,
+}
+
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < failures && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Subscription sends the events of some types to an endpoint.
+type Subscription struct {
+	Id  string `json:"id"`
+	Url string `json:"url"`
+	// Secret is the HMAC-SHA256 key signing the payloads. Generated if
+	// empty when the subscription is added.
+	Secret string `json:"secret,omitempty"`
+	// Events are the event types sent, such as "payment_succeeded". Empty
+	// sends all of them.
+	Events []string `json:"events,omitempty"`
+	// Disabled subscriptions get no new deliveries.
+	Disabled  bool      `json:"disabled,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// matches reports whether the subscription wants events of type eventType.
+func (s *Subscription) matches(eventType string) bool {
+	if s.Disabled {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, t := range s.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the body posted to the endpoints.
+type Payload struct {
+	// Id identifies the event: it is the same in the deliveries of the
+	// event to every subscription, and for an event read from the event
+	// log, in every delivery of its sequence number, so that endpoints can
+	// drop duplicates.
+	Id        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	// Seq is the sequence number of the event in the event log, if the
+	// events are read from one.
+	Seq uint64 `json:"seq,omitempty"`
+	// Data is the event, encoded as by the JSON APIs of this module.
+	Data json.RawMessage `json:"data"`
+}
+
+// Delivery is the delivery of an event to a subscription, and its
+// progress.
+type Delivery struct {
+	Id             string `json:"id"`
+	SubscriptionId string `json:"subscription_id"`
+	EventId        string `json:"event_id"`
+	EventType      string `json:"event_type"`
+	// Body is the payload posted. It is persisted once per event, apart
+	// from the deliveries.
+	Body      json.RawMessage `json:"body,omitempty"`
+	State     State           `json:"state"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	// Failures is the number of failed attempts.
+	Failures      int       `json:"failures"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   time.Time `json:"delivered_at,omitempty"`
+	// LastStatus is the HTTP status of the last attempt, zero if it got no
+	// response.
+	LastStatus int    `json:"last_status,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+	// Retry, if set, overrides Options.Retry. Redeliver sets it to extend
+	// the attempts of a dead delivery.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+}
+
+// StatusError is the error of an attempt answered with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("endpoint answered %s", e.Status)
+}
+
+// permanent reports whether a delivery answered with status can never
+// succeed: client errors other than timeouts and rate limiting.
+func permanent(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400 && status < 500
+}
@@ -0,0 +1,753 @@
+// Package eventhook posts the SDK events to HTTP endpoints.
+//
+// Each Subscription names an endpoint URL, the event types it wants and the
+// secret signing its payloads with HMAC-SHA256 (see Sign and Verify). Every
+// event creates a persisted Delivery per matching subscription, posted by a
+// pool of workers while Run is running. Failed attempts are retried with
+// exponential backoff; deliveries answered with a client error, or which
+// exhaust their attempts, are dead-lettered and kept until redelivered or
+// purged.
+//
+// Events are received from the SDK while Run is running, or read from an
+// eventlog.Log given as Options.Log, in which case the events emitted while
+// the process was down are delivered too.
+//
+// AdminHandler serves an API to manage the subscriptions, inspect the
+// deliveries and redeliver them.
+package eventhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/eventlog"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultConcurrency  = 4
+	DefaultTimeout      = 10 * time.Second
+	DefaultPollInterval = 15 * time.Second
+	DefaultRetention    = 7 * 24 * time.Hour
+	DefaultCursorName   = "eventhook"
+)
+
+// EventTypes are the event types subscriptions can select.
+var EventTypes = []string{
+	"payment_failed",
+	"payment_pending",
+	"payment_refundable",
+	"payment_refunded",
+	"payment_refund_pending",
+	"payment_succeeded",
+	"payment_waiting_confirmation",
+	"payment_waiting_fee_acceptance",
+	"synced",
+	"sync_failed",
+	"data_synced",
+}
+
+var (
+	// ErrNotFound is returned for unknown subscription or delivery ids.
+	ErrNotFound = errors.New("eventhook: not found")
+	// ErrInvalidState is returned when redelivering a delivery being
+	// attempted.
+	ErrInvalidState = errors.New("eventhook: invalid delivery state")
+)
+
+// Options configures a Dispatcher.
+type Options struct {
+	// StatePath is the file the subscriptions and deliveries are persisted
+	// to, the payloads being persisted to StatePath + ".bodies". Required.
+	StatePath string
+	// Log, if set, is the source of the events, read from the cursor
+	// saved under CursorName.
+	Log *eventlog.Log
+	// CursorName is the name of the cursor in Log. Defaults to
+	// DefaultCursorName.
+	CursorName string
+	// Concurrency is the number of deliveries attempted at once. Defaults
+	// to DefaultConcurrency.
+	Concurrency int
+	// Timeout bounds an attempt. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Retry is the retry policy of deliveries. Defaults to
+	// DefaultRetryPolicy.
+	Retry *RetryPolicy
+	// PollInterval between scans for due deliveries. Defaults to
+	// DefaultPollInterval.
+	PollInterval time.Duration
+	// Retention is how long succeeded deliveries are kept. Defaults to
+	// DefaultRetention.
+	Retention time.Duration
+	// AdminToken, if set, must be sent to AdminHandler as a bearer token.
+	AdminToken string
+	// Client posts the deliveries. Defaults to http.DefaultClient.
+	Client *http.Client
+	// OnUpdate, if set, is called whenever a delivery changes.
+	OnUpdate func(Delivery)
+	// OnDeadLetter, if set, is called when a delivery is dead-lettered.
+	OnDeadLetter func(Delivery)
+	// OnError, if set, is called with the errors recording events.
+	OnError func(error)
+}
+
+// state is the persisted state.
+type state struct {
+	Subscriptions map[string]*Subscription `json:"subscriptions"`
+	Deliveries    map[string]*Delivery     `json:"deliveries"`
+}
+
+// Dispatcher delivers the SDK events to the subscriptions.
+type Dispatcher struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	trigger chan struct{}
+
+	mu    sync.Mutex
+	state state
+	// bodies are the payloads of the events of the deliveries. They are
+	// saved apart so that the attempts only rewrite the state of the
+	// deliveries, and recording an event only appends its payload.
+	bodies   *bodies
+	inFlight map[string]bool
+}
+
+// New creates a Dispatcher and loads its persisted state.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Dispatcher, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("eventhook: StatePath is required")
+	}
+	if opts.CursorName == "" {
+		opts.CursorName = DefaultCursorName
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Retry == nil {
+		opts.Retry = &DefaultRetryPolicy
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	d := &Dispatcher{
+		sdk:      sdk,
+		opts:     opts,
+		trigger:  make(chan struct{}, 1),
+		inFlight: map[string]bool{},
+	}
+	if err := filestore.Load(opts.StatePath, &d.state); err != nil {
+		return nil, fmt.Errorf("eventhook: loading state: %w", err)
+	}
+	bodies, err := loadBodies(opts.StatePath + ".bodies")
+	if err != nil {
+		return nil, fmt.Errorf("eventhook: loading payloads: %w", err)
+	}
+	d.bodies = bodies
+	if d.state.Subscriptions == nil {
+		d.state.Subscriptions = map[string]*Subscription{}
+	}
+	if d.state.Deliveries == nil {
+		d.state.Deliveries = map[string]*Delivery{}
+	}
+	return d, nil
+}
+
+// AddSubscription adds a subscription, generating its id and secret if
+// empty, and returns it.
+func (d *Dispatcher) AddSubscription(sub Subscription) (Subscription, error) {
+	if err := checkSubscription(sub); err != nil {
+		return Subscription{}, err
+	}
+	for _, field := range []*string{&sub.Id, &sub.Secret} {
+		if *field == "" {
+			id, err := newId()
+			if err != nil {
+				return Subscription{}, err
+			}
+			*field = id
+		}
+	}
+	now := time.Now().UTC()
+	sub.CreatedAt, sub.UpdatedAt = now, now
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.state.Subscriptions[sub.Id]; ok {
+		return Subscription{}, fmt.Errorf("eventhook: subscription %q already exists", sub.Id)
+	}
+	d.state.Subscriptions[sub.Id] = &sub
+	if err := d.saveLocked(); err != nil {
+		delete(d.state.Subscriptions, sub.Id)
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// UpdateSubscription replaces the URL, event types and disabled flag of a
+// subscription, and its secret if sub.Secret is set. Pending deliveries are
+// signed with the new secret.
+func (d *Dispatcher) UpdateSubscription(sub Subscription) (Subscription, error) {
+	if err := checkSubscription(sub); err != nil {
+		return Subscription{}, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	existing, ok := d.state.Subscriptions[sub.Id]
+	if !ok {
+		return Subscription{}, ErrNotFound
+	}
+	updated := *existing
+	updated.Url, updated.Events, updated.Disabled = sub.Url, sub.Events, sub.Disabled
+	if sub.Secret != "" {
+		updated.Secret = sub.Secret
+	}
+	updated.UpdatedAt = time.Now().UTC()
+	d.state.Subscriptions[sub.Id] = &updated
+	if err := d.saveLocked(); err != nil {
+		d.state.Subscriptions[sub.Id] = existing
+		return Subscription{}, err
+	}
+	return updated, nil
+}
+
+// RemoveSubscription removes a subscription and its deliveries.
+func (d *Dispatcher) RemoveSubscription(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.state.Subscriptions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.state.Subscriptions, id)
+	for deliveryId, dl := range d.state.Deliveries {
+		if dl.SubscriptionId == id {
+			delete(d.state.Deliveries, deliveryId)
+		}
+	}
+	if err := d.saveLocked(); err != nil {
+		return err
+	}
+	return d.pruneBodiesLocked()
+}
+
+// Subscription returns a subscription.
+func (d *Dispatcher) Subscription(id string) (Subscription, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sub, ok := d.state.Subscriptions[id]
+	if !ok {
+		return Subscription{}, false
+	}
+	return *sub, true
+}
+
+// Subscriptions returns the subscriptions ordered by creation time.
+func (d *Dispatcher) Subscriptions() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	subs := make([]Subscription, 0, len(d.state.Subscriptions))
+	for _, sub := range d.state.Subscriptions {
+		subs = append(subs, *sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].Id < subs[j].Id
+	})
+	return subs
+}
+
+func checkSubscription(sub Subscription) error {
+	u, err := url.Parse(sub.Url)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("eventhook: subscription URL must be an absolute http(s) URL")
+	}
+	for _, t := range sub.Events {
+		if !knownEventType(t) {
+			return fmt.Errorf("eventhook: unknown event type %q", t)
+		}
+	}
+	return nil
+}
+
+func knownEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery returns a delivery.
+func (d *Dispatcher) Delivery(id string) (Delivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl, ok := d.state.Deliveries[id]
+	if !ok {
+		return Delivery{}, false
+	}
+	return d.withBody(dl), true
+}
+
+// Filter selects deliveries. Zero fields match all deliveries.
+type Filter struct {
+	State          State
+	SubscriptionId string
+	EventType      string
+}
+
+func (f Filter) matches(dl *Delivery) bool {
+	return (f.State == "" || dl.State == f.State) &&
+		(f.SubscriptionId == "" || dl.SubscriptionId == f.SubscriptionId) &&
+		(f.EventType == "" || dl.EventType == f.EventType)
+}
+
+// Deliveries returns the deliveries matching f, ordered by creation time.
+func (d *Dispatcher) Deliveries(f Filter) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	deliveries := []Delivery{}
+	for _, dl := range d.state.Deliveries {
+		if f.matches(dl) {
+			deliveries = append(deliveries, d.withBody(dl))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].Id < deliveries[j].Id
+	})
+	return deliveries
+}
+
+// DeadLetters returns the dead-lettered deliveries.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	return d.Deliveries(Filter{State: StateDead})
+}
+
+// Redeliver attempts a delivery again as soon as possible: a dead delivery
+// gets a fresh retry budget, a succeeded one is sent again.
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	d.mu.Lock()
+	dl, ok := d.state.Deliveries[id]
+	if !ok {
+		d.mu.Unlock()
+		return Delivery{}, ErrNotFound
+	}
+	if d.inFlight[id] {
+		d.mu.Unlock()
+		return Delivery{}, ErrInvalidState
+	}
+	if dl.State != StatePending {
+		retry := *d.policy(dl)
+		retry.MaxAttempts += dl.Failures
+		dl.Retry = &retry
+	}
+	dl.State, dl.NextAttemptAt = StatePending, time.Time{}
+	dl.UpdatedAt = time.Now().UTC()
+	delivery := d.withBody(dl)
+	err := d.saveLocked()
+	d.mu.Unlock()
+	d.notify(delivery)
+	d.Trigger()
+	return delivery, err
+}
+
+// Purge deletes the dead-lettered deliveries created before t and returns
+// their number.
+func (d *Dispatcher) Purge(before time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for id, dl := range d.state.Deliveries {
+		if dl.State == StateDead && dl.CreatedAt.Before(before) {
+			delete(d.state.Deliveries, id)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err := d.saveLocked(); err != nil {
+		return n, err
+	}
+	return n, d.pruneBodiesLocked()
+}
+
+// Stats returns the number of deliveries in each state.
+func (d *Dispatcher) Stats() map[State]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := map[State]int{}
+	for _, dl := range d.state.Deliveries {
+		stats[dl.State]++
+	}
+	return stats
+}
+
+// Record creates the deliveries of an event received at t. seq is its
+// sequence number in the event log, zero if it does not come from one.
+// The events are recorded by Run: Record is for events from other sources.
+func (d *Dispatcher) Record(e breez_sdk_liquid.SdkEvent, t time.Time, seq uint64) error {
+	data, err := sdkjson.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return d.record(sdkutil.EventName(e), data, t, seq)
+}
+
+func (d *Dispatcher) record(eventType string, data json.RawMessage, t time.Time, seq uint64) error {
+	t = t.UTC()
+	// An event read from the log is recorded again if the process stopped
+	// before its cursor was saved: its id is derived from its sequence
+	// number and time so that it keeps its deliveries.
+	var eventId string
+	if seq > 0 {
+		eventId = derivedId(fmt.Sprintf("%d\x00%d", seq, t.UnixNano()))
+	} else {
+		id, err := newId()
+		if err != nil {
+			return err
+		}
+		eventId = id
+	}
+	payload, err := json.Marshal(Payload{Id: eventId, Type: eventType, CreatedAt: t, Seq: seq, Data: data})
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var created []string
+	for _, sub := range d.state.Subscriptions {
+		if !sub.matches(eventType) {
+			continue
+		}
+		id := derivedId(eventId + "\x00" + sub.Id)
+		if _, ok := d.state.Deliveries[id]; ok {
+			continue
+		}
+		d.state.Deliveries[id] = &Delivery{
+			Id:             id,
+			SubscriptionId: sub.Id,
+			EventId:        eventId,
+			EventType:      eventType,
+			State:          StatePending,
+			CreatedAt:      t,
+			UpdatedAt:      t,
+		}
+		created = append(created, id)
+	}
+	if len(created) == 0 {
+		return nil
+	}
+	// The payload is saved first, so that no delivery lacks it.
+	if err := d.bodies.add(eventId, payload); err != nil {
+		for _, id := range created {
+			delete(d.state.Deliveries, id)
+		}
+		return err
+	}
+	if err := d.saveLocked(); err != nil {
+		for _, id := range created {
+			delete(d.state.Deliveries, id)
+		}
+		return err
+	}
+	d.Trigger()
+	return nil
+}
+
+// Trigger makes Run scan the deliveries immediately.
+func (d *Dispatcher) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run records the events and attempts the due deliveries until ctx is
+// done, then waits for the attempts in flight.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 1)
+	if d.opts.Log != nil {
+		go func() {
+			errs <- d.opts.Log.Consume(ctx, d.opts.CursorName, func(r eventlog.Record) error {
+				return d.record(r.Type, r.Event, r.Time, r.Seq)
+			})
+		}()
+	} else {
+		listenerId, sdkErr := d.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+			if err := d.Record(e, time.Now(), 0); err != nil && d.opts.OnError != nil {
+				d.opts.OnError(fmt.Errorf("eventhook: recording event: %w", err))
+			}
+		}))
+		if sdkErr != nil {
+			return sdkErr
+		}
+		defer func() { _ = d.sdk.RemoveEventListener(listenerId) }()
+	}
+
+	slots := make(chan struct{}, d.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.prune()
+		d.dispatch(ctx, slots, &wg)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return fmt.Errorf("eventhook: reading event log: %w", err)
+		case <-ticker.C:
+		case <-d.trigger:
+		}
+	}
+}
+
+// dispatch starts attempting the due deliveries while workers are
+// available.
+func (d *Dispatcher) dispatch(ctx context.Context, slots chan struct{}, wg *sync.WaitGroup) {
+	for _, dl := range d.due() {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return
+		}
+		if !d.claim(dl.Id) {
+			<-slots
+			continue
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			d.attempt(ctx, id)
+		}(dl.Id)
+	}
+}
+
+func (d *Dispatcher) due() []Delivery {
+	now := time.Now()
+	var due []Delivery
+	for _, dl := range d.Deliveries(Filter{State: StatePending}) {
+		if !dl.NextAttemptAt.After(now) {
+			due = append(due, dl)
+		}
+	}
+	return due
+}
+
+func (d *Dispatcher) claim(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl, ok := d.state.Deliveries[id]
+	if !ok || dl.State != StatePending || d.inFlight[id] {
+		return false
+	}
+	d.inFlight[id] = true
+	return true
+}
+
+// attempt posts a delivery and records the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, id string) {
+	defer func() {
+		d.mu.Lock()
+		delete(d.inFlight, id)
+		d.mu.Unlock()
+	}()
+	dl, ok := d.Delivery(id)
+	if !ok {
+		return
+	}
+	sub, ok := d.Subscription(dl.SubscriptionId)
+	if !ok {
+		return
+	}
+
+	status, retryAfter, err := d.post(ctx, sub, dl)
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by the shutdown: attempted again on the next run.
+		return
+	}
+	var dead bool
+	delivery := d.update(id, func(dl *Delivery) {
+		now := time.Now().UTC()
+		dl.LastStatus = status
+		if err == nil {
+			dl.State, dl.DeliveredAt, dl.LastError = StateSucceeded, now, ""
+			dl.NextAttemptAt = time.Time{}
+			return
+		}
+		dl.Failures++
+		dl.LastError = err.Error()
+		policy := d.policy(dl)
+		if permanent(status) || dl.Failures >= policy.MaxAttempts {
+			dl.State, dl.NextAttemptAt, dead = StateDead, time.Time{}, true
+			return
+		}
+		delay := policy.backoff(dl.Failures)
+		if retryAfter > delay {
+			delay = retryAfter
+			if delay > policy.MaxBackoff {
+				delay = policy.MaxBackoff
+			}
+		}
+		dl.NextAttemptAt = now.Add(delay)
+	})
+	if dead && d.opts.OnDeadLetter != nil {
+		d.opts.OnDeadLetter(delivery)
+	}
+}
+
+// post sends a delivery and returns the response status and the delay
+// asked by a Retry-After header.
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, dl Delivery) (int, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url, bytes.NewReader(dl.Body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, dl.EventType)
+	req.Header.Set(HeaderDelivery, dl.Id)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, time.Now(), dl.Body))
+	res, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode/100 == 2 {
+		return res.StatusCode, 0, nil
+	}
+	return res.StatusCode, parseRetryAfter(res.Header.Get("Retry-After")), &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+}
+
+// parseRetryAfter parses a Retry-After header, given in seconds or as an
+// HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(t); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// prune deletes the succeeded deliveries older than Options.Retention.
+func (d *Dispatcher) prune() {
+	cutoff := time.Now().Add(-d.opts.Retention)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	pruned := false
+	for id, dl := range d.state.Deliveries {
+		if dl.State == StateSucceeded && dl.DeliveredAt.Before(cutoff) && !d.inFlight[id] {
+			delete(d.state.Deliveries, id)
+			pruned = true
+		}
+	}
+	if pruned && d.saveLocked() == nil {
+		_ = d.pruneBodiesLocked()
+	}
+}
+
+func (d *Dispatcher) policy(dl *Delivery) *RetryPolicy {
+	if dl.Retry != nil {
+		return dl.Retry
+	}
+	return d.opts.Retry
+}
+
+// update applies a change to a delivery, persists and notifies it.
+func (d *Dispatcher) update(id string, change func(dl *Delivery)) Delivery {
+	d.mu.Lock()
+	dl, ok := d.state.Deliveries[id]
+	if !ok {
+		d.mu.Unlock()
+		return Delivery{}
+	}
+	change(dl)
+	dl.UpdatedAt = time.Now().UTC()
+	delivery := d.withBody(dl)
+	_ = d.saveLocked()
+	d.mu.Unlock()
+	d.notify(delivery)
+	return delivery
+}
+
+func (d *Dispatcher) saveLocked() error {
+	return filestore.Save(d.opts.StatePath, d.state)
+}
+
+// pruneBodiesLocked deletes the payloads no delivery refers to.
+func (d *Dispatcher) pruneBodiesLocked() error {
+	used := map[string]bool{}
+	for _, dl := range d.state.Deliveries {
+		used[dl.EventId] = true
+	}
+	return d.bodies.compact(used)
+}
+
+func (d *Dispatcher) withBody(dl *Delivery) Delivery {
+	delivery := *dl
+	delivery.Body = d.bodies.get(dl.EventId)
+	return delivery
+}
+
+func (d *Dispatcher) notify(dl Delivery) {
+	if d.opts.OnUpdate != nil {
+		d.opts.OnUpdate(dl)
+	}
+}
+
+// derivedId returns an id derived from key, in the format of newId.
+func derivedId(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,160 @@
+package eventhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+func newDispatcher(t *testing.T, path string) *Dispatcher {
+	t.Helper()
+	d, err := New(nil, Options{StatePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// endpoint returns a server answering status and recording the bodies
+// posted with a valid signature.
+func endpoint(t *testing.T, secret string, status int) (*httptest.Server, *[][]byte) {
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(secret, r.Header.Get(HeaderSignature), body, 0); err != nil {
+			t.Errorf("delivery %s: %v", r.Header.Get(HeaderDelivery), err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+// deliver attempts the pending deliveries as a worker would, regardless of
+// their backoff.
+func deliver(t *testing.T, d *Dispatcher) []Delivery {
+	t.Helper()
+	var deliveries []Delivery
+	for _, dl := range d.Deliveries(Filter{State: StatePending}) {
+		if !d.claim(dl.Id) {
+			t.Fatalf("delivery %s not claimed", dl.Id)
+		}
+		d.attempt(context.Background(), dl.Id)
+		dl, _ = d.Delivery(dl.Id)
+		deliveries = append(deliveries, dl)
+	}
+	return deliveries
+}
+
+func TestDelivery(t *testing.T) {
+	srv, bodies := endpoint(t, "secret", http.StatusOK)
+	path := filepath.Join(t.TempDir(), "eventhook.json")
+	d := newDispatcher(t, path)
+	if _, err := d.AddSubscription(Subscription{Id: "s", Url: srv.URL, Secret: "secret", Events: []string{"synced"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Record(breez_sdk_liquid.SdkEventSynced{}, time.Now(), 0); err != nil {
+		t.Fatal(err)
+	}
+	// Not subscribed to.
+	if err := d.Record(breez_sdk_liquid.SdkEventDataSynced{}, time.Now(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The deliveries and their payloads survive a restart.
+	d = newDispatcher(t, path)
+	deliveries := deliver(t, d)
+	if len(deliveries) != 1 || deliveries[0].State != StateSucceeded || deliveries[0].LastStatus != http.StatusOK {
+		t.Fatalf("deliveries %+v", deliveries)
+	}
+	var payload Payload
+	if len(*bodies) != 1 || json.Unmarshal((*bodies)[0], &payload) != nil || payload.Type != "synced" {
+		t.Fatalf("posted %q", *bodies)
+	}
+	if string(deliveries[0].Body) != string((*bodies)[0]) {
+		t.Fatalf("body %s, posted %s", deliveries[0].Body, (*bodies)[0])
+	}
+}
+
+func TestDeliveryFailures(t *testing.T) {
+	unavailable, _ := endpoint(t, "secret", http.StatusServiceUnavailable)
+	rejecting, _ := endpoint(t, "secret", http.StatusBadRequest)
+	d := newDispatcher(t, filepath.Join(t.TempDir(), "eventhook.json"))
+	var dead []Delivery
+	d.opts.OnDeadLetter = func(dl Delivery) { dead = append(dead, dl) }
+	for id, url := range map[string]string{"unavailable": unavailable.URL, "rejecting": rejecting.URL} {
+		if _, err := d.AddSubscription(Subscription{Id: id, Url: url, Secret: "secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Record(breez_sdk_liquid.SdkEventSynced{}, time.Now(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	deliver(t, d)
+	// A server error is retried later.
+	retried := d.Deliveries(Filter{SubscriptionId: "unavailable"})
+	if len(retried) != 1 || retried[0].State != StatePending || retried[0].Failures != 1 || !retried[0].NextAttemptAt.After(time.Now()) {
+		t.Fatalf("unavailable: %+v", retried)
+	}
+	// A client error is dead-lettered.
+	if len(dead) != 1 || dead[0].SubscriptionId != "rejecting" || dead[0].LastStatus != http.StatusBadRequest {
+		t.Fatalf("dead letters %+v", dead)
+	}
+	if _, err := d.Redeliver(dead[0].Id); err != nil {
+		t.Fatal(err)
+	}
+	if dl, _ := d.Delivery(dead[0].Id); dl.State != StatePending || len(dl.Body) == 0 {
+		t.Fatalf("redelivered %+v", dl)
+	}
+}
+
+func TestBodiesCompacted(t *testing.T) {
+	srv, _ := endpoint(t, "secret", http.StatusOK)
+	path := filepath.Join(t.TempDir(), "eventhook.json")
+	d := newDispatcher(t, path)
+	if _, err := d.AddSubscription(Subscription{Id: "s", Url: srv.URL, Secret: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := d.Record(breez_sdk_liquid.SdkEventSynced{}, time.Now(), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A payload torn by a crash while appending is dropped.
+	f, err := os.OpenFile(path+".bodies", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"event_id":"torn","bo`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	d = newDispatcher(t, path)
+	if n := len(d.bodies.bodies); n != 2 {
+		t.Fatalf("%d payloads loaded, want 2", n)
+	}
+	if err := d.Record(breez_sdk_liquid.SdkEventSynced{}, time.Now(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := loadBodies(path + ".bodies"); err != nil || len(b.bodies) != 3 {
+		t.Fatalf("appended after the torn payload: %v, %v", b, err)
+	}
+
+	// Removing the deliveries compacts the payloads.
+	if err := d.RemoveSubscription("s"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path + ".bodies"); err != nil || len(data) != 0 {
+		t.Fatalf("payloads %q, %v", data, err)
+	}
+}
@@ -0,0 +1,84 @@
+package eventhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of the deliveries.
+const (
+	// HeaderSignature carries "t=<unix time>,v1=<hex HMAC-SHA256>", the
+	// HMAC of "<unix time>.<body>" keyed with the subscription secret.
+	HeaderSignature = "X-Breez-Signature"
+	// HeaderEvent carries the event type.
+	HeaderEvent = "X-Breez-Event"
+	// HeaderDelivery carries the delivery id, the same across attempts.
+	HeaderDelivery = "X-Breez-Delivery"
+)
+
+// DefaultTolerance is the signature age accepted by Verify when given
+// zero.
+const DefaultTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned by Verify for a missing, malformed,
+// stale or wrong signature.
+var ErrInvalidSignature = errors.New("eventhook: invalid signature")
+
+// Sign returns the HeaderSignature value of body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac(secret, ts, body))
+}
+
+// Verify checks the HeaderSignature value of a received body, for
+// endpoints written in Go. Signatures older than tolerance are rejected,
+// which limits replays.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+	expected := mac(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func mac(secret, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts))
+	h.Write([]byte{'.'})
+	h.Write(body)
+	return h.Sum(nil)
+}
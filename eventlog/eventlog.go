@@ -0,0 +1,305 @@
+// Package eventlog records the SDK events in an append-only log on disk, so
+// that consumers which were down or restarting can catch up.
+//
+// Every event is stored as a Record with a sequence number, increasing by
+// one from 1, the time it was received, its variant tag and its sdkjson
+// encoding. Records are written as JSON lines to segment files named after
+// their first sequence number; a segment is closed once it exceeds
+// Options.SegmentSize and is deleted by Compact once all its records are
+// older than Options.Retention.
+//
+// Consumers read from a cursor, the sequence number of the last record they
+// processed, which they can save in the log under their name.
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultSegmentSize     = 4 << 20
+	DefaultRetention       = 30 * 24 * time.Hour
+	DefaultCompactInterval = time.Hour
+)
+
+var (
+	// ErrCompacted is returned when reading from a cursor whose next
+	// records were compacted away.
+	ErrCompacted = errors.New("eventlog: records after the cursor were compacted")
+	// ErrClosed is returned by the methods of a closed Log.
+	ErrClosed = errors.New("eventlog: closed")
+)
+
+// Record is a logged event.
+type Record struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Type is the variant tag of the event, such as "payment_succeeded".
+	Type  string          `json:"type"`
+	Event json.RawMessage `json:"event"`
+}
+
+// Decode decodes the event of the record.
+func (r Record) Decode() (breez_sdk_liquid.SdkEvent, error) {
+	var e breez_sdk_liquid.SdkEvent
+	if err := sdkjson.Unmarshal(r.Event, &e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Options configures a Log.
+type Options struct {
+	// Dir is the directory of the segments and cursors. Required.
+	Dir string
+	// SegmentSize is the size in bytes past which a segment is closed.
+	// Defaults to DefaultSegmentSize.
+	SegmentSize int64
+	// Retention is how long records are kept by Compact. Defaults to
+	// DefaultRetention.
+	Retention time.Duration
+	// CompactInterval is the interval between the compactions run by Run.
+	// Defaults to DefaultCompactInterval.
+	CompactInterval time.Duration
+	// NoSync skips syncing the segment to disk after every append,
+	// trading durability on power loss for speed.
+	NoSync bool
+	// OnError, if set, is called with the errors of Run's appends and
+	// compactions.
+	OnError func(error)
+}
+
+// Log is an on-disk log of SDK events.
+type Log struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	mu       sync.Mutex
+	segments []*segment
+	active   *os.File
+	lastSeq  uint64
+	appended chan struct{}
+	closed   bool
+}
+
+// New opens the log in Options.Dir, recovering from an interrupted append.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Log, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("eventlog: Dir is required")
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	if opts.CompactInterval <= 0 {
+		opts.CompactInterval = DefaultCompactInterval
+	}
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	l := &Log{sdk: sdk, opts: opts, appended: make(chan struct{})}
+	segments, err := loadSegments(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("eventlog: %w", err)
+	}
+	l.segments = segments
+	if n := len(segments); n > 0 {
+		l.lastSeq = segments[n-1].lastSeq
+		if l.lastSeq == 0 {
+			// The last segment is empty.
+			l.lastSeq = segments[n-1].firstSeq - 1
+		}
+	}
+	return l, nil
+}
+
+// Append logs an event.
+func (l *Log) Append(e breez_sdk_liquid.SdkEvent) (Record, error) {
+	encoded, err := sdkjson.Marshal(e)
+	if err != nil {
+		return Record{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return Record{}, ErrClosed
+	}
+	r := Record{Seq: l.lastSeq + 1, Time: time.Now().UTC(), Type: sdkjson.VariantTag(e), Event: encoded}
+	if err := l.write(r); err != nil {
+		return Record{}, err
+	}
+	l.lastSeq = r.Seq
+	close(l.appended)
+	l.appended = make(chan struct{})
+	return r, nil
+}
+
+// write appends r to the active segment, starting a segment if needed.
+func (l *Log) write(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	seg := l.current()
+	if seg == nil || seg.size >= l.opts.SegmentSize {
+		if err := l.roll(r.Seq); err != nil {
+			return err
+		}
+		seg = l.current()
+	}
+	if l.active == nil {
+		f, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		l.active = f
+	}
+	if _, err := l.active.Write(line); err != nil {
+		// Drop the partial line, if any, so that the segment stays
+		// readable.
+		_ = l.active.Truncate(seg.size)
+		return err
+	}
+	if !l.opts.NoSync {
+		if err := l.active.Sync(); err != nil {
+			// The record is not appended: drop it, or a later append
+			// would reuse its sequence number.
+			_ = l.active.Truncate(seg.size)
+			return err
+		}
+	}
+	seg.size += int64(len(line))
+	seg.lastSeq, seg.lastTime = r.Seq, r.Time
+	return nil
+}
+
+func (l *Log) current() *segment {
+	if len(l.segments) == 0 {
+		return nil
+	}
+	return l.segments[len(l.segments)-1]
+}
+
+// roll closes the active segment and starts one at firstSeq.
+func (l *Log) roll(firstSeq uint64) error {
+	if l.active != nil {
+		if err := l.active.Close(); err != nil {
+			return err
+		}
+		l.active = nil
+	}
+	seg, err := createSegment(l.opts.Dir, firstSeq)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, seg)
+	return nil
+}
+
+// LastSeq returns the sequence number of the last record, zero if none.
+func (l *Log) LastSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastSeq
+}
+
+// FirstSeq returns the sequence number of the oldest record kept, or the
+// next sequence number if the log is empty.
+func (l *Log) FirstSeq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.firstSeq()
+}
+
+func (l *Log) firstSeq() uint64 {
+	if len(l.segments) == 0 {
+		return l.lastSeq + 1
+	}
+	return l.segments[0].firstSeq
+}
+
+// Wait blocks until a record after cursor is appended or ctx is done.
+func (l *Log) Wait(ctx context.Context, cursor uint64) error {
+	for {
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return ErrClosed
+		}
+		if l.lastSeq > cursor {
+			l.mu.Unlock()
+			return nil
+		}
+		appended := l.appended
+		l.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-appended:
+		}
+	}
+}
+
+// Appended returns a channel closed when the next record is appended or the
+// log is closed, for readers waiting on several sources.
+func (l *Log) Appended() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.appended
+}
+
+// Run logs the SDK events and compacts the log until ctx is done.
+func (l *Log) Run(ctx context.Context) error {
+	listenerId, sdkErr := l.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if _, err := l.Append(e); err != nil && l.opts.OnError != nil {
+			l.opts.OnError(fmt.Errorf("eventlog: appending: %w", err))
+		}
+	}))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	defer func() { _ = l.sdk.RemoveEventListener(listenerId) }()
+
+	ticker := time.NewTicker(l.opts.CompactInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := l.Compact(); err != nil && l.opts.OnError != nil {
+			l.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the log. Waiting readers get ErrClosed.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	close(l.appended)
+	if l.active != nil {
+		return l.active.Close()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package eventlog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+func appendSynced(t *testing.T, l *Log, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := l.Append(breez_sdk_liquid.SdkEventDataSynced{DidPullNewRecords: i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRecoverTruncatedTail(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(nil, Options{Dir: dir, NoSync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendSynced(t, l, 3)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate an append interrupted midway through its line.
+	path := segmentPath(dir, 1)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":4,"time":"2024-`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	l, err = New(nil, Options{Dir: dir, NoSync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if got := l.LastSeq(); got != 3 {
+		t.Fatalf("LastSeq = %d, want 3", got)
+	}
+	r, err := l.Append(breez_sdk_liquid.SdkEventSynced{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Seq != 4 {
+		t.Fatalf("appended seq %d, want 4", r.Seq)
+	}
+	records, err := l.Read(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("read %d records, want 4", len(records))
+	}
+	for i, r := range records {
+		if r.Seq != uint64(i+1) {
+			t.Fatalf("record %d has seq %d", i, r.Seq)
+		}
+	}
+	e, err := records[3].Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(breez_sdk_liquid.SdkEventSynced); !ok {
+		t.Fatalf("decoded %T, want SdkEventSynced", e)
+	}
+}
+
+func TestCorruptClosedSegment(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(nil, Options{Dir: dir, NoSync: true, SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendSynced(t, l, 3)
+	l.Close()
+
+	// Only the last segment may have an interrupted append.
+	if err := os.WriteFile(segmentPath(dir, 1), []byte("{\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(nil, Options{Dir: dir}); err == nil {
+		t.Fatal("opened a log with a corrupt closed segment")
+	}
+}
+
+func TestReadAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(nil, Options{Dir: dir, NoSync: true, SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	appendSynced(t, l, 5)
+
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 5 {
+		t.Fatalf("%d segments, want 5", len(matches))
+	}
+	records, err := l.Read(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].Seq != 2 || records[2].Seq != 4 {
+		t.Fatalf("read %+v, want records 2 to 4", records)
+	}
+}
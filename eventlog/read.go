@@ -0,0 +1,197 @@
+package eventlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+)
+
+// DefaultReadMax is the number of records Consume reads at once.
+const DefaultReadMax = 256
+
+// Read returns up to max records after cursor, in order, or ErrCompacted if
+// some of them were compacted away. A cursor of zero reads from the oldest
+// record kept only if none was compacted yet; use FirstSeq()-1 to read from
+// the oldest record regardless.
+func (l *Log) Read(cursor uint64, max int) ([]Record, error) {
+	if max <= 0 {
+		max = DefaultReadMax
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if cursor >= l.lastSeq {
+		l.mu.Unlock()
+		return nil, nil
+	}
+	if cursor+1 < l.firstSeq() {
+		l.mu.Unlock()
+		return nil, ErrCompacted
+	}
+	// Snapshot the segments to read, as appends and compactions go on.
+	type view struct {
+		seg  *segment
+		size int64
+	}
+	var views []view
+	for _, seg := range l.segments {
+		if seg.lastSeq > cursor {
+			views = append(views, view{seg, seg.size})
+		}
+	}
+	l.mu.Unlock()
+
+	var records []Record
+	for _, v := range views {
+		recs, err := v.seg.read(cursor, max-len(records), v.size)
+		if os.IsNotExist(err) {
+			// Compacted since the snapshot.
+			return nil, ErrCompacted
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+		if len(records) == max {
+			break
+		}
+	}
+	return records, nil
+}
+
+// Compact deletes the segments whose records are all older than
+// Options.Retention, except the one being appended to, and returns the
+// number of records deleted.
+func (l *Log) Compact() (int, error) {
+	cutoff := time.Now().Add(-l.opts.Retention)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return 0, ErrClosed
+	}
+	deleted := 0
+	for len(l.segments) > 1 {
+		seg := l.segments[0]
+		if !seg.lastTime.Before(cutoff) {
+			break
+		}
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			return deleted, fmt.Errorf("eventlog: compacting: %w", err)
+		}
+		deleted += int(seg.lastSeq - seg.firstSeq + 1)
+		l.segments = l.segments[1:]
+	}
+	return deleted, nil
+}
+
+// cursorsFile is the file of the saved cursors in Options.Dir.
+const cursorsFile = "cursors.json"
+
+// LoadCursor returns the cursor saved under name, zero if none.
+func (l *Log) LoadCursor(name string) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cursors, err := l.loadCursors()
+	if err != nil {
+		return 0, err
+	}
+	return cursors[name], nil
+}
+
+// SaveCursor saves the cursor of the consumer name.
+func (l *Log) SaveCursor(name string, cursor uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cursors, err := l.loadCursors()
+	if err != nil {
+		return err
+	}
+	cursors[name] = cursor
+	return filestore.Save(filepath.Join(l.opts.Dir, cursorsFile), cursors)
+}
+
+// DeleteCursor forgets the cursor of the consumer name.
+func (l *Log) DeleteCursor(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cursors, err := l.loadCursors()
+	if err != nil {
+		return err
+	}
+	if _, ok := cursors[name]; !ok {
+		return nil
+	}
+	delete(cursors, name)
+	return filestore.Save(filepath.Join(l.opts.Dir, cursorsFile), cursors)
+}
+
+// Cursors returns the names of the consumers with a saved cursor.
+func (l *Log) Cursors() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cursors, err := l.loadCursors()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cursors))
+	for name := range cursors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (l *Log) loadCursors() (map[string]uint64, error) {
+	cursors := map[string]uint64{}
+	if err := filestore.Load(filepath.Join(l.opts.Dir, cursorsFile), &cursors); err != nil {
+		return nil, fmt.Errorf("eventlog: loading cursors: %w", err)
+	}
+	return cursors, nil
+}
+
+// Consume calls fn with the records after the cursor saved under name, then
+// with the records appended, until ctx is done, the log is closed or fn
+// fails. The cursor is saved after each record fn returns nil for, so that
+// a restarted consumer resumes after it. Records compacted before fn saw
+// them are skipped.
+func (l *Log) Consume(ctx context.Context, name string, fn func(Record) error) error {
+	cursor, err := l.LoadCursor(name)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		records, err := l.Read(cursor, DefaultReadMax)
+		if errors.Is(err, ErrCompacted) {
+			cursor = l.FirstSeq() - 1
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if err := fn(r); err != nil {
+				return err
+			}
+			cursor = r.Seq
+			if err := l.SaveCursor(name, cursor); err != nil {
+				return err
+			}
+		}
+		if len(records) == 0 {
+			if err := l.Wait(ctx, cursor); err != nil {
+				return err
+			}
+		}
+	}
+}
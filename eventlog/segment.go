@@ -0,0 +1,158 @@
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// segmentExt is the extension of the segment files, named after the
+// sequence number of their first record.
+const segmentExt = ".log"
+
+// segment is a file of consecutive records.
+type segment struct {
+	path     string
+	firstSeq uint64
+	// lastSeq and lastTime are those of the last record, zero if the
+	// segment is empty.
+	lastSeq  uint64
+	lastTime time.Time
+	size     int64
+}
+
+func segmentPath(dir string, firstSeq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", firstSeq, segmentExt))
+}
+
+func createSegment(dir string, firstSeq uint64) (*segment, error) {
+	path := segmentPath(dir, firstSeq)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return &segment{path: path, firstSeq: firstSeq}, nil
+}
+
+// loadSegments lists the segments of dir in order and scans the last one,
+// truncating it after its last complete record.
+func loadSegments(dir string) ([]*segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []*segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		firstSeq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil || firstSeq == 0 {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, &segment{path: filepath.Join(dir, name), firstSeq: firstSeq, size: info.Size()})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].firstSeq < segments[j].firstSeq })
+
+	for i, seg := range segments {
+		if err := seg.scan(i == len(segments)-1); err != nil {
+			return nil, err
+		}
+	}
+	for i := 1; i < len(segments); i++ {
+		if prev := segments[i-1]; prev.lastSeq+1 != segments[i].firstSeq {
+			return nil, fmt.Errorf("segment %s does not follow %s", filepath.Base(segments[i].path), filepath.Base(prev.path))
+		}
+	}
+	return segments, nil
+}
+
+// scan reads the last record of the segment. If recover is set, a trailing
+// partial or corrupt record, left by an interrupted append, is truncated.
+func (s *segment) scan(recover bool) error {
+	f, err := os.OpenFile(s.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && len(line) == 0 {
+			break
+		}
+		var rec Record
+		if err != nil || json.Unmarshal(line, &rec) != nil || !s.follows(rec.Seq) {
+			if !recover {
+				return fmt.Errorf("segment %s: corrupt record at offset %d", filepath.Base(s.path), offset)
+			}
+			break
+		}
+		offset += int64(len(line))
+		s.lastSeq, s.lastTime = rec.Seq, rec.Time
+	}
+	if offset != s.size {
+		if err := f.Truncate(offset); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		s.size = offset
+	}
+	return nil
+}
+
+// follows reports whether seq is the next sequence number of the segment.
+func (s *segment) follows(seq uint64) bool {
+	if s.lastSeq == 0 {
+		return seq == s.firstSeq
+	}
+	return seq == s.lastSeq+1
+}
+
+// read returns up to max records of the segment after cursor, reading no
+// further than size bytes.
+func (s *segment) read(cursor uint64, max int, size int64) ([]Record, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > size {
+		data = data[:size]
+	}
+	var records []Record
+	for len(data) > 0 && len(records) < max {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		line := data[:i]
+		data = data[i+1:]
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("eventlog: segment %s: %w", filepath.Base(s.path), err)
+		}
+		if rec.Seq > cursor {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,404 @@
+// Package eventstream streams the wallet events to browsers and apps, so
+// that frontends update without polling ListPayments.
+//
+// Server serves the SDK events, and optionally the NWC events, as
+// Server-Sent Events (SSE) and over WebSocket. Each message is the JSON
+// object
+//
+//	{"id": "42", "type": "payment_succeeded", "time": "...", "event": {...}}
+//
+// where event is encoded as by the JSON APIs of this module. NWC event types
+// are prefixed with "nwc.", such as "nwc.pay_invoice". A message of type
+// "reset" reports that events were missed, for instance because the client
+// lagged past the replay window: the client should refetch the state it
+// displays.
+//
+// Clients select the events with query parameters:
+//
+//	types=payment_succeeded,synced   only these event types
+//	payments=<id>,<id>               only the events of these payments, by
+//	                                 payment id, transaction id, swap id or
+//	                                 payment hash
+//	nwc=true                         also the NWC events
+//
+// A client resumes after a disconnection by sending the id of the last
+// message received, in the Last-Event-ID header, which browsers send when
+// an EventSource reconnects, or in the last_event_id query parameter. Events
+// are replayed from Options.Log if set, so that resumption also works across
+// restarts, and otherwise from the last Options.Replay events kept in
+// memory. The ids of the events kept in memory are only valid for the run
+// of the process which sent them: a client resuming from an earlier run
+// gets a reset.
+//
+// Clients read the events at their own pace: a slow client does not slow
+// down the others, and gets a reset once it lags behind the events kept.
+package eventstream
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/eventlog"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultReplay       = 1024
+	DefaultHeartbeat    = 15 * time.Second
+	DefaultWriteTimeout = 10 * time.Second
+	// DefaultBatch is the number of events read from a feed at once.
+	DefaultBatch = 256
+)
+
+// TypeReset is the type of the message reporting missed events.
+const TypeReset = "reset"
+
+// Options configures a Server.
+type Options struct {
+	// Log, if set, is the source of the SDK events, which must be kept
+	// running. Otherwise the events are received while Run is running.
+	Log *eventlog.Log
+	// Nwc, if set, is the source of the NWC events, received while Run is
+	// running.
+	Nwc breez_sdk_liquid.BindingNwcServiceInterface
+	// Replay is the number of events of each source kept in memory for
+	// resumption, when not read from Log. Defaults to DefaultReplay.
+	Replay int
+	// Heartbeat is the interval of the heartbeats keeping idle
+	// connections open. Defaults to DefaultHeartbeat.
+	Heartbeat time.Duration
+	// WriteTimeout bounds the writes to a client, after which it is
+	// disconnected. SSE writes are bounded when the response writer
+	// supports write deadlines, as those of net/http do since Go 1.20.
+	// Defaults to DefaultWriteTimeout.
+	WriteTimeout time.Duration
+	// Token, if set, must be sent by clients as a bearer token or, as
+	// browsers cannot set headers on EventSource and WebSocket, as the
+	// access_token query parameter.
+	Token string
+	// CheckOrigin reports whether a WebSocket upgrade request may proceed.
+	// Defaults to accepting requests without an Origin header or whose
+	// Origin host is the request host.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Message is a message sent to the clients.
+type Message struct {
+	Id    string          `json:"id"`
+	Type  string          `json:"type"`
+	Time  *time.Time      `json:"time,omitempty"`
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+// Server serves the event streams.
+type Server struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	sdkFeed feed
+	sdkRing *ring
+	nwcRing *ring
+	// epoch identifies the run of the process in the message ids of the
+	// in-memory feeds.
+	epoch string
+}
+
+// New creates a Server.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Server {
+	if opts.Replay <= 0 {
+		opts.Replay = DefaultReplay
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = DefaultHeartbeat
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+	if opts.CheckOrigin == nil {
+		opts.CheckOrigin = sameOrigin
+	}
+	s := &Server{sdk: sdk, opts: opts, epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+	if opts.Log != nil {
+		s.sdkFeed = logFeed{log: opts.Log}
+	} else {
+		s.sdkRing = newRing(opts.Replay)
+		s.sdkFeed = s.sdkRing
+	}
+	if opts.Nwc != nil {
+		s.nwcRing = newRing(opts.Replay)
+	}
+	return s
+}
+
+// Run receives the events kept in memory until ctx is done.
+func (s *Server) Run(ctx context.Context) error {
+	if s.sdkRing != nil {
+		listenerId, sdkErr := s.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+			var payments []string
+			if p, ok := sdkutil.EventPayment(e); ok {
+				payments = paymentKeys(p)
+			}
+			s.sdkRing.add(time.Now().UTC(), sdkutil.EventName(e), e, payments)
+		}))
+		if sdkErr != nil {
+			return sdkErr
+		}
+		defer func() { _ = s.sdk.RemoveEventListener(listenerId) }()
+	}
+	if s.nwcRing != nil {
+		nwc := s.opts.Nwc
+		listenerId := nwc.AddEventListener(sdkutil.NwcEventListenerFunc(func(e breez_sdk_liquid.NwcEvent) {
+			s.nwcRing.add(time.Now().UTC(), "nwc."+sdkutil.NwcEventName(e), e, nil)
+		}))
+		defer nwc.RemoveEventListener(listenerId)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// filter selects the events sent to a client.
+type filter struct {
+	types    map[string]bool
+	payments map[string]bool
+	nwc      bool
+}
+
+func (f filter) matches(e entry) bool {
+	if f.types != nil && !f.types[e.typ] {
+		return false
+	}
+	if f.payments != nil {
+		for _, key := range e.payments {
+			if f.payments[key] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// cursor is the position of a client in the feeds.
+type cursor struct {
+	sdk, nwc uint64
+	// stale is set when the client resumes from a position in an
+	// in-memory feed of an earlier run: it gets a reset, and the events
+	// from then on.
+	stale bool
+}
+
+// messageId returns the message id of a cursor: "<sdk>", or "<sdk>-<nwc>"
+// with the NWC events, prefixed by "<epoch>:" if read from an in-memory
+// feed.
+func (s *Server) messageId(c cursor, nwc bool) string {
+	id := strconv.FormatUint(c.sdk, 10)
+	if nwc {
+		id = fmt.Sprintf("%d-%d", c.sdk, c.nwc)
+	}
+	if s.sdkRing != nil || nwc {
+		id = s.epoch + ":" + id
+	}
+	return id
+}
+
+// open authorizes a stream request and parses its filter and starting
+// cursor.
+func (s *Server) open(r *http.Request) (filter, cursor, int, error) {
+	if s.opts.Token != "" && !s.authorized(r) {
+		return filter{}, cursor{}, http.StatusUnauthorized, errors.New("invalid token")
+	}
+	q := r.URL.Query()
+	var f filter
+	if types := splitList(q.Get("types")); types != nil {
+		f.types = map[string]bool{}
+		for _, t := range types {
+			f.types[t] = true
+		}
+	}
+	if payments := splitList(q.Get("payments")); payments != nil {
+		f.payments = map[string]bool{}
+		for _, p := range payments {
+			f.payments[p] = true
+		}
+	}
+	if v := q.Get("nwc"); v != "" {
+		nwc, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter{}, cursor{}, http.StatusBadRequest, errors.New("nwc must be a boolean")
+		}
+		if nwc && s.nwcRing == nil {
+			return filter{}, cursor{}, http.StatusBadRequest, errors.New("NWC events are not served")
+		}
+		f.nwc = nwc
+	}
+
+	c := cursor{sdk: s.sdkFeed.last()}
+	if f.nwc {
+		c.nwc = s.nwcRing.last()
+	}
+	lastId := r.Header.Get("Last-Event-ID")
+	if lastId == "" {
+		lastId = q.Get("last_event_id")
+	}
+	if lastId != "" {
+		epoch, position, hasEpoch := strings.Cut(lastId, ":")
+		if !hasEpoch {
+			epoch, position = "", lastId
+		}
+		sameRun := epoch == s.epoch
+		sdkPart, nwcPart, hasNwc := strings.Cut(position, "-")
+		seq, err := strconv.ParseUint(sdkPart, 10, 64)
+		if err != nil {
+			return filter{}, cursor{}, http.StatusBadRequest, errors.New("invalid last event id")
+		}
+		if s.sdkRing == nil || sameRun {
+			c.sdk = seq
+		} else {
+			c.sdk, c.stale = s.sdkFeed.last(), true
+		}
+		if hasNwc && f.nwc {
+			nwcSeq, err := strconv.ParseUint(nwcPart, 10, 64)
+			if err != nil {
+				return filter{}, cursor{}, http.StatusBadRequest, errors.New("invalid last event id")
+			}
+			if sameRun {
+				c.nwc = nwcSeq
+			} else {
+				c.nwc, c.stale = s.nwcRing.last(), true
+			}
+		}
+	}
+	return f, c, http.StatusOK, nil
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("access_token")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// sink writes the messages of a stream.
+type sink interface {
+	send(msgs []Message) error
+	heartbeat() error
+	// closed is closed when the client goes away.
+	closed() <-chan struct{}
+}
+
+// stream sends the events after c matching f to out, until ctx is done or
+// a write fails.
+func (s *Server) stream(ctx context.Context, f filter, c cursor, out sink) error {
+	if c.stale {
+		if err := out.send([]Message{{Id: s.messageId(c, f.nwc), Type: TypeReset}}); err != nil {
+			return err
+		}
+	}
+	heartbeat := time.NewTicker(s.opts.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		// Take the notification channels before reading, so that no
+		// append is missed in between.
+		sdkAppended := s.sdkFeed.appended()
+		var nwcAppended <-chan struct{}
+		if f.nwc {
+			nwcAppended = s.nwcRing.appended()
+		}
+		if err := s.catchUp(f, &c, out); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-out.closed():
+			return nil
+		case <-sdkAppended:
+		case <-nwcAppended:
+		case <-heartbeat.C:
+			if err := out.heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// catchUp sends the events of the feeds after c, advancing it.
+func (s *Server) catchUp(f filter, c *cursor, out sink) error {
+	if err := s.catchUpFeed(f, s.sdkFeed, &c.sdk, c, out); err != nil {
+		return err
+	}
+	if f.nwc {
+		return s.catchUpFeed(f, s.nwcRing, &c.nwc, c, out)
+	}
+	return nil
+}
+
+func (s *Server) catchUpFeed(f filter, fd feed, seq *uint64, c *cursor, out sink) error {
+	if *seq > fd.last() {
+		// The cursor comes from before a restart of the in-memory feed.
+		*seq = fd.last()
+		if err := out.send([]Message{{Id: s.messageId(*c, f.nwc), Type: TypeReset}}); err != nil {
+			return err
+		}
+	}
+	for {
+		entries, err := fd.read(*seq, DefaultBatch)
+		if errors.Is(err, errBehind) {
+			*seq = fd.last()
+			if err := out.send([]Message{{Id: s.messageId(*c, f.nwc), Type: TypeReset}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		var msgs []Message
+		for _, e := range entries {
+			*seq = e.seq
+			if !f.matches(e) {
+				continue
+			}
+			t := e.time
+			msgs = append(msgs, Message{Id: s.messageId(*c, f.nwc), Type: e.typ, Time: &t, Event: e.data})
+		}
+		if len(msgs) > 0 {
+			if err := out.send(msgs); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	// Types such as "bad_request", as in the REST API.
+	typ := strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	_ = json.NewEncoder(w).Encode(map[string]sdkjson.Error{"error": {Type: typ, Message: err.Error()}})
+}
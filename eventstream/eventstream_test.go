@@ -0,0 +1,166 @@
+package eventstream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk keeps the event listener registered by Run.
+type fakeSdk struct {
+	mu       sync.Mutex
+	listener breez_sdk_liquid.EventListener
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		switch call.Method {
+		case "AddEventListener":
+			f.mu.Lock()
+			f.listener = call.Request.(breez_sdk_liquid.EventListener)
+			f.mu.Unlock()
+			return "listener", nil
+		}
+		return nil, nil
+	})
+}
+
+func (f *fakeSdk) emit(t *testing.T, e breez_sdk_liquid.SdkEvent) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.mu.Lock()
+		listener := f.listener
+		f.mu.Unlock()
+		if listener != nil {
+			listener.OnEvent(e)
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no event listener registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func startServer(t *testing.T, opts Options) (*fakeSdk, *httptest.Server) {
+	t.Helper()
+	f := &fakeSdk{}
+	s := New(f.sdk(), opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = s.Run(ctx)
+	}()
+	srv := httptest.NewServer(s.SSE())
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+		<-done
+	})
+	return f, srv
+}
+
+// openStream connects to the SSE stream and waits for its first comment.
+func openStream(t *testing.T, url string) (*bufio.Reader, func()) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	r := bufio.NewReader(resp.Body)
+	if line, err := r.ReadString('\n'); err != nil || line != ": connected\n" {
+		resp.Body.Close()
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	return r, func() { resp.Body.Close() }
+}
+
+// nextMessage returns the data of the next event of the stream.
+func nextMessage(t *testing.T, r *bufio.Reader) Message {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			var m Message
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m); err != nil {
+				t.Fatal(err)
+			}
+			return m
+		}
+	}
+}
+
+func TestSSE(t *testing.T) {
+	f, srv := startServer(t, Options{Token: "secret"})
+	r, closeStream := openStream(t, srv.URL+"?types=synced&access_token=secret")
+	defer closeStream()
+
+	f.emit(t, breez_sdk_liquid.SdkEventSyncFailed{Error: "offline"})
+	f.emit(t, breez_sdk_liquid.SdkEventSynced{})
+	m := nextMessage(t, r)
+	if m.Type != "synced" || m.Id == "" || m.Time == nil {
+		t.Fatalf("message = %+v, want a synced event", m)
+	}
+
+	// Resuming from an earlier run gets a reset first.
+	r2, closeStream2 := openStream(t, srv.URL+"?access_token=secret&last_event_id=earlier:7")
+	defer closeStream2()
+	if m := nextMessage(t, r2); m.Type != TypeReset {
+		t.Fatalf("message = %+v, want a reset", m)
+	}
+}
+
+func TestSSERejected(t *testing.T) {
+	_, srv := startServer(t, Options{Token: "secret"})
+	for _, tc := range []struct {
+		query, auth string
+		status      int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"?access_token=wrong", "", http.StatusUnauthorized},
+		{"", "Bearer wrong", http.StatusUnauthorized},
+		{"?nwc=maybe", "Bearer secret", http.StatusBadRequest},
+		{"?nwc=true", "Bearer secret", http.StatusBadRequest},
+		{"?last_event_id=x", "Bearer secret", http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tc.query, nil)
+		if tc.auth != "" {
+			req.Header.Set("Authorization", tc.auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%q %q: status = %d, want %d", tc.query, tc.auth, resp.StatusCode, tc.status)
+		}
+	}
+
+	resp, err := http.Post(srv.URL+"?access_token=secret", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", resp.StatusCode)
+	}
+}
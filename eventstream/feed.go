@@ -0,0 +1,154 @@
+package eventstream
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/eventlog"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// errBehind is returned by a feed read from a cursor whose next entries
+// are gone.
+var errBehind = errors.New("eventstream: cursor behind the feed")
+
+// entry is an event of a feed.
+type entry struct {
+	seq  uint64
+	time time.Time
+	// typ is the event name sent to the clients, such as
+	// "payment_succeeded" or "nwc.pay_invoice".
+	typ  string
+	data json.RawMessage
+	// payments are the identifiers of the payment of the event, if any:
+	// its payment id, transaction id, swap id and payment hash.
+	payments []string
+}
+
+// feed is a sequence of entries clients read from their cursor.
+type feed interface {
+	// read returns up to max entries after cursor, or errBehind.
+	read(cursor uint64, max int) ([]entry, error)
+	// last returns the sequence number of the last entry.
+	last() uint64
+	// appended returns a channel closed when the next entry is appended.
+	appended() <-chan struct{}
+}
+
+func sdkEntry(seq uint64, t time.Time, e breez_sdk_liquid.SdkEvent, data json.RawMessage) entry {
+	en := entry{seq: seq, time: t, typ: sdkutil.EventName(e), data: data}
+	if p, ok := sdkutil.EventPayment(e); ok {
+		en.payments = paymentKeys(p)
+	}
+	return en
+}
+
+func paymentKeys(p breez_sdk_liquid.Payment) []string {
+	var keys []string
+	for _, key := range []string{sdkutil.PaymentId(p), sdkutil.StringValue(p.TxId), sdkutil.SwapId(p), sdkutil.PaymentHash(p)} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ring is an in-memory feed keeping the last entries.
+type ring struct {
+	size int
+
+	mu      sync.Mutex
+	entries []entry
+	lastSeq uint64
+	changed chan struct{}
+}
+
+func newRing(size int) *ring {
+	return &ring{size: size, changed: make(chan struct{})}
+}
+
+func (r *ring) add(t time.Time, typ string, e any, payments []string) {
+	data, err := sdkjson.Marshal(e)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastSeq++
+	if len(r.entries) == r.size {
+		copy(r.entries, r.entries[1:])
+		r.entries = r.entries[:len(r.entries)-1]
+	}
+	r.entries = append(r.entries, entry{seq: r.lastSeq, time: t, typ: typ, data: data, payments: payments})
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+func (r *ring) read(cursor uint64, max int) ([]entry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cursor >= r.lastSeq {
+		return nil, nil
+	}
+	first := r.lastSeq - uint64(len(r.entries)) + 1
+	if cursor+1 < first {
+		return nil, errBehind
+	}
+	start := int(cursor + 1 - first)
+	end := len(r.entries)
+	if end-start > max {
+		end = start + max
+	}
+	return append([]entry(nil), r.entries[start:end]...), nil
+}
+
+func (r *ring) last() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastSeq
+}
+
+func (r *ring) appended() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changed
+}
+
+// logFeed reads the SDK events from an event log.
+type logFeed struct {
+	log *eventlog.Log
+}
+
+func (f logFeed) read(cursor uint64, max int) ([]entry, error) {
+	records, err := f.log.Read(cursor, max)
+	if errors.Is(err, eventlog.ErrCompacted) {
+		return nil, errBehind
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(records))
+	for _, r := range records {
+		e, err := r.Decode()
+		if err != nil {
+			// Written by a different version of the bindings: sent
+			// without payment identifiers.
+			entries = append(entries, entry{seq: r.Seq, time: r.Time, typ: r.Type, data: r.Event})
+			continue
+		}
+		entries = append(entries, sdkEntry(r.Seq, r.Time, e, r.Event))
+	}
+	return entries, nil
+}
+
+func (f logFeed) last() uint64 {
+	return f.log.LastSeq()
+}
+
+func (f logFeed) appended() <-chan struct{} {
+	return f.log.Appended()
+}
@@ -0,0 +1,108 @@
+package eventstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// SSE returns the handler streaming the events as Server-Sent Events, for
+// the EventSource API of browsers. Each message is sent with its id and
+// with its type as the event name.
+func (s *Server) SSE() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("GET required"))
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+			return
+		}
+		f, c, status, err := s.open(r)
+		if err != nil {
+			writeError(w, status, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		out := &sseSink{
+			w:        bufio.NewWriter(w),
+			flusher:  flusher,
+			deadline: writeDeadline(w),
+			timeout:  s.opts.WriteTimeout,
+			done:     r.Context().Done(),
+		}
+		_, _ = out.w.WriteString(": connected\n\n")
+		if err := out.flush(); err != nil {
+			return
+		}
+		_ = s.stream(r.Context(), f, c, out)
+	})
+}
+
+// sseSink writes Server-Sent Events. Without write deadlines, a stalled
+// client is noticed when the connection breaks, and until then holds only
+// its goroutine, as it reads the events at its own pace.
+type sseSink struct {
+	w        *bufio.Writer
+	flusher  http.Flusher
+	deadline func(time.Time) error
+	timeout  time.Duration
+	done     <-chan struct{}
+}
+
+// writeDeadline returns the function setting the write deadline of a
+// response writer, or nil if it has none.
+func writeDeadline(w http.ResponseWriter) func(time.Time) error {
+	for {
+		if d, ok := w.(interface{ SetWriteDeadline(time.Time) error }); ok {
+			return d.SetWriteDeadline
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+}
+
+func (o *sseSink) send(msgs []Message) error {
+	for _, m := range msgs {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, _ = o.w.WriteString("id: " + m.Id + "\nevent: " + m.Type + "\ndata: ")
+		_, _ = o.w.Write(data)
+		_, _ = o.w.WriteString("\n\n")
+	}
+	return o.flush()
+}
+
+func (o *sseSink) heartbeat() error {
+	_, _ = o.w.WriteString(": heartbeat\n\n")
+	return o.flush()
+}
+
+func (o *sseSink) flush() error {
+	if o.deadline != nil {
+		_ = o.deadline(time.Now().Add(o.timeout))
+	}
+	if err := o.w.Flush(); err != nil {
+		return err
+	}
+	o.flusher.Flush()
+	return nil
+}
+
+func (o *sseSink) closed() <-chan struct{} {
+	return o.done
+}
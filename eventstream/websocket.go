@@ -0,0 +1,303 @@
+package eventstream
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes and close codes, from RFC 6455.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+
+	closeNormal        = 1000
+	closeGoingAway     = 1001
+	closeProtocolError = 1002
+	closeTooBig        = 1009
+)
+
+// wsGUID is appended to the client key to compute the accept key.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxClientFrame caps the frames read from clients, which have nothing to
+// send but control frames.
+const maxClientFrame = 4 << 10
+
+// WebSocket returns the handler streaming the events over WebSocket, one
+// text message per event. Messages sent by the client are ignored. The
+// connection is pinged every heartbeat interval and closed if the client
+// stops answering.
+func (s *Server) WebSocket() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("GET required"))
+			return
+		}
+		if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+			writeError(w, http.StatusBadRequest, errors.New("WebSocket upgrade required"))
+			return
+		}
+		if r.Header.Get("Sec-WebSocket-Version") != "13" {
+			w.Header().Set("Sec-WebSocket-Version", "13")
+			writeError(w, http.StatusUpgradeRequired, errors.New("unsupported WebSocket version"))
+			return
+		}
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid Sec-WebSocket-Key"))
+			return
+		}
+		if !s.opts.CheckOrigin(r) {
+			writeError(w, http.StatusForbidden, errors.New("origin not allowed"))
+			return
+		}
+		f, c, status, err := s.open(r)
+		if err != nil {
+			writeError(w, status, err)
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, errors.New("WebSocket unsupported"))
+			return
+		}
+		netConn, rw, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		defer netConn.Close()
+
+		_ = netConn.SetDeadline(time.Time{})
+		_ = netConn.SetWriteDeadline(time.Now().Add(s.opts.WriteTimeout))
+		if _, err := io.WriteString(netConn, "HTTP/1.1 101 Switching Protocols\r\n"+
+			"Upgrade: websocket\r\n"+
+			"Connection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: "+acceptKey(key)+"\r\n\r\n"); err != nil {
+			return
+		}
+
+		conn := &wsConn{conn: netConn, r: rw.Reader, writeTimeout: s.opts.WriteTimeout, done: make(chan struct{})}
+		// Pings are sent every heartbeat: a client answering none of the
+		// last two is gone.
+		go conn.readLoop(2*s.opts.Heartbeat + s.opts.WriteTimeout)
+		code := closeNormal
+		if err := s.stream(r.Context(), f, c, conn); err != nil {
+			code = closeGoingAway
+		}
+		conn.close(code)
+	})
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether the comma-separated header values of name
+// contain token, case-insensitively.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sameOrigin accepts requests without an Origin header, sent by non-browser
+// clients, or whose Origin host is the request host.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// wsConn is a server-side WebSocket connection.
+type wsConn struct {
+	conn         net.Conn
+	r            *bufio.Reader
+	writeTimeout time.Duration
+
+	mu        sync.Mutex
+	closeSent bool
+
+	doneOnce sync.Once
+	done     chan struct{}
+}
+
+func (c *wsConn) send(msgs []Message) error {
+	for _, m := range msgs {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		if err := c.writeFrame(opText, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *wsConn) heartbeat() error {
+	return c.writeFrame(opPing, nil)
+}
+
+func (c *wsConn) closed() <-chan struct{} {
+	return c.done
+}
+
+func (c *wsConn) finish() {
+	c.doneOnce.Do(func() { close(c.done) })
+}
+
+// writeFrame writes an unfragmented, unmasked frame, as servers do.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closeSent {
+		return net.ErrClosed
+	}
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if opcode == opClose {
+		c.closeSent = true
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	bufs := net.Buffers{header, payload}
+	_, err := bufs.WriteTo(c.conn)
+	return err
+}
+
+// close sends a close frame with code, unless one was sent already.
+func (c *wsConn) close(code int) {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	_ = c.writeFrame(opClose, payload)
+}
+
+// readLoop reads the frames of the client, answering pings and close
+// frames, until the connection fails or no frame is received within idle.
+func (c *wsConn) readLoop(idle time.Duration) {
+	defer c.finish()
+	for {
+		_ = c.conn.SetReadDeadline(time.Now().Add(idle))
+		opcode, payload, err := c.readFrame()
+		var protoErr *protocolError
+		switch {
+		case errors.As(err, &protoErr):
+			c.close(protoErr.code)
+			return
+		case err != nil:
+			return
+		}
+		switch opcode {
+		case opPing:
+			if c.writeFrame(opPong, payload) != nil {
+				return
+			}
+		case opClose:
+			// Echo the close code, as required before closing.
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.close(code)
+			return
+		}
+	}
+}
+
+// protocolError is a violation of the protocol by the client, answered
+// with a close frame.
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "eventstream: websocket: " + e.msg
+}
+
+// readFrame reads a frame and unmasks its payload.
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	if head[0]&0x70 != 0 {
+		return 0, nil, &protocolError{closeProtocolError, "reserved bits set"}
+	}
+	opcode := head[0] & 0x0f
+	if head[1]&0x80 == 0 {
+		return 0, nil, &protocolError{closeProtocolError, "unmasked client frame"}
+	}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	switch opcode {
+	case opClose, opPing, opPong:
+		if n > 125 || head[0]&0x80 == 0 {
+			return 0, nil, &protocolError{closeProtocolError, "invalid control frame"}
+		}
+	case opContinuation, opText, opBinary:
+	default:
+		return 0, nil, &protocolError{closeProtocolError, "unknown opcode"}
+	}
+	if n > maxClientFrame {
+		return 0, nil, &protocolError{closeTooBig, "frame too large"}
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
@@ -0,0 +1,297 @@
+// Package feeanalytics aggregates the fees paid and charged across the
+// payment history by payment method, direction and time bucket.
+package feeanalytics
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Granularity is the width of a time bucket.
+type Granularity string
+
+const (
+	Hour  Granularity = "hour"
+	Day   Granularity = "day"
+	Week  Granularity = "week"
+	Month Granularity = "month"
+)
+
+// truncate returns the start of the bucket containing t.
+func (g Granularity) truncate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch g {
+	case Hour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case Week:
+		// Weeks start on Monday.
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Options configures which payments are aggregated and how.
+type Options struct {
+	// Granularity of the time buckets. Defaults to Day.
+	Granularity Granularity
+	// Location used to compute bucket boundaries. Defaults to UTC.
+	Location *time.Location
+	// From and To restrict the payments by timestamp, if set.
+	From *time.Time
+	To   *time.Time
+	// States restricts the payments by state. Defaults to completed
+	// payments only, plus failed payments that were refunded so that
+	// refund fees are accounted for.
+	States []breez_sdk_liquid.PaymentState
+}
+
+// Fees holds the aggregated amounts of a group of payments.
+type Fees struct {
+	Count     int    `json:"count"`
+	AmountSat uint64 `json:"amount_sat"`
+	// FeesSat is the total of Payment.FeesSat, of the payments which did
+	// not fail: the fees of a failed swap are never charged.
+	FeesSat uint64 `json:"fees_sat"`
+	// SwapperFeesSat is the part of FeesSat charged by the swapper.
+	SwapperFeesSat uint64 `json:"swapper_fees_sat"`
+	// ClaimFeesSat is the on-chain part of FeesSat, used to lock up and
+	// claim swaps: FeesSat minus SwapperFeesSat.
+	ClaimFeesSat uint64 `json:"claim_fees_sat"`
+	// RefundFeesSat is the on-chain fee lost when refunding failed swaps.
+	RefundFeesSat uint64 `json:"refund_fees_sat"`
+	// EffectiveRate is the total of FeesSat and RefundFeesSat relative to
+	// AmountSat.
+	EffectiveRate float64 `json:"effective_rate"`
+	// MinRate, MaxRate and MeanRate describe the per-payment fee rates.
+	MinRate  float64 `json:"min_rate"`
+	MaxRate  float64 `json:"max_rate"`
+	MeanRate float64 `json:"mean_rate"`
+
+	rateSum float64
+}
+
+func (f *Fees) add(p breez_sdk_liquid.Payment) {
+	refundFees := RefundFeesSat(p)
+	var fees, swapperFees uint64
+	if refundFees == 0 && !failed(p) {
+		fees = p.FeesSat
+		swapperFees = sdkutil.Uint64Value(p.SwapperFeesSat)
+		if swapperFees > fees {
+			swapperFees = fees
+		}
+	}
+	rate := 0.0
+	if p.AmountSat > 0 {
+		rate = float64(fees+refundFees) / float64(p.AmountSat)
+	}
+
+	if f.Count == 0 || rate < f.MinRate {
+		f.MinRate = rate
+	}
+	if f.Count == 0 || rate > f.MaxRate {
+		f.MaxRate = rate
+	}
+	f.Count++
+	f.AmountSat += p.AmountSat
+	f.FeesSat += fees
+	f.SwapperFeesSat += swapperFees
+	f.ClaimFeesSat += fees - swapperFees
+	f.RefundFeesSat += refundFees
+	f.rateSum += rate
+	f.MeanRate = f.rateSum / float64(f.Count)
+	if f.AmountSat > 0 {
+		f.EffectiveRate = float64(f.FeesSat+f.RefundFeesSat) / float64(f.AmountSat)
+	}
+}
+
+// failed reports whether the swap of the payment failed, whether or not it
+// was refunded yet.
+func failed(p breez_sdk_liquid.Payment) bool {
+	switch p.Status {
+	case breez_sdk_liquid.PaymentStateFailed, breez_sdk_liquid.PaymentStateTimedOut,
+		breez_sdk_liquid.PaymentStateRefundable, breez_sdk_liquid.PaymentStateRefundPending:
+		return true
+	}
+	return false
+}
+
+// RefundFeesSat returns the on-chain fee paid to refund a failed swap: the
+// locked up amount minus the refunded amount.
+func RefundFeesSat(p breez_sdk_liquid.Payment) uint64 {
+	var refunded *uint64
+	switch details := p.Details.(type) {
+	case breez_sdk_liquid.PaymentDetailsLightning:
+		refunded = details.RefundTxAmountSat
+	case breez_sdk_liquid.PaymentDetailsBitcoin:
+		refunded = details.RefundTxAmountSat
+	}
+	locked := p.AmountSat + p.FeesSat
+	if refunded == nil || *refunded >= locked {
+		return 0
+	}
+	return locked - *refunded
+}
+
+// Group is the aggregate of the payments sharing a method and direction.
+type Group struct {
+	Method    string `json:"method"`
+	Direction string `json:"direction"`
+	Fees
+}
+
+// Bucket is the aggregate of the payments of a group within a time bucket.
+type Bucket struct {
+	Start     time.Time `json:"start"`
+	Method    string    `json:"method"`
+	Direction string    `json:"direction"`
+	Fees
+}
+
+// Summary is the result of an aggregation.
+type Summary struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	Granularity Granularity `json:"granularity"`
+	Total       Fees        `json:"total"`
+	Groups      []Group     `json:"groups"`
+	Buckets     []Bucket    `json:"buckets"`
+}
+
+// WriteJSON writes the summary as JSON to w.
+func (s Summary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Analyzer aggregates the fees of an SDK instance's payment history.
+type Analyzer struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+}
+
+// New creates an Analyzer for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Analyzer {
+	return &Analyzer{sdk: sdk, opts: opts}
+}
+
+// Analyze lists the payments matching the options and aggregates them.
+func (a *Analyzer) Analyze() (Summary, error) {
+	req := breez_sdk_liquid.ListPaymentsRequest{}
+	if a.opts.From != nil {
+		from := a.opts.From.Unix()
+		req.FromTimestamp = &from
+	}
+	if a.opts.To != nil {
+		to := a.opts.To.Unix()
+		req.ToTimestamp = &to
+	}
+	payments, err := sdkutil.ListAllPayments(a.sdk, req)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Aggregate(payments, a.opts), nil
+}
+
+type groupKey struct {
+	method    string
+	direction string
+}
+
+type bucketKey struct {
+	start int64
+	groupKey
+}
+
+// Aggregate aggregates the given payments according to opts.
+func Aggregate(payments []breez_sdk_liquid.Payment, opts Options) Summary {
+	if opts.Granularity == "" {
+		opts.Granularity = Day
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	summary := Summary{
+		GeneratedAt: time.Now().UTC(),
+		Granularity: opts.Granularity,
+	}
+	groups := map[groupKey]*Group{}
+	buckets := map[bucketKey]*Bucket{}
+	for _, p := range payments {
+		if !included(p, opts) {
+			continue
+		}
+		key := groupKey{
+			method:    sdkutil.PaymentDetailsName(p.Details),
+			direction: sdkutil.PaymentTypeName(p.PaymentType),
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &Group{Method: key.method, Direction: key.direction}
+			groups[key] = group
+		}
+		start := opts.Granularity.truncate(time.Unix(int64(p.Timestamp), 0).In(opts.Location))
+		bkey := bucketKey{start.Unix(), key}
+		bucket, ok := buckets[bkey]
+		if !ok {
+			bucket = &Bucket{Start: start, Method: key.method, Direction: key.direction}
+			buckets[bkey] = bucket
+		}
+		summary.Total.add(p)
+		group.add(p)
+		bucket.add(p)
+	}
+
+	for _, group := range groups {
+		summary.Groups = append(summary.Groups, *group)
+	}
+	sort.Slice(summary.Groups, func(i, j int) bool {
+		a, b := summary.Groups[i], summary.Groups[j]
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Direction < b.Direction
+	})
+	for _, bucket := range buckets {
+		summary.Buckets = append(summary.Buckets, *bucket)
+	}
+	sort.Slice(summary.Buckets, func(i, j int) bool {
+		a, b := summary.Buckets[i], summary.Buckets[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Direction < b.Direction
+	})
+	return summary
+}
+
+func included(p breez_sdk_liquid.Payment, opts Options) bool {
+	ts := int64(p.Timestamp)
+	if opts.From != nil && ts < opts.From.Unix() {
+		return false
+	}
+	if opts.To != nil && ts > opts.To.Unix() {
+		return false
+	}
+	if len(opts.States) == 0 {
+		return p.Status == breez_sdk_liquid.PaymentStateComplete || RefundFeesSat(p) > 0
+	}
+	for _, state := range opts.States {
+		if p.Status == state {
+			return true
+		}
+	}
+	return false
+}
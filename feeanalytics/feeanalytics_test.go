@@ -0,0 +1,65 @@
+package feeanalytics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+var day = time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+
+func lightning(at time.Time, state breez_sdk_liquid.PaymentState, amountSat, feesSat, swapperFeesSat uint64, refundedSat *uint64) breez_sdk_liquid.Payment {
+	return breez_sdk_liquid.Payment{
+		Timestamp:      uint32(at.Unix()),
+		AmountSat:      amountSat,
+		FeesSat:        feesSat,
+		SwapperFeesSat: &swapperFeesSat,
+		PaymentType:    breez_sdk_liquid.PaymentTypeSend,
+		Status:         state,
+		Details:        breez_sdk_liquid.PaymentDetailsLightning{RefundTxAmountSat: refundedSat},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	refunded := uint64(10050)
+	payments := []breez_sdk_liquid.Payment{
+		lightning(day, breez_sdk_liquid.PaymentStateComplete, 10000, 100, 60, nil),
+		lightning(day.Add(2*time.Hour), breez_sdk_liquid.PaymentStateComplete, 20000, 100, 50, nil),
+		// Refunded, losing 60 sat to on-chain fees.
+		lightning(day.Add(24*time.Hour), breez_sdk_liquid.PaymentStateFailed, 10000, 110, 50, &refunded),
+		// Not included by default.
+		lightning(day, breez_sdk_liquid.PaymentStatePending, 5000, 50, 20, nil),
+	}
+	s := Aggregate(payments, Options{})
+	total := s.Total
+	if total.Count != 3 || total.AmountSat != 40000 || total.FeesSat != 200 || total.SwapperFeesSat != 110 ||
+		total.ClaimFeesSat != 90 || total.RefundFeesSat != 60 {
+		t.Fatalf("total %+v", total)
+	}
+	if total.EffectiveRate != 260.0/40000 || total.MinRate != 0.005 || total.MaxRate != 0.01 {
+		t.Fatalf("rates %+v", total)
+	}
+	if len(s.Groups) != 1 || s.Groups[0].Method != "lightning" || s.Groups[0].Direction != "send" {
+		t.Fatalf("groups %+v", s.Groups)
+	}
+	if len(s.Buckets) != 2 || !s.Buckets[0].Start.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)) || s.Buckets[0].Count != 2 {
+		t.Fatalf("buckets %+v", s.Buckets)
+	}
+
+	// Weeks start on Monday, 4 March 2024.
+	if s := Aggregate(payments, Options{Granularity: Week}); len(s.Buckets) != 1 || s.Buckets[0].Start.Weekday() != time.Monday {
+		t.Fatalf("weekly buckets %+v", s.Buckets)
+	}
+}
+
+func TestAnalyzeFailure(t *testing.T) {
+	sdk := intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		return nil, breez_sdk_liquid.NewPaymentErrorPersistError()
+	})
+	if _, err := New(sdk, Options{}).Analyze(); !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorPersistError) {
+		t.Fatalf("got %v, want the ListPayments error", err)
+	}
+}
@@ -0,0 +1,339 @@
+// Package feepolicy automatically handles amountless Bitcoin receives that
+// are waiting for fee acceptance. Proposed fees are evaluated against
+// configurable rules and either accepted or escalated to a callback, and
+// every decision is recorded.
+package feepolicy
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Proposal holds the fees proposed for a swap.
+type Proposal struct {
+	SwapId            string `json:"swap_id"`
+	BitcoinAddress    string `json:"bitcoin_address"`
+	FeesSat           uint64 `json:"fees_sat"`
+	PayerAmountSat    uint64 `json:"payer_amount_sat"`
+	ReceiverAmountSat uint64 `json:"receiver_amount_sat"`
+	Quote             *Quote `json:"quote,omitempty"`
+
+	response breez_sdk_liquid.FetchPaymentProposedFeesResponse
+}
+
+// FeeRate returns FeesSat relative to PayerAmountSat.
+func (p Proposal) FeeRate() float64 {
+	if p.PayerAmountSat == 0 {
+		return 0
+	}
+	return float64(p.FeesSat) / float64(p.PayerAmountSat)
+}
+
+// QuoteDeviation returns by how much FeesSat exceeds the fees expected from
+// the quote, relative to the expected fees.
+func (p Proposal) QuoteDeviation() (float64, bool) {
+	if p.Quote == nil {
+		return 0, false
+	}
+	expected := p.Quote.ExpectedFeesSat(p.PayerAmountSat)
+	if expected == 0 {
+		if p.FeesSat == 0 {
+			return 0, true
+		}
+		return 1, true
+	}
+	return (float64(p.FeesSat) - float64(expected)) / float64(expected), true
+}
+
+// ErrDeclined can be returned by an escalation callback to decline the fees
+// without it being recorded as a failure.
+var ErrDeclined = errors.New("fees declined")
+
+// Options configures an Engine.
+type Options struct {
+	Rules Rules
+	// Escalate is called for proposals the rules do not accept. Returning
+	// true accepts the fees. If nil, such proposals are left waiting. It is
+	// called once per proposal: a swap whose proposed fees are unchanged
+	// since they were declined or left waiting is not escalated again.
+	Escalate func(Proposal) (bool, error)
+	// Recorder receives every decision. Defaults to a MemoryRecorder
+	// keeping the last 1000 decisions.
+	Recorder Recorder
+}
+
+// Engine listens for SdkEventPaymentWaitingFeeAcceptance events and applies
+// the rules to the proposed fees.
+type Engine struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	mu       sync.Mutex
+	quotes   map[string]Quote
+	inFlight map[string]bool
+	// escalated holds the last escalation of the swaps still waiting,
+	// by swap id.
+	escalated  map[string]Decision
+	listenerId string
+	queue      chan breez_sdk_liquid.Payment
+	done       chan struct{}
+	wg         sync.WaitGroup
+}
+
+// New creates an Engine for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Engine {
+	if opts.Recorder == nil {
+		opts.Recorder = NewMemoryRecorder(1000)
+	}
+	return &Engine{
+		sdk:       sdk,
+		opts:      opts,
+		quotes:    map[string]Quote{},
+		inFlight:  map[string]bool{},
+		escalated: map[string]Decision{},
+	}
+}
+
+// Recorder returns the recorder decisions are written to.
+func (e *Engine) Recorder() Recorder {
+	return e.opts.Recorder
+}
+
+// RecordQuote remembers the quote of a receive so that the proposed fees can
+// be compared against it. destination is ReceivePaymentResponse.Destination,
+// either a Bitcoin address or a BIP21 URI. The quote is forgotten once the
+// fees are accepted or the swap finishes.
+func (e *Engine) RecordQuote(destination string, quote Quote) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.quotes[sdkutil.Address(destination)] = quote
+}
+
+func (e *Engine) quote(address string) *Quote {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if quote, ok := e.quotes[address]; ok {
+		return &quote
+	}
+	return nil
+}
+
+// Start registers the event listener and evaluates the payments that are
+// already waiting for fee acceptance.
+func (e *Engine) Start() error {
+	e.mu.Lock()
+	if e.done != nil {
+		e.mu.Unlock()
+		return errors.New("engine already started")
+	}
+	queue := make(chan breez_sdk_liquid.Payment, 64)
+	done := make(chan struct{})
+	e.queue, e.done = queue, done
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go e.loop(queue, done)
+
+	listenerId, err := e.sdk.AddEventListener(sdkutil.EventListenerFunc(e.onEvent))
+	if err != nil {
+		e.Stop()
+		return err
+	}
+	e.mu.Lock()
+	e.listenerId = listenerId
+	e.mu.Unlock()
+
+	states := []breez_sdk_liquid.PaymentState{breez_sdk_liquid.PaymentStateWaitingFeeAcceptance}
+	payments, listErr := sdkutil.ListAllPayments(e.sdk, breez_sdk_liquid.ListPaymentsRequest{States: &states})
+	if listErr != nil {
+		e.Stop()
+		return listErr
+	}
+	for _, payment := range payments {
+		e.enqueue(payment, true)
+	}
+	return nil
+}
+
+// Stop removes the event listener and waits for the pending evaluation to
+// finish.
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	done, listenerId := e.done, e.listenerId
+	e.done, e.listenerId = nil, ""
+	e.mu.Unlock()
+	if done == nil {
+		return
+	}
+	if listenerId != "" {
+		_ = e.sdk.RemoveEventListener(listenerId)
+	}
+	close(done)
+	e.wg.Wait()
+}
+
+func (e *Engine) onEvent(event breez_sdk_liquid.SdkEvent) {
+	if event, ok := event.(breez_sdk_liquid.SdkEventPaymentWaitingFeeAcceptance); ok {
+		e.enqueue(event.Details, false)
+		return
+	}
+	payment, ok := sdkutil.EventPayment(event)
+	if !ok {
+		return
+	}
+	details, ok := payment.Details.(breez_sdk_liquid.PaymentDetailsBitcoin)
+	if !ok {
+		return
+	}
+	switch payment.Status {
+	case breez_sdk_liquid.PaymentStateComplete, breez_sdk_liquid.PaymentStateFailed,
+		breez_sdk_liquid.PaymentStateTimedOut, breez_sdk_liquid.PaymentStateRefundable:
+		// The swap finished.
+		e.mu.Lock()
+		delete(e.quotes, details.BitcoinAddress)
+		delete(e.escalated, details.SwapId)
+		e.mu.Unlock()
+	}
+}
+
+// enqueue queues a payment for evaluation unless it is already queued. With
+// wait unset, as on the event listener goroutine which must not block, the
+// payment is dropped if the queue is full: it is evaluated again on its next
+// event or on the next Start.
+func (e *Engine) enqueue(payment breez_sdk_liquid.Payment, wait bool) {
+	swapId := sdkutil.SwapId(payment)
+	e.mu.Lock()
+	if swapId == "" || e.inFlight[swapId] || e.done == nil {
+		e.mu.Unlock()
+		return
+	}
+	e.inFlight[swapId] = true
+	queue, done := e.queue, e.done
+	e.mu.Unlock()
+
+	if !wait {
+		select {
+		case queue <- payment:
+		default:
+			e.mu.Lock()
+			delete(e.inFlight, swapId)
+			e.mu.Unlock()
+		}
+		return
+	}
+	select {
+	case queue <- payment:
+	case <-done:
+	}
+}
+
+func (e *Engine) loop(queue <-chan breez_sdk_liquid.Payment, done <-chan struct{}) {
+	defer e.wg.Done()
+	for {
+		select {
+		case <-done:
+			return
+		case payment := <-queue:
+			e.Evaluate(payment)
+			e.mu.Lock()
+			delete(e.inFlight, sdkutil.SwapId(payment))
+			e.mu.Unlock()
+		}
+	}
+}
+
+// Evaluate fetches the proposed fees of a payment waiting for fee
+// acceptance, applies the rules and records the decision.
+func (e *Engine) Evaluate(payment breez_sdk_liquid.Payment) Decision {
+	details, _ := payment.Details.(breez_sdk_liquid.PaymentDetailsBitcoin)
+	proposal := Proposal{
+		SwapId:         details.SwapId,
+		BitcoinAddress: details.BitcoinAddress,
+		Quote:          e.quote(details.BitcoinAddress),
+	}
+
+	res, err := e.sdk.FetchPaymentProposedFees(breez_sdk_liquid.FetchPaymentProposedFeesRequest{SwapId: details.SwapId})
+	if err != nil {
+		return e.record(proposal, ActionFailed, nil, err)
+	}
+	proposal.FeesSat = res.FeesSat
+	proposal.PayerAmountSat = res.PayerAmountSat
+	proposal.ReceiverAmountSat = res.ReceiverAmountSat
+	proposal.response = res
+
+	pass, reasons := e.opts.Rules.evaluate(proposal)
+	if pass {
+		if err := e.accept(proposal); err != nil {
+			return e.record(proposal, ActionFailed, reasons, err)
+		}
+		return e.record(proposal, ActionAccepted, reasons, nil)
+	}
+
+	if previous, ok := e.escalation(proposal); ok {
+		return previous
+	}
+	if e.opts.Escalate == nil {
+		return e.escalate(e.record(proposal, ActionEscalated, reasons, nil))
+	}
+	accepted, escalateErr := e.opts.Escalate(proposal)
+	if escalateErr != nil && !errors.Is(escalateErr, ErrDeclined) {
+		return e.record(proposal, ActionFailed, reasons, escalateErr)
+	}
+	if !accepted {
+		return e.escalate(e.record(proposal, ActionEscalationDeclined, reasons, nil))
+	}
+	if err := e.accept(proposal); err != nil {
+		return e.record(proposal, ActionFailed, reasons, err)
+	}
+	return e.record(proposal, ActionEscalationAccepted, reasons, nil)
+}
+
+// escalation returns the last escalation of the swap if the proposed fees
+// are unchanged since.
+func (e *Engine) escalation(proposal Proposal) (Decision, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	previous, ok := e.escalated[proposal.SwapId]
+	if !ok || previous.Proposal.FeesSat != proposal.FeesSat || previous.Proposal.PayerAmountSat != proposal.PayerAmountSat {
+		return Decision{}, false
+	}
+	return previous, true
+}
+
+// escalate remembers an escalation left waiting.
+func (e *Engine) escalate(decision Decision) Decision {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.escalated[decision.Proposal.SwapId] = decision
+	return decision
+}
+
+func (e *Engine) accept(proposal Proposal) error {
+	return e.sdk.AcceptPaymentProposedFees(breez_sdk_liquid.AcceptPaymentProposedFeesRequest{
+		Response: proposal.response,
+	}).AsError()
+}
+
+func (e *Engine) record(proposal Proposal, action Action, reasons []string, err error) Decision {
+	if action == ActionAccepted || action == ActionEscalationAccepted {
+		e.mu.Lock()
+		delete(e.quotes, proposal.BitcoinAddress)
+		delete(e.escalated, proposal.SwapId)
+		e.mu.Unlock()
+	}
+	decision := Decision{
+		Time:     time.Now().UTC(),
+		Proposal: proposal,
+		Action:   action,
+		Reasons:  reasons,
+	}
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	e.opts.Recorder.Record(decision)
+	return decision
+}
@@ -0,0 +1,107 @@
+package feepolicy
+
+import (
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk proposes fees and records the proposals accepted.
+type fakeSdk struct {
+	proposed breez_sdk_liquid.FetchPaymentProposedFeesResponse
+	fetchErr *breez_sdk_liquid.SdkError
+	accepted []breez_sdk_liquid.FetchPaymentProposedFeesResponse
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		switch call.Method {
+		case "FetchPaymentProposedFees":
+			if f.fetchErr != nil {
+				return nil, f.fetchErr
+			}
+			return f.proposed, nil
+		case "AcceptPaymentProposedFees":
+			f.accepted = append(f.accepted, call.Request.(breez_sdk_liquid.AcceptPaymentProposedFeesRequest).Response)
+		}
+		return nil, nil
+	})
+}
+
+func waiting(swapId, address string) breez_sdk_liquid.Payment {
+	return breez_sdk_liquid.Payment{
+		PaymentType: breez_sdk_liquid.PaymentTypeReceive,
+		Status:      breez_sdk_liquid.PaymentStateWaitingFeeAcceptance,
+		Details:     breez_sdk_liquid.PaymentDetailsBitcoin{SwapId: swapId, BitcoinAddress: address},
+	}
+}
+
+func TestAcceptWithinQuote(t *testing.T) {
+	f := &fakeSdk{proposed: breez_sdk_liquid.FetchPaymentProposedFeesResponse{SwapId: "s", FeesSat: 105, PayerAmountSat: 10000, ReceiverAmountSat: 9895}}
+	e := New(f.sdk(), Options{Rules: Rules{MaxQuoteDeviation: 0.1}})
+	e.RecordQuote("bitcoin:bc1q?amount=0.0001", Quote{FeesSat: 100})
+
+	d := e.Evaluate(waiting("s", "bc1q"))
+	if d.Action != ActionAccepted || d.Proposal.Quote == nil {
+		t.Fatalf("decision %+v", d)
+	}
+	if len(f.accepted) != 1 || f.accepted[0].FeesSat != 105 {
+		t.Fatalf("accepted %+v", f.accepted)
+	}
+	if len(e.quotes) != 0 {
+		t.Fatalf("quotes kept after acceptance: %+v", e.quotes)
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	f := &fakeSdk{proposed: breez_sdk_liquid.FetchPaymentProposedFeesResponse{SwapId: "s", FeesSat: 500, PayerAmountSat: 10000}}
+	recorder := NewMemoryRecorder(0)
+	e := New(f.sdk(), Options{Rules: Rules{MaxFeesSat: 200}, Recorder: recorder})
+	e.RecordQuote("bc1q", Quote{FeesSat: 100})
+
+	if d := e.Evaluate(waiting("s", "bc1q")); d.Action != ActionEscalated {
+		t.Fatalf("decision %+v", d)
+	}
+	// Unchanged fees are not escalated again.
+	if d := e.Evaluate(waiting("s", "bc1q")); d.Action != ActionEscalated || len(recorder.Decisions()) != 1 {
+		t.Fatalf("decision %+v, %d recorded", d, len(recorder.Decisions()))
+	}
+	if len(f.accepted) != 0 {
+		t.Fatalf("accepted %+v", f.accepted)
+	}
+
+	// The swap finishes without the fees being accepted.
+	failed := waiting("s", "bc1q")
+	failed.Status = breez_sdk_liquid.PaymentStateFailed
+	e.onEvent(breez_sdk_liquid.SdkEventPaymentFailed{Details: failed})
+	if len(e.quotes) != 0 || len(e.escalated) != 0 {
+		t.Fatalf("kept quotes %+v and escalations %+v", e.quotes, e.escalated)
+	}
+}
+
+func TestEscalationCallback(t *testing.T) {
+	f := &fakeSdk{proposed: breez_sdk_liquid.FetchPaymentProposedFeesResponse{SwapId: "s", FeesSat: 500, PayerAmountSat: 10000}}
+	var escalated []Proposal
+	e := New(f.sdk(), Options{Rules: Rules{MaxFeeRate: 0.01}, Escalate: func(p Proposal) (bool, error) {
+		escalated = append(escalated, p)
+		return true, nil
+	}})
+	if d := e.Evaluate(waiting("s", "bc1q")); d.Action != ActionEscalationAccepted {
+		t.Fatalf("decision %+v", d)
+	}
+	if len(escalated) != 1 || len(f.accepted) != 1 {
+		t.Fatalf("escalated %+v, accepted %+v", escalated, f.accepted)
+	}
+}
+
+func TestFetchFailure(t *testing.T) {
+	f := &fakeSdk{fetchErr: breez_sdk_liquid.NewSdkErrorServiceConnectivity()}
+	e := New(f.sdk(), Options{Rules: Rules{MaxFeesSat: 1000}})
+	if d := e.Evaluate(waiting("s", "bc1q")); d.Action != ActionFailed || d.Error == "" {
+		t.Fatalf("decision %+v", d)
+	}
+	if len(f.accepted) != 0 {
+		t.Fatalf("accepted %+v", f.accepted)
+	}
+}
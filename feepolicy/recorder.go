@@ -0,0 +1,87 @@
+package feepolicy
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Action is the outcome of a decision.
+type Action string
+
+const (
+	// ActionAccepted means the rules accepted the fees automatically.
+	ActionAccepted Action = "accepted"
+	// ActionEscalated means the rules did not accept the fees and no
+	// escalation callback is configured; the payment stays waiting.
+	ActionEscalated Action = "escalated"
+	// ActionEscalationAccepted means the escalation callback accepted the
+	// fees.
+	ActionEscalationAccepted Action = "escalation_accepted"
+	// ActionEscalationDeclined means the escalation callback declined the
+	// fees; the payment stays waiting.
+	ActionEscalationDeclined Action = "escalation_declined"
+	// ActionFailed means fetching or accepting the fees failed.
+	ActionFailed Action = "failed"
+)
+
+// Decision records how a proposal was handled.
+type Decision struct {
+	Time     time.Time `json:"time"`
+	Proposal Proposal  `json:"proposal"`
+	Action   Action    `json:"action"`
+	Reasons  []string  `json:"reasons,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Recorder stores decisions.
+type Recorder interface {
+	Record(d Decision)
+}
+
+// MemoryRecorder keeps the most recent decisions in memory.
+type MemoryRecorder struct {
+	mu        sync.Mutex
+	max       int
+	decisions []Decision
+}
+
+// NewMemoryRecorder creates a MemoryRecorder holding up to max decisions,
+// or all of them if max is zero.
+func NewMemoryRecorder(max int) *MemoryRecorder {
+	return &MemoryRecorder{max: max}
+}
+
+func (r *MemoryRecorder) Record(d Decision) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decisions = append(r.decisions, d)
+	if r.max > 0 && len(r.decisions) > r.max {
+		r.decisions = append([]Decision(nil), r.decisions[len(r.decisions)-r.max:]...)
+	}
+}
+
+// Decisions returns the recorded decisions, oldest first.
+func (r *MemoryRecorder) Decisions() []Decision {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Decision(nil), r.decisions...)
+}
+
+// JSONRecorder writes every decision as a line of JSON.
+type JSONRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONRecorder creates a JSONRecorder writing to w.
+func NewJSONRecorder(w io.Writer) *JSONRecorder {
+	return &JSONRecorder{enc: json.NewEncoder(w)}
+}
+
+func (r *JSONRecorder) Record(d Decision) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(d)
+}
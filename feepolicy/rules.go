@@ -0,0 +1,105 @@
+package feepolicy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// Rules decide whether proposed fees are accepted automatically. A zero
+// value disables the corresponding rule; proposals that pass every enabled
+// rule are accepted. With no rule enabled every proposal is escalated.
+type Rules struct {
+	// MaxFeesSat caps the proposed FeesSat.
+	MaxFeesSat uint64
+	// MaxFeeRate caps FeesSat relative to PayerAmountSat, e.g. 0.01 for 1%.
+	MaxFeeRate float64
+	// MaxQuoteDeviation caps how much the proposed fees may exceed the fees
+	// expected from the quote recorded with Engine.RecordQuote, e.g. 0.1
+	// for 10%. Proposals without a recorded quote fail this rule.
+	MaxQuoteDeviation float64
+	// AllowedSwapIds are accepted without evaluating any other rule.
+	AllowedSwapIds []string
+}
+
+func (r Rules) enabled() bool {
+	return r.MaxFeesSat > 0 || r.MaxFeeRate > 0 || r.MaxQuoteDeviation > 0
+}
+
+func (r Rules) allowed(swapId string) bool {
+	for _, id := range r.AllowedSwapIds {
+		if id == swapId {
+			return true
+		}
+	}
+	return false
+}
+
+// Quote is the fee quote given when the receive was created.
+type Quote struct {
+	// FeesSat is PrepareReceiveResponse.FeesSat.
+	FeesSat uint64 `json:"fees_sat"`
+	// SwapperFeerate is PrepareReceiveResponse.SwapperFeerate, a
+	// percentage of the payer amount.
+	SwapperFeerate float64 `json:"swapper_feerate"`
+}
+
+// QuoteFromPrepare builds a Quote from the response used to create the
+// receive.
+func QuoteFromPrepare(res breez_sdk_liquid.PrepareReceiveResponse) Quote {
+	quote := Quote{FeesSat: res.FeesSat}
+	if res.SwapperFeerate != nil {
+		quote.SwapperFeerate = *res.SwapperFeerate
+	}
+	return quote
+}
+
+// ExpectedFeesSat returns the fees the quote implies for payerAmountSat.
+func (q Quote) ExpectedFeesSat(payerAmountSat uint64) uint64 {
+	return q.FeesSat + uint64(math.Ceil(float64(payerAmountSat)*q.SwapperFeerate/100))
+}
+
+// evaluate returns whether the proposal passes the rules, and the reasons
+// for the outcome.
+func (r Rules) evaluate(proposal Proposal) (bool, []string) {
+	if r.allowed(proposal.SwapId) {
+		return true, []string{"swap is allowlisted"}
+	}
+	if !r.enabled() {
+		return false, []string{"no rule configured"}
+	}
+
+	pass := true
+	var reasons []string
+	if r.MaxFeesSat > 0 {
+		if proposal.FeesSat > r.MaxFeesSat {
+			pass = false
+			reasons = append(reasons, fmt.Sprintf("fees %d sat exceed cap of %d sat", proposal.FeesSat, r.MaxFeesSat))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("fees %d sat within cap of %d sat", proposal.FeesSat, r.MaxFeesSat))
+		}
+	}
+	if r.MaxFeeRate > 0 {
+		rate := proposal.FeeRate()
+		if rate > r.MaxFeeRate {
+			pass = false
+			reasons = append(reasons, fmt.Sprintf("fee rate %.4f exceeds cap of %.4f", rate, r.MaxFeeRate))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("fee rate %.4f within cap of %.4f", rate, r.MaxFeeRate))
+		}
+	}
+	if r.MaxQuoteDeviation > 0 {
+		switch deviation, ok := proposal.QuoteDeviation(); {
+		case !ok:
+			pass = false
+			reasons = append(reasons, "no quote recorded for the swap")
+		case deviation > r.MaxQuoteDeviation:
+			pass = false
+			reasons = append(reasons, fmt.Sprintf("fees deviate %.4f from the quote, cap is %.4f", deviation, r.MaxQuoteDeviation))
+		default:
+			reasons = append(reasons, fmt.Sprintf("fees deviate %.4f from the quote, within cap of %.4f", deviation, r.MaxQuoteDeviation))
+		}
+	}
+	return pass, reasons
+}
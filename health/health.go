@@ -0,0 +1,336 @@
+// Package health provides liveness and readiness probes for services
+// embedding the SDK. The handler serves /healthz and /readyz with a JSON body
+// describing each check.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultTimeout          = 5 * time.Second
+	DefaultMaxSyncAge       = 5 * time.Minute
+	DefaultLiquidTipMaxAge  = 10 * time.Minute
+	DefaultBitcoinTipMaxAge = 2 * time.Hour
+)
+
+// Status of a check or of a whole probe.
+type Status string
+
+const (
+	StatusOk   Status = "ok"
+	StatusFail Status = "fail"
+)
+
+// Check is an additional readiness check.
+type Check struct {
+	Name string
+	// Run returns an error if the check fails.
+	Run func(ctx context.Context) error
+	// Optional checks are reported but do not fail the probe.
+	Optional bool
+}
+
+// LimitsCheck fails if the Lightning swap limits cannot be fetched.
+func LimitsCheck(sdk breez_sdk_liquid.BindingLiquidSdkInterface) Check {
+	return Check{
+		Name: "limits",
+		Run: func(context.Context) error {
+			_, err := sdk.FetchLightningLimits()
+			return err.AsError()
+		},
+	}
+}
+
+// SignerCheck fails if the signer does not sign a message.
+func SignerCheck(sdk breez_sdk_liquid.BindingLiquidSdkInterface) Check {
+	return Check{
+		Name: "signer",
+		Run: func(context.Context) error {
+			_, err := sdk.SignMessage(breez_sdk_liquid.SignMessageRequest{Message: "health check"})
+			return err.AsError()
+		},
+	}
+}
+
+// Options configures a Checker.
+type Options struct {
+	// Timeout of each check. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// MaxSyncAge is how long ago the last SdkEventSynced may have been
+	// received. Defaults to DefaultMaxSyncAge.
+	MaxSyncAge time.Duration
+	// LiquidTipMaxAge and BitcoinTipMaxAge are how long a blockchain tip
+	// may stay unchanged. Default to DefaultLiquidTipMaxAge and
+	// DefaultBitcoinTipMaxAge.
+	LiquidTipMaxAge  time.Duration
+	BitcoinTipMaxAge time.Duration
+	// Checks are run by the readiness probe in addition to the built-in
+	// ones.
+	Checks []Check
+}
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Optional   bool   `json:"optional,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Message    string `json:"message,omitempty"`
+}
+
+// Result is the outcome of a probe.
+type Result struct {
+	Status    Status        `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+type tip struct {
+	height    uint32
+	changedAt time.Time
+}
+
+// Checker runs the health checks. Start must be called to track sync
+// events.
+type Checker struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	mu         sync.Mutex
+	listenerId string
+	startedAt  time.Time
+	lastSynced time.Time
+	liquidTip  tip
+	bitcoinTip tip
+}
+
+// New creates a Checker for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Checker {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxSyncAge <= 0 {
+		opts.MaxSyncAge = DefaultMaxSyncAge
+	}
+	if opts.LiquidTipMaxAge <= 0 {
+		opts.LiquidTipMaxAge = DefaultLiquidTipMaxAge
+	}
+	if opts.BitcoinTipMaxAge <= 0 {
+		opts.BitcoinTipMaxAge = DefaultBitcoinTipMaxAge
+	}
+	return &Checker{sdk: sdk, opts: opts}
+}
+
+// Start registers the event listener used to track syncs.
+func (c *Checker) Start() error {
+	listenerId, err := c.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if _, ok := e.(breez_sdk_liquid.SdkEventSynced); ok {
+			c.mu.Lock()
+			c.lastSynced = time.Now()
+			c.mu.Unlock()
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.listenerId = listenerId
+	c.startedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// Stop removes the event listener.
+func (c *Checker) Stop() {
+	c.mu.Lock()
+	listenerId := c.listenerId
+	c.listenerId = ""
+	c.mu.Unlock()
+	if listenerId != "" {
+		_ = c.sdk.RemoveEventListener(listenerId)
+	}
+}
+
+// probeInfo fetches GetInfo once for all the checks of a probe.
+type probeInfo struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	once sync.Once
+	res  breez_sdk_liquid.GetInfoResponse
+	err  error
+}
+
+func (p *probeInfo) get() (breez_sdk_liquid.GetInfoResponse, error) {
+	p.once.Do(func() {
+		res, err := p.sdk.GetInfo()
+		p.res, p.err = res, err.AsError()
+	})
+	return p.res, p.err
+}
+
+// Liveness checks that the SDK is connected.
+func (c *Checker) Liveness(ctx context.Context) Result {
+	info := &probeInfo{sdk: c.sdk}
+	return c.run(ctx, []Check{{Name: "connected", Run: func(context.Context) error { return c.connected(info) }}})
+}
+
+// Readiness checks that the SDK is connected, the blockchain tips are
+// advancing, a sync completed recently, and runs the configured checks.
+// The built-in checks share a single GetInfo call.
+func (c *Checker) Readiness(ctx context.Context) Result {
+	info := &probeInfo{sdk: c.sdk}
+	checks := []Check{
+		{Name: "connected", Run: func(context.Context) error { return c.connected(info) }},
+		{Name: "liquid_tip", Run: func(context.Context) error {
+			return c.tipAdvancing(info, "liquid", func() tip { return c.liquidTip }, c.opts.LiquidTipMaxAge)
+		}},
+		{Name: "bitcoin_tip", Run: func(context.Context) error {
+			return c.tipAdvancing(info, "bitcoin", func() tip { return c.bitcoinTip }, c.opts.BitcoinTipMaxAge)
+		}},
+		{Name: "synced", Run: c.synced},
+	}
+	return c.run(ctx, append(checks, c.opts.Checks...))
+}
+
+func (c *Checker) run(ctx context.Context, checks []Check) Result {
+	result := Result{Status: StatusOk, CheckedAt: time.Now().UTC()}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+	for _, r := range results {
+		if r.Status == StatusFail && !r.Optional {
+			result.Status = StatusFail
+		}
+	}
+	result.Checks = results
+	return result
+}
+
+func (c *Checker) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	// SDK calls cannot be cancelled, so the check runs in its own goroutine
+	// and is abandoned when the timeout expires.
+	errc := make(chan error, 1)
+	go func() {
+		errc <- check.Run(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", c.opts.Timeout)
+	}
+	result := CheckResult{
+		Name:       check.Name,
+		Status:     StatusOk,
+		Optional:   check.Optional,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Message = err.Error()
+	}
+	return result
+}
+
+func (c *Checker) connected(probe *probeInfo) error {
+	info, err := probe.get()
+	if err != nil {
+		if errors.Is(err, breez_sdk_liquid.ErrSdkErrorNotStarted) {
+			return errors.New("sdk not started")
+		}
+		return err
+	}
+	c.observeTips(info.BlockchainInfo)
+	return nil
+}
+
+func (c *Checker) observeTips(info breez_sdk_liquid.BlockchainInfo) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info.LiquidTip != c.liquidTip.height {
+		c.liquidTip = tip{height: info.LiquidTip, changedAt: now}
+	}
+	if info.BitcoinTip != c.bitcoinTip.height {
+		c.bitcoinTip = tip{height: info.BitcoinTip, changedAt: now}
+	}
+}
+
+func (c *Checker) tipAdvancing(probe *probeInfo, chain string, current func() tip, maxAge time.Duration) error {
+	info, err := probe.get()
+	if err != nil {
+		return err
+	}
+	c.observeTips(info.BlockchainInfo)
+	c.mu.Lock()
+	t := current()
+	c.mu.Unlock()
+	if t.height == 0 {
+		return fmt.Errorf("%s tip unknown", chain)
+	}
+	if age := time.Since(t.changedAt); age > maxAge {
+		return fmt.Errorf("%s tip %d unchanged for %s", chain, t.height, age.Round(time.Second))
+	}
+	return nil
+}
+
+func (c *Checker) synced(context.Context) error {
+	c.mu.Lock()
+	lastSynced, startedAt := c.lastSynced, c.startedAt
+	c.mu.Unlock()
+	if startedAt.IsZero() {
+		return errors.New("checker not started")
+	}
+	if lastSynced.IsZero() {
+		return fmt.Errorf("no sync since %s", startedAt.UTC().Format(time.RFC3339))
+	}
+	if age := time.Since(lastSynced); age > c.opts.MaxSyncAge {
+		return fmt.Errorf("last sync %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// Handler serves /healthz and /readyz. Failing probes respond with 503.
+func (c *Checker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, c.Liveness(r.Context()))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, c.Readiness(r.Context()))
+	})
+	return mux
+}
+
+func writeResult(w http.ResponseWriter, result Result) {
+	sort.SliceStable(result.Checks, func(i, j int) bool {
+		return result.Checks[i].Name < result.Checks[j].Name
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if result.Status != StatusOk {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(result)
+}
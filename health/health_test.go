@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk reports blockchain tips and keeps the event listener.
+type fakeSdk struct {
+	mu       sync.Mutex
+	infoErr  *breez_sdk_liquid.SdkError
+	infos    int
+	listener breez_sdk_liquid.EventListener
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch call.Method {
+		case "AddEventListener":
+			f.listener = call.Request.(breez_sdk_liquid.EventListener)
+			return "listener", nil
+		case "GetInfo":
+			f.infos++
+			if f.infoErr != nil {
+				return nil, f.infoErr
+			}
+			return breez_sdk_liquid.GetInfoResponse{BlockchainInfo: breez_sdk_liquid.BlockchainInfo{LiquidTip: 100, BitcoinTip: 200}}, nil
+		}
+		return nil, nil
+	})
+}
+
+func status(r Result, name string) Status {
+	for _, check := range r.Checks {
+		if check.Name == name {
+			return check.Status
+		}
+	}
+	return ""
+}
+
+func TestReadiness(t *testing.T) {
+	f := &fakeSdk{}
+	c := New(f.sdk(), Options{})
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+
+	// No sync yet.
+	r := c.Readiness(context.Background())
+	if r.Status != StatusFail || status(r, "synced") != StatusFail || status(r, "liquid_tip") != StatusOk {
+		t.Fatalf("before sync: %+v", r)
+	}
+	if f.infos != 1 {
+		t.Fatalf("GetInfo called %d times by a probe, want 1", f.infos)
+	}
+
+	f.listener.OnEvent(breez_sdk_liquid.SdkEventSynced{})
+	if r := c.Readiness(context.Background()); r.Status != StatusOk || len(r.Checks) != 4 {
+		t.Fatalf("after sync: %+v", r)
+	}
+}
+
+func TestNotStarted(t *testing.T) {
+	f := &fakeSdk{infoErr: breez_sdk_liquid.NewSdkErrorNotStarted()}
+	c := New(f.sdk(), Options{})
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	r := c.Liveness(context.Background())
+	if r.Status != StatusFail || r.Checks[0].Message != "sdk not started" {
+		t.Fatalf("liveness %+v", r)
+	}
+}
@@ -0,0 +1,405 @@
+// Package idempotency executes payments at most once per caller-supplied
+// key, across process restarts.
+//
+// The intent to pay is persisted before the SDK is called. Calling again
+// with a key returns the original result: the payment, looked up again so
+// that its status is current, or the original error. A key whose execution
+// was interrupted, or failed with an error that does not rule out the
+// payment, is resolved against the payment history and never executed
+// again.
+//
+// The state file must not be shared by several processes.
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// DefaultClockSkew is how long before a record's creation a matching
+// payment may be timestamped, used when Options.ClockSkew is not set.
+const DefaultClockSkew = 5 * time.Minute
+
+// Options configures an Executor.
+type Options struct {
+	// StatePath is the file the records are persisted to. Required.
+	StatePath string
+	// ClockSkew is how long before a record's creation a matching payment
+	// may be timestamped. Defaults to DefaultClockSkew.
+	ClockSkew time.Duration
+}
+
+// Executor executes payments keyed by idempotency keys.
+type Executor struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	mu       sync.Mutex
+	records  map[string]*Record
+	inFlight map[string]bool
+}
+
+// New creates an Executor and loads its persisted records.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Executor, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("idempotency: StatePath is required")
+	}
+	if opts.ClockSkew <= 0 {
+		opts.ClockSkew = DefaultClockSkew
+	}
+	e := &Executor{
+		sdk:      sdk,
+		opts:     opts,
+		records:  map[string]*Record{},
+		inFlight: map[string]bool{},
+	}
+	if err := filestore.Load(opts.StatePath, &e.records); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Records returns the records ordered by creation time.
+func (e *Executor) Records() []Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	records := make([]Record, 0, len(e.records))
+	for _, r := range e.records {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return records
+}
+
+// Record returns the record of a key.
+func (e *Executor) Record(key string) (Record, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r, ok := e.records[key]
+	if !ok {
+		return Record{}, false
+	}
+	return *r, true
+}
+
+// Forget removes the record of a key, allowing it to be executed again.
+// It must only be used once the payment is known not to have gone through.
+func (e *Executor) Forget(key string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.inFlight[key] {
+		return ErrInProgress
+	}
+	delete(e.records, key)
+	return filestore.Save(e.opts.StatePath, e.records)
+}
+
+// Replay returns the result of an executed key, as calling again with the
+// key would, without needing the request. It returns false if the key is
+// unknown.
+func (e *Executor) Replay(key string) (breez_sdk_liquid.Payment, bool, error) {
+	e.mu.Lock()
+	r, ok := e.records[key]
+	if !ok {
+		e.mu.Unlock()
+		return breez_sdk_liquid.Payment{}, false, nil
+	}
+	if e.inFlight[key] {
+		e.mu.Unlock()
+		return breez_sdk_liquid.Payment{}, true, ErrInProgress
+	}
+	e.inFlight[key] = true
+	rec := *r
+	e.mu.Unlock()
+	defer e.end(key)
+	payment, err := e.replay(&rec)
+	return payment, true, err
+}
+
+// SendPayment calls SendPayment at most once for the key.
+func (e *Executor) SendPayment(key string, req breez_sdk_liquid.SendPaymentRequest) (breez_sdk_liquid.SendPaymentResponse, error) {
+	target := sendTarget(req.PrepareResponse.Destination, req.PrepareResponse.Amount)
+	payment, err := e.execute(key, OperationSendPayment, target, target.fingerprint(), func(r *Record) (breez_sdk_liquid.Payment, error) {
+		res, err := e.sdk.SendPayment(req)
+		return res.Payment, err.AsError()
+	})
+	return breez_sdk_liquid.SendPaymentResponse{Payment: payment}, err
+}
+
+// PayOnchain calls PayOnchain at most once for the key.
+func (e *Executor) PayOnchain(key string, req breez_sdk_liquid.PayOnchainRequest) (breez_sdk_liquid.SendPaymentResponse, error) {
+	target := Target{
+		Kind:        KindBitcoinAddress,
+		Destination: req.Address,
+		AmountSat:   req.PrepareResponse.ReceiverAmountSat,
+	}
+	payment, err := e.execute(key, OperationPayOnchain, target, target.fingerprint(), func(r *Record) (breez_sdk_liquid.Payment, error) {
+		res, err := e.sdk.PayOnchain(req)
+		return res.Payment, err.AsError()
+	})
+	return breez_sdk_liquid.SendPaymentResponse{Payment: payment}, err
+}
+
+// LnurlPay calls LnurlPay at most once for the key. A replayed success
+// carries no success action, which the SDK does not persist.
+func (e *Executor) LnurlPay(key string, req breez_sdk_liquid.LnUrlPayRequest) (breez_sdk_liquid.LnUrlPayResult, error) {
+	// Each prepare call fetches a new invoice, so a retried job pays the
+	// same LNURL endpoint and amount through a different target.
+	target := sendTarget(req.PrepareResponse.Destination, nil)
+	fingerprint := fmt.Sprintf("%s:%d", req.PrepareResponse.Data.Callback, lnurlAmountSat(req.PrepareResponse.Amount))
+	var result breez_sdk_liquid.LnUrlPayResult
+	payment, err := e.execute(key, OperationLnurlPay, target, fingerprint, func(r *Record) (breez_sdk_liquid.Payment, error) {
+		res, err := e.sdk.LnurlPay(req)
+		if err != nil {
+			return breez_sdk_liquid.Payment{}, err.AsError()
+		}
+		result = res
+		switch res := res.(type) {
+		case breez_sdk_liquid.LnUrlPayResultEndpointSuccess:
+			return res.Data.Payment, nil
+		case breez_sdk_liquid.LnUrlPayResultEndpointError:
+			r.LnurlResult, r.Error = "endpoint_error", res.Data.Reason
+		case breez_sdk_liquid.LnUrlPayResultPayError:
+			r.LnurlResult, r.Error = "pay_error", res.Data.Reason
+		}
+		return breez_sdk_liquid.Payment{}, errLnurlFailed
+	})
+	if result != nil {
+		return result, nil
+	}
+	if err == nil {
+		return breez_sdk_liquid.LnUrlPayResultEndpointSuccess{
+			Data: breez_sdk_liquid.LnUrlPaySuccessData{Payment: payment},
+		}, nil
+	}
+	// Replay a failure reported in the result rather than as an error.
+	var failed *FailedError
+	if errors.As(err, &failed) && failed.Variant == "" {
+		if r, ok := e.Record(key); ok {
+			switch r.LnurlResult {
+			case "endpoint_error":
+				return breez_sdk_liquid.LnUrlPayResultEndpointError{
+					Data: breez_sdk_liquid.LnUrlErrorData{Reason: r.Error},
+				}, nil
+			case "pay_error":
+				return breez_sdk_liquid.LnUrlPayResultPayError{
+					Data: breez_sdk_liquid.LnUrlPayErrorData{PaymentHash: r.Target.PaymentHash, Reason: r.Error},
+				}, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+// errLnurlFailed marks a LnurlPay call returning a failure result.
+var errLnurlFailed = errors.New("lnurl pay failed")
+
+func lnurlAmountSat(amount breez_sdk_liquid.PayAmount) uint64 {
+	if a, ok := amount.(breez_sdk_liquid.PayAmountBitcoin); ok {
+		return a.ReceiverAmountSat
+	}
+	return 0
+}
+
+// execute runs call at most once for the key. call may set the error
+// fields of the record when it returns errLnurlFailed.
+func (e *Executor) execute(key string, op Operation, target Target, fingerprint string, call func(r *Record) (breez_sdk_liquid.Payment, error)) (breez_sdk_liquid.Payment, error) {
+	r, existing, err := e.begin(key, op, target, fingerprint)
+	if err != nil {
+		return breez_sdk_liquid.Payment{}, err
+	}
+	defer e.end(key)
+	if existing {
+		return e.replay(r)
+	}
+
+	payment, err := call(r)
+	switch {
+	case err == nil:
+		r.Status, r.PaymentId = StatusSucceeded, sdkutil.PaymentId(payment)
+	case errors.Is(err, errLnurlFailed):
+		r.Status = StatusFailed
+	case alreadyPaid(err):
+		// The payment was made before, possibly under another key or by
+		// a previous execution whose record was lost.
+		r.Status, r.Error = StatusUnknown, err.Error()
+		if err := e.save(r); err != nil {
+			return breez_sdk_liquid.Payment{}, err
+		}
+		return e.resolve(r)
+	case sdkutil.Ambiguous(err):
+		r.Status, r.Error, r.ErrorVariant = StatusUnknown, err.Error(), sdkutil.ErrorVariant(err)
+	default:
+		r.Status, r.Error, r.ErrorVariant = StatusFailed, err.Error(), sdkutil.ErrorVariant(err)
+	}
+	if saveErr := e.save(r); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if errors.Is(err, errLnurlFailed) {
+		err = &FailedError{Key: key, Message: r.Error}
+	}
+	return payment, err
+}
+
+// begin returns the record of the key, persisting a new pending one if the
+// key is unknown, and marks the key in flight.
+func (e *Executor) begin(key string, op Operation, target Target, fingerprint string) (*Record, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.inFlight[key] {
+		return nil, false, ErrInProgress
+	}
+	if r, ok := e.records[key]; ok {
+		if r.Operation != op || r.Fingerprint != fingerprint {
+			return nil, false, ErrKeyConflict
+		}
+		e.inFlight[key] = true
+		rec := *r
+		return &rec, true, nil
+	}
+	now := time.Now().UTC()
+	r := &Record{
+		Key:         key,
+		Operation:   op,
+		Target:      target,
+		Fingerprint: fingerprint,
+		Status:      StatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	e.records[key] = r
+	if err := filestore.Save(e.opts.StatePath, e.records); err != nil {
+		delete(e.records, key)
+		return nil, false, err
+	}
+	e.inFlight[key] = true
+	rec := *r
+	return &rec, false, nil
+}
+
+func (e *Executor) end(key string) {
+	e.mu.Lock()
+	delete(e.inFlight, key)
+	e.mu.Unlock()
+}
+
+func (e *Executor) save(r *Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r.UpdatedAt = time.Now().UTC()
+	rec := *r
+	e.records[r.Key] = &rec
+	return filestore.Save(e.opts.StatePath, e.records)
+}
+
+// replay returns the result of an executed key.
+func (e *Executor) replay(r *Record) (breez_sdk_liquid.Payment, error) {
+	switch r.Status {
+	case StatusSucceeded:
+		payment, found, err := e.lookup(r)
+		if err != nil {
+			return breez_sdk_liquid.Payment{}, err
+		}
+		if !found {
+			return breez_sdk_liquid.Payment{}, &UnknownOutcomeError{Key: r.Key, Err: "payment " + r.PaymentId + " not found"}
+		}
+		return payment, nil
+	case StatusFailed:
+		return breez_sdk_liquid.Payment{}, &FailedError{Key: r.Key, Variant: r.ErrorVariant, Message: r.Error}
+	default:
+		return e.resolve(r)
+	}
+}
+
+// errSeveralMatches is returned by lookup when the payment of a record
+// cannot be told apart from other payments to the same target.
+var errSeveralMatches = errors.New("several payments match the target")
+
+// resolve looks for a payment matching a pending or unknown record.
+func (e *Executor) resolve(r *Record) (breez_sdk_liquid.Payment, error) {
+	payment, found, err := e.lookup(r)
+	if errors.Is(err, errSeveralMatches) {
+		return breez_sdk_liquid.Payment{}, &UnknownOutcomeError{Key: r.Key, Err: err.Error()}
+	}
+	if err != nil {
+		return breez_sdk_liquid.Payment{}, err
+	}
+	if !found {
+		return breez_sdk_liquid.Payment{}, &UnknownOutcomeError{Key: r.Key, Err: r.Error}
+	}
+	r.Status, r.PaymentId, r.Error, r.ErrorVariant = StatusSucceeded, sdkutil.PaymentId(payment), "", ""
+	if err := e.save(r); err != nil {
+		return breez_sdk_liquid.Payment{}, err
+	}
+	return payment, nil
+}
+
+// lookup finds the payment of a record: by its payment id if known,
+// otherwise the only payment matching the target which is not bound to
+// another record. It returns errSeveralMatches if there are several.
+func (e *Executor) lookup(r *Record) (breez_sdk_liquid.Payment, bool, error) {
+	if r.Target.PaymentHash != "" {
+		payment, err := e.sdk.GetPayment(breez_sdk_liquid.GetPaymentRequestPaymentHash{PaymentHash: r.Target.PaymentHash})
+		if err != nil {
+			return breez_sdk_liquid.Payment{}, false, err
+		}
+		if payment != nil && r.Target.matches(*payment) {
+			return *payment, true, nil
+		}
+	}
+
+	from := r.CreatedAt.Add(-e.opts.ClockSkew).Unix()
+	ascending := true
+	payments, err := sdkutil.ListAllPayments(e.sdk, breez_sdk_liquid.ListPaymentsRequest{
+		Filters:       &[]breez_sdk_liquid.PaymentType{breez_sdk_liquid.PaymentTypeSend},
+		FromTimestamp: &from,
+		SortAscending: &ascending,
+	})
+	if err != nil {
+		return breez_sdk_liquid.Payment{}, false, err
+	}
+	bound := e.boundPaymentIds(r.Key)
+	var candidates []breez_sdk_liquid.Payment
+	for _, p := range payments {
+		id := sdkutil.PaymentId(p)
+		if r.PaymentId != "" {
+			if id == r.PaymentId {
+				return p, true, nil
+			}
+			continue
+		}
+		if r.Target.matches(p) && !bound[id] {
+			candidates = append(candidates, p)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return breez_sdk_liquid.Payment{}, false, nil
+	case 1:
+		return candidates[0], true, nil
+	default:
+		return breez_sdk_liquid.Payment{}, false, errSeveralMatches
+	}
+}
+
+// boundPaymentIds returns the payment ids of the records other than key.
+func (e *Executor) boundPaymentIds(key string) map[string]bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	bound := map[string]bool{}
+	for k, r := range e.records {
+		if k != key && r.PaymentId != "" {
+			bound[r.PaymentId] = true
+		}
+	}
+	return bound
+}
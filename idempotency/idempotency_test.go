@@ -0,0 +1,168 @@
+package idempotency
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+var errTimeout = breez_sdk_liquid.NewPaymentErrorPaymentTimeout()
+
+func newExecutor(t *testing.T, sdk *sdktest.Fake) *Executor {
+	t.Helper()
+	e, err := New(sdk, Options{StatePath: filepath.Join(t.TempDir(), "idempotency.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestReplay(t *testing.T) {
+	sdk := &sdktest.Fake{}
+	e := newExecutor(t, sdk)
+	first, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sdk.Sends != 1 {
+		t.Fatalf("%d sends, want 1", sdk.Sends)
+	}
+	if *second.Payment.TxId != *first.Payment.TxId {
+		t.Fatalf("replayed %s, want %s", *second.Payment.TxId, *first.Payment.TxId)
+	}
+
+	if _, err := e.SendPayment("k", sdktest.SendRequest("lq1", 2000, 0)); !errors.Is(err, ErrKeyConflict) {
+		t.Fatalf("reused key: got %v, want ErrKeyConflict", err)
+	}
+
+	if p, found, err := e.Replay("k"); !found || err != nil || *p.TxId != *first.Payment.TxId {
+		t.Fatalf("Replay: %+v, %v, %v", p, found, err)
+	}
+	if _, found, err := e.Replay("other"); found || err != nil {
+		t.Fatalf("Replay of an unknown key: %v, %v", found, err)
+	}
+
+	// The records survive a restart.
+	e, err = New(sdk, Options{StatePath: e.opts.StatePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0)); err != nil || sdk.Sends != 1 {
+		t.Fatalf("after restart: err %v, %d sends", err, sdk.Sends)
+	}
+}
+
+func TestReplayFailure(t *testing.T) {
+	sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorInsufficientFunds()}}
+	e := newExecutor(t, sdk)
+	if _, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0)); err == nil {
+		t.Fatal("no error")
+	}
+	_, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0))
+	var failed *FailedError
+	if !errors.As(err, &failed) || !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorInsufficientFunds) {
+		t.Fatalf("replayed %v, want the original failure", err)
+	}
+	if sdk.Sends != 1 {
+		t.Fatalf("%d sends, want 1", sdk.Sends)
+	}
+}
+
+func TestUnknownOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		// payments are made to lq1 besides the one of the call.
+		payments []uint64
+		paid     bool
+		want     bool
+	}{
+		{name: "paid", paid: true, want: true},
+		{name: "not paid"},
+		{name: "other amount", payments: []uint64{2000}},
+		{name: "several matches", payments: []uint64{1000}, paid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{errTimeout}, PaidOnError: tt.paid}
+			e := newExecutor(t, sdk)
+			for _, amount := range tt.payments {
+				sdk.Pay("lq1", amount)
+			}
+			if _, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0)); !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorPaymentTimeout) {
+				t.Fatalf("got %v, want the timeout", err)
+			}
+			if r, _ := e.Record("k"); r.Status != StatusUnknown {
+				t.Fatalf("status %s, want unknown", r.Status)
+			}
+
+			res, err := e.SendPayment("k", sdktest.SendRequest("lq1", 1000, 0))
+			if sdk.Sends != 1 {
+				t.Fatalf("%d sends, want 1", sdk.Sends)
+			}
+			if !tt.want {
+				var unknown *UnknownOutcomeError
+				if !errors.As(err, &unknown) {
+					t.Fatalf("got %v, want an UnknownOutcomeError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Payment.AmountSat != 1000 {
+				t.Fatalf("resolved to %+v", res.Payment)
+			}
+			if r, _ := e.Record("k"); r.Status != StatusSucceeded || r.PaymentId != *res.Payment.TxId {
+				t.Fatalf("record %+v", r)
+			}
+		})
+	}
+}
+
+func TestBoundPaymentsNotMatched(t *testing.T) {
+	sdk := &sdktest.Fake{}
+	e := newExecutor(t, sdk)
+	if _, err := e.SendPayment("first", sdktest.SendRequest("lq1", 1000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// The same payment again under another key, with an ambiguous error
+	// and no payment made: the payment of the first key is not its own.
+	sdk.SendErrs = []*breez_sdk_liquid.PaymentError{errTimeout}
+	if _, err := e.SendPayment("second", sdktest.SendRequest("lq1", 1000, 0)); err == nil {
+		t.Fatal("no error")
+	}
+	_, err := e.SendPayment("second", sdktest.SendRequest("lq1", 1000, 0))
+	var unknown *UnknownOutcomeError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("got %v, want an UnknownOutcomeError", err)
+	}
+}
+
+func TestOfferMatchesAmount(t *testing.T) {
+	offer := "lno1"
+	target := sendTarget(breez_sdk_liquid.SendDestinationBolt12{
+		Offer:             breez_sdk_liquid.LnOffer{Offer: offer},
+		ReceiverAmountSat: 1000,
+	}, nil)
+	payment := func(amountSat uint64) breez_sdk_liquid.Payment {
+		return breez_sdk_liquid.Payment{
+			AmountSat:   amountSat,
+			PaymentType: breez_sdk_liquid.PaymentTypeSend,
+			Details:     breez_sdk_liquid.PaymentDetailsLightning{Bolt12Offer: &offer},
+		}
+	}
+	if !target.matches(payment(1000)) {
+		t.Fatal("payment of the offer not matched")
+	}
+	// Another payment of the same offer.
+	if target.matches(payment(2000)) {
+		t.Fatal("payment of another amount matched")
+	}
+}
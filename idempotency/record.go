@@ -0,0 +1,182 @@
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Operation is the SDK method executed under a key.
+type Operation string
+
+const (
+	OperationSendPayment Operation = "send_payment"
+	OperationPayOnchain  Operation = "pay_onchain"
+	OperationLnurlPay    Operation = "lnurl_pay"
+)
+
+// Status of a keyed execution.
+type Status string
+
+const (
+	// StatusPending means the intent was persisted and the SDK called. A
+	// record left pending by a crash is resolved against the payment
+	// history on the next call with its key.
+	StatusPending Status = "pending"
+	// StatusSucceeded means the SDK returned a payment, or one matching
+	// the target was found.
+	StatusSucceeded Status = "succeeded"
+	// StatusFailed means the SDK rejected the payment.
+	StatusFailed Status = "failed"
+	// StatusUnknown means the SDK returned an error which does not rule
+	// out that the payment went through, e.g. a timeout, and no matching
+	// payment was found yet.
+	StatusUnknown Status = "unknown"
+)
+
+// Kind of payment target.
+const (
+	KindBolt11         = "bolt11"
+	KindBolt12         = "bolt12"
+	KindLiquidAddress  = "liquid_address"
+	KindBitcoinAddress = "bitcoin_address"
+)
+
+// Target identifies what a keyed execution pays.
+type Target struct {
+	Kind        string `json:"kind"`
+	Destination string `json:"destination"`
+	PaymentHash string `json:"payment_hash,omitempty"`
+	AmountSat   uint64 `json:"amount_sat,omitempty"`
+}
+
+// Record is the persisted state of a keyed execution.
+type Record struct {
+	Key       string    `json:"key"`
+	Operation Operation `json:"operation"`
+	Target    Target    `json:"target"`
+	// Fingerprint identifies the payment requested under the key, to
+	// detect a key reused for another payment.
+	Fingerprint string    `json:"fingerprint"`
+	Status      Status    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// PaymentId is the swap id or transaction id of the resulting payment.
+	PaymentId string `json:"payment_id,omitempty"`
+	// Error and ErrorVariant describe the error returned by the SDK.
+	Error        string `json:"error,omitempty"`
+	ErrorVariant string `json:"error_variant,omitempty"`
+	// LnurlResult is the LnUrlPayResult variant returned by LnurlPay when
+	// it is not a success: "endpoint_error" or "pay_error".
+	LnurlResult string `json:"lnurl_result,omitempty"`
+}
+
+var (
+	// ErrKeyConflict is returned when a key is reused for a different
+	// operation or target.
+	ErrKeyConflict = errors.New("idempotency key already used for a different payment")
+	// ErrInProgress is returned when a key is already being executed.
+	ErrInProgress = errors.New("idempotency key is being executed")
+)
+
+// UnknownOutcomeError is returned for a key whose payment may or may not
+// have been executed. It is never executed again: the call should be
+// repeated later, once the payment shows up in the history, or the key
+// resolved manually with Forget.
+type UnknownOutcomeError struct {
+	Key string
+	// Err is the error returned by the SDK, if known.
+	Err string
+}
+
+func (e *UnknownOutcomeError) Error() string {
+	if e.Err == "" {
+		return fmt.Sprintf("outcome of %q unknown", e.Key)
+	}
+	return fmt.Sprintf("outcome of %q unknown: %s", e.Key, e.Err)
+}
+
+// FailedError is returned when replaying a key whose execution failed.
+type FailedError struct {
+	Key     string
+	Variant string
+	Message string
+}
+
+func (e *FailedError) Error() string {
+	return fmt.Sprintf("%q failed: %s", e.Key, e.Message)
+}
+
+// Is matches the Err* sentinel of the SDK error variant originally
+// returned, e.g. breez_sdk_liquid.ErrPaymentErrorInsufficientFunds.
+func (e *FailedError) Is(target error) bool {
+	return e.Variant != "" && target.Error() == e.Variant
+}
+
+// sendTarget returns the target of a SendDestination.
+func sendTarget(destination breez_sdk_liquid.SendDestination, amount *breez_sdk_liquid.PayAmount) Target {
+	switch d := destination.(type) {
+	case breez_sdk_liquid.SendDestinationBolt11:
+		return Target{
+			Kind:        KindBolt11,
+			Destination: d.Invoice.Bolt11,
+			PaymentHash: d.Invoice.PaymentHash,
+			AmountSat:   sdkutil.Uint64Value(d.Invoice.AmountMsat) / 1000,
+		}
+	case breez_sdk_liquid.SendDestinationBolt12:
+		return Target{Kind: KindBolt12, Destination: d.Offer.Offer, AmountSat: d.ReceiverAmountSat}
+	case breez_sdk_liquid.SendDestinationLiquidAddress:
+		t := Target{Kind: KindLiquidAddress, Destination: d.AddressData.Address}
+		if amount != nil {
+			if a, ok := (*amount).(breez_sdk_liquid.PayAmountBitcoin); ok {
+				t.AmountSat = a.ReceiverAmountSat
+			}
+		} else {
+			t.AmountSat = sdkutil.Uint64Value(d.AddressData.AmountSat)
+		}
+		return t
+	default:
+		return Target{}
+	}
+}
+
+func (t Target) fingerprint() string {
+	return fmt.Sprintf("%s:%s:%d", t.Kind, t.Destination, t.AmountSat)
+}
+
+// matches reports whether a sent payment pays the target. Payments to an
+// address or an offer must also have the amount of the target, if known.
+func (t Target) matches(p breez_sdk_liquid.Payment) bool {
+	if p.PaymentType != breez_sdk_liquid.PaymentTypeSend {
+		return false
+	}
+	switch details := p.Details.(type) {
+	case breez_sdk_liquid.PaymentDetailsLightning:
+		switch t.Kind {
+		case KindBolt11:
+			return sdkutil.PaymentHash(p) == t.PaymentHash
+		case KindBolt12:
+			return sdkutil.StringValue(details.Bolt12Offer) == t.Destination && t.matchesAmount(p)
+		}
+	case breez_sdk_liquid.PaymentDetailsLiquid:
+		return t.Kind == KindLiquidAddress && details.Destination == t.Destination && t.matchesAmount(p)
+	case breez_sdk_liquid.PaymentDetailsBitcoin:
+		return t.Kind == KindBitcoinAddress && details.BitcoinAddress == t.Destination && t.matchesAmount(p)
+	}
+	return false
+}
+
+func (t Target) matchesAmount(p breez_sdk_liquid.Payment) bool {
+	return t.AmountSat == 0 || p.AmountSat == t.AmountSat
+}
+
+// alreadyPaid reports whether the SDK refused the payment because it was
+// already made or is being made.
+func alreadyPaid(err error) bool {
+	return errors.Is(err, breez_sdk_liquid.ErrPaymentErrorAlreadyPaid) ||
+		errors.Is(err, breez_sdk_liquid.ErrPaymentErrorPaymentInProgress) ||
+		errors.Is(err, breez_sdk_liquid.ErrLnUrlPayErrorAlreadyPaid)
+}
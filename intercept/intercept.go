@@ -0,0 +1,50 @@
+// Package intercept routes the calls made to the SDK and to the NWC service
+// through a chain of interceptors, so that cross-cutting behaviour such as
+// logging, metrics or authorization is written once for every method.
+//
+// The decorators in intercept_gen.go are generated from the bindings; run go
+// generate after updating them.
+//
+// Interceptors see the typed request and response of each call. Methods
+// taking no argument have a nil Call.Request, methods taking several have an
+// Args struct. An error returned by an interceptor that is not the method's
+// own error type is converted to its Generic variant, which errors.Is and
+// errors.As see through. Methods returning no
+// error drop any error returned by the chain.
+package intercept
+
+//go:generate go run ./internal/gen
+
+// Call describes a method call going through the chain.
+type Call struct {
+	// Service is the name of the decorated binding, "BindingLiquidSdk" or
+	// "BindingNwcService".
+	Service string
+	// Method is the name of the method called, e.g. "SendPayment".
+	Method string
+	// Request is the argument of the method. Interceptors may replace it
+	// with a value of the same type before calling the next handler.
+	Request any
+}
+
+// Handler invokes the rest of the chain and returns the method's response
+// and error.
+type Handler func(call *Call) (any, error)
+
+// Interceptor is called for every method call. It calls next to continue
+// the chain, or returns without calling it to short-circuit the call.
+type Interceptor func(call *Call, next Handler) (any, error)
+
+// Chain composes interceptors into one, the first being the outermost.
+func Chain(interceptors ...Interceptor) Interceptor {
+	return func(call *Call, final Handler) (any, error) {
+		h := final
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, next := interceptors[i], h
+			h = func(call *Call) (any, error) {
+				return interceptor(call, next)
+			}
+		}
+		return h(call)
+	}
+}
@@ -0,0 +1,596 @@
+// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+package intercept
+
+import (
+	"errors"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// LiquidSdk decorates a BindingLiquidSdkInterface, routing every method call
+// through an interceptor chain.
+type LiquidSdk struct {
+	next  breez_sdk_liquid.BindingLiquidSdkInterface
+	chain Interceptor
+}
+
+var _ breez_sdk_liquid.BindingLiquidSdkInterface = (*LiquidSdk)(nil)
+
+// WrapLiquidSdk returns a LiquidSdk calling next through the
+// interceptors, the first interceptor being the outermost.
+func WrapLiquidSdk(next breez_sdk_liquid.BindingLiquidSdkInterface, interceptors ...Interceptor) *LiquidSdk {
+	return &LiquidSdk{next: next, chain: Chain(interceptors...)}
+}
+
+func (s *LiquidSdk) AcceptPaymentProposedFees(req breez_sdk_liquid.AcceptPaymentProposedFeesRequest) *breez_sdk_liquid.PaymentError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "AcceptPaymentProposedFees", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.AcceptPaymentProposedFeesRequest)
+		return nil, s.next.AcceptPaymentProposedFees(req).AsError()
+	})
+	return toPaymentError(err)
+}
+
+func (s *LiquidSdk) AddEventListener(listener breez_sdk_liquid.EventListener) (string, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "AddEventListener", Request: listener}, func(call *Call) (any, error) {
+		listener := call.Request.(breez_sdk_liquid.EventListener)
+		res, err := s.next.AddEventListener(listener)
+		return res, err.AsError()
+	})
+	out, _ := res.(string)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) Backup(req breez_sdk_liquid.BackupRequest) *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "Backup", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.BackupRequest)
+		return nil, s.next.Backup(req).AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) BuyBitcoin(req breez_sdk_liquid.BuyBitcoinRequest) (string, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "BuyBitcoin", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.BuyBitcoinRequest)
+		res, err := s.next.BuyBitcoin(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(string)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) CheckMessage(req breez_sdk_liquid.CheckMessageRequest) (breez_sdk_liquid.CheckMessageResponse, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "CheckMessage", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.CheckMessageRequest)
+		res, err := s.next.CheckMessage(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.CheckMessageResponse)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) CreateBolt12Invoice(req breez_sdk_liquid.CreateBolt12InvoiceRequest) (breez_sdk_liquid.CreateBolt12InvoiceResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "CreateBolt12Invoice", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.CreateBolt12InvoiceRequest)
+		res, err := s.next.CreateBolt12Invoice(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.CreateBolt12InvoiceResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) Disconnect() *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "Disconnect", Request: nil}, func(call *Call) (any, error) {
+		return nil, s.next.Disconnect().AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) FetchFiatRates() ([]breez_sdk_liquid.Rate, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "FetchFiatRates", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.FetchFiatRates()
+		return res, err.AsError()
+	})
+	out, _ := res.([]breez_sdk_liquid.Rate)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) FetchLightningLimits() (breez_sdk_liquid.LightningPaymentLimitsResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "FetchLightningLimits", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.FetchLightningLimits()
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.LightningPaymentLimitsResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) FetchOnchainLimits() (breez_sdk_liquid.OnchainPaymentLimitsResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "FetchOnchainLimits", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.FetchOnchainLimits()
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.OnchainPaymentLimitsResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) FetchPaymentProposedFees(req breez_sdk_liquid.FetchPaymentProposedFeesRequest) (breez_sdk_liquid.FetchPaymentProposedFeesResponse, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "FetchPaymentProposedFees", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.FetchPaymentProposedFeesRequest)
+		res, err := s.next.FetchPaymentProposedFees(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.FetchPaymentProposedFeesResponse)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) GetInfo() (breez_sdk_liquid.GetInfoResponse, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "GetInfo", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.GetInfo()
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.GetInfoResponse)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) GetPayment(req breez_sdk_liquid.GetPaymentRequest) (*breez_sdk_liquid.Payment, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "GetPayment", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.GetPaymentRequest)
+		res, err := s.next.GetPayment(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(*breez_sdk_liquid.Payment)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) ListFiatCurrencies() ([]breez_sdk_liquid.FiatCurrency, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "ListFiatCurrencies", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.ListFiatCurrencies()
+		return res, err.AsError()
+	})
+	out, _ := res.([]breez_sdk_liquid.FiatCurrency)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) ListPayments(req breez_sdk_liquid.ListPaymentsRequest) ([]breez_sdk_liquid.Payment, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "ListPayments", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.ListPaymentsRequest)
+		res, err := s.next.ListPayments(req)
+		return res, err.AsError()
+	})
+	out, _ := res.([]breez_sdk_liquid.Payment)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) ListRefundables() ([]breez_sdk_liquid.RefundableSwap, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "ListRefundables", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.ListRefundables()
+		return res, err.AsError()
+	})
+	out, _ := res.([]breez_sdk_liquid.RefundableSwap)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) LnurlAuth(reqData breez_sdk_liquid.LnUrlAuthRequestData) (breez_sdk_liquid.LnUrlCallbackStatus, *breez_sdk_liquid.LnUrlAuthError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "LnurlAuth", Request: reqData}, func(call *Call) (any, error) {
+		reqData := call.Request.(breez_sdk_liquid.LnUrlAuthRequestData)
+		res, err := s.next.LnurlAuth(reqData)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.LnUrlCallbackStatus)
+	return out, toLnUrlAuthError(err)
+}
+
+func (s *LiquidSdk) LnurlPay(req breez_sdk_liquid.LnUrlPayRequest) (breez_sdk_liquid.LnUrlPayResult, *breez_sdk_liquid.LnUrlPayError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "LnurlPay", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.LnUrlPayRequest)
+		res, err := s.next.LnurlPay(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.LnUrlPayResult)
+	return out, toLnUrlPayError(err)
+}
+
+func (s *LiquidSdk) LnurlWithdraw(req breez_sdk_liquid.LnUrlWithdrawRequest) (breez_sdk_liquid.LnUrlWithdrawResult, *breez_sdk_liquid.LnUrlWithdrawError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "LnurlWithdraw", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.LnUrlWithdrawRequest)
+		res, err := s.next.LnurlWithdraw(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.LnUrlWithdrawResult)
+	return out, toLnUrlWithdrawError(err)
+}
+
+func (s *LiquidSdk) Parse(input string) (breez_sdk_liquid.InputType, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "Parse", Request: input}, func(call *Call) (any, error) {
+		input := call.Request.(string)
+		res, err := s.next.Parse(input)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.InputType)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) PayOnchain(req breez_sdk_liquid.PayOnchainRequest) (breez_sdk_liquid.SendPaymentResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PayOnchain", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PayOnchainRequest)
+		res, err := s.next.PayOnchain(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.SendPaymentResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) PrepareBuyBitcoin(req breez_sdk_liquid.PrepareBuyBitcoinRequest) (breez_sdk_liquid.PrepareBuyBitcoinResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PrepareBuyBitcoin", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PrepareBuyBitcoinRequest)
+		res, err := s.next.PrepareBuyBitcoin(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.PrepareBuyBitcoinResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) PrepareLnurlPay(req breez_sdk_liquid.PrepareLnUrlPayRequest) (breez_sdk_liquid.PrepareLnUrlPayResponse, *breez_sdk_liquid.LnUrlPayError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PrepareLnurlPay", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PrepareLnUrlPayRequest)
+		res, err := s.next.PrepareLnurlPay(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.PrepareLnUrlPayResponse)
+	return out, toLnUrlPayError(err)
+}
+
+func (s *LiquidSdk) PreparePayOnchain(req breez_sdk_liquid.PreparePayOnchainRequest) (breez_sdk_liquid.PreparePayOnchainResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PreparePayOnchain", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PreparePayOnchainRequest)
+		res, err := s.next.PreparePayOnchain(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.PreparePayOnchainResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) PrepareReceivePayment(req breez_sdk_liquid.PrepareReceiveRequest) (breez_sdk_liquid.PrepareReceiveResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PrepareReceivePayment", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PrepareReceiveRequest)
+		res, err := s.next.PrepareReceivePayment(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.PrepareReceiveResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) PrepareRefund(req breez_sdk_liquid.PrepareRefundRequest) (breez_sdk_liquid.PrepareRefundResponse, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PrepareRefund", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PrepareRefundRequest)
+		res, err := s.next.PrepareRefund(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.PrepareRefundResponse)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) PrepareSendPayment(req breez_sdk_liquid.PrepareSendRequest) (breez_sdk_liquid.PrepareSendResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "PrepareSendPayment", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.PrepareSendRequest)
+		res, err := s.next.PrepareSendPayment(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.PrepareSendResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) ReceivePayment(req breez_sdk_liquid.ReceivePaymentRequest) (breez_sdk_liquid.ReceivePaymentResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "ReceivePayment", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.ReceivePaymentRequest)
+		res, err := s.next.ReceivePayment(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.ReceivePaymentResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) RecommendedFees() (breez_sdk_liquid.RecommendedFees, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "RecommendedFees", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.RecommendedFees()
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.RecommendedFees)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) Refund(req breez_sdk_liquid.RefundRequest) (breez_sdk_liquid.RefundResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "Refund", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.RefundRequest)
+		res, err := s.next.Refund(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.RefundResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) RegisterWebhook(webhookUrl string) *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "RegisterWebhook", Request: webhookUrl}, func(call *Call) (any, error) {
+		webhookUrl := call.Request.(string)
+		return nil, s.next.RegisterWebhook(webhookUrl).AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) RemoveEventListener(id string) *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "RemoveEventListener", Request: id}, func(call *Call) (any, error) {
+		id := call.Request.(string)
+		return nil, s.next.RemoveEventListener(id).AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) RescanOnchainSwaps() *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "RescanOnchainSwaps", Request: nil}, func(call *Call) (any, error) {
+		return nil, s.next.RescanOnchainSwaps().AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) Restore(req breez_sdk_liquid.RestoreRequest) *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "Restore", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.RestoreRequest)
+		return nil, s.next.Restore(req).AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) SendPayment(req breez_sdk_liquid.SendPaymentRequest) (breez_sdk_liquid.SendPaymentResponse, *breez_sdk_liquid.PaymentError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "SendPayment", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.SendPaymentRequest)
+		res, err := s.next.SendPayment(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.SendPaymentResponse)
+	return out, toPaymentError(err)
+}
+
+func (s *LiquidSdk) SignMessage(req breez_sdk_liquid.SignMessageRequest) (breez_sdk_liquid.SignMessageResponse, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "SignMessage", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.SignMessageRequest)
+		res, err := s.next.SignMessage(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.SignMessageResponse)
+	return out, toSdkError(err)
+}
+
+func (s *LiquidSdk) Sync() *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "Sync", Request: nil}, func(call *Call) (any, error) {
+		return nil, s.next.Sync().AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) UnregisterWebhook() *breez_sdk_liquid.SdkError {
+	_, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "UnregisterWebhook", Request: nil}, func(call *Call) (any, error) {
+		return nil, s.next.UnregisterWebhook().AsError()
+	})
+	return toSdkError(err)
+}
+
+func (s *LiquidSdk) UseNwcPlugin(config breez_sdk_liquid.NwcConfig) (*breez_sdk_liquid.BindingNwcService, *breez_sdk_liquid.SdkError) {
+	res, err := s.chain(&Call{Service: "BindingLiquidSdk", Method: "UseNwcPlugin", Request: config}, func(call *Call) (any, error) {
+		config := call.Request.(breez_sdk_liquid.NwcConfig)
+		res, err := s.next.UseNwcPlugin(config)
+		return res, err.AsError()
+	})
+	out, _ := res.(*breez_sdk_liquid.BindingNwcService)
+	return out, toSdkError(err)
+}
+
+// NwcService decorates a BindingNwcServiceInterface, routing every method call
+// through an interceptor chain.
+type NwcService struct {
+	next  breez_sdk_liquid.BindingNwcServiceInterface
+	chain Interceptor
+}
+
+var _ breez_sdk_liquid.BindingNwcServiceInterface = (*NwcService)(nil)
+
+// WrapNwcService returns a NwcService calling next through the
+// interceptors, the first interceptor being the outermost.
+func WrapNwcService(next breez_sdk_liquid.BindingNwcServiceInterface, interceptors ...Interceptor) *NwcService {
+	return &NwcService{next: next, chain: Chain(interceptors...)}
+}
+
+// NwcServiceTrackZapArgs is the Call.Request of BindingNwcService.TrackZap.
+type NwcServiceTrackZapArgs struct {
+	Invoice    string
+	ZapRequest string
+}
+
+func (s *NwcService) AddConnection(req breez_sdk_liquid.AddConnectionRequest) (breez_sdk_liquid.AddConnectionResponse, *breez_sdk_liquid.NwcError) {
+	res, err := s.chain(&Call{Service: "BindingNwcService", Method: "AddConnection", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.AddConnectionRequest)
+		res, err := s.next.AddConnection(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.AddConnectionResponse)
+	return out, toNwcError(err)
+}
+
+func (s *NwcService) AddEventListener(listener breez_sdk_liquid.NwcEventListener) string {
+	res, _ := s.chain(&Call{Service: "BindingNwcService", Method: "AddEventListener", Request: listener}, func(call *Call) (any, error) {
+		listener := call.Request.(breez_sdk_liquid.NwcEventListener)
+		return s.next.AddEventListener(listener), nil
+	})
+	out, _ := res.(string)
+	return out
+}
+
+func (s *NwcService) EditConnection(req breez_sdk_liquid.EditConnectionRequest) (breez_sdk_liquid.EditConnectionResponse, *breez_sdk_liquid.NwcError) {
+	res, err := s.chain(&Call{Service: "BindingNwcService", Method: "EditConnection", Request: req}, func(call *Call) (any, error) {
+		req := call.Request.(breez_sdk_liquid.EditConnectionRequest)
+		res, err := s.next.EditConnection(req)
+		return res, err.AsError()
+	})
+	out, _ := res.(breez_sdk_liquid.EditConnectionResponse)
+	return out, toNwcError(err)
+}
+
+func (s *NwcService) GetInfo() *breez_sdk_liquid.NostrServiceInfo {
+	res, _ := s.chain(&Call{Service: "BindingNwcService", Method: "GetInfo", Request: nil}, func(call *Call) (any, error) {
+		return s.next.GetInfo(), nil
+	})
+	out, _ := res.(*breez_sdk_liquid.NostrServiceInfo)
+	return out
+}
+
+func (s *NwcService) HandleEvent(rawEvent string) *breez_sdk_liquid.NwcError {
+	_, err := s.chain(&Call{Service: "BindingNwcService", Method: "HandleEvent", Request: rawEvent}, func(call *Call) (any, error) {
+		rawEvent := call.Request.(string)
+		return nil, s.next.HandleEvent(rawEvent).AsError()
+	})
+	return toNwcError(err)
+}
+
+func (s *NwcService) IsZap(invoice string) (bool, *breez_sdk_liquid.NwcError) {
+	res, err := s.chain(&Call{Service: "BindingNwcService", Method: "IsZap", Request: invoice}, func(call *Call) (any, error) {
+		invoice := call.Request.(string)
+		res, err := s.next.IsZap(invoice)
+		return res, err.AsError()
+	})
+	out, _ := res.(bool)
+	return out, toNwcError(err)
+}
+
+func (s *NwcService) ListConnectionPayments(name string) ([]breez_sdk_liquid.Payment, *breez_sdk_liquid.NwcError) {
+	res, err := s.chain(&Call{Service: "BindingNwcService", Method: "ListConnectionPayments", Request: name}, func(call *Call) (any, error) {
+		name := call.Request.(string)
+		res, err := s.next.ListConnectionPayments(name)
+		return res, err.AsError()
+	})
+	out, _ := res.([]breez_sdk_liquid.Payment)
+	return out, toNwcError(err)
+}
+
+func (s *NwcService) ListConnections() (map[string]breez_sdk_liquid.NwcConnection, *breez_sdk_liquid.NwcError) {
+	res, err := s.chain(&Call{Service: "BindingNwcService", Method: "ListConnections", Request: nil}, func(call *Call) (any, error) {
+		res, err := s.next.ListConnections()
+		return res, err.AsError()
+	})
+	out, _ := res.(map[string]breez_sdk_liquid.NwcConnection)
+	return out, toNwcError(err)
+}
+
+func (s *NwcService) RemoveConnection(name string) *breez_sdk_liquid.NwcError {
+	_, err := s.chain(&Call{Service: "BindingNwcService", Method: "RemoveConnection", Request: name}, func(call *Call) (any, error) {
+		name := call.Request.(string)
+		return nil, s.next.RemoveConnection(name).AsError()
+	})
+	return toNwcError(err)
+}
+
+func (s *NwcService) RemoveEventListener(listenerId string) {
+	_, _ = s.chain(&Call{Service: "BindingNwcService", Method: "RemoveEventListener", Request: listenerId}, func(call *Call) (any, error) {
+		listenerId := call.Request.(string)
+		s.next.RemoveEventListener(listenerId)
+		return nil, nil
+	})
+}
+
+func (s *NwcService) Stop() {
+	_, _ = s.chain(&Call{Service: "BindingNwcService", Method: "Stop", Request: nil}, func(call *Call) (any, error) {
+		s.next.Stop()
+		return nil, nil
+	})
+}
+
+func (s *NwcService) TrackZap(invoice string, zapRequest string) *breez_sdk_liquid.NwcError {
+	_, err := s.chain(&Call{Service: "BindingNwcService", Method: "TrackZap", Request: NwcServiceTrackZapArgs{Invoice: invoice, ZapRequest: zapRequest}}, func(call *Call) (any, error) {
+		args := call.Request.(NwcServiceTrackZapArgs)
+		return nil, s.next.TrackZap(args.Invoice, args.ZapRequest).AsError()
+	})
+	return toNwcError(err)
+}
+
+// toLnUrlAuthError returns err as a *breez_sdk_liquid.LnUrlAuthError. Errors of any other type, returned
+// by an interceptor, are converted to the Generic variant, through which
+// errors.Is and errors.As still reach them.
+func toLnUrlAuthError(err error) *breez_sdk_liquid.LnUrlAuthError {
+	if err == nil {
+		return nil
+	}
+	var typed *breez_sdk_liquid.LnUrlAuthError
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return sdkutil.WithCause(breez_sdk_liquid.NewLnUrlAuthErrorGeneric(err.Error()), err)
+}
+
+// toLnUrlPayError returns err as a *breez_sdk_liquid.LnUrlPayError. Errors of any other type, returned
+// by an interceptor, are converted to the Generic variant, through which
+// errors.Is and errors.As still reach them.
+func toLnUrlPayError(err error) *breez_sdk_liquid.LnUrlPayError {
+	if err == nil {
+		return nil
+	}
+	var typed *breez_sdk_liquid.LnUrlPayError
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return sdkutil.WithCause(breez_sdk_liquid.NewLnUrlPayErrorGeneric(err.Error()), err)
+}
+
+// toLnUrlWithdrawError returns err as a *breez_sdk_liquid.LnUrlWithdrawError. Errors of any other type, returned
+// by an interceptor, are converted to the Generic variant, through which
+// errors.Is and errors.As still reach them.
+func toLnUrlWithdrawError(err error) *breez_sdk_liquid.LnUrlWithdrawError {
+	if err == nil {
+		return nil
+	}
+	var typed *breez_sdk_liquid.LnUrlWithdrawError
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return sdkutil.WithCause(breez_sdk_liquid.NewLnUrlWithdrawErrorGeneric(err.Error()), err)
+}
+
+// toNwcError returns err as a *breez_sdk_liquid.NwcError. Errors of any other type, returned
+// by an interceptor, are converted to the Generic variant, through which
+// errors.Is and errors.As still reach them.
+func toNwcError(err error) *breez_sdk_liquid.NwcError {
+	if err == nil {
+		return nil
+	}
+	var typed *breez_sdk_liquid.NwcError
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return sdkutil.WithCause(breez_sdk_liquid.NewNwcErrorGeneric(err.Error()), err)
+}
+
+// toPaymentError returns err as a *breez_sdk_liquid.PaymentError. Errors of any other type, returned
+// by an interceptor, are converted to the Generic variant, through which
+// errors.Is and errors.As still reach them.
+func toPaymentError(err error) *breez_sdk_liquid.PaymentError {
+	if err == nil {
+		return nil
+	}
+	var typed *breez_sdk_liquid.PaymentError
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return sdkutil.WithCause(sdkutil.PaymentErrorWithMessage(breez_sdk_liquid.NewPaymentErrorGeneric(), err.Error()), err)
+}
+
+// toSdkError returns err as a *breez_sdk_liquid.SdkError. Errors of any other type, returned
+// by an interceptor, are converted to the Generic variant, through which
+// errors.Is and errors.As still reach them.
+func toSdkError(err error) *breez_sdk_liquid.SdkError {
+	if err == nil {
+		return nil
+	}
+	var typed *breez_sdk_liquid.SdkError
+	if errors.As(err, &typed) {
+		return typed
+	}
+	return sdkutil.WithCause(sdkutil.SdkErrorWithMessage(breez_sdk_liquid.NewSdkErrorGeneric(), err.Error()), err)
+}
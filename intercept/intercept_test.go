@@ -0,0 +1,75 @@
+package intercept
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	record := func(name string) Interceptor {
+		return func(call *Call, next Handler) (any, error) {
+			order = append(order, name+">"+call.Method)
+			res, err := next(call)
+			order = append(order, "<"+name)
+			return res, err
+		}
+	}
+	// The outer interceptor rewrites the request seen by the inner one and
+	// the SDK.
+	rewrite := func(call *Call, next Handler) (any, error) {
+		call.Request = "lq" + call.Request.(string)
+		return next(call)
+	}
+	sdk := WrapLiquidSdk(&sdktest.Fake{}, record("a"), rewrite, record("b"))
+	res, err := sdk.Parse("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := res.(breez_sdk_liquid.InputTypeLiquidAddress); !ok || addr.Address.Address != "lq1" {
+		t.Fatalf("parsed %+v", res)
+	}
+	if got := strings.Join(order, " "); got != "a>Parse b>Parse <b <a" {
+		t.Fatalf("order %s", got)
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	fake := &sdktest.Fake{BalanceSat: 1000}
+	sdk := WrapLiquidSdk(fake, func(call *Call, next Handler) (any, error) {
+		return breez_sdk_liquid.GetInfoResponse{WalletInfo: breez_sdk_liquid.WalletInfo{BalanceSat: 5}}, nil
+	})
+	res, err := sdk.GetInfo()
+	if err != nil || res.WalletInfo.BalanceSat != 5 {
+		t.Fatalf("got %+v, %v", res, err)
+	}
+}
+
+func TestErrorConversion(t *testing.T) {
+	errDenied := errors.New("denied")
+	deny := func(call *Call, next Handler) (any, error) {
+		return nil, errDenied
+	}
+	_, err := WrapLiquidSdk(&sdktest.Fake{}, deny).SendPayment(sdktest.SendRequest("lq1", 1, 0))
+	if err == nil {
+		t.Fatal("no error")
+	}
+	if !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorGeneric) || !errors.Is(err, errDenied) {
+		t.Fatalf("got %v, want a Generic PaymentError caused by errDenied", err)
+	}
+	if !strings.Contains(err.Error(), "denied") || sdkutil.ErrorVariant(err) != "PaymentErrorGeneric" {
+		t.Fatalf("message %q, variant %s", err.Error(), sdkutil.ErrorVariant(err))
+	}
+
+	// Errors of the method's type are returned as is.
+	timeout := breez_sdk_liquid.NewPaymentErrorPaymentTimeout()
+	fake := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{timeout}}
+	if _, err := WrapLiquidSdk(fake).SendPayment(sdktest.SendRequest("lq1", 1, 0)); err != timeout {
+		t.Fatalf("got %v, want the SDK error", err)
+	}
+}
@@ -0,0 +1,327 @@
+// Command gen generates the interceptor decorators of package intercept from
+// the interfaces declared in the breez_sdk_liquid bindings.
+//
+// It is run from the intercept directory with go generate.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const pkg = "breez_sdk_liquid"
+
+// service describes an interface to generate a decorator for.
+type service struct {
+	iface   string // interface name in the bindings
+	name    string // service name reported in Call.Service
+	wrapper string // decorator type name
+	doc     string
+}
+
+var services = []service{
+	{
+		iface:   "BindingLiquidSdkInterface",
+		name:    "BindingLiquidSdk",
+		wrapper: "LiquidSdk",
+		doc:     "LiquidSdk decorates a BindingLiquidSdkInterface, routing every method call\n// through an interceptor chain.",
+	},
+	{
+		iface:   "BindingNwcServiceInterface",
+		name:    "BindingNwcService",
+		wrapper: "NwcService",
+		doc:     "NwcService decorates a BindingNwcServiceInterface, routing every method call\n// through an interceptor chain.",
+	},
+}
+
+type param struct {
+	name string
+	typ  string
+}
+
+type method struct {
+	name    string
+	params  []param
+	results []string
+}
+
+func main() {
+	input := flag.String("input", "../breez_sdk_liquid/breez_sdk_liquid.go", "bindings source file")
+	output := flag.String("output", "intercept_gen.go", "generated file")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, *input, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	interfaces := map[string]*ast.InterfaceType{}
+	exported := map[string]bool{}
+	genericCtorTakesMessage := map[string]bool{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					exported[ts.Name.Name] = ts.Name.IsExported()
+					if it, ok := ts.Type.(*ast.InterfaceType); ok {
+						interfaces[ts.Name.Name] = it
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil && strings.HasPrefix(decl.Name.Name, "New") && strings.HasSuffix(decl.Name.Name, "Generic") {
+				errType := strings.TrimSuffix(strings.TrimPrefix(decl.Name.Name, "New"), "Generic")
+				genericCtorTakesMessage[errType] = decl.Type.Params.NumFields() == 1
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go run ./internal/gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package intercept\n\n")
+	fmt.Fprintf(&buf, "import (\n\t\"errors\"\n\n\t\"github.com/breez/breez-sdk-liquid-go/%s\"\n\t\"github.com/breez/breez-sdk-liquid-go/internal/sdkutil\"\n)\n\n", pkg)
+
+	errorTypes := map[string]bool{}
+	for _, svc := range services {
+		it, ok := interfaces[svc.iface]
+		if !ok {
+			log.Fatalf("interface %s not found", svc.iface)
+		}
+		methods := collect(fset, it, exported)
+		writeService(&buf, svc, methods, errorTypes)
+	}
+
+	var names []string
+	for name := range errorTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		takesMessage, ok := genericCtorTakesMessage[name]
+		if !ok {
+			log.Fatalf("no generic constructor for %s", name)
+		}
+		writeErrorConverter(&buf, name, takesMessage)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v\n%s", err, buf.Bytes())
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func collect(fset *token.FileSet, it *ast.InterfaceType, exported map[string]bool) []method {
+	var methods []method
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+		m := method{name: field.Names[0].Name}
+		for _, p := range ft.Params.List {
+			typ := qualify(fset, p.Type, exported)
+			for _, name := range p.Names {
+				m.params = append(m.params, param{name: name.Name, typ: typ})
+			}
+		}
+		if ft.Results != nil {
+			for _, r := range ft.Results.List {
+				m.results = append(m.results, qualify(fset, r.Type, exported))
+			}
+		}
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].name < methods[j].name })
+	return methods
+}
+
+// qualify prints a type expression, prefixing the identifiers declared in
+// the bindings with the package name.
+func qualify(fset *token.FileSet, expr ast.Expr, exported map[string]bool) string {
+	expr = qualifyExpr(expr, exported)
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, expr); err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
+
+func qualifyExpr(expr ast.Expr, exported map[string]bool) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if exported[e.Name] {
+			return &ast.SelectorExpr{X: ast.NewIdent(pkg), Sel: ast.NewIdent(e.Name)}
+		}
+		return e
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualifyExpr(e.X, exported)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: e.Len, Elt: qualifyExpr(e.Elt, exported)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualifyExpr(e.Key, exported), Value: qualifyExpr(e.Value, exported)}
+	default:
+		return e
+	}
+}
+
+// errorType returns the bindings error type name of a result such as
+// "*breez_sdk_liquid.PaymentError", or "" if it is not an error.
+func errorType(result string) string {
+	name := strings.TrimPrefix(result, "*"+pkg+".")
+	if name != result && strings.HasSuffix(name, "Error") {
+		return name
+	}
+	return ""
+}
+
+func argsType(svc service, m method) string {
+	return svc.wrapper + m.name + "Args"
+}
+
+func exportName(name string) string {
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func writeService(buf *bytes.Buffer, svc service, methods []method, errorTypes map[string]bool) {
+	fmt.Fprintf(buf, "// %s\ntype %s struct {\n\tnext %s.%s\n\tchain Interceptor\n}\n\n", svc.doc, svc.wrapper, pkg, svc.iface)
+	fmt.Fprintf(buf, "var _ %s.%s = (*%s)(nil)\n\n", pkg, svc.iface, svc.wrapper)
+	fmt.Fprintf(buf, "// Wrap%s returns a %s calling next through the\n// interceptors, the first interceptor being the outermost.\n", svc.wrapper, svc.wrapper)
+	fmt.Fprintf(buf, "func Wrap%s(next %s.%s, interceptors ...Interceptor) *%s {\n", svc.wrapper, pkg, svc.iface, svc.wrapper)
+	fmt.Fprintf(buf, "\treturn &%s{next: next, chain: Chain(interceptors...)}\n}\n\n", svc.wrapper)
+
+	// Argument structs for methods taking more than one parameter.
+	for _, m := range methods {
+		if len(m.params) < 2 {
+			continue
+		}
+		fmt.Fprintf(buf, "// %s is the Call.Request of %s.%s.\ntype %s struct {\n", argsType(svc, m), svc.name, m.name, argsType(svc, m))
+		for _, p := range m.params {
+			fmt.Fprintf(buf, "\t%s %s\n", exportName(p.name), p.typ)
+		}
+		fmt.Fprintf(buf, "}\n\n")
+	}
+
+	for _, m := range methods {
+		writeMethod(buf, svc, m, errorTypes)
+	}
+}
+
+func writeMethod(buf *bytes.Buffer, svc service, m method, errorTypes map[string]bool) {
+	var params []string
+	for _, p := range m.params {
+		params = append(params, p.name+" "+p.typ)
+	}
+	results := strings.Join(m.results, ", ")
+	if len(m.results) > 1 {
+		results = "(" + results + ")"
+	}
+
+	// Split the results into the value and the error, if any.
+	var value, errType string
+	for _, r := range m.results {
+		if name := errorType(r); name != "" {
+			errType = name
+			errorTypes[name] = true
+		} else {
+			value = r
+		}
+	}
+
+	var request, unpack string
+	switch len(m.params) {
+	case 0:
+		request = "nil"
+	case 1:
+		request = m.params[0].name
+		unpack = fmt.Sprintf("%s := call.Request.(%s)\n", m.params[0].name, m.params[0].typ)
+	default:
+		var fields []string
+		for _, p := range m.params {
+			fields = append(fields, exportName(p.name)+": "+p.name)
+		}
+		request = fmt.Sprintf("%s{%s}", argsType(svc, m), strings.Join(fields, ", "))
+		unpack = fmt.Sprintf("args := call.Request.(%s)\n", argsType(svc, m))
+	}
+	var args []string
+	for _, p := range m.params {
+		if len(m.params) > 1 {
+			args = append(args, "args."+exportName(p.name))
+		} else {
+			args = append(args, p.name)
+		}
+	}
+	invoke := fmt.Sprintf("s.next.%s(%s)", m.name, strings.Join(args, ", "))
+
+	fmt.Fprintf(buf, "func (s *%s) %s(%s) %s {\n", svc.wrapper, m.name, strings.Join(params, ", "), results)
+	resVar, errVar := "_", "_"
+	if value != "" {
+		resVar = "res"
+	}
+	if errType != "" {
+		errVar = "err"
+	}
+	assign := "="
+	if resVar != "_" || errVar != "_" {
+		assign = ":="
+	}
+	fmt.Fprintf(buf, "\t%s, %s %s s.chain(&Call{Service: %q, Method: %q, Request: %s}, func(call *Call) (any, error) {\n",
+		resVar, errVar, assign, svc.name, m.name, request)
+	if unpack != "" {
+		fmt.Fprintf(buf, "\t\t%s", unpack)
+	}
+	switch {
+	case value != "" && errType != "":
+		fmt.Fprintf(buf, "\t\tres, err := %s\n\t\treturn res, err.AsError()\n", invoke)
+	case value != "":
+		fmt.Fprintf(buf, "\t\treturn %s, nil\n", invoke)
+	case errType != "":
+		fmt.Fprintf(buf, "\t\treturn nil, %s.AsError()\n", invoke)
+	default:
+		fmt.Fprintf(buf, "\t\t%s\n\t\treturn nil, nil\n", invoke)
+	}
+	fmt.Fprintf(buf, "\t})\n")
+
+	switch {
+	case value != "" && errType != "":
+		fmt.Fprintf(buf, "\tout, _ := res.(%s)\n\treturn out, to%s(err)\n", value, errType)
+	case value != "":
+		fmt.Fprintf(buf, "\tout, _ := res.(%s)\n\treturn out\n", value)
+	case errType != "":
+		fmt.Fprintf(buf, "\treturn to%s(err)\n", errType)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+}
+
+func writeErrorConverter(buf *bytes.Buffer, name string, takesMessage bool) {
+	fmt.Fprintf(buf, "// to%s returns err as a *%s.%s. Errors of any other type, returned\n", name, pkg, name)
+	fmt.Fprintf(buf, "// by an interceptor, are converted to the Generic variant, through which\n")
+	fmt.Fprintf(buf, "// errors.Is and errors.As still reach them.\n")
+	fmt.Fprintf(buf, "func to%s(err error) *%s.%s {\n", name, pkg, name)
+	fmt.Fprintf(buf, "\tif err == nil {\n\t\treturn nil\n\t}\n")
+	fmt.Fprintf(buf, "\tvar typed *%s.%s\n\tif errors.As(err, &typed) {\n\t\treturn typed\n\t}\n", pkg, name)
+	if takesMessage {
+		fmt.Fprintf(buf, "\treturn sdkutil.WithCause(%s.New%sGeneric(err.Error()), err)\n}\n\n", pkg, name)
+	} else {
+		// The Generic variants without a message field carry the message
+		// of the Rust error, set through sdkutil.
+		fmt.Fprintf(buf, "\treturn sdkutil.WithCause(sdkutil.%sWithMessage(%s.New%sGeneric(), err.Error()), err)\n}\n\n", name, pkg, name)
+	}
+}
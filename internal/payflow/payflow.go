@@ -0,0 +1,233 @@
+// Package payflow prepares and executes outbound payments to any destination
+// accepted by Parse, choosing the SDK calls matching the parsed input.
+package payflow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/idempotency"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Kinds of destination.
+const (
+	KindBolt11         = "bolt11"
+	KindBolt12         = "bolt12"
+	KindLiquidAddress  = "liquid_address"
+	KindBitcoinAddress = "bitcoin_address"
+	KindLnurlPay       = "lnurl_pay"
+)
+
+var (
+	// ErrUnsupported is returned for inputs which cannot be paid.
+	ErrUnsupported = errors.New("destination cannot be paid")
+	// ErrAmountRequired is returned when neither the destination nor the
+	// caller set an amount.
+	ErrAmountRequired = errors.New("amount required")
+	// ErrAmountMismatch is returned when the caller sets an amount other
+	// than the amount of a Bolt11 invoice.
+	ErrAmountMismatch = errors.New("amount differs from the invoice amount")
+)
+
+// LnurlError is returned when the LNURL service or the payment of its
+// invoice failed.
+type LnurlError struct {
+	Reason string
+}
+
+func (e *LnurlError) Error() string {
+	return "lnurl pay failed: " + e.Reason
+}
+
+// Prepared is a payment ready to be executed.
+type Prepared struct {
+	Destination string
+	Input       breez_sdk_liquid.InputType
+	Kind        string
+	// AmountSat is the amount received by the destination.
+	AmountSat uint64
+	FeesSat   uint64
+
+	send    *breez_sdk_liquid.SendPaymentRequest
+	onchain *breez_sdk_liquid.PayOnchainRequest
+	lnurl   *breez_sdk_liquid.LnUrlPayRequest
+}
+
+// Kind returns the kind of destination of a parsed input, or an empty
+// string if it cannot be paid.
+func Kind(input breez_sdk_liquid.InputType) string {
+	switch input.(type) {
+	case breez_sdk_liquid.InputTypeBolt11:
+		return KindBolt11
+	case breez_sdk_liquid.InputTypeBolt12Offer:
+		return KindBolt12
+	case breez_sdk_liquid.InputTypeLiquidAddress:
+		return KindLiquidAddress
+	case breez_sdk_liquid.InputTypeBitcoinAddress:
+		return KindBitcoinAddress
+	case breez_sdk_liquid.InputTypeLnUrlPay:
+		return KindLnurlPay
+	default:
+		return ""
+	}
+}
+
+// Prepare runs the prepare call matching the parsed input. A zero amountSat
+// uses the amount set by the destination, if any, and any other must match
+// the amount of a Bolt11 invoice. comment is only sent to LNURL services.
+func Prepare(sdk breez_sdk_liquid.BindingLiquidSdkInterface, destination string, input breez_sdk_liquid.InputType, amountSat uint64, comment string) (Prepared, error) {
+	p := Prepared{Destination: destination, Input: input, Kind: Kind(input)}
+	var amount *breez_sdk_liquid.PayAmount
+	if amountSat > 0 {
+		var a breez_sdk_liquid.PayAmount = breez_sdk_liquid.PayAmountBitcoin{ReceiverAmountSat: amountSat}
+		amount = &a
+	}
+
+	switch input := input.(type) {
+	case breez_sdk_liquid.InputTypeBolt11, breez_sdk_liquid.InputTypeBolt12Offer, breez_sdk_liquid.InputTypeLiquidAddress:
+		if bolt11, ok := input.(breez_sdk_liquid.InputTypeBolt11); ok && bolt11.Invoice.AmountMsat != nil {
+			// The invoice amount cannot be overridden.
+			if invoiceSat := *bolt11.Invoice.AmountMsat / 1000; amountSat > 0 && amountSat != invoiceSat {
+				return p, fmt.Errorf("%w: invoice of %d sat, %d sat requested", ErrAmountMismatch, invoiceSat, amountSat)
+			}
+			amount = nil
+		}
+		res, err := sdk.PrepareSendPayment(breez_sdk_liquid.PrepareSendRequest{Destination: destination, Amount: amount})
+		if err != nil {
+			return p, err.AsError()
+		}
+		p.FeesSat = sdkutil.Uint64Value(res.FeesSat)
+		p.AmountSat = SendAmountSat(res, amountSat)
+		p.send = &breez_sdk_liquid.SendPaymentRequest{PrepareResponse: res}
+
+	case breez_sdk_liquid.InputTypeBitcoinAddress:
+		if amountSat == 0 {
+			amountSat = sdkutil.Uint64Value(input.Address.AmountSat)
+		}
+		if amountSat == 0 {
+			return p, ErrAmountRequired
+		}
+		res, err := sdk.PreparePayOnchain(breez_sdk_liquid.PreparePayOnchainRequest{
+			Amount: breez_sdk_liquid.PayAmountBitcoin{ReceiverAmountSat: amountSat},
+		})
+		if err != nil {
+			return p, err.AsError()
+		}
+		p.FeesSat, p.AmountSat = res.TotalFeesSat, res.ReceiverAmountSat
+		p.onchain = &breez_sdk_liquid.PayOnchainRequest{Address: input.Address.Address, PrepareResponse: res}
+
+	case breez_sdk_liquid.InputTypeLnUrlPay:
+		if amountSat == 0 {
+			return p, ErrAmountRequired
+		}
+		req := breez_sdk_liquid.PrepareLnUrlPayRequest{
+			Data:          input.Data,
+			Amount:        breez_sdk_liquid.PayAmountBitcoin{ReceiverAmountSat: amountSat},
+			Bip353Address: input.Bip353Address,
+		}
+		if comment != "" && input.Data.CommentAllowed > 0 {
+			req.Comment = &comment
+		}
+		res, err := sdk.PrepareLnurlPay(req)
+		if err != nil {
+			return p, err.AsError()
+		}
+		p.FeesSat, p.AmountSat = res.FeesSat, amountSat
+		p.lnurl = &breez_sdk_liquid.LnUrlPayRequest{PrepareResponse: res}
+
+	default:
+		return p, ErrUnsupported
+	}
+	return p, nil
+}
+
+// SendAmountSat returns the amount received by the destination of a
+// prepared send, amountSat being the amount requested, if any.
+func SendAmountSat(res breez_sdk_liquid.PrepareSendResponse, amountSat uint64) uint64 {
+	if res.Amount != nil {
+		if a, ok := (*res.Amount).(breez_sdk_liquid.PayAmountBitcoin); ok {
+			return a.ReceiverAmountSat
+		}
+	}
+	switch d := res.Destination.(type) {
+	case breez_sdk_liquid.SendDestinationBolt11:
+		return sdkutil.Uint64Value(d.Invoice.AmountMsat) / 1000
+	case breez_sdk_liquid.SendDestinationBolt12:
+		return d.ReceiverAmountSat
+	case breez_sdk_liquid.SendDestinationLiquidAddress:
+		if amountSat == 0 {
+			return sdkutil.Uint64Value(d.AddressData.AmountSat)
+		}
+	}
+	return amountSat
+}
+
+// SendDestination returns the invoice, offer or address of a prepared
+// send.
+func SendDestination(destination breez_sdk_liquid.SendDestination) string {
+	switch d := destination.(type) {
+	case breez_sdk_liquid.SendDestinationBolt11:
+		return d.Invoice.Bolt11
+	case breez_sdk_liquid.SendDestinationBolt12:
+		return d.Offer.Offer
+	case breez_sdk_liquid.SendDestinationLiquidAddress:
+		return d.AddressData.Address
+	default:
+		return ""
+	}
+}
+
+// Execute sends the prepared payment.
+func (p Prepared) Execute(sdk breez_sdk_liquid.BindingLiquidSdkInterface) (breez_sdk_liquid.Payment, error) {
+	switch {
+	case p.send != nil:
+		res, err := sdk.SendPayment(*p.send)
+		return res.Payment, err.AsError()
+	case p.onchain != nil:
+		res, err := sdk.PayOnchain(*p.onchain)
+		return res.Payment, err.AsError()
+	case p.lnurl != nil:
+		res, err := sdk.LnurlPay(*p.lnurl)
+		if err != nil {
+			return breez_sdk_liquid.Payment{}, err.AsError()
+		}
+		return lnurlPayment(res)
+	default:
+		return breez_sdk_liquid.Payment{}, ErrUnsupported
+	}
+}
+
+// ExecuteOnce sends the prepared payment at most once for the key.
+func (p Prepared) ExecuteOnce(exec *idempotency.Executor, key string) (breez_sdk_liquid.Payment, error) {
+	switch {
+	case p.send != nil:
+		res, err := exec.SendPayment(key, *p.send)
+		return res.Payment, err
+	case p.onchain != nil:
+		res, err := exec.PayOnchain(key, *p.onchain)
+		return res.Payment, err
+	case p.lnurl != nil:
+		res, err := exec.LnurlPay(key, *p.lnurl)
+		if err != nil {
+			return breez_sdk_liquid.Payment{}, err
+		}
+		return lnurlPayment(res)
+	default:
+		return breez_sdk_liquid.Payment{}, ErrUnsupported
+	}
+}
+
+func lnurlPayment(res breez_sdk_liquid.LnUrlPayResult) (breez_sdk_liquid.Payment, error) {
+	switch res := res.(type) {
+	case breez_sdk_liquid.LnUrlPayResultEndpointSuccess:
+		return res.Data.Payment, nil
+	case breez_sdk_liquid.LnUrlPayResultEndpointError:
+		return breez_sdk_liquid.Payment{}, &LnurlError{Reason: res.Data.Reason}
+	case breez_sdk_liquid.LnUrlPayResultPayError:
+		return breez_sdk_liquid.Payment{}, &LnurlError{Reason: res.Data.Reason}
+	default:
+		return breez_sdk_liquid.Payment{}, fmt.Errorf("unexpected lnurl pay result %T", res)
+	}
+}
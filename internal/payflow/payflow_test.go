@@ -0,0 +1,61 @@
+package payflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+func TestPrepareBolt11Amount(t *testing.T) {
+	amountMsat := uint64(5_000_000)
+	invoice := breez_sdk_liquid.InputTypeBolt11{Invoice: breez_sdk_liquid.LnInvoice{Bolt11: "lnbc1", AmountMsat: &amountMsat}}
+	var requests []breez_sdk_liquid.PrepareSendRequest
+	sdk := intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		requests = append(requests, call.Request.(breez_sdk_liquid.PrepareSendRequest))
+		fees := uint64(10)
+		return breez_sdk_liquid.PrepareSendResponse{
+			Destination: breez_sdk_liquid.SendDestinationBolt11{Invoice: invoice.Invoice},
+			FeesSat:     &fees,
+		}, nil
+	})
+
+	for _, amountSat := range []uint64{0, 5000} {
+		p, err := Prepare(sdk, "lnbc1", invoice, amountSat, "")
+		if err != nil {
+			t.Fatalf("amount %d: %v", amountSat, err)
+		}
+		if p.AmountSat != 5000 || p.FeesSat != 10 || p.Kind != KindBolt11 {
+			t.Fatalf("amount %d: prepared %+v", amountSat, p)
+		}
+	}
+	// The invoice sets the amount.
+	for _, req := range requests {
+		if req.Amount != nil {
+			t.Fatalf("prepared with amount %+v", *req.Amount)
+		}
+	}
+
+	requests = nil
+	if _, err := Prepare(sdk, "lnbc1", invoice, 6000, ""); !errors.Is(err, ErrAmountMismatch) {
+		t.Fatalf("got %v, want ErrAmountMismatch", err)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("prepared %+v", requests)
+	}
+}
+
+func TestPrepareAmountRequired(t *testing.T) {
+	sdk := intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		t.Fatalf("called %s", call.Method)
+		return nil, nil
+	})
+	address := breez_sdk_liquid.InputTypeBitcoinAddress{Address: breez_sdk_liquid.BitcoinAddressData{Address: "bc1"}}
+	if _, err := Prepare(sdk, "bc1", address, 0, ""); !errors.Is(err, ErrAmountRequired) {
+		t.Fatalf("got %v, want ErrAmountRequired", err)
+	}
+	if _, err := Prepare(sdk, "x", breez_sdk_liquid.InputTypeUrl{}, 1000, ""); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("got %v, want ErrUnsupported", err)
+	}
+}
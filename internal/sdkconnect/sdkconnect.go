@@ -0,0 +1,110 @@
+// Package sdkconnect connects an SDK instance configured by command-line
+// flags and environment variables. It is shared by the commands under cmd.
+package sdkconnect
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// Environment variables read when the matching flag is not set.
+const (
+	EnvApiKey   = "BREEZ_API_KEY"
+	EnvMnemonic = "BREEZ_MNEMONIC"
+)
+
+// Flags configures the connection.
+type Flags struct {
+	Network      string
+	DataDir      string
+	ApiKey       string
+	MnemonicFile string
+	Passphrase   string
+}
+
+// Register defines the flags on fs.
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Network, "network", "mainnet", "network: mainnet, testnet or regtest")
+	fs.StringVar(&f.DataDir, "data-dir", "breez-data", "SDK working directory")
+	fs.StringVar(&f.ApiKey, "api-key", "", "Breez API key, defaults to $"+EnvApiKey)
+	fs.StringVar(&f.MnemonicFile, "mnemonic-file", "", "file holding the mnemonic, defaults to $"+EnvMnemonic)
+	fs.StringVar(&f.Passphrase, "passphrase", "", "mnemonic passphrase")
+}
+
+// ParseNetwork parses a network name.
+func ParseNetwork(name string) (breez_sdk_liquid.LiquidNetwork, error) {
+	switch strings.ToLower(name) {
+	case "mainnet", "":
+		return breez_sdk_liquid.LiquidNetworkMainnet, nil
+	case "testnet":
+		return breez_sdk_liquid.LiquidNetworkTestnet, nil
+	case "regtest":
+		return breez_sdk_liquid.LiquidNetworkRegtest, nil
+	default:
+		return 0, fmt.Errorf("unknown network %q", name)
+	}
+}
+
+// Config returns the default configuration of the network, working in
+// DataDir.
+func (f *Flags) Config() (breez_sdk_liquid.Config, error) {
+	network, err := ParseNetwork(f.Network)
+	if err != nil {
+		return breez_sdk_liquid.Config{}, err
+	}
+	apiKey := f.ApiKey
+	if apiKey == "" {
+		apiKey = os.Getenv(EnvApiKey)
+	}
+	var key *string
+	if apiKey != "" {
+		key = &apiKey
+	}
+	config, sdkErr := breez_sdk_liquid.DefaultConfig(network, key)
+	if sdkErr != nil {
+		return breez_sdk_liquid.Config{}, sdkErr
+	}
+	config.WorkingDir = f.DataDir
+	return config, nil
+}
+
+// Mnemonic returns the mnemonic read from MnemonicFile or the environment.
+func (f *Flags) Mnemonic() (string, error) {
+	if f.MnemonicFile != "" {
+		data, err := os.ReadFile(f.MnemonicFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if m := strings.TrimSpace(os.Getenv(EnvMnemonic)); m != "" {
+		return m, nil
+	}
+	return "", errors.New("no mnemonic: set -mnemonic-file or $" + EnvMnemonic)
+}
+
+// Connect connects the SDK.
+func (f *Flags) Connect() (*breez_sdk_liquid.BindingLiquidSdk, error) {
+	config, err := f.Config()
+	if err != nil {
+		return nil, err
+	}
+	mnemonic, err := f.Mnemonic()
+	if err != nil {
+		return nil, err
+	}
+	req := breez_sdk_liquid.ConnectRequest{Config: config, Mnemonic: &mnemonic}
+	if f.Passphrase != "" {
+		req.Passphrase = &f.Passphrase
+	}
+	sdk, sdkErr := breez_sdk_liquid.Connect(req)
+	if sdkErr != nil {
+		return nil, sdkErr
+	}
+	return sdk, nil
+}
@@ -0,0 +1,351 @@
+package sdkjson
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeKey is the member holding the tag of a sum type variant.
+const TypeKey = "type"
+
+// variantTags maps each variant type to its tag.
+var variantTags = map[reflect.Type]string{}
+
+func init() {
+	for _, variants := range sumTypes {
+		for tag, t := range variants {
+			variantTags[t] = tag
+		}
+	}
+}
+
+// Marshal returns the JSON encoding of v.
+func Marshal(v any) ([]byte, error) {
+	return json.Marshal(Encode(v))
+}
+
+// Encode converts v to a value made of maps, slices and scalars, which
+// encoding/json encodes as described in the package documentation.
+func Encode(v any) any {
+	return encode(reflect.ValueOf(v))
+}
+
+func encode(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return encode(v.Elem())
+	case reflect.Struct:
+		t := v.Type()
+		m := make(map[string]any, t.NumField()+1)
+		if tag, ok := variantTags[t]; ok {
+			m[TypeKey] = tag
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() {
+				m[Snake(f.Name)] = encode(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return hex.EncodeToString(v.Bytes())
+		}
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = encode(v.Index(i))
+		}
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = encode(iter.Value())
+		}
+		return m
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if names, ok := enumTypes[v.Type()]; ok {
+			if name, ok := names[v.Uint()]; ok {
+				return name
+			}
+		}
+		return v.Uint()
+	default:
+		return v.Interface()
+	}
+}
+
+// Unmarshal decodes the JSON document data into the value pointed to by v.
+// Unknown members are rejected.
+func Unmarshal(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var src any
+	if err := dec.Decode(&src); err != nil {
+		return err
+	}
+	return Decode(src, v)
+}
+
+// Decode stores in the value pointed to by v the value src, as decoded by
+// encoding/json into an any, preferably with UseNumber.
+func Decode(src any, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("sdkjson: Decode of non-pointer %T", v)
+	}
+	return decode(src, rv.Elem(), "")
+}
+
+// DecodeError reports a value which cannot be decoded.
+type DecodeError struct {
+	// Path of the value, such as "amount.receiver_amount_sat".
+	Path string
+	Msg  string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return "sdkjson: " + e.Msg
+	}
+	return "sdkjson: " + e.Path + ": " + e.Msg
+}
+
+func decode(src any, dst reflect.Value, path string) error {
+	fail := func(format string, args ...any) error {
+		return &DecodeError{Path: path, Msg: fmt.Sprintf(format, args...)}
+	}
+	t := dst.Type()
+	if src == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			dst.Set(reflect.Zero(t))
+			return nil
+		}
+		return fail("value required")
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(t.Elem())
+		if err := decode(src, elem.Elem(), path); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+
+	case reflect.Interface:
+		variants, ok := sumTypes[t]
+		if !ok {
+			if t.NumMethod() == 0 {
+				dst.Set(reflect.ValueOf(src))
+				return nil
+			}
+			return fail("cannot decode %s", t)
+		}
+		m, ok := src.(map[string]any)
+		if !ok {
+			return fail("expected object")
+		}
+		tag, _ := m[TypeKey].(string)
+		vt, ok := variants[tag]
+		if !ok {
+			return fail("unknown %s type %q", t.Name(), tag)
+		}
+		elem := reflect.New(vt).Elem()
+		if err := decode(m, elem, path); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+
+	case reflect.Struct:
+		m, ok := src.(map[string]any)
+		if !ok {
+			return fail("expected object")
+		}
+		fields := map[string]int{}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() {
+				fields[Snake(f.Name)] = i
+			}
+		}
+		for key, value := range m {
+			i, ok := fields[key]
+			if !ok {
+				if _, variant := variantTags[t]; variant && key == TypeKey {
+					continue
+				}
+				return fail("unknown member %q", key)
+			}
+			if err := decode(value, dst.Field(i), join(path, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s, ok := src.(string)
+			if !ok {
+				return fail("expected hex string")
+			}
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return fail("invalid hex: %v", err)
+			}
+			dst.SetBytes(b)
+			return nil
+		}
+		items, ok := src.([]any)
+		if !ok {
+			return fail("expected array")
+		}
+		s := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := decode(item, s.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+		return nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fail("cannot decode %s", t)
+		}
+		obj, ok := src.(map[string]any)
+		if !ok {
+			return fail("expected object")
+		}
+		m := reflect.MakeMapWithSize(t, len(obj))
+		for key, value := range obj {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := decode(value, elem, join(path, key)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+		dst.Set(m)
+		return nil
+
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fail("expected string")
+		}
+		dst.SetString(s)
+		return nil
+
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return fail("expected boolean")
+		}
+		dst.SetBool(b)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if names, ok := enumTypes[t]; ok {
+			if s, ok := src.(string); ok {
+				for value, name := range names {
+					if name == s {
+						dst.SetUint(value)
+						return nil
+					}
+				}
+				return fail("unknown %s %q", t.Name(), s)
+			}
+		}
+		n, err := strconv.ParseUint(number(src), 10, t.Bits())
+		if err != nil {
+			return fail("expected unsigned integer")
+		}
+		dst.SetUint(n)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(number(src), 10, t.Bits())
+		if err != nil {
+			return fail("expected integer")
+		}
+		dst.SetInt(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(number(src), t.Bits())
+		if err != nil {
+			return fail("expected number")
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+	return fail("cannot decode %s", t)
+}
+
+// number returns the text of a JSON number decoded as json.Number or
+// float64, or an empty string for other values.
+func number(src any) string {
+	switch n := src.(type) {
+	case json.Number:
+		return n.String()
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Snake converts a CamelCase identifier of the bindings to snake_case,
+// e.g. "TxId" to "tx_id" and "LnUrlInfo" to "lnurl_info".
+func Snake(name string) string {
+	var b strings.Builder
+	r := []rune(strings.ReplaceAll(name, "LnUrl", "Lnurl"))
+	for i, c := range r {
+		upper := c >= 'A' && c <= 'Z'
+		if upper && i > 0 {
+			prev := r[i-1]
+			prevLower := prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9'
+			nextLower := i+1 < len(r) && r[i+1] >= 'a' && r[i+1] <= 'z'
+			if prevLower || (prev >= 'A' && prev <= 'Z' && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		if upper {
+			c += 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// VariantTag returns the tag of a sum type variant, such as "bolt11" for
+// an InputTypeBolt11, or an empty string if v is not a variant.
+func VariantTag(v any) string {
+	if v == nil {
+		return ""
+	}
+	return variantTags[reflect.TypeOf(v)]
+}
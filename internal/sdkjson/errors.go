@@ -0,0 +1,55 @@
+package sdkjson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Error describes an error in JSON bodies.
+type Error struct {
+	// Type is the SDK error type, such as "payment_error", or "error" for
+	// errors of any other type.
+	Type string `json:"type"`
+	// Variant is the SDK error variant, such as "already_paid".
+	Variant string `json:"variant,omitempty"`
+	Message string `json:"message"`
+}
+
+// EncodeError describes err, which may wrap an SDK error or convert to one
+// with errors.As.
+func EncodeError(err error) Error {
+	e := Error{Type: "error", Message: err.Error()}
+	var typed error
+	switch {
+	case as[breez_sdk_liquid.PaymentError](err, &typed):
+	case as[breez_sdk_liquid.SdkError](err, &typed):
+	case as[breez_sdk_liquid.LnUrlPayError](err, &typed):
+	case as[breez_sdk_liquid.LnUrlWithdrawError](err, &typed):
+	case as[breez_sdk_liquid.LnUrlAuthError](err, &typed):
+	case as[breez_sdk_liquid.NwcError](err, &typed):
+	case as[breez_sdk_liquid.SignerError](err, &typed):
+	default:
+		return e
+	}
+	typeName := reflect.TypeOf(typed).Elem().Name()
+	e.Type = Snake(typeName)
+	e.Variant = Snake(strings.TrimPrefix(sdkutil.ErrorVariant(typed), typeName))
+	return e
+}
+
+// as sets *typed to the *T in err's chain, if any.
+func as[T any, PT interface {
+	*T
+	error
+}](err error, typed *error) bool {
+	var target PT
+	if !errors.As(err, &target) {
+		return false
+	}
+	*typed = target
+	return true
+}
@@ -0,0 +1,186 @@
+// Command gen generates the tables of sum types and enums of package
+// sdkjson from the breez_sdk_liquid bindings.
+//
+// It is run from the internal/sdkjson directory with go generate.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const pkg = "breez_sdk_liquid"
+
+type variant struct {
+	typ string
+	tag string
+}
+
+type enumValue struct {
+	name  string
+	value string
+}
+
+func main() {
+	input := flag.String("input", "../../breez_sdk_liquid/breez_sdk_liquid.go", "bindings source file")
+	output := flag.String("output", "sdkjson_gen.go", "generated file")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, *input, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	interfaces := map[string]bool{}
+	enumTypes := map[string]bool{}
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if !ts.Name.IsExported() {
+				continue
+			}
+			switch t := ts.Type.(type) {
+			case *ast.InterfaceType:
+				interfaces[ts.Name.Name] = true
+			case *ast.Ident:
+				if t.Name == "uint" {
+					enumTypes[ts.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	// The variants of a sum type are the cases of the type switch in the
+	// Write method of its FFI converter.
+	sums := map[string][]variant{}
+	enums := map[string][]enumValue{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil || decl.Name.Name != "Write" || decl.Type.Params.NumFields() != 2 {
+				continue
+			}
+			value, ok := decl.Type.Params.List[1].Type.(*ast.Ident)
+			if !ok || !interfaces[value.Name] {
+				continue
+			}
+			ast.Inspect(decl.Body, func(n ast.Node) bool {
+				clause, ok := n.(*ast.CaseClause)
+				if !ok {
+					return true
+				}
+				for _, expr := range clause.List {
+					if id, ok := expr.(*ast.Ident); ok && strings.HasPrefix(id.Name, value.Name) {
+						sums[value.Name] = append(sums[value.Name], variant{
+							typ: id.Name,
+							tag: snake(strings.TrimPrefix(id.Name, value.Name)),
+						})
+					}
+				}
+				return true
+			})
+		case *ast.GenDecl:
+			if decl.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				typ, ok := vs.Type.(*ast.Ident)
+				if !ok || !enumTypes[typ.Name] {
+					continue
+				}
+				for i, name := range vs.Names {
+					lit, ok := vs.Values[i].(*ast.BasicLit)
+					if !ok {
+						log.Fatalf("enum value %s is not a literal", name.Name)
+					}
+					enums[typ.Name] = append(enums[typ.Name], enumValue{
+						name:  snake(strings.TrimPrefix(name.Name, typ.Name)),
+						value: lit.Value,
+					})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go run ./internal/gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package sdkjson\n\n")
+	fmt.Fprintf(&buf, "import (\n\t\"reflect\"\n\n\t\"github.com/breez/breez-sdk-liquid-go/%s\"\n)\n\n", pkg)
+
+	fmt.Fprintf(&buf, "// sumTypes maps each sum type of the bindings to its variants by tag.\n")
+	fmt.Fprintf(&buf, "var sumTypes = map[reflect.Type]map[string]reflect.Type{\n")
+	for _, name := range sortedKeys(sums) {
+		fmt.Fprintf(&buf, "\treflect.TypeOf((*%s.%s)(nil)).Elem(): {\n", pkg, name)
+		for _, v := range sums[name] {
+			fmt.Fprintf(&buf, "\t\t%q: reflect.TypeOf(%s.%s{}),\n", v.tag, pkg, v.typ)
+		}
+		fmt.Fprintf(&buf, "\t},\n")
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+
+	fmt.Fprintf(&buf, "// enumTypes maps each enum of the bindings to the names of its values.\n")
+	fmt.Fprintf(&buf, "var enumTypes = map[reflect.Type]map[uint64]string{\n")
+	for _, name := range sortedKeys(enums) {
+		fmt.Fprintf(&buf, "\treflect.TypeOf(%s.%s(0)): {\n", pkg, name)
+		for _, v := range enums[name] {
+			fmt.Fprintf(&buf, "\t\t%s: %q,\n", v.value, v.name)
+		}
+		fmt.Fprintf(&buf, "\t},\n")
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v\n%s", err, buf.Bytes())
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// snake converts a CamelCase identifier to snake_case. It must match the
+// conversion of field names in package sdkjson.
+func snake(name string) string {
+	var b strings.Builder
+	r := []rune(strings.ReplaceAll(name, "LnUrl", "Lnurl"))
+	for i, c := range r {
+		upper := c >= 'A' && c <= 'Z'
+		if upper && i > 0 {
+			prev := r[i-1]
+			prevLower := prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9'
+			nextLower := i+1 < len(r) && r[i+1] >= 'a' && r[i+1] <= 'z'
+			if prevLower || (prev >= 'A' && prev <= 'Z' && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		if upper {
+			c += 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,10 @@
+// Package sdkjson converts values of the breez_sdk_liquid bindings to and
+// from JSON.
+//
+// Struct fields are snake_case, variants of sum types such as InputType are
+// objects tagged with a "type" member, enums are snake_case strings and
+// byte slices are hex strings. For example an InputTypeBolt11 is encoded as
+// {"type": "bolt11", "invoice": {"bolt11": "lnbc...", ...}}.
+package sdkjson
+
+//go:generate go run ./internal/gen
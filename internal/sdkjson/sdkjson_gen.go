@@ -0,0 +1,156 @@
+// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+package sdkjson
+
+import (
+	"reflect"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// sumTypes maps each sum type of the bindings to its variants by tag.
+var sumTypes = map[reflect.Type]map[string]reflect.Type{
+	reflect.TypeOf((*breez_sdk_liquid.AesSuccessActionDataResult)(nil)).Elem(): {
+		"decrypted":    reflect.TypeOf(breez_sdk_liquid.AesSuccessActionDataResultDecrypted{}),
+		"error_status": reflect.TypeOf(breez_sdk_liquid.AesSuccessActionDataResultErrorStatus{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.Amount)(nil)).Elem(): {
+		"bitcoin":  reflect.TypeOf(breez_sdk_liquid.AmountBitcoin{}),
+		"currency": reflect.TypeOf(breez_sdk_liquid.AmountCurrency{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.BlockchainExplorer)(nil)).Elem(): {
+		"electrum": reflect.TypeOf(breez_sdk_liquid.BlockchainExplorerElectrum{}),
+		"esplora":  reflect.TypeOf(breez_sdk_liquid.BlockchainExplorerEsplora{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.DescriptionHash)(nil)).Elem(): {
+		"use_description": reflect.TypeOf(breez_sdk_liquid.DescriptionHashUseDescription{}),
+		"custom":          reflect.TypeOf(breez_sdk_liquid.DescriptionHashCustom{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.GetPaymentRequest)(nil)).Elem(): {
+		"payment_hash": reflect.TypeOf(breez_sdk_liquid.GetPaymentRequestPaymentHash{}),
+		"swap_id":      reflect.TypeOf(breez_sdk_liquid.GetPaymentRequestSwapId{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.InputType)(nil)).Elem(): {
+		"bitcoin_address":          reflect.TypeOf(breez_sdk_liquid.InputTypeBitcoinAddress{}),
+		"liquid_address":           reflect.TypeOf(breez_sdk_liquid.InputTypeLiquidAddress{}),
+		"bolt11":                   reflect.TypeOf(breez_sdk_liquid.InputTypeBolt11{}),
+		"bolt12_offer":             reflect.TypeOf(breez_sdk_liquid.InputTypeBolt12Offer{}),
+		"node_id":                  reflect.TypeOf(breez_sdk_liquid.InputTypeNodeId{}),
+		"url":                      reflect.TypeOf(breez_sdk_liquid.InputTypeUrl{}),
+		"lnurl_pay":                reflect.TypeOf(breez_sdk_liquid.InputTypeLnUrlPay{}),
+		"lnurl_withdraw":           reflect.TypeOf(breez_sdk_liquid.InputTypeLnUrlWithdraw{}),
+		"lnurl_auth":               reflect.TypeOf(breez_sdk_liquid.InputTypeLnUrlAuth{}),
+		"lnurl_error":              reflect.TypeOf(breez_sdk_liquid.InputTypeLnUrlError{}),
+		"nostr_wallet_connect_uri": reflect.TypeOf(breez_sdk_liquid.InputTypeNostrWalletConnectUri{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.ListPaymentDetails)(nil)).Elem(): {
+		"liquid":  reflect.TypeOf(breez_sdk_liquid.ListPaymentDetailsLiquid{}),
+		"bitcoin": reflect.TypeOf(breez_sdk_liquid.ListPaymentDetailsBitcoin{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.LnUrlCallbackStatus)(nil)).Elem(): {
+		"ok":           reflect.TypeOf(breez_sdk_liquid.LnUrlCallbackStatusOk{}),
+		"error_status": reflect.TypeOf(breez_sdk_liquid.LnUrlCallbackStatusErrorStatus{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.LnUrlPayResult)(nil)).Elem(): {
+		"endpoint_success": reflect.TypeOf(breez_sdk_liquid.LnUrlPayResultEndpointSuccess{}),
+		"endpoint_error":   reflect.TypeOf(breez_sdk_liquid.LnUrlPayResultEndpointError{}),
+		"pay_error":        reflect.TypeOf(breez_sdk_liquid.LnUrlPayResultPayError{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.LnUrlWithdrawResult)(nil)).Elem(): {
+		"ok":           reflect.TypeOf(breez_sdk_liquid.LnUrlWithdrawResultOk{}),
+		"timeout":      reflect.TypeOf(breez_sdk_liquid.LnUrlWithdrawResultTimeout{}),
+		"error_status": reflect.TypeOf(breez_sdk_liquid.LnUrlWithdrawResultErrorStatus{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.NwcEventDetails)(nil)).Elem(): {
+		"connected":            reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsConnected{}),
+		"disconnected":         reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsDisconnected{}),
+		"pay_invoice":          reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsPayInvoice{}),
+		"make_invoice":         reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsMakeInvoice{}),
+		"list_transactions":    reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsListTransactions{}),
+		"get_balance":          reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsGetBalance{}),
+		"get_info":             reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsGetInfo{}),
+		"connection_expired":   reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsConnectionExpired{}),
+		"connection_refreshed": reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsConnectionRefreshed{}),
+		"zap_received":         reflect.TypeOf(breez_sdk_liquid.NwcEventDetailsZapReceived{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.PayAmount)(nil)).Elem(): {
+		"bitcoin": reflect.TypeOf(breez_sdk_liquid.PayAmountBitcoin{}),
+		"asset":   reflect.TypeOf(breez_sdk_liquid.PayAmountAsset{}),
+		"drain":   reflect.TypeOf(breez_sdk_liquid.PayAmountDrain{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.PaymentDetails)(nil)).Elem(): {
+		"lightning": reflect.TypeOf(breez_sdk_liquid.PaymentDetailsLightning{}),
+		"liquid":    reflect.TypeOf(breez_sdk_liquid.PaymentDetailsLiquid{}),
+		"bitcoin":   reflect.TypeOf(breez_sdk_liquid.PaymentDetailsBitcoin{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.ReceiveAmount)(nil)).Elem(): {
+		"bitcoin": reflect.TypeOf(breez_sdk_liquid.ReceiveAmountBitcoin{}),
+		"asset":   reflect.TypeOf(breez_sdk_liquid.ReceiveAmountAsset{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.SdkEvent)(nil)).Elem(): {
+		"payment_failed":                 reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentFailed{}),
+		"payment_pending":                reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentPending{}),
+		"payment_refundable":             reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentRefundable{}),
+		"payment_refunded":               reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentRefunded{}),
+		"payment_refund_pending":         reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentRefundPending{}),
+		"payment_succeeded":              reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentSucceeded{}),
+		"payment_waiting_confirmation":   reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentWaitingConfirmation{}),
+		"payment_waiting_fee_acceptance": reflect.TypeOf(breez_sdk_liquid.SdkEventPaymentWaitingFeeAcceptance{}),
+		"synced":                         reflect.TypeOf(breez_sdk_liquid.SdkEventSynced{}),
+		"sync_failed":                    reflect.TypeOf(breez_sdk_liquid.SdkEventSyncFailed{}),
+		"data_synced":                    reflect.TypeOf(breez_sdk_liquid.SdkEventDataSynced{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.SendDestination)(nil)).Elem(): {
+		"liquid_address": reflect.TypeOf(breez_sdk_liquid.SendDestinationLiquidAddress{}),
+		"bolt11":         reflect.TypeOf(breez_sdk_liquid.SendDestinationBolt11{}),
+		"bolt12":         reflect.TypeOf(breez_sdk_liquid.SendDestinationBolt12{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.SuccessAction)(nil)).Elem(): {
+		"aes":     reflect.TypeOf(breez_sdk_liquid.SuccessActionAes{}),
+		"message": reflect.TypeOf(breez_sdk_liquid.SuccessActionMessage{}),
+		"url":     reflect.TypeOf(breez_sdk_liquid.SuccessActionUrl{}),
+	},
+	reflect.TypeOf((*breez_sdk_liquid.SuccessActionProcessed)(nil)).Elem(): {
+		"aes":     reflect.TypeOf(breez_sdk_liquid.SuccessActionProcessedAes{}),
+		"message": reflect.TypeOf(breez_sdk_liquid.SuccessActionProcessedMessage{}),
+		"url":     reflect.TypeOf(breez_sdk_liquid.SuccessActionProcessedUrl{}),
+	},
+}
+
+// enumTypes maps each enum of the bindings to the names of its values.
+var enumTypes = map[reflect.Type]map[uint64]string{
+	reflect.TypeOf(breez_sdk_liquid.BuyBitcoinProvider(0)): {
+		1: "moonpay",
+	},
+	reflect.TypeOf(breez_sdk_liquid.LiquidNetwork(0)): {
+		1: "mainnet",
+		2: "testnet",
+		3: "regtest",
+	},
+	reflect.TypeOf(breez_sdk_liquid.Network(0)): {
+		1: "bitcoin",
+		2: "testnet",
+		3: "signet",
+		4: "regtest",
+	},
+	reflect.TypeOf(breez_sdk_liquid.PaymentMethod(0)): {
+		1: "bolt11_invoice",
+		2: "bolt12_offer",
+		3: "bitcoin_address",
+		4: "liquid_address",
+	},
+	reflect.TypeOf(breez_sdk_liquid.PaymentState(0)): {
+		1: "created",
+		2: "pending",
+		3: "complete",
+		4: "failed",
+		5: "timed_out",
+		6: "refundable",
+		7: "refund_pending",
+		8: "waiting_fee_acceptance",
+	},
+	reflect.TypeOf(breez_sdk_liquid.PaymentType(0)): {
+		1: "receive",
+		2: "send",
+	},
+}
@@ -0,0 +1,95 @@
+package sdkjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+func TestRoundTrip(t *testing.T) {
+	fees := uint64(21)
+	txId := "ab01"
+	var in breez_sdk_liquid.SdkEvent = breez_sdk_liquid.SdkEventPaymentSucceeded{Details: breez_sdk_liquid.Payment{
+		Timestamp:      1700000000,
+		AmountSat:      50000,
+		FeesSat:        143,
+		PaymentType:    breez_sdk_liquid.PaymentTypeReceive,
+		Status:         breez_sdk_liquid.PaymentStateComplete,
+		SwapperFeesSat: &fees,
+		TxId:           &txId,
+		Details: breez_sdk_liquid.PaymentDetailsBitcoin{
+			SwapId:         "swap1",
+			BitcoinAddress: "bc1qexample",
+		},
+	}}
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	payment := doc["details"].(map[string]any)
+	if doc[TypeKey] != "payment_succeeded" || payment["status"] != "complete" || payment["payment_type"] != "receive" {
+		t.Fatalf("unexpected encoding %s", data)
+	}
+	if details := payment["details"].(map[string]any); details[TypeKey] != "bitcoin" || details["swap_id"] != "swap1" {
+		t.Fatalf("unexpected details encoding %s", data)
+	}
+	if payment["destination"] != nil {
+		t.Fatalf("nil pointer encoded as %v", payment["destination"])
+	}
+
+	var out breez_sdk_liquid.SdkEvent
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("decoded %+v, want %+v", out, in)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, path string
+	}{
+		{"unknown member", `{"type":"synced","extra":1}`, ""},
+		{"unknown variant", `{"type":"nope"}`, ""},
+		{"wrong type", `{"type":"data_synced","did_pull_new_records":"yes"}`, "did_pull_new_records"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e breez_sdk_liquid.SdkEvent
+			err := Unmarshal([]byte(tt.doc), &e)
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("got %v, want a DecodeError", err)
+			}
+			if tt.path != "" && decodeErr.Path != tt.path {
+				t.Fatalf("error path %q, want %q", decodeErr.Path, tt.path)
+			}
+		})
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	wrapped := fmt.Errorf("paying: %w", sdkutil.PaymentErrorWithMessage(breez_sdk_liquid.NewPaymentErrorInsufficientFunds(), "not enough"))
+	got := EncodeError(wrapped)
+	if got.Type != "payment_error" || got.Variant != "insufficient_funds" {
+		t.Fatalf("encoded %+v", got)
+	}
+	if got.Message != wrapped.Error() {
+		t.Fatalf("message %q, want %q", got.Message, wrapped.Error())
+	}
+
+	if got := EncodeError(errors.New("boom")); got.Type != "error" || got.Variant != "" || got.Message != "boom" {
+		t.Fatalf("encoded %+v", got)
+	}
+}
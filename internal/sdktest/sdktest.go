@@ -0,0 +1,147 @@
+// Package sdktest provides a fake SDK for the tests of the packages built on
+// BindingLiquidSdkInterface.
+package sdktest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// Fake is an SDK paying Liquid addresses, which are the inputs starting with
+// "lq". The methods it does not implement panic when called.
+type Fake struct {
+	breez_sdk_liquid.BindingLiquidSdkInterface
+
+	mu sync.Mutex
+	// BalanceSat is reported by GetInfo.
+	BalanceSat uint64
+	// FeesSat are the fees of every payment.
+	FeesSat uint64
+	// Rates are returned by FetchFiatRates.
+	Rates []breez_sdk_liquid.Rate
+	// PrepareErr fails every prepare.
+	PrepareErr *breez_sdk_liquid.PaymentError
+	// SendErrs fail the next sends, one each. The payment is made anyway
+	// if PaidOnError is set.
+	SendErrs    []*breez_sdk_liquid.PaymentError
+	PaidOnError bool
+
+	// Payments are listed in the order they were made.
+	Payments []breez_sdk_liquid.Payment
+	Prepares int
+	Sends    int
+}
+
+// Pay records a payment to a Liquid address as if sent by the SDK.
+func (f *Fake) Pay(address string, amountSat uint64) breez_sdk_liquid.Payment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pay(address, amountSat)
+}
+
+func (f *Fake) pay(address string, amountSat uint64) breez_sdk_liquid.Payment {
+	txId := fmt.Sprintf("tx%d", len(f.Payments)+1)
+	p := breez_sdk_liquid.Payment{
+		Timestamp:   uint32(time.Now().Unix()),
+		AmountSat:   amountSat,
+		FeesSat:     f.FeesSat,
+		PaymentType: breez_sdk_liquid.PaymentTypeSend,
+		Status:      breez_sdk_liquid.PaymentStateComplete,
+		TxId:        &txId,
+		Details:     breez_sdk_liquid.PaymentDetailsLiquid{Destination: address},
+	}
+	f.Payments = append(f.Payments, p)
+	return p
+}
+
+func (f *Fake) GetInfo() (breez_sdk_liquid.GetInfoResponse, *breez_sdk_liquid.SdkError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return breez_sdk_liquid.GetInfoResponse{WalletInfo: breez_sdk_liquid.WalletInfo{BalanceSat: f.BalanceSat}}, nil
+}
+
+func (f *Fake) FetchFiatRates() ([]breez_sdk_liquid.Rate, *breez_sdk_liquid.SdkError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Rates, nil
+}
+
+func (f *Fake) Parse(input string) (breez_sdk_liquid.InputType, *breez_sdk_liquid.PaymentError) {
+	if !strings.HasPrefix(input, "lq") {
+		return nil, breez_sdk_liquid.NewPaymentErrorInvalidInvoice()
+	}
+	return breez_sdk_liquid.InputTypeLiquidAddress{Address: breez_sdk_liquid.LiquidAddressData{Address: input}}, nil
+}
+
+func (f *Fake) PrepareSendPayment(req breez_sdk_liquid.PrepareSendRequest) (breez_sdk_liquid.PrepareSendResponse, *breez_sdk_liquid.PaymentError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Prepares++
+	if f.PrepareErr != nil {
+		return breez_sdk_liquid.PrepareSendResponse{}, f.PrepareErr
+	}
+	fees := f.FeesSat
+	return breez_sdk_liquid.PrepareSendResponse{
+		Destination: breez_sdk_liquid.SendDestinationLiquidAddress{
+			AddressData: breez_sdk_liquid.LiquidAddressData{Address: req.Destination},
+		},
+		Amount:  req.Amount,
+		FeesSat: &fees,
+	}, nil
+}
+
+func (f *Fake) SendPayment(req breez_sdk_liquid.SendPaymentRequest) (breez_sdk_liquid.SendPaymentResponse, *breez_sdk_liquid.PaymentError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Sends++
+	var err *breez_sdk_liquid.PaymentError
+	if len(f.SendErrs) > 0 {
+		err, f.SendErrs = f.SendErrs[0], f.SendErrs[1:]
+	}
+	if err != nil && !f.PaidOnError {
+		return breez_sdk_liquid.SendPaymentResponse{}, err
+	}
+	d := req.PrepareResponse.Destination.(breez_sdk_liquid.SendDestinationLiquidAddress)
+	// Asset payments are recorded without an amount in sats.
+	var amountSat uint64
+	switch amount := (*req.PrepareResponse.Amount).(type) {
+	case breez_sdk_liquid.PayAmountBitcoin:
+		amountSat = amount.ReceiverAmountSat
+	case breez_sdk_liquid.PayAmountDrain:
+		amountSat = f.BalanceSat - f.FeesSat
+	}
+	return breez_sdk_liquid.SendPaymentResponse{Payment: f.pay(d.AddressData.Address, amountSat)}, err
+}
+
+func (f *Fake) ListPayments(req breez_sdk_liquid.ListPaymentsRequest) ([]breez_sdk_liquid.Payment, *breez_sdk_liquid.PaymentError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payments := f.Payments
+	if req.Offset != nil {
+		if int(*req.Offset) >= len(payments) {
+			return nil, nil
+		}
+		payments = payments[*req.Offset:]
+	}
+	if req.Limit != nil && int(*req.Limit) < len(payments) {
+		payments = payments[:*req.Limit]
+	}
+	return append([]breez_sdk_liquid.Payment(nil), payments...), nil
+}
+
+// SendRequest returns the request sending amountSat to a Liquid address,
+// as prepared by the SDK.
+func SendRequest(address string, amountSat, feesSat uint64) breez_sdk_liquid.SendPaymentRequest {
+	var amount breez_sdk_liquid.PayAmount = breez_sdk_liquid.PayAmountBitcoin{ReceiverAmountSat: amountSat}
+	return breez_sdk_liquid.SendPaymentRequest{PrepareResponse: breez_sdk_liquid.PrepareSendResponse{
+		Destination: breez_sdk_liquid.SendDestinationLiquidAddress{
+			AddressData: breez_sdk_liquid.LiquidAddressData{Address: address},
+		},
+		Amount:  &amount,
+		FeesSat: &feesSat,
+	}}
+}
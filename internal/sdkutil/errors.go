@@ -0,0 +1,132 @@
+package sdkutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"unsafe"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// ErrorVariant returns the name of the variant of an SDK error, such as
+// "PaymentErrorAmountOutOfRange", or the type name of any other error. It
+// returns an empty string for a nil error.
+func ErrorVariant(err error) string {
+	if err == nil {
+		return ""
+	}
+	if inner := errors.Unwrap(err); inner != nil {
+		err = inner
+	}
+	if c, ok := err.(*caused); ok {
+		err = c.variant
+	}
+	t := reflect.TypeOf(err)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return "error"
+	}
+	return t.Name()
+}
+
+// Ambiguous reports whether an error returned by a payment call leaves
+// open whether the payment was executed.
+func Ambiguous(err error) bool {
+	for _, target := range []error{
+		breez_sdk_liquid.ErrPaymentErrorPaymentTimeout,
+		breez_sdk_liquid.ErrPaymentErrorGeneric,
+		breez_sdk_liquid.ErrPaymentErrorPersistError,
+		breez_sdk_liquid.ErrPaymentErrorSendError,
+		breez_sdk_liquid.ErrSdkErrorServiceConnectivity,
+		breez_sdk_liquid.ErrLnUrlPayErrorPaymentTimeout,
+		breez_sdk_liquid.ErrLnUrlPayErrorGeneric,
+		breez_sdk_liquid.ErrLnUrlPayErrorServiceConnectivity,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	// Errors not coming from the SDK, e.g. returned by interceptors, give
+	// no guarantee either.
+	var paymentErr *breez_sdk_liquid.PaymentError
+	var sdkErr *breez_sdk_liquid.SdkError
+	var lnurlErr *breez_sdk_liquid.LnUrlPayError
+	return !errors.As(err, &paymentErr) && !errors.As(err, &sdkErr) && !errors.As(err, &lnurlErr)
+}
+
+// PaymentErrorWithMessage returns the variant of a PaymentError, such as
+// breez_sdk_liquid.NewPaymentErrorGeneric(), with a message. The bindings
+// only set the message of these errors when decoding them.
+func PaymentErrorWithMessage(variant *breez_sdk_liquid.PaymentError, message string) *breez_sdk_liquid.PaymentError {
+	if message == "" {
+		return variant
+	}
+	var buf bytes.Buffer
+	breez_sdk_liquid.FfiConverterPaymentError{}.Write(&buf, variant)
+	return breez_sdk_liquid.FfiConverterPaymentError{}.Read(withMessage(buf.Bytes(), message))
+}
+
+// SdkErrorWithMessage returns the variant of an SdkError with a message,
+// as PaymentErrorWithMessage.
+func SdkErrorWithMessage(variant *breez_sdk_liquid.SdkError, message string) *breez_sdk_liquid.SdkError {
+	if message == "" {
+		return variant
+	}
+	var buf bytes.Buffer
+	breez_sdk_liquid.FfiConverterSdkError{}.Write(&buf, variant)
+	return breez_sdk_liquid.FfiConverterSdkError{}.Read(withMessage(buf.Bytes(), message))
+}
+
+// withMessage appends a message to an encoded error variant.
+func withMessage(variant []byte, message string) *bytes.Reader {
+	data := binary.BigEndian.AppendUint32(variant, uint32(len(message)))
+	return bytes.NewReader(append(data, message...))
+}
+
+// Error is the type of the errors returned by the bindings.
+type Error interface {
+	breez_sdk_liquid.PaymentError | breez_sdk_liquid.SdkError | breez_sdk_liquid.LnUrlAuthError |
+		breez_sdk_liquid.LnUrlPayError | breez_sdk_liquid.LnUrlWithdrawError | breez_sdk_liquid.NwcError
+}
+
+// WithCause returns a copy of an SDK error converted from cause, such as an
+// error returned by an interceptor. errors.Is matches the copy as its
+// variant, and errors.Is and errors.As reach cause through it. The copy
+// cannot be encoded by the bindings, nor passed to PaymentErrorWithMessage
+// and SdkErrorWithMessage.
+func WithCause[T Error](err *T, cause error) *T {
+	out := new(T)
+	*out = *err
+	variant := variantField(out)
+	variant.Set(reflect.ValueOf(&caused{variant: variant.Interface().(error), cause: cause}))
+	return out
+}
+
+// variantField returns the field of an SDK error holding its variant,
+// which the bindings do not export.
+func variantField(err any) reflect.Value {
+	f := reflect.ValueOf(err).Elem().Field(0)
+	return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+}
+
+// caused is the variant of an SDK error returned by WithCause.
+type caused struct {
+	variant error
+	cause   error
+}
+
+func (e *caused) Error() string {
+	return e.variant.Error()
+}
+
+func (e *caused) Is(target error) bool {
+	return errors.Is(e.variant, target)
+}
+
+func (e *caused) Unwrap() error {
+	return e.cause
+}
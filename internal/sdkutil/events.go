@@ -0,0 +1,105 @@
+package sdkutil
+
+import (
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// EventListenerFunc adapts a function to the breez_sdk_liquid.EventListener
+// interface.
+type EventListenerFunc func(e breez_sdk_liquid.SdkEvent)
+
+// OnEvent calls f(e).
+func (f EventListenerFunc) OnEvent(e breez_sdk_liquid.SdkEvent) {
+	f(e)
+}
+
+// NwcEventListenerFunc adapts a function to the
+// breez_sdk_liquid.NwcEventListener interface.
+type NwcEventListenerFunc func(e breez_sdk_liquid.NwcEvent)
+
+// OnEvent calls f(e).
+func (f NwcEventListenerFunc) OnEvent(e breez_sdk_liquid.NwcEvent) {
+	f(e)
+}
+
+// EventPayment returns the payment carried by a payment event.
+func EventPayment(e breez_sdk_liquid.SdkEvent) (breez_sdk_liquid.Payment, bool) {
+	switch e := e.(type) {
+	case breez_sdk_liquid.SdkEventPaymentFailed:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentPending:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentRefundable:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentRefunded:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentRefundPending:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentSucceeded:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentWaitingConfirmation:
+		return e.Details, true
+	case breez_sdk_liquid.SdkEventPaymentWaitingFeeAcceptance:
+		return e.Details, true
+	default:
+		return breez_sdk_liquid.Payment{}, false
+	}
+}
+
+// EventName returns the snake_case name of the SdkEvent variant.
+func EventName(e breez_sdk_liquid.SdkEvent) string {
+	switch e.(type) {
+	case breez_sdk_liquid.SdkEventPaymentFailed:
+		return "payment_failed"
+	case breez_sdk_liquid.SdkEventPaymentPending:
+		return "payment_pending"
+	case breez_sdk_liquid.SdkEventPaymentRefundable:
+		return "payment_refundable"
+	case breez_sdk_liquid.SdkEventPaymentRefunded:
+		return "payment_refunded"
+	case breez_sdk_liquid.SdkEventPaymentRefundPending:
+		return "payment_refund_pending"
+	case breez_sdk_liquid.SdkEventPaymentSucceeded:
+		return "payment_succeeded"
+	case breez_sdk_liquid.SdkEventPaymentWaitingConfirmation:
+		return "payment_waiting_confirmation"
+	case breez_sdk_liquid.SdkEventPaymentWaitingFeeAcceptance:
+		return "payment_waiting_fee_acceptance"
+	case breez_sdk_liquid.SdkEventSynced:
+		return "synced"
+	case breez_sdk_liquid.SdkEventSyncFailed:
+		return "sync_failed"
+	case breez_sdk_liquid.SdkEventDataSynced:
+		return "data_synced"
+	default:
+		return "unknown"
+	}
+}
+
+// NwcEventName returns the snake_case name of the NwcEventDetails variant.
+func NwcEventName(e breez_sdk_liquid.NwcEvent) string {
+	switch e.Details.(type) {
+	case breez_sdk_liquid.NwcEventDetailsConnected:
+		return "connected"
+	case breez_sdk_liquid.NwcEventDetailsDisconnected:
+		return "disconnected"
+	case breez_sdk_liquid.NwcEventDetailsPayInvoice:
+		return "pay_invoice"
+	case breez_sdk_liquid.NwcEventDetailsMakeInvoice:
+		return "make_invoice"
+	case breez_sdk_liquid.NwcEventDetailsListTransactions:
+		return "list_transactions"
+	case breez_sdk_liquid.NwcEventDetailsGetBalance:
+		return "get_balance"
+	case breez_sdk_liquid.NwcEventDetailsGetInfo:
+		return "get_info"
+	case breez_sdk_liquid.NwcEventDetailsConnectionExpired:
+		return "connection_expired"
+	case breez_sdk_liquid.NwcEventDetailsConnectionRefreshed:
+		return "connection_refreshed"
+	case breez_sdk_liquid.NwcEventDetailsZapReceived:
+		return "zap_received"
+	default:
+		return "unknown"
+	}
+}
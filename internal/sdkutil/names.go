@@ -0,0 +1,73 @@
+// Package sdkutil contains small helpers shared by the packages built on top
+// of the generated breez_sdk_liquid bindings.
+package sdkutil
+
+import (
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// PaymentStateName returns the snake_case name of a PaymentState.
+func PaymentStateName(state breez_sdk_liquid.PaymentState) string {
+	switch state {
+	case breez_sdk_liquid.PaymentStateCreated:
+		return "created"
+	case breez_sdk_liquid.PaymentStatePending:
+		return "pending"
+	case breez_sdk_liquid.PaymentStateComplete:
+		return "complete"
+	case breez_sdk_liquid.PaymentStateFailed:
+		return "failed"
+	case breez_sdk_liquid.PaymentStateTimedOut:
+		return "timed_out"
+	case breez_sdk_liquid.PaymentStateRefundable:
+		return "refundable"
+	case breez_sdk_liquid.PaymentStateRefundPending:
+		return "refund_pending"
+	case breez_sdk_liquid.PaymentStateWaitingFeeAcceptance:
+		return "waiting_fee_acceptance"
+	default:
+		return "unknown"
+	}
+}
+
+// PaymentTypeName returns the snake_case name of a PaymentType.
+func PaymentTypeName(paymentType breez_sdk_liquid.PaymentType) string {
+	switch paymentType {
+	case breez_sdk_liquid.PaymentTypeReceive:
+		return "receive"
+	case breez_sdk_liquid.PaymentTypeSend:
+		return "send"
+	default:
+		return "unknown"
+	}
+}
+
+// PaymentMethodName returns the snake_case name of a PaymentMethod.
+func PaymentMethodName(method breez_sdk_liquid.PaymentMethod) string {
+	switch method {
+	case breez_sdk_liquid.PaymentMethodBolt11Invoice:
+		return "bolt11_invoice"
+	case breez_sdk_liquid.PaymentMethodBolt12Offer:
+		return "bolt12_offer"
+	case breez_sdk_liquid.PaymentMethodBitcoinAddress:
+		return "bitcoin_address"
+	case breez_sdk_liquid.PaymentMethodLiquidAddress:
+		return "liquid_address"
+	default:
+		return "unknown"
+	}
+}
+
+// PaymentDetailsName returns the snake_case name of the PaymentDetails variant.
+func PaymentDetailsName(details breez_sdk_liquid.PaymentDetails) string {
+	switch details.(type) {
+	case breez_sdk_liquid.PaymentDetailsLightning:
+		return "lightning"
+	case breez_sdk_liquid.PaymentDetailsLiquid:
+		return "liquid"
+	case breez_sdk_liquid.PaymentDetailsBitcoin:
+		return "bitcoin"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,119 @@
+package sdkutil
+
+import (
+	"strings"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// PageSize is the number of payments requested per ListPayments call when
+// paging through the whole payment history.
+const PageSize uint32 = 100
+
+// Asset ids of L-BTC on each Liquid network.
+const (
+	LbtcAssetIdMainnet = "6f0279e9ed041c3d710a9f57d0c02928416460c4b722ae3457a11eec381c526d"
+	LbtcAssetIdTestnet = "144c654344aa716d6f3abcc1ca90e5641e4e2a7f633bc09fe3baf64585819a49"
+	LbtcAssetIdRegtest = "5ac9f65c0efcc4775e0baec4ec03abdde22473cd3cf33c0419ca290e0751b225"
+)
+
+// LbtcAssetId returns the L-BTC asset id of the given network.
+func LbtcAssetId(network breez_sdk_liquid.LiquidNetwork) string {
+	switch network {
+	case breez_sdk_liquid.LiquidNetworkTestnet:
+		return LbtcAssetIdTestnet
+	case breez_sdk_liquid.LiquidNetworkRegtest:
+		return LbtcAssetIdRegtest
+	default:
+		return LbtcAssetIdMainnet
+	}
+}
+
+// ListAllPayments pages through ListPayments until every payment matching
+// req has been fetched. Offset and Limit of req are ignored.
+func ListAllPayments(sdk breez_sdk_liquid.BindingLiquidSdkInterface, req breez_sdk_liquid.ListPaymentsRequest) ([]breez_sdk_liquid.Payment, error) {
+	var payments []breez_sdk_liquid.Payment
+	offset := uint32(0)
+	limit := PageSize
+	for {
+		req.Offset = &offset
+		req.Limit = &limit
+		page, err := sdk.ListPayments(req)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, page...)
+		if uint32(len(page)) < limit {
+			return payments, nil
+		}
+		offset += uint32(len(page))
+	}
+}
+
+// SwapId returns the swap id of a Lightning or Bitcoin payment, or an empty
+// string for direct Liquid payments.
+func SwapId(payment breez_sdk_liquid.Payment) string {
+	switch details := payment.Details.(type) {
+	case breez_sdk_liquid.PaymentDetailsLightning:
+		return details.SwapId
+	case breez_sdk_liquid.PaymentDetailsBitcoin:
+		return details.SwapId
+	default:
+		return ""
+	}
+}
+
+// PaymentHash returns the payment hash of a Lightning payment, or an empty
+// string if the payment has none.
+func PaymentHash(payment breez_sdk_liquid.Payment) string {
+	if details, ok := payment.Details.(breez_sdk_liquid.PaymentDetailsLightning); ok && details.PaymentHash != nil {
+		return *details.PaymentHash
+	}
+	return ""
+}
+
+// AssetId returns the asset moved by the payment. Lightning and Bitcoin
+// payments always settle in L-BTC, identified by lbtcAssetId.
+func AssetId(payment breez_sdk_liquid.Payment, lbtcAssetId string) string {
+	if details, ok := payment.Details.(breez_sdk_liquid.PaymentDetailsLiquid); ok && details.AssetId != "" {
+		return details.AssetId
+	}
+	return lbtcAssetId
+}
+
+// PaymentId returns a stable identifier for the payment: the swap id when
+// there is one, otherwise the transaction id.
+func PaymentId(payment breez_sdk_liquid.Payment) string {
+	if swapId := SwapId(payment); swapId != "" {
+		return swapId
+	}
+	return StringValue(payment.TxId)
+}
+
+// Address returns the address of a destination which may be a BIP21 URI,
+// such as the destinations returned by ReceivePayment.
+func Address(destination string) string {
+	if i := strings.IndexByte(destination, '?'); i >= 0 {
+		destination = destination[:i]
+	}
+	if i := strings.IndexByte(destination, ':'); i >= 0 {
+		destination = destination[i+1:]
+	}
+	return destination
+}
+
+// StringValue dereferences s, returning an empty string for nil.
+func StringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// Uint64Value dereferences v, returning zero for nil.
+func Uint64Value(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,138 @@
+// Command gen generates the method tables of package jsonrpc from the
+// interfaces declared in the breez_sdk_liquid bindings.
+//
+// It is run from the jsonrpc directory with go generate.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// service describes an interface to generate a method table for.
+type service struct {
+	iface  string // interface name in the bindings
+	table  string // generated variable name
+	prefix string // JSON-RPC method name prefix
+}
+
+var services = []service{
+	{iface: "BindingLiquidSdkInterface", table: "sdkMethods"},
+	{iface: "BindingNwcServiceInterface", table: "nwcMethods", prefix: "nwc."},
+}
+
+// skipped methods take callbacks or return objects, and are replaced by
+// the subscription methods of the server, or manage the lifecycle of the
+// services, which belongs to the process serving them.
+var skipped = map[string]bool{
+	"AddEventListener":    true,
+	"RemoveEventListener": true,
+	"UseNwcPlugin":        true,
+	"Disconnect":          true,
+	"Stop":                true,
+}
+
+func main() {
+	input := flag.String("input", "../breez_sdk_liquid/breez_sdk_liquid.go", "bindings source file")
+	output := flag.String("output", "methods_gen.go", "generated file")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, *input, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	interfaces := map[string]*ast.InterfaceType{}
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok {
+				if it, ok := ts.Type.(*ast.InterfaceType); ok {
+					interfaces[ts.Name.Name] = it
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by go run ./internal/gen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package jsonrpc\n\n")
+	for _, svc := range services {
+		it, ok := interfaces[svc.iface]
+		if !ok {
+			log.Fatalf("interface %s not found", svc.iface)
+		}
+		type method struct {
+			name, goName string
+			params       []string
+		}
+		var methods []method
+		for _, field := range it.Methods.List {
+			ft, ok := field.Type.(*ast.FuncType)
+			if !ok || skipped[field.Names[0].Name] {
+				continue
+			}
+			m := method{name: svc.prefix + snake(field.Names[0].Name), goName: field.Names[0].Name}
+			for _, p := range ft.Params.List {
+				for _, name := range p.Names {
+					m.params = append(m.params, snake(name.Name))
+				}
+			}
+			methods = append(methods, m)
+		}
+		sort.Slice(methods, func(i, j int) bool { return methods[i].name < methods[j].name })
+
+		fmt.Fprintf(&buf, "// %s are the methods of %s served.\n", svc.table, svc.iface)
+		fmt.Fprintf(&buf, "var %s = []methodSpec{\n", svc.table)
+		for _, m := range methods {
+			var params []string
+			for _, p := range m.params {
+				params = append(params, fmt.Sprintf("%q", p))
+			}
+			fmt.Fprintf(&buf, "\t{name: %q, goName: %q, params: []string{%s}},\n", m.name, m.goName, strings.Join(params, ", "))
+		}
+		fmt.Fprintf(&buf, "}\n\n")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v\n%s", err, buf.Bytes())
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// snake converts a camelCase or CamelCase identifier to snake_case.
+func snake(name string) string {
+	var b strings.Builder
+	r := []rune(strings.ReplaceAll(name, "LnUrl", "Lnurl"))
+	for i, c := range r {
+		upper := c >= 'A' && c <= 'Z'
+		if upper && i > 0 {
+			prev := r[i-1]
+			prevLower := prev >= 'a' && prev <= 'z' || prev >= '0' && prev <= '9'
+			nextLower := i+1 < len(r) && r[i+1] >= 'a' && r[i+1] <= 'z'
+			if prevLower || (prev >= 'A' && prev <= 'Z' && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		if upper {
+			c += 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
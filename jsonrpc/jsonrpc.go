@@ -0,0 +1,213 @@
+// Package jsonrpc serves the SDK and the NWC service over JSON-RPC 2.0.
+//
+// Every method of BindingLiquidSdkInterface is served under its snake_case
+// name, such as "send_payment", and every method of
+// BindingNwcServiceInterface under "nwc." and its name, such as
+// "nwc.add_connection". Parameters are SDK types encoded as described in
+// package restapi: snake_case members, sum type variants tagged with a
+// "type" member. They are given by position, by name ({"req": {...}}), or
+// for methods taking a single request, as the request itself.
+//
+// Event listeners are replaced by subscriptions: "subscribe", with
+// parameters such as {"sdk": true, "nwc": true}, returns a subscription id,
+// after which the events are sent as "sdk_event" and "nwc_event"
+// notifications until "unsubscribe" or the end of the connection.
+//
+// Messages are JSON values written one per line on a stream such as a Unix
+// socket. Batches are supported.
+package jsonrpc
+
+//go:generate go run ./internal/gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+)
+
+// Error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	// CodeSdkError is returned for errors of the SDK, described by the
+	// error data.
+	CodeSdkError = -32000
+)
+
+// Request is a JSON-RPC request or notification.
+type Request struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response.
+type Response struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Notification is a message sent by the server without a request.
+type Notification struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Error is a JSON-RPC error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc: %d %s", e.Code, e.Message)
+}
+
+// methodSpec describes a method of a binding, see methods_gen.go.
+type methodSpec struct {
+	name   string
+	goName string
+	params []string
+}
+
+// method is a served method bound to its receiver.
+type method struct {
+	spec methodSpec
+	fn   reflect.Value
+}
+
+// bind returns the methods of recv served under specs.
+func bind(recv any, specs []methodSpec, methods map[string]method) {
+	v := reflect.ValueOf(recv)
+	for _, spec := range specs {
+		methods[spec.name] = method{spec: spec, fn: v.MethodByName(spec.goName)}
+	}
+}
+
+// call decodes the parameters, calls the method and returns its result,
+// null for methods returning no value.
+func (m method) call(params json.RawMessage) (json.RawMessage, *Error) {
+	args, rpcErr := m.args(params)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	out := m.fn.Call(args)
+
+	var result any
+	for _, v := range out {
+		if asError, ok := v.Interface().(interface{ AsError() error }); ok && v.Type().Implements(errorType) {
+			if err := asError.AsError(); err != nil {
+				return nil, sdkError(err)
+			}
+			continue
+		}
+		result = sdkjson.Encode(v.Interface())
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+	return data, nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// args decodes params: an array of positional parameters, an object of
+// named parameters, or the single parameter of the method.
+func (m method) args(params json.RawMessage) ([]reflect.Value, *Error) {
+	t := m.fn.Type()
+	n := t.NumIn()
+	invalid := func(format string, a ...any) *Error {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, a...)}
+	}
+
+	var raw []any
+	if len(params) > 0 && string(params) != "null" {
+		var src any
+		if err := sdkjson.Unmarshal(params, &src); err != nil {
+			return nil, invalid("%v", err)
+		}
+		switch src := src.(type) {
+		case []any:
+			raw = src
+		case map[string]any:
+			if named(src, m.spec.params) {
+				raw = make([]any, n)
+				for i, name := range m.spec.params {
+					raw[i] = src[name]
+				}
+			} else if n == 1 {
+				raw = []any{src}
+			} else {
+				return nil, invalid("unknown parameters, expected %v", m.spec.params)
+			}
+		default:
+			return nil, invalid("params must be an array or an object")
+		}
+	}
+	if len(raw) != n {
+		return nil, invalid("%s takes %d parameters %v", m.spec.name, n, m.spec.params)
+	}
+
+	args := make([]reflect.Value, n)
+	for i := range args {
+		arg := reflect.New(t.In(i))
+		if err := sdkjson.Decode(raw[i], arg.Interface()); err != nil {
+			return nil, invalid("%s: %v", m.spec.params[i], err)
+		}
+		args[i] = arg.Elem()
+	}
+	return args, nil
+}
+
+// named reports whether the members of obj are all parameter names.
+func named(obj map[string]any, params []string) bool {
+	if len(obj) == 0 {
+		return len(params) == 0
+	}
+	for key := range obj {
+		found := false
+		for _, p := range params {
+			found = found || p == key
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sdkError(err error) *Error {
+	return &Error{Code: CodeSdkError, Message: err.Error(), Data: sdkjson.EncodeError(err)}
+}
+
+// Event notifications.
+const (
+	NotificationSdkEvent = "sdk_event"
+	NotificationNwcEvent = "nwc_event"
+)
+
+// EventParams are the parameters of event notifications.
+type EventParams struct {
+	Subscription string `json:"subscription"`
+	Event        any    `json:"event"`
+}
+
+func sdkEventNotification(subscription string, e breez_sdk_liquid.SdkEvent) Notification {
+	return Notification{Jsonrpc: "2.0", Method: NotificationSdkEvent, Params: EventParams{Subscription: subscription, Event: sdkjson.Encode(e)}}
+}
+
+func nwcEventNotification(subscription string, e breez_sdk_liquid.NwcEvent) Notification {
+	return Notification{Jsonrpc: "2.0", Method: NotificationNwcEvent, Params: EventParams{Subscription: subscription, Event: sdkjson.Encode(e)}}
+}
@@ -0,0 +1,90 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// dial serves a connection to an SDK with a balance of 1000 sat, whose
+// Sync fails.
+func dial(t *testing.T) (net.Conn, *bufio.Scanner) {
+	t.Helper()
+	sdk := intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		switch call.Method {
+		case "GetInfo":
+			return breez_sdk_liquid.GetInfoResponse{WalletInfo: breez_sdk_liquid.WalletInfo{BalanceSat: 1000}}, nil
+		case "Sync":
+			return nil, breez_sdk_liquid.NewSdkErrorServiceConnectivity()
+		}
+		return nil, nil
+	})
+	server, client := net.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = New(sdk, Options{}).ServeConn(ctx, server)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		client.Close()
+		<-done
+	})
+	return client, bufio.NewScanner(client)
+}
+
+func roundTrip(t *testing.T, conn net.Conn, scanner *bufio.Scanner, req string) Response {
+	t.Helper()
+	if _, err := conn.Write([]byte(req + "\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !scanner.Scan() {
+		t.Fatalf("no response: %v", scanner.Err())
+	}
+	var res Response
+	if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+		t.Fatalf("%s: %v", scanner.Bytes(), err)
+	}
+	return res
+}
+
+func TestCall(t *testing.T) {
+	conn, scanner := dial(t)
+	res := roundTrip(t, conn, scanner, `{"jsonrpc":"2.0","id":1,"method":"get_info"}`)
+	if res.Error != nil || string(res.Id) != "1" {
+		t.Fatalf("response %+v", res)
+	}
+	var info struct {
+		WalletInfo struct {
+			BalanceSat uint64 `json:"balance_sat"`
+		} `json:"wallet_info"`
+	}
+	if err := json.Unmarshal(res.Result, &info); err != nil || info.WalletInfo.BalanceSat != 1000 {
+		t.Fatalf("result %s", res.Result)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	conn, scanner := dial(t)
+	tests := []struct {
+		req  string
+		code int
+	}{
+		{`{"jsonrpc":"2.0","id":1,"method":"unknown"}`, CodeMethodNotFound},
+		{`{"jsonrpc":"2.0","id":2,"method":"sync"}`, CodeSdkError},
+		{`{"jsonrpc":"2.0","id":3,"method":"parse","params":[1, 2]}`, CodeInvalidParams},
+		{`{"id":4}`, CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		res := roundTrip(t, conn, scanner, tt.req)
+		if res.Error == nil || res.Error.Code != tt.code {
+			t.Fatalf("%s: response %+v, want code %d", tt.req, res, tt.code)
+		}
+	}
+}
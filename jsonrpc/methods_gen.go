@@ -0,0 +1,55 @@
+// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+package jsonrpc
+
+// sdkMethods are the methods of BindingLiquidSdkInterface served.
+var sdkMethods = []methodSpec{
+	{name: "accept_payment_proposed_fees", goName: "AcceptPaymentProposedFees", params: []string{"req"}},
+	{name: "backup", goName: "Backup", params: []string{"req"}},
+	{name: "buy_bitcoin", goName: "BuyBitcoin", params: []string{"req"}},
+	{name: "check_message", goName: "CheckMessage", params: []string{"req"}},
+	{name: "create_bolt12_invoice", goName: "CreateBolt12Invoice", params: []string{"req"}},
+	{name: "fetch_fiat_rates", goName: "FetchFiatRates", params: []string{}},
+	{name: "fetch_lightning_limits", goName: "FetchLightningLimits", params: []string{}},
+	{name: "fetch_onchain_limits", goName: "FetchOnchainLimits", params: []string{}},
+	{name: "fetch_payment_proposed_fees", goName: "FetchPaymentProposedFees", params: []string{"req"}},
+	{name: "get_info", goName: "GetInfo", params: []string{}},
+	{name: "get_payment", goName: "GetPayment", params: []string{"req"}},
+	{name: "list_fiat_currencies", goName: "ListFiatCurrencies", params: []string{}},
+	{name: "list_payments", goName: "ListPayments", params: []string{"req"}},
+	{name: "list_refundables", goName: "ListRefundables", params: []string{}},
+	{name: "lnurl_auth", goName: "LnurlAuth", params: []string{"req_data"}},
+	{name: "lnurl_pay", goName: "LnurlPay", params: []string{"req"}},
+	{name: "lnurl_withdraw", goName: "LnurlWithdraw", params: []string{"req"}},
+	{name: "parse", goName: "Parse", params: []string{"input"}},
+	{name: "pay_onchain", goName: "PayOnchain", params: []string{"req"}},
+	{name: "prepare_buy_bitcoin", goName: "PrepareBuyBitcoin", params: []string{"req"}},
+	{name: "prepare_lnurl_pay", goName: "PrepareLnurlPay", params: []string{"req"}},
+	{name: "prepare_pay_onchain", goName: "PreparePayOnchain", params: []string{"req"}},
+	{name: "prepare_receive_payment", goName: "PrepareReceivePayment", params: []string{"req"}},
+	{name: "prepare_refund", goName: "PrepareRefund", params: []string{"req"}},
+	{name: "prepare_send_payment", goName: "PrepareSendPayment", params: []string{"req"}},
+	{name: "receive_payment", goName: "ReceivePayment", params: []string{"req"}},
+	{name: "recommended_fees", goName: "RecommendedFees", params: []string{}},
+	{name: "refund", goName: "Refund", params: []string{"req"}},
+	{name: "register_webhook", goName: "RegisterWebhook", params: []string{"webhook_url"}},
+	{name: "rescan_onchain_swaps", goName: "RescanOnchainSwaps", params: []string{}},
+	{name: "restore", goName: "Restore", params: []string{"req"}},
+	{name: "send_payment", goName: "SendPayment", params: []string{"req"}},
+	{name: "sign_message", goName: "SignMessage", params: []string{"req"}},
+	{name: "sync", goName: "Sync", params: []string{}},
+	{name: "unregister_webhook", goName: "UnregisterWebhook", params: []string{}},
+}
+
+// nwcMethods are the methods of BindingNwcServiceInterface served.
+var nwcMethods = []methodSpec{
+	{name: "nwc.add_connection", goName: "AddConnection", params: []string{"req"}},
+	{name: "nwc.edit_connection", goName: "EditConnection", params: []string{"req"}},
+	{name: "nwc.get_info", goName: "GetInfo", params: []string{}},
+	{name: "nwc.handle_event", goName: "HandleEvent", params: []string{"raw_event"}},
+	{name: "nwc.is_zap", goName: "IsZap", params: []string{"invoice"}},
+	{name: "nwc.list_connection_payments", goName: "ListConnectionPayments", params: []string{"name"}},
+	{name: "nwc.list_connections", goName: "ListConnections", params: []string{}},
+	{name: "nwc.remove_connection", goName: "RemoveConnection", params: []string{"name"}},
+	{name: "nwc.track_zap", goName: "TrackZap", params: []string{"invoice", "zap_request"}},
+}
@@ -0,0 +1,351 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// DefaultEventBuffer is the number of notifications buffered per
+// connection.
+const DefaultEventBuffer = 256
+
+// Subscription methods.
+const (
+	MethodSubscribe   = "subscribe"
+	MethodUnsubscribe = "unsubscribe"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Nwc, if set, is served under the "nwc." methods and its events can
+	// be subscribed to.
+	Nwc breez_sdk_liquid.BindingNwcServiceInterface
+	// EventBuffer is the number of notifications buffered per connection;
+	// notifications are dropped while it is full. Defaults to
+	// DefaultEventBuffer.
+	EventBuffer int
+}
+
+// Server serves JSON-RPC connections.
+type Server struct {
+	sdk     breez_sdk_liquid.BindingLiquidSdkInterface
+	opts    Options
+	methods map[string]method
+}
+
+// New creates a Server.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Server {
+	if opts.EventBuffer <= 0 {
+		opts.EventBuffer = DefaultEventBuffer
+	}
+	s := &Server{sdk: sdk, opts: opts, methods: map[string]method{}}
+	bind(sdk, sdkMethods, s.methods)
+	if opts.Nwc != nil {
+		bind(opts.Nwc, nwcMethods, s.methods)
+	}
+	return s
+}
+
+// Listen listens on a Unix socket readable only by the current user,
+// replacing a stale socket file left by a previous run.
+func Listen(path string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errors.New("jsonrpc: socket " + path + " already in use")
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Serve accepts connections on l until ctx is done.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = s.ServeConn(ctx, conn)
+		}()
+	}
+}
+
+// ServeConn serves a connection until it is closed or ctx is done.
+func (s *Server) ServeConn(ctx context.Context, rwc io.ReadWriteCloser) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	c := &conn{
+		s:     s,
+		w:     bufio.NewWriter(rwc),
+		notes: make(chan Notification, s.opts.EventBuffer),
+		subs:  map[string]func(){},
+	}
+	defer c.unsubscribeAll()
+	go func() {
+		<-ctx.Done()
+		rwc.Close()
+	}()
+	go c.notify(ctx)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	dec := json.NewDecoder(rwc)
+	for {
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return nil
+			}
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				c.write(Response{Jsonrpc: "2.0", Id: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if res := c.handleMessage(msg); res != nil {
+				c.write(res)
+			}
+		}()
+	}
+}
+
+// conn is a served connection.
+type conn struct {
+	s *Server
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	notes chan Notification
+
+	mu   sync.Mutex
+	subs map[string]func()
+}
+
+func (c *conn) write(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, _ = c.w.Write(append(data, '\n'))
+	_ = c.w.Flush()
+}
+
+// notify writes the notifications until ctx is done.
+func (c *conn) notify(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-c.notes:
+			c.write(n)
+		}
+	}
+}
+
+// queue queues a notification, dropping it if the buffer is full.
+func (c *conn) queue(n Notification) {
+	select {
+	case c.notes <- n:
+	default:
+	}
+}
+
+// handleMessage handles a request or a batch, returning the response to
+// write, if any.
+func (c *conn) handleMessage(msg json.RawMessage) any {
+	trimmed := bytes.TrimSpace(msg)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil || len(batch) == 0 {
+			return invalidRequest()
+		}
+		var responses []*Response
+		for _, item := range batch {
+			if res := c.handle(item); res != nil {
+				responses = append(responses, res)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return responses
+	}
+	if res := c.handle(msg); res != nil {
+		return res
+	}
+	return nil
+}
+
+func invalidRequest() *Response {
+	return &Response{Jsonrpc: "2.0", Id: json.RawMessage("null"), Error: &Error{Code: CodeInvalidRequest, Message: "invalid request"}}
+}
+
+// handle handles a single request, returning nil for notifications.
+func (c *conn) handle(msg json.RawMessage) *Response {
+	var req Request
+	if err := json.Unmarshal(msg, &req); err != nil || req.Jsonrpc != "2.0" || req.Method == "" {
+		return invalidRequest()
+	}
+	result, rpcErr := c.dispatch(req)
+	if req.Id == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return &Response{Jsonrpc: "2.0", Id: req.Id, Error: rpcErr}
+	}
+	return &Response{Jsonrpc: "2.0", Id: req.Id, Result: result}
+}
+
+func (c *conn) dispatch(req Request) (json.RawMessage, *Error) {
+	switch req.Method {
+	case MethodSubscribe:
+		return c.subscribe(req.Params)
+	case MethodUnsubscribe:
+		return c.unsubscribe(req.Params)
+	}
+	m, ok := c.s.methods[req.Method]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+	return m.call(req.Params)
+}
+
+// SubscribeParams are the parameters of "subscribe".
+type SubscribeParams struct {
+	// Sdk subscribes to the SDK events. Defaults to true when Nwc is
+	// not set either.
+	Sdk bool `json:"sdk"`
+	// Nwc subscribes to the NWC events.
+	Nwc bool `json:"nwc"`
+}
+
+// UnsubscribeParams are the parameters of "unsubscribe".
+type UnsubscribeParams struct {
+	Subscription string `json:"subscription"`
+}
+
+func (c *conn) subscribe(params json.RawMessage) (json.RawMessage, *Error) {
+	var p SubscribeParams
+	if len(params) > 0 && string(params) != "null" {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+	}
+	if !p.Sdk && !p.Nwc {
+		p.Sdk = true
+	}
+	if p.Nwc && c.s.opts.Nwc == nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "NWC is not enabled"}
+	}
+	id, err := newId()
+	if err != nil {
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+
+	var cancels []func()
+	if p.Sdk {
+		listenerId, sdkErr := c.s.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+			c.queue(sdkEventNotification(id, e))
+		}))
+		if sdkErr != nil {
+			return nil, sdkError(sdkErr)
+		}
+		cancels = append(cancels, func() { _ = c.s.sdk.RemoveEventListener(listenerId) })
+	}
+	if p.Nwc {
+		nwc := c.s.opts.Nwc
+		listenerId := nwc.AddEventListener(sdkutil.NwcEventListenerFunc(func(e breez_sdk_liquid.NwcEvent) {
+			c.queue(nwcEventNotification(id, e))
+		}))
+		cancels = append(cancels, func() { nwc.RemoveEventListener(listenerId) })
+	}
+	c.mu.Lock()
+	c.subs[id] = func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+	c.mu.Unlock()
+	data, _ := json.Marshal(id)
+	return data, nil
+}
+
+func (c *conn) unsubscribe(params json.RawMessage) (json.RawMessage, *Error) {
+	var p UnsubscribeParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		// Also accept the id as the only positional parameter.
+		var ids []string
+		if json.Unmarshal(params, &ids) != nil || len(ids) != 1 {
+			return nil, &Error{Code: CodeInvalidParams, Message: "expected {\"subscription\": id}"}
+		}
+		p.Subscription = ids[0]
+	}
+	c.mu.Lock()
+	cancel, ok := c.subs[p.Subscription]
+	delete(c.subs, p.Subscription)
+	c.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	data, _ := json.Marshal(ok)
+	return data, nil
+}
+
+func (c *conn) unsubscribeAll() {
+	c.mu.Lock()
+	subs := c.subs
+	c.subs = map[string]func(){}
+	c.mu.Unlock()
+	for _, cancel := range subs {
+		cancel()
+	}
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
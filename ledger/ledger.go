@@ -0,0 +1,408 @@
+// Package ledger keeps a double-entry ledger of sub-accounts on top of a
+// single wallet.
+//
+// Every transaction moves sats between accounts and its postings sum to
+// zero. Incoming payments move sats from the @external account to the
+// sub-account that created the receive request, matched by destination,
+// payer note or description. Sends move their amount to @external and
+// their fees to @fees. Payments which cannot be attributed go to
+// @unallocated. Transfers between sub-accounts never touch the chain.
+//
+// The sats held by the wallet are the total of the sub-accounts and
+// @unallocated, which Reconcile compares with the balance in WalletInfo.
+package ledger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// DefaultSyncInterval is the interval between syncs started by Run.
+const DefaultSyncInterval = time.Minute
+
+// System accounts, which always exist.
+const (
+	// AccountExternal is the counterparty of all payments.
+	AccountExternal = "@external"
+	// AccountFees receives the fees of sends.
+	AccountFees = "@fees"
+	// AccountUnallocated holds the payments not attributed to a
+	// sub-account.
+	AccountUnallocated = "@unallocated"
+)
+
+// Kinds of transaction.
+const (
+	KindReceive  = "receive"
+	KindSend     = "send"
+	KindTransfer = "transfer"
+)
+
+var (
+	// ErrUnknownAccount is returned for accounts which were not opened.
+	ErrUnknownAccount = errors.New("ledger: unknown account")
+	// ErrAccountExists is returned when opening an existing account.
+	ErrAccountExists = errors.New("ledger: account already exists")
+	// ErrInvalidAccount is returned for account ids reserved for system
+	// accounts.
+	ErrInvalidAccount = errors.New("ledger: invalid account id")
+)
+
+// InsufficientFundsError is returned when an account cannot cover a send
+// or a transfer.
+type InsufficientFundsError struct {
+	Account      string `json:"account"`
+	AvailableSat int64  `json:"available_sat"`
+	RequiredSat  uint64 `json:"required_sat"`
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("ledger: account %s has %d sat available, %d sat required", e.Account, e.AvailableSat, e.RequiredSat)
+}
+
+// Account is a sub-account or a system account.
+type Account struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// BalanceSat is the sum of the account's postings.
+	BalanceSat int64 `json:"balance_sat"`
+	// ReservedSat is held by sends in progress.
+	ReservedSat uint64 `json:"reserved_sat,omitempty"`
+}
+
+// AvailableSat returns the balance not held by sends in progress.
+func (a Account) AvailableSat() int64 {
+	return a.BalanceSat - int64(a.ReservedSat)
+}
+
+// Posting credits an account with a positive amount or debits it with a
+// negative one.
+type Posting struct {
+	Account   string `json:"account"`
+	AmountSat int64  `json:"amount_sat"`
+}
+
+// Transaction is a set of postings summing to zero.
+type Transaction struct {
+	Id   string    `json:"id"`
+	Time time.Time `json:"time"`
+	Kind string    `json:"kind"`
+	// PaymentId is the id of the payment, for receives and sends.
+	PaymentId string    `json:"payment_id,omitempty"`
+	Memo      string    `json:"memo,omitempty"`
+	Postings  []Posting `json:"postings"`
+}
+
+// Reconciliation compares the ledger with the wallet balance.
+type Reconciliation struct {
+	// HeldSat is the total of the sub-accounts and @unallocated.
+	HeldSat int64 `json:"held_sat"`
+	// WalletSat is WalletInfo.BalanceSat.
+	WalletSat uint64 `json:"wallet_sat"`
+	// DiffSat is WalletSat minus HeldSat.
+	DiffSat  int64     `json:"diff_sat"`
+	Balanced bool      `json:"balanced"`
+	Accounts []Account `json:"accounts"`
+}
+
+// Options configures a Ledger.
+type Options struct {
+	// StatePath is the file the ledger is persisted to. Required.
+	StatePath string
+	// Network selects the L-BTC asset id. Payments of other assets are
+	// not recorded. Defaults to mainnet.
+	Network breez_sdk_liquid.LiquidNetwork
+	// SyncInterval between syncs started by Run. Defaults to
+	// DefaultSyncInterval.
+	SyncInterval time.Duration
+	// OnTransaction, if set, is called with every recorded transaction.
+	OnTransaction func(Transaction)
+}
+
+type state struct {
+	Accounts     map[string]*Account       `json:"accounts"`
+	Transactions []Transaction             `json:"transactions"`
+	Attributions map[string]Attribution    `json:"attributions"`
+	Payments     map[string]*paymentRecord `json:"payments"`
+}
+
+// Ledger is a double-entry ledger of sub-accounts.
+type Ledger struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+	lbtc string
+
+	trigger chan struct{}
+	// syncMu serializes syncs.
+	syncMu sync.Mutex
+
+	mu    sync.Mutex
+	state state
+	// reservations are the amounts reserved for sent payments until Sync
+	// posts them, by payment id.
+	reservations map[string]reservation
+}
+
+// New creates a Ledger and loads its persisted state.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Ledger, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("ledger: StatePath is required")
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = DefaultSyncInterval
+	}
+	l := &Ledger{
+		sdk:          sdk,
+		opts:         opts,
+		lbtc:         sdkutil.LbtcAssetId(opts.Network),
+		trigger:      make(chan struct{}, 1),
+		reservations: map[string]reservation{},
+	}
+	if err := filestore.Load(opts.StatePath, &l.state); err != nil {
+		return nil, err
+	}
+	if l.state.Accounts == nil {
+		l.state.Accounts = map[string]*Account{}
+	}
+	if l.state.Attributions == nil {
+		l.state.Attributions = map[string]Attribution{}
+	}
+	if l.state.Payments == nil {
+		l.state.Payments = map[string]*paymentRecord{}
+	}
+	for _, id := range []string{AccountExternal, AccountFees, AccountUnallocated} {
+		if _, ok := l.state.Accounts[id]; !ok {
+			l.state.Accounts[id] = &Account{Id: id, CreatedAt: time.Now().UTC()}
+		}
+	}
+	// Reservations do not survive restarts.
+	for _, a := range l.state.Accounts {
+		a.ReservedSat = 0
+	}
+	return l, nil
+}
+
+// Open creates a sub-account.
+func (l *Ledger) Open(id, name string) (Account, error) {
+	if id == "" || strings.HasPrefix(id, "@") {
+		return Account{}, fmt.Errorf("%w: %q", ErrInvalidAccount, id)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.state.Accounts[id]; ok {
+		return Account{}, ErrAccountExists
+	}
+	a := &Account{Id: id, Name: name, CreatedAt: time.Now().UTC()}
+	l.state.Accounts[id] = a
+	if err := l.save(); err != nil {
+		delete(l.state.Accounts, id)
+		return Account{}, err
+	}
+	return *a, nil
+}
+
+// Account returns an account.
+func (l *Ledger) Account(id string) (Account, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.state.Accounts[id]
+	if !ok {
+		return Account{}, ErrUnknownAccount
+	}
+	return *a, nil
+}
+
+// Accounts returns all accounts sorted by id, system accounts first.
+func (l *Ledger) Accounts() []Account {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.accounts()
+}
+
+func (l *Ledger) accounts() []Account {
+	accounts := make([]Account, 0, len(l.state.Accounts))
+	for _, a := range l.state.Accounts {
+		accounts = append(accounts, *a)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Id < accounts[j].Id })
+	return accounts
+}
+
+// Transactions returns the transactions posted to an account, or all
+// transactions for an empty account, oldest first.
+func (l *Ledger) Transactions(account string) []Transaction {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var txs []Transaction
+	for _, tx := range l.state.Transactions {
+		if account == "" || tx.touches(account) {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+func (tx Transaction) touches(account string) bool {
+	for _, p := range tx.Postings {
+		if p.Account == account {
+			return true
+		}
+	}
+	return false
+}
+
+// Transfer moves sats between two sub-accounts, or between @unallocated
+// and a sub-account.
+func (l *Ledger) Transfer(from, to string, amountSat uint64, memo string) (Transaction, error) {
+	if amountSat == 0 {
+		return Transaction{}, errors.New("ledger: amount is required")
+	}
+	if from == to || from == AccountExternal || from == AccountFees || to == AccountExternal || to == AccountFees {
+		return Transaction{}, ErrInvalidAccount
+	}
+	tx, err := l.transfer(from, to, amountSat, memo)
+	if err != nil {
+		return Transaction{}, err
+	}
+	l.notify([]Transaction{tx})
+	return tx, nil
+}
+
+func (l *Ledger) transfer(from, to string, amountSat uint64, memo string) (Transaction, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	src, ok := l.state.Accounts[from]
+	if !ok {
+		return Transaction{}, ErrUnknownAccount
+	}
+	if _, ok := l.state.Accounts[to]; !ok {
+		return Transaction{}, ErrUnknownAccount
+	}
+	if src.AvailableSat() < int64(amountSat) {
+		return Transaction{}, &InsufficientFundsError{Account: from, AvailableSat: src.AvailableSat(), RequiredSat: amountSat}
+	}
+	return l.post(KindTransfer, "", memo, []Posting{
+		{Account: from, AmountSat: -int64(amountSat)},
+		{Account: to, AmountSat: int64(amountSat)},
+	})
+}
+
+// Reconcile compares the total held by the ledger with the wallet balance,
+// after syncing the payments.
+func (l *Ledger) Reconcile() (Reconciliation, error) {
+	if err := l.Sync(); err != nil {
+		return Reconciliation{}, err
+	}
+	info, sdkErr := l.sdk.GetInfo()
+	if sdkErr != nil {
+		return Reconciliation{}, sdkErr
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := Reconciliation{WalletSat: info.WalletInfo.BalanceSat, Accounts: l.accounts()}
+	for _, a := range r.Accounts {
+		if a.Id != AccountExternal && a.Id != AccountFees {
+			r.HeldSat += a.BalanceSat
+		}
+	}
+	r.DiffSat = int64(r.WalletSat) - r.HeldSat
+	r.Balanced = r.DiffSat == 0
+	return r, nil
+}
+
+// Trigger requests a sync from Run.
+func (l *Ledger) Trigger() {
+	select {
+	case l.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run syncs the payments on payment events and every SyncInterval until
+// ctx is done.
+func (l *Ledger) Run(ctx context.Context) error {
+	listenerId, err := l.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if _, ok := sdkutil.EventPayment(e); ok {
+			l.Trigger()
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.sdk.RemoveEventListener(listenerId) }()
+
+	ticker := time.NewTicker(l.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		_ = l.Sync()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-l.trigger:
+		}
+	}
+}
+
+// post records a transaction. l.mu must be held.
+func (l *Ledger) post(kind, paymentId, memo string, postings []Posting) (Transaction, error) {
+	id, err := newId()
+	if err != nil {
+		return Transaction{}, err
+	}
+	tx := Transaction{Id: id, Time: time.Now().UTC(), Kind: kind, PaymentId: paymentId, Memo: memo, Postings: postings}
+	l.apply(tx)
+	if err := l.save(); err != nil {
+		l.revert(tx)
+		return Transaction{}, err
+	}
+	return tx, nil
+}
+
+func (l *Ledger) apply(tx Transaction) {
+	for _, p := range tx.Postings {
+		l.state.Accounts[p.Account].BalanceSat += p.AmountSat
+	}
+	l.state.Transactions = append(l.state.Transactions, tx)
+}
+
+func (l *Ledger) revert(tx Transaction) {
+	for _, p := range tx.Postings {
+		l.state.Accounts[p.Account].BalanceSat -= p.AmountSat
+	}
+	l.state.Transactions = l.state.Transactions[:len(l.state.Transactions)-1]
+}
+
+func (l *Ledger) save() error {
+	return filestore.Save(l.opts.StatePath, &l.state)
+}
+
+func (l *Ledger) notify(txs []Transaction) {
+	if l.opts.OnTransaction == nil {
+		return
+	}
+	for _, tx := range txs {
+		l.opts.OnTransaction(tx)
+	}
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
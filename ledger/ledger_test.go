@@ -0,0 +1,115 @@
+package ledger
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+func newLedger(t *testing.T, sdk *sdktest.Fake, accounts ...string) *Ledger {
+	t.Helper()
+	l, err := New(sdk, Options{StatePath: filepath.Join(t.TempDir(), "ledger.json")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range accounts {
+		if _, err := l.Open(id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return l
+}
+
+// receive records an incoming payment to a Liquid address.
+func receive(sdk *sdktest.Fake, txId, address string, amountSat uint64) {
+	sdk.Payments = append(sdk.Payments, breez_sdk_liquid.Payment{
+		TxId:        &txId,
+		AmountSat:   amountSat,
+		PaymentType: breez_sdk_liquid.PaymentTypeReceive,
+		Status:      breez_sdk_liquid.PaymentStateComplete,
+		Details:     breez_sdk_liquid.PaymentDetailsLiquid{Destination: address},
+	})
+}
+
+func balance(t *testing.T, l *Ledger, id string) int64 {
+	t.Helper()
+	a, err := l.Account(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.BalanceSat
+}
+
+func TestLedger(t *testing.T) {
+	sdk := &sdktest.Fake{FeesSat: 100}
+	l := newLedger(t, sdk, "alice", "bob")
+	if err := l.Attribute(Attribution{Account: "alice", Destination: "liquidnetwork:lqalice?amount=0.0001"}); err != nil {
+		t.Fatal(err)
+	}
+	receive(sdk, "rx1", "lqalice", 10000)
+	receive(sdk, "rx2", "lqother", 500)
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if balance(t, l, "alice") != 10000 || balance(t, l, AccountUnallocated) != 500 || balance(t, l, AccountExternal) != -10500 {
+		t.Fatalf("after receives: %+v", l.Accounts())
+	}
+
+	if _, err := l.Pay("alice", "lq1", 3000, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Transfer("alice", "bob", 1000, "lunch"); err != nil {
+		t.Fatal(err)
+	}
+	alice, _ := l.Account("alice")
+	if alice.BalanceSat != 5900 || alice.ReservedSat != 0 || balance(t, l, "bob") != 1000 || balance(t, l, AccountFees) != 100 {
+		t.Fatalf("after send and transfer: %+v", l.Accounts())
+	}
+	if txs := l.Transactions("bob"); len(txs) != 1 || txs[0].Kind != KindTransfer {
+		t.Fatalf("bob's transactions %+v", txs)
+	}
+
+	sdk.BalanceSat = 7400
+	if r, err := l.Reconcile(); err != nil || !r.Balanced || r.HeldSat != 7400 {
+		t.Fatalf("reconciliation %+v, %v", r, err)
+	}
+}
+
+func TestLedgerFailures(t *testing.T) {
+	sdk := &sdktest.Fake{}
+	l := newLedger(t, sdk, "alice")
+	if _, err := l.Open("@fees", ""); !errors.Is(err, ErrInvalidAccount) {
+		t.Fatalf("got %v, want ErrInvalidAccount", err)
+	}
+	if _, err := l.Open("alice", ""); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("got %v, want ErrAccountExists", err)
+	}
+	if _, err := l.Transfer("carol", "alice", 1, ""); !errors.Is(err, ErrUnknownAccount) {
+		t.Fatalf("got %v, want ErrUnknownAccount", err)
+	}
+
+	_, err := l.Pay("alice", "lq1", 1000, "")
+	var insufficient *InsufficientFundsError
+	if !errors.As(err, &insufficient) || insufficient.RequiredSat != 1000 || sdk.Sends != 0 {
+		t.Fatalf("got %v after %d sends", err, sdk.Sends)
+	}
+
+	// A failed send releases its reservation.
+	receive(sdk, "rx1", "lqalice", 5000)
+	if err := l.Assign("rx1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	sdk.SendErrs = []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorInsufficientFunds()}
+	if _, err := l.Pay("alice", "lq1", 1000, ""); !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorInsufficientFunds) {
+		t.Fatalf("got %v, want the send error", err)
+	}
+	if a, _ := l.Account("alice"); a.BalanceSat != 5000 || a.ReservedSat != 0 {
+		t.Fatalf("alice %+v", a)
+	}
+}
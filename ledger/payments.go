@@ -0,0 +1,383 @@
+package ledger
+
+import (
+	"strings"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/payflow"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Attribution ties a receive request to the sub-account it was created
+// for. Incoming payments are matched by destination first, then by payer
+// note, then by description. Destinations given as BIP21 URIs are matched
+// by their address.
+type Attribution struct {
+	Account     string    `json:"account"`
+	Destination string    `json:"destination"`
+	Description string    `json:"description,omitempty"`
+	PayerNote   string    `json:"payer_note,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// paymentRecord is the account of a payment and the amounts posted for
+// it so far.
+type paymentRecord struct {
+	Account   string `json:"account"`
+	Send      bool   `json:"send,omitempty"`
+	AmountSat int64  `json:"amount_sat"`
+	FeesSat   int64  `json:"fees_sat"`
+}
+
+// Receive creates a receive request for a sub-account.
+func (l *Ledger) Receive(account string, req breez_sdk_liquid.ReceivePaymentRequest) (breez_sdk_liquid.ReceivePaymentResponse, error) {
+	if _, err := l.Account(account); err != nil {
+		return breez_sdk_liquid.ReceivePaymentResponse{}, err
+	}
+	res, err := l.sdk.ReceivePayment(req)
+	if err != nil {
+		return res, err.AsError()
+	}
+	return res, l.Attribute(Attribution{
+		Account:     account,
+		Destination: res.Destination,
+		Description: sdkutil.StringValue(req.Description),
+		PayerNote:   sdkutil.StringValue(req.PayerNote),
+	})
+}
+
+// Attribute registers a receive request created without Receive.
+func (l *Ledger) Attribute(a Attribution) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.holds(a.Account) {
+		return ErrUnknownAccount
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	key := sdkutil.Address(a.Destination)
+	prev, existed := l.state.Attributions[key]
+	l.state.Attributions[key] = a
+	if err := l.save(); err != nil {
+		if existed {
+			l.state.Attributions[key] = prev
+		} else {
+			delete(l.state.Attributions, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Pay sends a payment debited from an account, which must cover the
+// amount and fees. A zero amountSat uses the amount set by the
+// destination. The amount stays reserved until the payment is posted.
+// Sends whose outcome is unknown when Pay fails are posted to @unallocated
+// once seen, and can be moved with Assign.
+func (l *Ledger) Pay(account, destination string, amountSat uint64, comment string) (breez_sdk_liquid.Payment, error) {
+	if _, err := l.Account(account); err != nil {
+		return breez_sdk_liquid.Payment{}, err
+	}
+	input, sdkErr := l.sdk.Parse(destination)
+	if sdkErr != nil {
+		return breez_sdk_liquid.Payment{}, sdkErr.AsError()
+	}
+	prepared, err := payflow.Prepare(l.sdk, destination, input, amountSat, comment)
+	if err != nil {
+		return breez_sdk_liquid.Payment{}, err
+	}
+	required := prepared.AmountSat + prepared.FeesSat
+	if err := l.reserve(account, required); err != nil {
+		return breez_sdk_liquid.Payment{}, err
+	}
+	payment, err := prepared.Execute(l.sdk)
+	id := sdkutil.PaymentId(payment)
+	if err != nil || id == "" {
+		l.release(account, required)
+		return payment, err
+	}
+	// Sync releases the reservation once it posts the payment, possibly
+	// after this call if it fails.
+	l.mu.Lock()
+	l.reservations[id] = reservation{account: account, amountSat: required}
+	l.mu.Unlock()
+	if err := l.Assign(id, account); err != nil {
+		return payment, err
+	}
+	return payment, l.Sync()
+}
+
+// reservation is the amount reserved for a payment not posted yet.
+type reservation struct {
+	account   string
+	amountSat uint64
+}
+
+func (l *Ledger) release(account string, amountSat uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked(account, amountSat)
+}
+
+func (l *Ledger) releaseLocked(account string, amountSat uint64) {
+	if a, ok := l.state.Accounts[account]; ok {
+		a.ReservedSat -= amountSat
+	}
+}
+
+func (l *Ledger) reserve(account string, amountSat uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.state.Accounts[account]
+	if !ok || !l.holds(account) {
+		return ErrUnknownAccount
+	}
+	if a.AvailableSat() < int64(amountSat) {
+		return &InsufficientFundsError{Account: account, AvailableSat: a.AvailableSat(), RequiredSat: amountSat}
+	}
+	a.ReservedSat += amountSat
+	return nil
+}
+
+// Assign attributes a payment to an account, moving what was already
+// posted for it from its previous account.
+func (l *Ledger) Assign(paymentId, account string) error {
+	l.mu.Lock()
+	if !l.holds(account) {
+		l.mu.Unlock()
+		return ErrUnknownAccount
+	}
+	rec, ok := l.state.Payments[paymentId]
+	if !ok {
+		l.state.Payments[paymentId] = &paymentRecord{Account: account}
+		err := l.save()
+		if err != nil {
+			delete(l.state.Payments, paymentId)
+		}
+		l.mu.Unlock()
+		return err
+	}
+	if rec.Account == account {
+		l.mu.Unlock()
+		return nil
+	}
+
+	// What the payment added to its account, negative for sends.
+	held, kind := rec.AmountSat, KindReceive
+	if rec.Send {
+		held, kind = -(rec.AmountSat + rec.FeesSat), KindSend
+	}
+	prev := rec.Account
+	rec.Account = account
+	if held == 0 {
+		err := l.save()
+		if err != nil {
+			rec.Account = prev
+		}
+		l.mu.Unlock()
+		return err
+	}
+	tx, err := l.post(kind, paymentId, "reassigned from "+prev, []Posting{
+		{Account: prev, AmountSat: -held},
+		{Account: account, AmountSat: held},
+	})
+	if err != nil {
+		rec.Account = prev
+	}
+	l.mu.Unlock()
+	if err == nil {
+		l.notify([]Transaction{tx})
+	}
+	return err
+}
+
+// Sync posts the payments not yet posted, and the changes of state of
+// those already posted: sends are reversed when they fail.
+func (l *Ledger) Sync() error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	payments, err := sdkutil.ListAllPayments(l.sdk, breez_sdk_liquid.ListPaymentsRequest{})
+	if err != nil {
+		return err
+	}
+
+	type undo struct {
+		id      string
+		rec     *paymentRecord
+		prev    paymentRecord
+		created bool
+	}
+	var (
+		txs   []Transaction
+		undos []undo
+	)
+	l.mu.Lock()
+	for _, p := range payments {
+		if sdkutil.AssetId(p, l.lbtc) != l.lbtc {
+			continue
+		}
+		id := sdkutil.PaymentId(p)
+		if id == "" {
+			continue
+		}
+		// The payment is posted below, or already was.
+		if r, ok := l.reservations[id]; ok {
+			l.releaseLocked(r.account, r.amountSat)
+			delete(l.reservations, id)
+		}
+		rec, ok := l.state.Payments[id]
+		if !ok {
+			rec = &paymentRecord{Account: AccountUnallocated}
+			if p.PaymentType == breez_sdk_liquid.PaymentTypeReceive {
+				rec.Account = l.attribute(p)
+			}
+		}
+		send := p.PaymentType == breez_sdk_liquid.PaymentTypeSend
+		amount, fees := effect(p)
+		if ok && amount == rec.AmountSat && fees == rec.FeesSat && send == rec.Send {
+			continue
+		}
+		undos = append(undos, undo{id: id, rec: rec, prev: *rec, created: !ok})
+		l.state.Payments[id] = rec
+		rec.Send = send
+		dAmount, dFees := amount-rec.AmountSat, fees-rec.FeesSat
+		rec.AmountSat, rec.FeesSat = amount, fees
+		if dAmount == 0 && dFees == 0 {
+			continue
+		}
+
+		txId, err := newId()
+		if err != nil {
+			l.mu.Unlock()
+			return err
+		}
+		tx := Transaction{
+			Id:        txId,
+			Time:      time.Now().UTC(),
+			PaymentId: id,
+			Memo:      sdkutil.PaymentStateName(p.Status),
+		}
+		if !send {
+			tx.Kind = KindReceive
+			tx.Postings = []Posting{
+				{Account: AccountExternal, AmountSat: -dAmount},
+				{Account: rec.Account, AmountSat: dAmount},
+			}
+		} else {
+			tx.Kind = KindSend
+			tx.Postings = []Posting{{Account: rec.Account, AmountSat: -(dAmount + dFees)}}
+			if dAmount != 0 {
+				tx.Postings = append(tx.Postings, Posting{Account: AccountExternal, AmountSat: dAmount})
+			}
+			if dFees != 0 {
+				tx.Postings = append(tx.Postings, Posting{Account: AccountFees, AmountSat: dFees})
+			}
+		}
+		l.apply(tx)
+		txs = append(txs, tx)
+	}
+	if len(undos) > 0 {
+		if err := l.save(); err != nil {
+			for i := len(txs) - 1; i >= 0; i-- {
+				l.revert(txs[i])
+			}
+			for _, u := range undos {
+				if u.created {
+					delete(l.state.Payments, u.id)
+				} else {
+					*u.rec = u.prev
+				}
+			}
+			l.mu.Unlock()
+			return err
+		}
+	}
+	l.mu.Unlock()
+	l.notify(txs)
+	return nil
+}
+
+// effect returns what a payment adds to the balance of its account and
+// what it pays in fees, the same way the SDK derives the wallet balance.
+func effect(p breez_sdk_liquid.Payment) (amountSat, feesSat int64) {
+	if p.PaymentType == breez_sdk_liquid.PaymentTypeReceive {
+		if p.Status == breez_sdk_liquid.PaymentStateComplete {
+			return int64(p.AmountSat), 0
+		}
+		return 0, 0
+	}
+	switch p.Status {
+	case breez_sdk_liquid.PaymentStateComplete, breez_sdk_liquid.PaymentStatePending, breez_sdk_liquid.PaymentStateRefundPending:
+		return int64(p.AmountSat), int64(p.FeesSat)
+	}
+	return 0, 0
+}
+
+// attribute returns the account of an incoming payment. l.mu must be held.
+func (l *Ledger) attribute(p breez_sdk_liquid.Payment) string {
+	var destinations []string
+	var description, payerNote string
+	switch d := p.Details.(type) {
+	case breez_sdk_liquid.PaymentDetailsLightning:
+		destinations = append(destinations, sdkutil.StringValue(d.Invoice), sdkutil.StringValue(d.Bolt12Offer))
+		description, payerNote = d.Description, sdkutil.StringValue(d.PayerNote)
+	case breez_sdk_liquid.PaymentDetailsLiquid:
+		destinations = append(destinations, d.Destination)
+		description, payerNote = d.Description, sdkutil.StringValue(d.PayerNote)
+	case breez_sdk_liquid.PaymentDetailsBitcoin:
+		destinations = append(destinations, d.BitcoinAddress)
+		description = d.Description
+	}
+	destinations = append(destinations, sdkutil.StringValue(p.Destination))
+	for _, dest := range destinations {
+		if a, ok := l.state.Attributions[sdkutil.Address(dest)]; ok && dest != "" {
+			return a.Account
+		}
+	}
+	// Bitcoin addresses may be uppercased in URIs.
+	if d, ok := p.Details.(breez_sdk_liquid.PaymentDetailsBitcoin); ok && d.BitcoinAddress != "" {
+		for key, a := range l.state.Attributions {
+			if strings.EqualFold(key, d.BitcoinAddress) {
+				return a.Account
+			}
+		}
+	}
+
+	// Fall back to the most recent request with the same payer note, then
+	// with the same description.
+	match := func(field func(Attribution) string, value string) (string, bool) {
+		if value == "" {
+			return "", false
+		}
+		var best *Attribution
+		for _, a := range l.state.Attributions {
+			a := a
+			if field(a) == value && (best == nil || a.CreatedAt.After(best.CreatedAt)) {
+				best = &a
+			}
+		}
+		if best == nil {
+			return "", false
+		}
+		return best.Account, true
+	}
+	if account, ok := match(func(a Attribution) string { return a.PayerNote }, payerNote); ok {
+		return account
+	}
+	if account, ok := match(func(a Attribution) string { return a.Description }, description); ok {
+		return account
+	}
+	return AccountUnallocated
+}
+
+// holds reports whether an account holds wallet funds: a sub-account or
+// @unallocated. l.mu must be held.
+func (l *Ledger) holds(account string) bool {
+	if account == AccountExternal || account == AccountFees {
+		return false
+	}
+	_, ok := l.state.Accounts[account]
+	return ok
+}
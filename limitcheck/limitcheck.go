@@ -0,0 +1,314 @@
+// Package limitcheck validates payment amounts against the Lightning and
+// on-chain swap limits before any Prepare* call is made, so that users get an
+// explanation of the allowed range without a round-trip to the swapper.
+package limitcheck
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// DefaultTTL is how long fetched limits are cached when Options.TTL is not
+// set.
+const DefaultTTL = 5 * time.Minute
+
+// Code identifies why an amount was rejected or flagged.
+type Code string
+
+const (
+	// CodeBelowMinimum is reported when the amount is below Limits.MinSat.
+	CodeBelowMinimum Code = "below_minimum"
+	// CodeAboveMaximum is reported when the amount is above Limits.MaxSat.
+	CodeAboveMaximum Code = "above_maximum"
+	// CodeZeroAmount is reported for a zero amount on a method without
+	// swap limits.
+	CodeZeroAmount Code = "zero_amount"
+	// CodeAboveZeroConf is reported when a receive is above
+	// Limits.MaxZeroConfSat and will only be credited after confirmation.
+	// It does not make the amount invalid.
+	CodeAboveZeroConf Code = "above_zero_conf"
+)
+
+// Problem describes a single finding about an amount.
+type Problem struct {
+	Code     Code   `json:"code"`
+	LimitSat uint64 `json:"limit_sat"`
+	Message  string `json:"message"`
+}
+
+// Result is the outcome of checking an amount.
+type Result struct {
+	Direction breez_sdk_liquid.PaymentType   `json:"-"`
+	Method    breez_sdk_liquid.PaymentMethod `json:"-"`
+	AmountSat uint64                         `json:"amount_sat"`
+	// Limits applied to the amount, nil for methods without swap limits.
+	Limits *breez_sdk_liquid.Limits `json:"limits,omitempty"`
+	// Valid is false if the amount would be rejected by the SDK.
+	Valid bool `json:"valid"`
+	// ZeroConf is true if a receive of this amount is credited without
+	// waiting for confirmation.
+	ZeroConf bool      `json:"zero_conf"`
+	Problems []Problem `json:"problems,omitempty"`
+}
+
+// Err returns an *OutOfRangeError if the amount is invalid, nil otherwise.
+func (r Result) Err() error {
+	if r.Valid {
+		return nil
+	}
+	return &OutOfRangeError{Result: r}
+}
+
+// OutOfRangeError is returned by Result.Err for invalid amounts. It matches
+// breez_sdk_liquid.ErrPaymentErrorAmountOutOfRange with errors.Is, like the
+// error the SDK would have returned.
+type OutOfRangeError struct {
+	Result Result
+}
+
+func (e *OutOfRangeError) Error() string {
+	for _, problem := range e.Result.Problems {
+		if problem.Code != CodeAboveZeroConf {
+			return problem.Message
+		}
+	}
+	return "amount out of range"
+}
+
+func (e *OutOfRangeError) Is(target error) bool {
+	return target == breez_sdk_liquid.ErrPaymentErrorAmountOutOfRange
+}
+
+// ErrUnknownMethod is returned when checking an amount for an unsupported
+// payment method.
+var ErrUnknownMethod = errors.New("unknown payment method")
+
+// Options configures a Validator.
+type Options struct {
+	// TTL of the cached limits. Defaults to DefaultTTL.
+	TTL time.Duration
+}
+
+// Validator checks amounts against cached swap limits.
+type Validator struct {
+	sdk breez_sdk_liquid.BindingLiquidSdkInterface
+	ttl time.Duration
+
+	mu          sync.Mutex
+	lightning   *breez_sdk_liquid.LightningPaymentLimitsResponse
+	lightningAt time.Time
+	onchain     *breez_sdk_liquid.OnchainPaymentLimitsResponse
+	onchainAt   time.Time
+}
+
+// New creates a Validator for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Validator {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	return &Validator{sdk: sdk, ttl: opts.TTL}
+}
+
+// Refresh fetches both Lightning and on-chain limits, replacing the cache.
+func (v *Validator) Refresh() error {
+	lightning, err := v.sdk.FetchLightningLimits()
+	if err != nil {
+		return err
+	}
+	onchain, err := v.sdk.FetchOnchainLimits()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lightning, v.lightningAt = &lightning, now
+	v.onchain, v.onchainAt = &onchain, now
+	return nil
+}
+
+// LightningLimits returns the cached Lightning limits, fetching them if
+// missing or expired.
+func (v *Validator) LightningLimits() (breez_sdk_liquid.LightningPaymentLimitsResponse, error) {
+	v.mu.Lock()
+	if v.lightning != nil && time.Since(v.lightningAt) < v.ttl {
+		defer v.mu.Unlock()
+		return *v.lightning, nil
+	}
+	v.mu.Unlock()
+
+	limits, err := v.sdk.FetchLightningLimits()
+	if err != nil {
+		return breez_sdk_liquid.LightningPaymentLimitsResponse{}, err
+	}
+	v.mu.Lock()
+	v.lightning, v.lightningAt = &limits, time.Now()
+	v.mu.Unlock()
+	return limits, nil
+}
+
+// OnchainLimits returns the cached on-chain limits, fetching them if
+// missing or expired.
+func (v *Validator) OnchainLimits() (breez_sdk_liquid.OnchainPaymentLimitsResponse, error) {
+	v.mu.Lock()
+	if v.onchain != nil && time.Since(v.onchainAt) < v.ttl {
+		defer v.mu.Unlock()
+		return *v.onchain, nil
+	}
+	v.mu.Unlock()
+
+	limits, err := v.sdk.FetchOnchainLimits()
+	if err != nil {
+		return breez_sdk_liquid.OnchainPaymentLimitsResponse{}, err
+	}
+	v.mu.Lock()
+	v.onchain, v.onchainAt = &limits, time.Now()
+	v.mu.Unlock()
+	return limits, nil
+}
+
+// Limits returns the limits that apply to the direction and method, or nil
+// if the method is not subject to swap limits.
+func (v *Validator) Limits(direction breez_sdk_liquid.PaymentType, method breez_sdk_liquid.PaymentMethod) (*breez_sdk_liquid.Limits, error) {
+	switch method {
+	case breez_sdk_liquid.PaymentMethodBolt11Invoice, breez_sdk_liquid.PaymentMethodBolt12Offer:
+		limits, err := v.LightningLimits()
+		if err != nil {
+			return nil, err
+		}
+		if direction == breez_sdk_liquid.PaymentTypeSend {
+			return &limits.Send, nil
+		}
+		return &limits.Receive, nil
+	case breez_sdk_liquid.PaymentMethodBitcoinAddress:
+		limits, err := v.OnchainLimits()
+		if err != nil {
+			return nil, err
+		}
+		if direction == breez_sdk_liquid.PaymentTypeSend {
+			return &limits.Send, nil
+		}
+		return &limits.Receive, nil
+	case breez_sdk_liquid.PaymentMethodLiquidAddress:
+		return nil, nil
+	default:
+		return nil, ErrUnknownMethod
+	}
+}
+
+// Check validates an amount to be sent or received with the given method.
+// The returned error is only set if the limits could not be fetched; use
+// Result.Valid or Result.Err to find out whether the amount is acceptable.
+func (v *Validator) Check(direction breez_sdk_liquid.PaymentType, method breez_sdk_liquid.PaymentMethod, amountSat uint64) (Result, error) {
+	limits, err := v.Limits(direction, method)
+	if err != nil {
+		return Result{}, err
+	}
+	return Evaluate(direction, method, amountSat, limits), nil
+}
+
+// CheckSend validates an amount to be sent to a parsed input.
+func (v *Validator) CheckSend(input breez_sdk_liquid.InputType, amountSat uint64) (Result, error) {
+	method, ok := MethodForInput(input)
+	if !ok {
+		return Result{}, ErrUnknownMethod
+	}
+	return v.Check(breez_sdk_liquid.PaymentTypeSend, method, amountSat)
+}
+
+// MethodForInput returns the payment method used to pay a parsed input.
+// LNURL-pay and Lightning addresses are paid with a Bolt11 invoice.
+func MethodForInput(input breez_sdk_liquid.InputType) (breez_sdk_liquid.PaymentMethod, bool) {
+	switch input.(type) {
+	case breez_sdk_liquid.InputTypeBolt11, breez_sdk_liquid.InputTypeLnUrlPay:
+		return breez_sdk_liquid.PaymentMethodBolt11Invoice, true
+	case breez_sdk_liquid.InputTypeBolt12Offer:
+		return breez_sdk_liquid.PaymentMethodBolt12Offer, true
+	case breez_sdk_liquid.InputTypeBitcoinAddress:
+		return breez_sdk_liquid.PaymentMethodBitcoinAddress, true
+	case breez_sdk_liquid.InputTypeLiquidAddress:
+		return breez_sdk_liquid.PaymentMethodLiquidAddress, true
+	default:
+		return 0, false
+	}
+}
+
+// Evaluate checks amountSat against limits. A nil limits only rejects a zero
+// amount.
+func Evaluate(direction breez_sdk_liquid.PaymentType, method breez_sdk_liquid.PaymentMethod, amountSat uint64, limits *breez_sdk_liquid.Limits) Result {
+	result := Result{
+		Direction: direction,
+		Method:    method,
+		AmountSat: amountSat,
+		Limits:    limits,
+		Valid:     true,
+		ZeroConf:  true,
+	}
+	verb := "send"
+	if direction == breez_sdk_liquid.PaymentTypeReceive {
+		verb = "receive"
+	}
+	via := sdkutil.PaymentMethodName(method)
+
+	if limits == nil {
+		if amountSat == 0 {
+			result.Valid = false
+			result.Problems = append(result.Problems, Problem{
+				Code:    CodeZeroAmount,
+				Message: fmt.Sprintf("amount must be greater than 0 sat to %s via %s", verb, via),
+			})
+		}
+		return result
+	}
+
+	if amountSat < limits.MinSat {
+		result.Valid = false
+		result.Problems = append(result.Problems, Problem{
+			Code:     CodeBelowMinimum,
+			LimitSat: limits.MinSat,
+			Message:  fmt.Sprintf("min %s sat to %s via %s, you entered %s", FormatSat(limits.MinSat), verb, via, FormatSat(amountSat)),
+		})
+	}
+	if amountSat > limits.MaxSat {
+		result.Valid = false
+		result.Problems = append(result.Problems, Problem{
+			Code:     CodeAboveMaximum,
+			LimitSat: limits.MaxSat,
+			Message:  fmt.Sprintf("max %s sat to %s via %s, you entered %s", FormatSat(limits.MaxSat), verb, via, FormatSat(amountSat)),
+		})
+	}
+	if direction == breez_sdk_liquid.PaymentTypeReceive && amountSat > limits.MaxZeroConfSat {
+		result.ZeroConf = false
+		result.Problems = append(result.Problems, Problem{
+			Code:     CodeAboveZeroConf,
+			LimitSat: limits.MaxZeroConfSat,
+			Message:  fmt.Sprintf("amounts above %s sat are only credited after confirmation", FormatSat(limits.MaxZeroConfSat)),
+		})
+	}
+	return result
+}
+
+// FormatSat formats an amount with thousands separators, e.g. 1,000.
+func FormatSat(sat uint64) string {
+	s := strconv.FormatUint(sat, 10)
+	if len(s) <= 3 {
+		return s
+	}
+	out := make([]byte, 0, len(s)+len(s)/3)
+	first := len(s) % 3
+	if first == 0 {
+		first = 3
+	}
+	out = append(out, s[:first]...)
+	for i := first; i < len(s); i += 3 {
+		out = append(out, ',')
+		out = append(out, s[i:i+3]...)
+	}
+	return string(out)
+}
@@ -0,0 +1,66 @@
+package limitcheck
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk returns the Lightning limits, or fails with fetchErr.
+func fakeSdk(fetches *int, fetchErr *breez_sdk_liquid.SdkError) breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		*fetches++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return breez_sdk_liquid.LightningPaymentLimitsResponse{
+			Send:    breez_sdk_liquid.Limits{MinSat: 1000, MaxSat: 25_000_000},
+			Receive: breez_sdk_liquid.Limits{MinSat: 100, MaxSat: 25_000_000, MaxZeroConfSat: 100_000},
+		}, nil
+	})
+}
+
+func TestCheck(t *testing.T) {
+	var fetches int
+	v := New(fakeSdk(&fetches, nil), Options{})
+
+	r, err := v.Check(breez_sdk_liquid.PaymentTypeSend, breez_sdk_liquid.PaymentMethodBolt11Invoice, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Valid || len(r.Problems) != 1 || r.Problems[0].Code != CodeBelowMinimum || r.Problems[0].LimitSat != 1000 {
+		t.Fatalf("result %+v", r)
+	}
+	if err := r.Err(); !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorAmountOutOfRange) ||
+		err.Error() != "min 1,000 sat to send via bolt11_invoice, you entered 500" {
+		t.Fatalf("error %v", err)
+	}
+
+	// Receives above the zero-conf limit are valid but flagged.
+	r, err = v.Check(breez_sdk_liquid.PaymentTypeReceive, breez_sdk_liquid.PaymentMethodBolt11Invoice, 200_000)
+	if err != nil || !r.Valid || r.ZeroConf || r.Err() != nil || r.Problems[0].Code != CodeAboveZeroConf {
+		t.Fatalf("result %+v, %v", r, err)
+	}
+	if fetches != 1 {
+		t.Fatalf("limits fetched %d times, want 1", fetches)
+	}
+
+	// Liquid payments have no swap limits.
+	r, err = v.Check(breez_sdk_liquid.PaymentTypeSend, breez_sdk_liquid.PaymentMethodLiquidAddress, 1)
+	if err != nil || !r.Valid || r.Limits != nil {
+		t.Fatalf("result %+v, %v", r, err)
+	}
+}
+
+func TestFetchFailure(t *testing.T) {
+	var fetches int
+	v := New(fakeSdk(&fetches, breez_sdk_liquid.NewSdkErrorServiceConnectivity()), Options{})
+	if _, err := v.Check(breez_sdk_liquid.PaymentTypeSend, breez_sdk_liquid.PaymentMethodBolt11Invoice, 5000); !errors.Is(err, breez_sdk_liquid.ErrSdkErrorServiceConnectivity) {
+		t.Fatalf("got %v, want the fetch error", err)
+	}
+	if _, err := v.CheckSend(breez_sdk_liquid.InputTypeUrl{}, 5000); !errors.Is(err, ErrUnknownMethod) {
+		t.Fatalf("got %v, want ErrUnknownMethod", err)
+	}
+}
@@ -0,0 +1,446 @@
+package lnbits
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+func (s *Server) routes() {
+	s.handle("/api/v1/wallet", map[string]endpoint{http.MethodGet: s.wallet})
+	s.handle("/api/v1/payments", map[string]endpoint{
+		http.MethodGet:  s.listPayments,
+		http.MethodPost: s.createPayment,
+	})
+	s.handle("/api/v1/payments/decode", map[string]endpoint{http.MethodPost: s.decode})
+	s.handle("/api/v1/payments/", map[string]endpoint{http.MethodGet: s.getPayment})
+}
+
+// Wallet is the response of GET /api/v1/wallet.
+type Wallet struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Balance is in msat.
+	Balance int64 `json:"balance"`
+}
+
+func (s *Server) wallet(r *http.Request, admin bool) (int, any, error) {
+	info, sdkErr := s.sdk.GetInfo()
+	if sdkErr != nil {
+		return 0, nil, sdkErr
+	}
+	return http.StatusOK, Wallet{Id: s.walletId(), Name: s.opts.WalletName, Balance: int64(info.WalletInfo.BalanceSat) * 1000}, nil
+}
+
+// walletId returns the reported wallet id, Options.WalletId or the wallet
+// fingerprint.
+func (s *Server) walletId() string {
+	if s.opts.WalletId != "" {
+		return s.opts.WalletId
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fingerprint == "" {
+		if info, sdkErr := s.sdk.GetInfo(); sdkErr == nil {
+			s.fingerprint = info.WalletInfo.Fingerprint
+		}
+	}
+	return s.fingerprint
+}
+
+// CreatePaymentRequest is the body of POST /api/v1/payments.
+type CreatePaymentRequest struct {
+	Out bool `json:"out"`
+
+	// Bolt11 is the invoice paid when Out is set.
+	Bolt11 string `json:"bolt11"`
+
+	// Amount is the invoice amount in Unit, sat by default.
+	Amount float64 `json:"amount"`
+	Unit   string  `json:"unit"`
+	Memo   string  `json:"memo"`
+	// DescriptionHash, if set, is committed to by the invoice instead of
+	// the memo. UnhashedDescription is a hex-encoded description whose
+	// hash is committed to instead.
+	DescriptionHash     string `json:"description_hash"`
+	UnhashedDescription string `json:"unhashed_description"`
+	// Expiry is accepted for compatibility; invoices use the swapper's
+	// expiry.
+	Expiry  int64  `json:"expiry"`
+	Webhook string `json:"webhook"`
+}
+
+// CreateInvoiceResponse is the response of POST /api/v1/payments creating
+// an invoice.
+type CreateInvoiceResponse struct {
+	PaymentHash    string `json:"payment_hash"`
+	PaymentRequest string `json:"payment_request"`
+	CheckingId     string `json:"checking_id"`
+	Bolt11         string `json:"bolt11"`
+}
+
+// PayInvoiceResponse is the response of POST /api/v1/payments paying an
+// invoice.
+type PayInvoiceResponse struct {
+	PaymentHash string `json:"payment_hash"`
+	CheckingId  string `json:"checking_id"`
+}
+
+func (s *Server) createPayment(r *http.Request, admin bool) (int, any, error) {
+	var req CreatePaymentRequest
+	if err := decodeBody(r.Body, &req); err != nil {
+		return 0, nil, err
+	}
+	if req.Out {
+		if !admin {
+			return 0, nil, &httpError{status: http.StatusUnauthorized, detail: "Invalid adminkey."}
+		}
+		res, err := s.payInvoice(req)
+		return http.StatusCreated, res, err
+	}
+	res, err := s.createInvoice(req)
+	return http.StatusCreated, res, err
+}
+
+func (s *Server) createInvoice(req CreatePaymentRequest) (CreateInvoiceResponse, error) {
+	if req.Webhook != "" {
+		if err := checkWebhook(req.Webhook, s.opts.AllowPrivateWebhooks); err != nil {
+			return CreateInvoiceResponse{}, err
+		}
+	}
+	amountSat, err := s.amountSat(req.Amount, req.Unit)
+	if err != nil {
+		return CreateInvoiceResponse{}, err
+	}
+	var amount breez_sdk_liquid.ReceiveAmount = breez_sdk_liquid.ReceiveAmountBitcoin{PayerAmountSat: amountSat}
+	prepared, payErr := s.sdk.PrepareReceivePayment(breez_sdk_liquid.PrepareReceiveRequest{
+		PaymentMethod: breez_sdk_liquid.PaymentMethodBolt11Invoice,
+		Amount:        &amount,
+	})
+	if payErr != nil {
+		return CreateInvoiceResponse{}, payErr
+	}
+
+	receiveReq := breez_sdk_liquid.ReceivePaymentRequest{PrepareResponse: prepared}
+	memo := req.Memo
+	switch {
+	case req.UnhashedDescription != "":
+		description, err := hex.DecodeString(req.UnhashedDescription)
+		if err != nil {
+			return CreateInvoiceResponse{}, &httpError{status: http.StatusBadRequest, detail: "unhashed_description must be hex"}
+		}
+		memo = string(description)
+		var hash breez_sdk_liquid.DescriptionHash = breez_sdk_liquid.DescriptionHashUseDescription{}
+		receiveReq.DescriptionHash = &hash
+	case req.DescriptionHash != "":
+		var hash breez_sdk_liquid.DescriptionHash = breez_sdk_liquid.DescriptionHashCustom{Hash: req.DescriptionHash}
+		receiveReq.DescriptionHash = &hash
+	}
+	if memo != "" {
+		receiveReq.Description = &memo
+	}
+	res, payErr := s.sdk.ReceivePayment(receiveReq)
+	if payErr != nil {
+		return CreateInvoiceResponse{}, payErr
+	}
+	decoded, payErr := breez_sdk_liquid.ParseInvoice(res.Destination)
+	if payErr != nil {
+		return CreateInvoiceResponse{}, payErr
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	s.invoices[decoded.PaymentHash] = &invoice{
+		bolt11:    res.Destination,
+		memo:      memo,
+		amountSat: amountSat,
+		createdAt: now,
+		expiresAt: time.Unix(int64(decoded.Timestamp+decoded.Expiry), 0),
+		webhook:   req.Webhook,
+	}
+	s.mu.Unlock()
+	return CreateInvoiceResponse{
+		PaymentHash:    decoded.PaymentHash,
+		PaymentRequest: res.Destination,
+		CheckingId:     decoded.PaymentHash,
+		Bolt11:         res.Destination,
+	}, nil
+}
+
+// amountSat converts an invoice amount to sat.
+func (s *Server) amountSat(amount float64, unit string) (uint64, error) {
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, &httpError{status: http.StatusBadRequest, detail: "amount must be positive"}
+	}
+	if unit == "" || strings.EqualFold(unit, "sat") {
+		if amount != math.Trunc(amount) {
+			return 0, &httpError{status: http.StatusBadRequest, detail: "amount in sat must be an integer"}
+		}
+		return uint64(amount), nil
+	}
+	rates, sdkErr := s.sdk.FetchFiatRates()
+	if sdkErr != nil {
+		return 0, sdkErr
+	}
+	for _, rate := range rates {
+		if strings.EqualFold(rate.Coin, unit) && rate.Value > 0 {
+			sats := uint64(math.Round(amount / rate.Value * 1e8))
+			if sats == 0 {
+				return 0, &httpError{status: http.StatusBadRequest, detail: "amount too small"}
+			}
+			return sats, nil
+		}
+	}
+	return 0, &httpError{status: http.StatusBadRequest, detail: fmt.Sprintf("unknown unit %q", unit)}
+}
+
+func (s *Server) payInvoice(req CreatePaymentRequest) (PayInvoiceResponse, error) {
+	if req.Bolt11 == "" {
+		return PayInvoiceResponse{}, &httpError{status: http.StatusBadRequest, detail: "bolt11 is required"}
+	}
+	decoded, payErr := breez_sdk_liquid.ParseInvoice(req.Bolt11)
+	if payErr != nil {
+		return PayInvoiceResponse{}, &httpError{status: http.StatusBadRequest, detail: "Invalid bolt11 invoice."}
+	}
+	if decoded.AmountMsat == nil {
+		return PayInvoiceResponse{}, &httpError{status: http.StatusBadRequest, detail: "Amountless invoices not supported."}
+	}
+	prepared, payErr := s.sdk.PrepareSendPayment(breez_sdk_liquid.PrepareSendRequest{Destination: req.Bolt11})
+	if payErr != nil {
+		return PayInvoiceResponse{}, payErr
+	}
+	if _, payErr := s.sdk.SendPayment(breez_sdk_liquid.SendPaymentRequest{PrepareResponse: prepared}); payErr != nil {
+		return PayInvoiceResponse{}, payErr
+	}
+	return PayInvoiceResponse{PaymentHash: decoded.PaymentHash, CheckingId: decoded.PaymentHash}, nil
+}
+
+// Payment is a payment as listed by LNbits. Amounts are in msat, negative
+// for outgoing payments.
+type Payment struct {
+	CheckingId  string         `json:"checking_id"`
+	PaymentHash string         `json:"payment_hash"`
+	WalletId    string         `json:"wallet_id"`
+	Amount      int64          `json:"amount"`
+	Fee         int64          `json:"fee"`
+	Memo        string         `json:"memo"`
+	Time        int64          `json:"time"`
+	Bolt11      string         `json:"bolt11"`
+	Preimage    string         `json:"preimage"`
+	Pending     bool           `json:"pending"`
+	Status      string         `json:"status"`
+	Expiry      *int64         `json:"expiry"`
+	Extra       map[string]any `json:"extra"`
+	Webhook     *string        `json:"webhook"`
+}
+
+// Payment statuses.
+const (
+	StatusSuccess = "success"
+	StatusPending = "pending"
+	StatusFailed  = "failed"
+)
+
+func paymentStatus(state breez_sdk_liquid.PaymentState) string {
+	switch state {
+	case breez_sdk_liquid.PaymentStateComplete:
+		return StatusSuccess
+	case breez_sdk_liquid.PaymentStateFailed, breez_sdk_liquid.PaymentStateTimedOut,
+		breez_sdk_liquid.PaymentStateRefundable, breez_sdk_liquid.PaymentStateRefundPending:
+		return StatusFailed
+	default:
+		return StatusPending
+	}
+}
+
+func paymentJSON(walletId string, p breez_sdk_liquid.Payment) Payment {
+	status := paymentStatus(p.Status)
+	res := Payment{
+		CheckingId:  sdkutil.PaymentHash(p),
+		PaymentHash: sdkutil.PaymentHash(p),
+		WalletId:    walletId,
+		Amount:      int64(p.AmountSat) * 1000,
+		Fee:         int64(p.FeesSat) * 1000,
+		Time:        int64(p.Timestamp),
+		Pending:     status == StatusPending,
+		Status:      status,
+		Extra: map[string]any{
+			"method": sdkutil.PaymentDetailsName(p.Details),
+			"state":  sdkutil.PaymentStateName(p.Status),
+		},
+	}
+	if res.CheckingId == "" {
+		res.CheckingId = sdkutil.PaymentId(p)
+	}
+	if p.PaymentType == breez_sdk_liquid.PaymentTypeSend {
+		res.Amount = -res.Amount
+	}
+	switch details := p.Details.(type) {
+	case breez_sdk_liquid.PaymentDetailsLightning:
+		res.Memo = details.Description
+		res.Bolt11 = sdkutil.StringValue(details.Invoice)
+		res.Preimage = sdkutil.StringValue(details.Preimage)
+	case breez_sdk_liquid.PaymentDetailsLiquid:
+		res.Memo = details.Description
+	case breez_sdk_liquid.PaymentDetailsBitcoin:
+		res.Memo = details.Description
+	}
+	return res
+}
+
+// invoicePayment returns the pending payment of an invoice created through
+// the API, not yet recorded by the SDK.
+func (s *Server) invoicePayment(hash string) (Payment, bool) {
+	s.mu.Lock()
+	inv, ok := s.invoices[hash]
+	s.mu.Unlock()
+	if !ok {
+		return Payment{}, false
+	}
+	expiry := inv.expiresAt.Unix()
+	status := StatusPending
+	if time.Now().After(inv.expiresAt) {
+		status = StatusFailed
+	}
+	return Payment{
+		CheckingId:  hash,
+		PaymentHash: hash,
+		WalletId:    s.walletId(),
+		Amount:      int64(inv.amountSat) * 1000,
+		Memo:        inv.memo,
+		Time:        inv.createdAt.Unix(),
+		Bolt11:      inv.bolt11,
+		Pending:     status == StatusPending,
+		Status:      status,
+		Expiry:      &expiry,
+		Extra:       map[string]any{},
+	}, true
+}
+
+// PaymentStatus is the response of GET /api/v1/payments/{hash}.
+type PaymentStatus struct {
+	Paid     bool    `json:"paid"`
+	Status   string  `json:"status"`
+	Preimage *string `json:"preimage"`
+	Details  Payment `json:"details"`
+}
+
+func (s *Server) getPayment(r *http.Request, admin bool) (int, any, error) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/payments/")
+	if id == "" || strings.Contains(id, "/") {
+		return 0, nil, &httpError{status: http.StatusNotFound, detail: "Not Found"}
+	}
+	payment, sdkErr := s.sdk.GetPayment(breez_sdk_liquid.GetPaymentRequestPaymentHash{PaymentHash: id})
+	if sdkErr != nil {
+		return 0, nil, sdkErr
+	}
+	if payment == nil {
+		payment, sdkErr = s.sdk.GetPayment(breez_sdk_liquid.GetPaymentRequestSwapId{SwapId: id})
+		if sdkErr != nil {
+			return 0, nil, sdkErr
+		}
+	}
+	if payment == nil {
+		p, ok := s.invoicePayment(id)
+		if !ok {
+			return 0, nil, &httpError{status: http.StatusNotFound, detail: "Payment does not exist."}
+		}
+		return http.StatusOK, PaymentStatus{Status: p.Status, Details: p}, nil
+	}
+	p := paymentJSON(s.walletId(), *payment)
+	res := PaymentStatus{Paid: p.Status == StatusSuccess, Status: p.Status, Details: p}
+	if p.Preimage != "" {
+		res.Preimage = &p.Preimage
+	}
+	return http.StatusOK, res, nil
+}
+
+func (s *Server) listPayments(r *http.Request, admin bool) (int, any, error) {
+	q := r.URL.Query()
+	var req breez_sdk_liquid.ListPaymentsRequest
+	for _, param := range []struct {
+		name string
+		dst  **uint32
+	}{{"limit", &req.Limit}, {"offset", &req.Offset}} {
+		v := q.Get(param.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return 0, nil, &httpError{status: http.StatusBadRequest, detail: param.name + " must be a non-negative integer"}
+		}
+		value := uint32(n)
+		*param.dst = &value
+	}
+	payments, sdkErr := s.sdk.ListPayments(req)
+	if sdkErr != nil {
+		return 0, nil, sdkErr
+	}
+	res := make([]Payment, 0, len(payments))
+	for _, p := range payments {
+		res = append(res, paymentJSON(s.walletId(), p))
+	}
+	return http.StatusOK, res, nil
+}
+
+// DecodeRequest is the body of POST /api/v1/payments/decode.
+type DecodeRequest struct {
+	Data string `json:"data"`
+}
+
+// DecodedInvoice is the response of POST /api/v1/payments/decode.
+type DecodedInvoice struct {
+	PaymentHash        string  `json:"payment_hash"`
+	AmountMsat         *uint64 `json:"amount_msat"`
+	Description        *string `json:"description"`
+	DescriptionHash    *string `json:"description_hash"`
+	Payee              string  `json:"payee"`
+	Date               uint64  `json:"date"`
+	Expiry             uint64  `json:"expiry"`
+	Secret             string  `json:"secret"`
+	RouteHints         []any   `json:"route_hints"`
+	MinFinalCltvExpiry uint64  `json:"min_final_cltv_expiry"`
+}
+
+func (s *Server) decode(r *http.Request, admin bool) (int, any, error) {
+	var req DecodeRequest
+	if err := decodeBody(r.Body, &req); err != nil {
+		return 0, nil, err
+	}
+	invoice, payErr := breez_sdk_liquid.ParseInvoice(strings.TrimPrefix(strings.ToLower(req.Data), "lightning:"))
+	if payErr != nil {
+		return 0, nil, &httpError{status: http.StatusBadRequest, detail: "Failed to decode"}
+	}
+	res := DecodedInvoice{
+		PaymentHash:        invoice.PaymentHash,
+		AmountMsat:         invoice.AmountMsat,
+		Description:        invoice.Description,
+		DescriptionHash:    invoice.DescriptionHash,
+		Payee:              invoice.PayeePubkey,
+		Date:               invoice.Timestamp,
+		Expiry:             invoice.Expiry,
+		Secret:             hex.EncodeToString(invoice.PaymentSecret),
+		RouteHints:         []any{},
+		MinFinalCltvExpiry: invoice.MinFinalCltvExpiryDelta,
+	}
+	return http.StatusOK, res, nil
+}
+
+// decodeBody decodes a JSON body, ignoring unknown members as LNbits
+// clients send fields of other versions.
+func decodeBody(body io.Reader, v any) error {
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		return &httpError{status: http.StatusBadRequest, detail: "invalid JSON body: " + err.Error()}
+	}
+	return nil
+}
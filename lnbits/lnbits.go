@@ -0,0 +1,290 @@
+// Package lnbits serves the core of the LNbits wallet API over the SDK, so
+// that LNbits clients such as point-of-sale apps can use a Liquid wallet
+// unmodified.
+//
+// The endpoints are:
+//
+//	GET  /api/v1/wallet                 wallet name and balance
+//	POST /api/v1/payments               {"out": false, ...} creates an invoice,
+//	                                    {"out": true, "bolt11": ...} pays one
+//	GET  /api/v1/payments               payment history, ?limit= and ?offset=
+//	GET  /api/v1/payments/{hash}        payment status
+//	POST /api/v1/payments/decode        decodes a BOLT11 invoice
+//
+// Clients authenticate with the X-Api-Key header or the api-key query
+// parameter. The invoice key allows everything but paying, which needs the
+// admin key. Amounts are in msat as in LNbits, except the amount of a new
+// invoice, in sat or in the fiat currency given as unit.
+//
+// Invoice webhooks are called while Run is running. They must be http or
+// https URLs of public hosts, unless Options.AllowPrivateWebhooks is set.
+// The invoices created, and their webhooks, are only remembered by the
+// process.
+package lnbits
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultWalletName     = "Breez Liquid"
+	DefaultMaxBodySize    = 64 << 10
+	DefaultWebhookTimeout = 10 * time.Second
+)
+
+// Options configures a Server.
+type Options struct {
+	// AdminKey and InvoiceKey are the API keys of the wallet. Required and
+	// distinct.
+	AdminKey   string
+	InvoiceKey string
+	// WalletId is reported as the wallet id. Defaults to the wallet
+	// fingerprint.
+	WalletId string
+	// WalletName is reported as the wallet name. Defaults to
+	// DefaultWalletName.
+	WalletName string
+	// MaxBodySize caps request bodies. Defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+	// WebhookTimeout bounds a webhook call. Defaults to
+	// DefaultWebhookTimeout.
+	WebhookTimeout time.Duration
+	// AllowPrivateWebhooks allows webhooks on loopback, link-local and
+	// private addresses, for services deployed next to the server.
+	AllowPrivateWebhooks bool
+	// Client sends the webhooks. Defaults to a client connecting only to
+	// public addresses, or to http.DefaultClient if AllowPrivateWebhooks
+	// is set.
+	Client *http.Client
+}
+
+// Server is an http.Handler serving the LNbits endpoints under /api/v1/.
+type Server struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+	mux  *http.ServeMux
+
+	mu          sync.Mutex
+	invoices    map[string]*invoice
+	fingerprint string
+}
+
+// invoice is an invoice created through the API, remembered for the status
+// checks made before the SDK records its payment and for its webhook.
+type invoice struct {
+	bolt11    string
+	memo      string
+	amountSat uint64
+	createdAt time.Time
+	expiresAt time.Time
+	webhook   string
+}
+
+// New creates a Server.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Server, error) {
+	if opts.AdminKey == "" || opts.InvoiceKey == "" {
+		return nil, errors.New("lnbits: AdminKey and InvoiceKey are required")
+	}
+	if opts.AdminKey == opts.InvoiceKey {
+		return nil, errors.New("lnbits: AdminKey and InvoiceKey must differ")
+	}
+	if opts.WalletName == "" {
+		opts.WalletName = DefaultWalletName
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.WebhookTimeout <= 0 {
+		opts.WebhookTimeout = DefaultWebhookTimeout
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+		if !opts.AllowPrivateWebhooks {
+			opts.Client = webhookClient()
+		}
+	}
+	s := &Server{sdk: sdk, opts: opts, mux: http.NewServeMux(), invoices: map[string]*invoice{}}
+	s.routes()
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Key kinds.
+const (
+	keyNone = iota
+	keyInvoice
+	keyAdmin
+)
+
+// key returns the kind of key sent with r.
+func (s *Server) key(r *http.Request) int {
+	key := r.Header.Get("X-Api-Key")
+	if key == "" {
+		key = r.URL.Query().Get("api-key")
+	}
+	switch {
+	case key == "":
+		return keyNone
+	case subtle.ConstantTimeCompare([]byte(key), []byte(s.opts.AdminKey)) == 1:
+		return keyAdmin
+	case subtle.ConstantTimeCompare([]byte(key), []byte(s.opts.InvoiceKey)) == 1:
+		return keyInvoice
+	default:
+		return keyNone
+	}
+}
+
+// endpoint handles an authenticated request, returning the status and the
+// value to encode as the response body.
+type endpoint func(r *http.Request, admin bool) (int, any, error)
+
+// handle registers endpoints by method on a path.
+func (s *Server) handle(path string, endpoints map[string]endpoint) {
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		fn, ok := endpoints[r.Method]
+		if !ok {
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+			return
+		}
+		key := s.key(r)
+		if key == keyNone {
+			writeError(w, http.StatusUnauthorized, "Invalid key")
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodySize)
+		status, res, err := fn(r, key == keyAdmin)
+		if err != nil {
+			status, detail := errorResponse(err)
+			writeError(w, status, detail)
+			return
+		}
+		writeJSON(w, status, res)
+	})
+}
+
+// httpError is an error reported with its status.
+type httpError struct {
+	status int
+	detail string
+}
+
+func (e *httpError) Error() string {
+	return e.detail
+}
+
+// errorResponse returns the status and detail reporting err.
+func errorResponse(err error) (int, string) {
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		return httpErr.status, httpErr.detail
+	}
+	switch variant := sdkjson.EncodeError(err).Variant; {
+	case variant == "insufficient_funds", variant == "amount_out_of_range", variant == "amount_missing",
+		variant == "already_paid", variant == "invoice_expired", variant == "network_not_supported",
+		strings.HasPrefix(variant, "invalid_"):
+		return http.StatusBadRequest, err.Error()
+	default:
+		// LNbits reports failures of the funding source with 520.
+		return 520, err.Error()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
+
+// Run calls the webhooks of the invoices paid until ctx is done, and
+// forgets expired invoices.
+func (s *Server) Run(ctx context.Context) error {
+	paid := make(chan breez_sdk_liquid.Payment, 64)
+	listenerId, sdkErr := s.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if _, ok := e.(breez_sdk_liquid.SdkEventPaymentSucceeded); !ok {
+			return
+		}
+		payment, _ := sdkutil.EventPayment(e)
+		if payment.PaymentType != breez_sdk_liquid.PaymentTypeReceive {
+			return
+		}
+		select {
+		case paid <- payment:
+		case <-ctx.Done():
+		}
+	}))
+	if sdkErr != nil {
+		return sdkErr
+	}
+	defer func() { _ = s.sdk.RemoveEventListener(listenerId) }()
+
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-prune.C:
+			s.prune()
+		case payment := <-paid:
+			hash := sdkutil.PaymentHash(payment)
+			s.mu.Lock()
+			inv := s.invoices[hash]
+			delete(s.invoices, hash)
+			s.mu.Unlock()
+			if inv != nil && inv.webhook != "" {
+				go s.callWebhook(ctx, inv.webhook, paymentJSON(s.walletId(), payment))
+			}
+		}
+	}
+}
+
+// prune forgets the invoices expired for a day.
+func (s *Server) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, inv := range s.invoices {
+		if time.Since(inv.expiresAt) > 24*time.Hour {
+			delete(s.invoices, hash)
+		}
+	}
+}
+
+// callWebhook posts a paid invoice to its webhook, as LNbits does, once.
+func (s *Server) callWebhook(ctx context.Context, url string, p Payment) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.WebhookTimeout)
+	defer cancel()
+	body, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := s.opts.Client.Do(req)
+	if err != nil {
+		return
+	}
+	res.Body.Close()
+}
@@ -0,0 +1,102 @@
+package lnbits
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+func newServer(t *testing.T, opts Options) (*Server, *[]string) {
+	t.Helper()
+	var calls []string
+	sdk := intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		calls = append(calls, call.Method)
+		if call.Method == "GetInfo" {
+			return breez_sdk_liquid.GetInfoResponse{WalletInfo: breez_sdk_liquid.WalletInfo{BalanceSat: 1500, Fingerprint: "fp"}}, nil
+		}
+		return nil, nil
+	})
+	opts.AdminKey, opts.InvoiceKey = "admin", "invoice"
+	s, err := New(sdk, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, &calls
+}
+
+func serve(s *Server, method, path, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set("X-Api-Key", key)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestWallet(t *testing.T) {
+	s, _ := newServer(t, Options{})
+	w := serve(s, http.MethodGet, "/api/v1/wallet", "invoice", "")
+	var wallet Wallet
+	if err := json.Unmarshal(w.Body.Bytes(), &wallet); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if wallet.Id != "fp" || wallet.Balance != 1_500_000 {
+		t.Fatalf("wallet %+v", wallet)
+	}
+
+	if w := serve(s, http.MethodGet, "/api/v1/wallet", "other", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown key: status %d", w.Code)
+	}
+	// Paying needs the admin key.
+	if w := serve(s, http.MethodPost, "/api/v1/payments", "invoice", `{"out":true,"bolt11":"lnbc1"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("pay with the invoice key: status %d", w.Code)
+	}
+}
+
+func TestWebhookRejected(t *testing.T) {
+	s, calls := newServer(t, Options{})
+	for _, webhook := range []string{
+		"ftp://example.com/hook",
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest",
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+	} {
+		body := `{"out":false,"amount":1000,"webhook":"` + webhook + `"}`
+		if w := serve(s, http.MethodPost, "/api/v1/payments", "invoice", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d: %s", webhook, w.Code, w.Body)
+		}
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("SDK called: %v", *calls)
+	}
+
+	for _, webhook := range []string{"https://example.com/hook", "http://203.0.113.7:8080/hook"} {
+		if err := checkWebhook(webhook, false); err != nil {
+			t.Fatalf("%s: %v", webhook, err)
+		}
+	}
+	if err := checkWebhook("http://127.0.0.1/hook", true); err != nil {
+		t.Fatalf("private webhook allowed: %v", err)
+	}
+}
+
+func TestWebhookClientPublicOnly(t *testing.T) {
+	var called bool
+	hook := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	defer hook.Close()
+
+	_, err := webhookClient().Post(hook.URL, "application/json", strings.NewReader("{}"))
+	if !errors.Is(err, errPrivateAddress) || called {
+		t.Fatalf("got %v, called %v", err, called)
+	}
+}
@@ -0,0 +1,64 @@
+package lnbits
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is returned when a webhook resolves to an address which
+// is not public.
+var errPrivateAddress = errors.New("lnbits: webhook address is not public")
+
+// checkWebhook validates the webhook of an invoice: an http or https URL
+// whose host is not a loopback, link-local or private address, unless
+// private addresses are allowed.
+func checkWebhook(raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Hostname() == "" {
+		return &httpError{status: http.StatusBadRequest, detail: "webhook must be an http or https URL"}
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &httpError{status: http.StatusBadRequest, detail: "webhook host must be public"}
+	}
+	if ip := net.ParseIP(host); ip != nil && !publicIP(ip) {
+		return &httpError{status: http.StatusBadRequest, detail: "webhook host must be public"}
+	}
+	return nil
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// webhookClient returns a client which only connects to public addresses,
+// so that a webhook host resolving, or redirecting, to a private address is
+// not called. Proxies are not used, as they would connect instead.
+func webhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Transport: transport}
+}
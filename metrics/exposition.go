@@ -0,0 +1,220 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// label is a name/value pair attached to a sample.
+type label struct {
+	name  string
+	value string
+}
+
+// sample is a single line of the exposition format.
+type sample struct {
+	suffix string
+	labels []label
+	value  float64
+}
+
+// family is a metric with its samples, written as one block.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+func (f *family) add(value float64, labels ...label) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// writeFamilies writes the families in the Prometheus text exposition
+// format, version 0.0.4.
+func writeFamilies(w io.Writer, families []*family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		bw.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+		bw.WriteString("# TYPE " + f.name + " " + f.typ + "\n")
+		for _, s := range f.samples {
+			bw.WriteString(f.name + s.suffix)
+			if len(s.labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range s.labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(l.name + `="` + escapeLabel(l.value) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+	return bw.Flush()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// counterVec is a set of counters keyed by the values of its labels.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+	keys   map[string][]string
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: map[string]float64{},
+		keys:   map[string][]string{},
+	}
+}
+
+func (c *counterVec) add(delta float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.keys[key]; !ok {
+		c.keys[key] = append([]string(nil), values...)
+	}
+	c.values[key] += delta
+}
+
+func (c *counterVec) inc(values ...string) {
+	c.add(1, values...)
+}
+
+func (c *counterVec) family() *family {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := &family{name: c.name, help: c.help, typ: "counter"}
+	for _, key := range sortedKeys(c.keys) {
+		f.add(c.values[key], labelPairs(c.labels, c.keys[key])...)
+	}
+	return f
+}
+
+// histogramVec is a set of histograms keyed by the values of its labels.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogram
+	keys   map[string][]string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &histogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		series:  map[string]*histogram{},
+		keys:    map[string][]string{},
+	}
+}
+
+func (h *histogramVec) observe(v float64, values ...string) {
+	key := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+		h.keys[key] = append([]string(nil), values...)
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogramVec) family() *family {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f := &family{name: h.name, help: h.help, typ: "histogram"}
+	for _, key := range sortedKeys(h.keys) {
+		s := h.series[key]
+		labels := labelPairs(h.labels, h.keys[key])
+		for i, upper := range h.buckets {
+			le := append(append([]label(nil), labels...), label{"le", formatValue(upper)})
+			f.samples = append(f.samples, sample{suffix: "_bucket", labels: le, value: float64(s.counts[i])})
+		}
+		inf := append(append([]label(nil), labels...), label{"le", "+Inf"})
+		f.samples = append(f.samples,
+			sample{suffix: "_bucket", labels: inf, value: float64(s.count)},
+			sample{suffix: "_sum", labels: labels, value: s.sum},
+			sample{suffix: "_count", labels: labels, value: float64(s.count)},
+		)
+	}
+	return f
+}
+
+func labelPairs(names, values []string) []label {
+	labels := make([]label, len(names))
+	for i, name := range names {
+		labels[i] = label{name, values[i]}
+	}
+	return labels
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
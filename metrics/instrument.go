@@ -0,0 +1,304 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// Interceptor returns an interceptor recording the latency and errors of
+// every call going through the chain, for SDKs wrapped with other
+// interceptors, where Instrument would add a layer of its own.
+func (c *Collector) Interceptor() intercept.Interceptor {
+	return func(call *intercept.Call, next intercept.Handler) (any, error) {
+		start := time.Now()
+		res, err := next(call)
+		c.ObserveCall(call.Method, time.Since(start), err)
+		return res, err
+	}
+}
+
+// Instrument wraps sdk so that the latency and errors of every method call
+// are recorded by c.
+func Instrument(sdk breez_sdk_liquid.BindingLiquidSdkInterface, c *Collector) breez_sdk_liquid.BindingLiquidSdkInterface {
+	return &instrumented{sdk: sdk, c: c}
+}
+
+type instrumented struct {
+	sdk breez_sdk_liquid.BindingLiquidSdkInterface
+	c   *Collector
+}
+
+func (s *instrumented) AcceptPaymentProposedFees(req breez_sdk_liquid.AcceptPaymentProposedFeesRequest) *breez_sdk_liquid.PaymentError {
+	start := time.Now()
+	err := s.sdk.AcceptPaymentProposedFees(req)
+	s.c.ObserveCall("AcceptPaymentProposedFees", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) AddEventListener(listener breez_sdk_liquid.EventListener) (string, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.AddEventListener(listener)
+	s.c.ObserveCall("AddEventListener", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) Backup(req breez_sdk_liquid.BackupRequest) *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.Backup(req)
+	s.c.ObserveCall("Backup", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) BuyBitcoin(req breez_sdk_liquid.BuyBitcoinRequest) (string, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.BuyBitcoin(req)
+	s.c.ObserveCall("BuyBitcoin", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) CheckMessage(req breez_sdk_liquid.CheckMessageRequest) (breez_sdk_liquid.CheckMessageResponse, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.CheckMessage(req)
+	s.c.ObserveCall("CheckMessage", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) CreateBolt12Invoice(req breez_sdk_liquid.CreateBolt12InvoiceRequest) (breez_sdk_liquid.CreateBolt12InvoiceResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.CreateBolt12Invoice(req)
+	s.c.ObserveCall("CreateBolt12Invoice", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) Disconnect() *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.Disconnect()
+	s.c.ObserveCall("Disconnect", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) FetchFiatRates() ([]breez_sdk_liquid.Rate, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.FetchFiatRates()
+	s.c.ObserveCall("FetchFiatRates", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) FetchLightningLimits() (breez_sdk_liquid.LightningPaymentLimitsResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.FetchLightningLimits()
+	s.c.ObserveCall("FetchLightningLimits", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) FetchOnchainLimits() (breez_sdk_liquid.OnchainPaymentLimitsResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.FetchOnchainLimits()
+	s.c.ObserveCall("FetchOnchainLimits", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) FetchPaymentProposedFees(req breez_sdk_liquid.FetchPaymentProposedFeesRequest) (breez_sdk_liquid.FetchPaymentProposedFeesResponse, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.FetchPaymentProposedFees(req)
+	s.c.ObserveCall("FetchPaymentProposedFees", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) GetInfo() (breez_sdk_liquid.GetInfoResponse, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.GetInfo()
+	s.c.ObserveCall("GetInfo", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) GetPayment(req breez_sdk_liquid.GetPaymentRequest) (*breez_sdk_liquid.Payment, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.GetPayment(req)
+	s.c.ObserveCall("GetPayment", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) ListFiatCurrencies() ([]breez_sdk_liquid.FiatCurrency, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.ListFiatCurrencies()
+	s.c.ObserveCall("ListFiatCurrencies", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) ListPayments(req breez_sdk_liquid.ListPaymentsRequest) ([]breez_sdk_liquid.Payment, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.ListPayments(req)
+	s.c.ObserveCall("ListPayments", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) ListRefundables() ([]breez_sdk_liquid.RefundableSwap, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.ListRefundables()
+	s.c.ObserveCall("ListRefundables", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) LnurlAuth(reqData breez_sdk_liquid.LnUrlAuthRequestData) (breez_sdk_liquid.LnUrlCallbackStatus, *breez_sdk_liquid.LnUrlAuthError) {
+	start := time.Now()
+	res, err := s.sdk.LnurlAuth(reqData)
+	s.c.ObserveCall("LnurlAuth", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) LnurlPay(req breez_sdk_liquid.LnUrlPayRequest) (breez_sdk_liquid.LnUrlPayResult, *breez_sdk_liquid.LnUrlPayError) {
+	start := time.Now()
+	res, err := s.sdk.LnurlPay(req)
+	s.c.ObserveCall("LnurlPay", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) LnurlWithdraw(req breez_sdk_liquid.LnUrlWithdrawRequest) (breez_sdk_liquid.LnUrlWithdrawResult, *breez_sdk_liquid.LnUrlWithdrawError) {
+	start := time.Now()
+	res, err := s.sdk.LnurlWithdraw(req)
+	s.c.ObserveCall("LnurlWithdraw", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) Parse(input string) (breez_sdk_liquid.InputType, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.Parse(input)
+	s.c.ObserveCall("Parse", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PayOnchain(req breez_sdk_liquid.PayOnchainRequest) (breez_sdk_liquid.SendPaymentResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.PayOnchain(req)
+	s.c.ObserveCall("PayOnchain", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PrepareBuyBitcoin(req breez_sdk_liquid.PrepareBuyBitcoinRequest) (breez_sdk_liquid.PrepareBuyBitcoinResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.PrepareBuyBitcoin(req)
+	s.c.ObserveCall("PrepareBuyBitcoin", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PrepareLnurlPay(req breez_sdk_liquid.PrepareLnUrlPayRequest) (breez_sdk_liquid.PrepareLnUrlPayResponse, *breez_sdk_liquid.LnUrlPayError) {
+	start := time.Now()
+	res, err := s.sdk.PrepareLnurlPay(req)
+	s.c.ObserveCall("PrepareLnurlPay", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PreparePayOnchain(req breez_sdk_liquid.PreparePayOnchainRequest) (breez_sdk_liquid.PreparePayOnchainResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.PreparePayOnchain(req)
+	s.c.ObserveCall("PreparePayOnchain", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PrepareReceivePayment(req breez_sdk_liquid.PrepareReceiveRequest) (breez_sdk_liquid.PrepareReceiveResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.PrepareReceivePayment(req)
+	s.c.ObserveCall("PrepareReceivePayment", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PrepareRefund(req breez_sdk_liquid.PrepareRefundRequest) (breez_sdk_liquid.PrepareRefundResponse, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.PrepareRefund(req)
+	s.c.ObserveCall("PrepareRefund", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) PrepareSendPayment(req breez_sdk_liquid.PrepareSendRequest) (breez_sdk_liquid.PrepareSendResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.PrepareSendPayment(req)
+	s.c.ObserveCall("PrepareSendPayment", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) ReceivePayment(req breez_sdk_liquid.ReceivePaymentRequest) (breez_sdk_liquid.ReceivePaymentResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.ReceivePayment(req)
+	s.c.ObserveCall("ReceivePayment", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) RecommendedFees() (breez_sdk_liquid.RecommendedFees, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.RecommendedFees()
+	s.c.ObserveCall("RecommendedFees", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) Refund(req breez_sdk_liquid.RefundRequest) (breez_sdk_liquid.RefundResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.Refund(req)
+	s.c.ObserveCall("Refund", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) RegisterWebhook(webhookUrl string) *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.RegisterWebhook(webhookUrl)
+	s.c.ObserveCall("RegisterWebhook", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) RemoveEventListener(id string) *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.RemoveEventListener(id)
+	s.c.ObserveCall("RemoveEventListener", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) RescanOnchainSwaps() *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.RescanOnchainSwaps()
+	s.c.ObserveCall("RescanOnchainSwaps", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) Restore(req breez_sdk_liquid.RestoreRequest) *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.Restore(req)
+	s.c.ObserveCall("Restore", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) SendPayment(req breez_sdk_liquid.SendPaymentRequest) (breez_sdk_liquid.SendPaymentResponse, *breez_sdk_liquid.PaymentError) {
+	start := time.Now()
+	res, err := s.sdk.SendPayment(req)
+	s.c.ObserveCall("SendPayment", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) SignMessage(req breez_sdk_liquid.SignMessageRequest) (breez_sdk_liquid.SignMessageResponse, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.SignMessage(req)
+	s.c.ObserveCall("SignMessage", time.Since(start), err.AsError())
+	return res, err
+}
+
+func (s *instrumented) Sync() *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.Sync()
+	s.c.ObserveCall("Sync", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) UnregisterWebhook() *breez_sdk_liquid.SdkError {
+	start := time.Now()
+	err := s.sdk.UnregisterWebhook()
+	s.c.ObserveCall("UnregisterWebhook", time.Since(start), err.AsError())
+	return err
+}
+
+func (s *instrumented) UseNwcPlugin(config breez_sdk_liquid.NwcConfig) (*breez_sdk_liquid.BindingNwcService, *breez_sdk_liquid.SdkError) {
+	start := time.Now()
+	res, err := s.sdk.UseNwcPlugin(config)
+	s.c.ObserveCall("UseNwcPlugin", time.Since(start), err.AsError())
+	return res, err
+}
@@ -0,0 +1,324 @@
+// Package metrics exports wallet and SDK activity in the Prometheus text
+// exposition format without depending on a Prometheus client library.
+//
+// Wallet balances, blockchain tips and payment counts are read from the SDK
+// when the metrics are scraped. Events are counted by a listener registered
+// with Start, NWC activity by AttachNwc, and method latencies and errors by
+// wrapping the SDK with Instrument.
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// DefaultPaymentsTTL is how long the payment counts are cached when
+// Options.PaymentsTTL is not set.
+const DefaultPaymentsTTL = time.Minute
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the method
+// latency histogram buckets.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Options configures a Collector.
+type Options struct {
+	// Namespace prefixes every metric name. Defaults to "breez_liquid".
+	Namespace string
+	// PaymentsTTL is how long the payment counts are cached between
+	// scrapes. Defaults to DefaultPaymentsTTL.
+	PaymentsTTL time.Duration
+	// LatencyBuckets of the method latency histogram, in seconds.
+	// Defaults to DefaultLatencyBuckets.
+	LatencyBuckets []float64
+}
+
+type paymentKey struct {
+	paymentType string
+	state       string
+	method      string
+}
+
+type paymentTotals struct {
+	count     int
+	amountSat uint64
+	feesSat   uint64
+}
+
+// Collector gathers the metrics of an SDK instance.
+type Collector struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	events       *counterVec
+	nwcEvents    *counterVec
+	nwcPayments  *counterVec
+	nwcFees      *counterVec
+	calls        *counterVec
+	callErrors   *counterVec
+	callDuration *histogramVec
+
+	mu            sync.Mutex
+	listenerId    string
+	nwc           breez_sdk_liquid.BindingNwcServiceInterface
+	nwcListenerId string
+	payments      map[paymentKey]paymentTotals
+	paymentsAt    time.Time
+}
+
+// New creates a Collector for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "breez_liquid"
+	}
+	if opts.PaymentsTTL <= 0 {
+		opts.PaymentsTTL = DefaultPaymentsTTL
+	}
+	if len(opts.LatencyBuckets) == 0 {
+		opts.LatencyBuckets = DefaultLatencyBuckets
+	}
+	ns := opts.Namespace + "_"
+	return &Collector{
+		sdk:  sdk,
+		opts: opts,
+		events: newCounterVec(ns+"events_total",
+			"SDK events received, by event variant.", "event"),
+		nwcEvents: newCounterVec(ns+"nwc_events_total",
+			"NWC events received, by event variant.", "event"),
+		nwcPayments: newCounterVec(ns+"nwc_payments_total",
+			"Invoices paid through NWC, by result.", "result"),
+		nwcFees: newCounterVec(ns+"nwc_fees_sat_total",
+			"Fees of invoices paid through NWC, in sats."),
+		calls: newCounterVec(ns+"method_calls_total",
+			"SDK method calls, by method.", "method"),
+		callErrors: newCounterVec(ns+"method_errors_total",
+			"SDK method calls that returned an error, by method and error variant.", "method", "error"),
+		callDuration: newHistogramVec(ns+"method_duration_seconds",
+			"Latency of SDK method calls, by method.", opts.LatencyBuckets, "method"),
+	}
+}
+
+// Start registers the SDK event listener.
+func (c *Collector) Start() error {
+	listenerId, err := c.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		c.events.inc(sdkutil.EventName(e))
+		if _, ok := sdkutil.EventPayment(e); ok {
+			c.invalidatePayments()
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.listenerId = listenerId
+	c.mu.Unlock()
+	return nil
+}
+
+// AttachNwc registers an NWC event listener on the service, and reports its
+// connections when scraped.
+func (c *Collector) AttachNwc(nwc breez_sdk_liquid.BindingNwcServiceInterface) {
+	listenerId := nwc.AddEventListener(sdkutil.NwcEventListenerFunc(func(e breez_sdk_liquid.NwcEvent) {
+		c.nwcEvents.inc(sdkutil.NwcEventName(e))
+		if details, ok := e.Details.(breez_sdk_liquid.NwcEventDetailsPayInvoice); ok {
+			result := "success"
+			if !details.Success {
+				result = "failure"
+			}
+			c.nwcPayments.inc(result)
+			c.nwcFees.add(float64(sdkutil.Uint64Value(details.FeesSat)))
+		}
+	}))
+	c.mu.Lock()
+	c.nwc, c.nwcListenerId = nwc, listenerId
+	c.mu.Unlock()
+}
+
+// Stop removes the event listeners.
+func (c *Collector) Stop() {
+	c.mu.Lock()
+	listenerId, nwc, nwcListenerId := c.listenerId, c.nwc, c.nwcListenerId
+	c.listenerId, c.nwc, c.nwcListenerId = "", nil, ""
+	c.mu.Unlock()
+	if listenerId != "" {
+		_ = c.sdk.RemoveEventListener(listenerId)
+	}
+	if nwc != nil {
+		nwc.RemoveEventListener(nwcListenerId)
+	}
+}
+
+// ObserveCall records a call to an SDK method.
+func (c *Collector) ObserveCall(method string, duration time.Duration, err error) {
+	c.calls.inc(method)
+	c.callDuration.observe(duration.Seconds(), method)
+	if err != nil {
+		c.callErrors.inc(method, sdkutil.ErrorVariant(err))
+	}
+}
+
+// Handler serves the metrics.
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := c.Write(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(buf.Bytes())
+	})
+}
+
+// Write gathers the metrics and writes them to w.
+func (c *Collector) Write(w io.Writer) error {
+	return writeFamilies(w, c.gather())
+}
+
+func (c *Collector) gather() []*family {
+	ns := c.opts.Namespace + "_"
+	scrapeErrors := &family{name: ns + "scrape_error", typ: "gauge",
+		help: "Whether reading a source failed during the last scrape."}
+	var families []*family
+
+	info, infoErr := c.sdk.GetInfo()
+	scrapeErrors.add(boolValue(infoErr != nil), label{"source", "get_info"})
+	if infoErr == nil {
+		wallet := info.WalletInfo
+		balance := &family{name: ns + "wallet_balance_sat", typ: "gauge",
+			help: "Usable L-BTC balance, in sats."}
+		balance.add(float64(wallet.BalanceSat))
+		pendingSend := &family{name: ns + "wallet_pending_send_sat", typ: "gauge",
+			help: "Amount used by ongoing sends, in sats."}
+		pendingSend.add(float64(wallet.PendingSendSat))
+		pendingReceive := &family{name: ns + "wallet_pending_receive_sat", typ: "gauge",
+			help: "Amount of ongoing receives, in sats."}
+		pendingReceive.add(float64(wallet.PendingReceiveSat))
+		assets := &family{name: ns + "wallet_asset_balance_sat", typ: "gauge",
+			help: "Balance of each asset, in the asset's base units."}
+		for _, asset := range wallet.AssetBalances {
+			assets.add(float64(asset.BalanceSat),
+				label{"asset_id", asset.AssetId},
+				label{"ticker", sdkutil.StringValue(asset.Ticker)})
+		}
+		tips := &family{name: ns + "blockchain_tip_height", typ: "gauge",
+			help: "Height of the blockchain tip, by chain."}
+		tips.add(float64(info.BlockchainInfo.LiquidTip), label{"chain", "liquid"})
+		tips.add(float64(info.BlockchainInfo.BitcoinTip), label{"chain", "bitcoin"})
+		families = append(families, balance, pendingSend, pendingReceive, assets, tips)
+	}
+
+	payments, paymentsErr := c.paymentTotals()
+	scrapeErrors.add(boolValue(paymentsErr != nil), label{"source", "list_payments"})
+	if paymentsErr == nil {
+		count := &family{name: ns + "payments", typ: "gauge",
+			help: "Payments, by type, state and method."}
+		amount := &family{name: ns + "payments_amount_sat", typ: "gauge",
+			help: "Total amount of payments, by type, state and method, in sats."}
+		fees := &family{name: ns + "payments_fees_sat", typ: "gauge",
+			help: "Total fees of payments, by type, state and method, in sats."}
+		keys := make([]paymentKey, 0, len(payments))
+		for key := range payments {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := keys[i], keys[j]
+			return strings.Join([]string{a.paymentType, a.state, a.method}, " ") <
+				strings.Join([]string{b.paymentType, b.state, b.method}, " ")
+		})
+		for _, key := range keys {
+			totals := payments[key]
+			labels := []label{{"type", key.paymentType}, {"state", key.state}, {"method", key.method}}
+			count.add(float64(totals.count), labels...)
+			amount.add(float64(totals.amountSat), labels...)
+			fees.add(float64(totals.feesSat), labels...)
+		}
+		families = append(families, count, amount, fees)
+	}
+
+	c.mu.Lock()
+	nwc := c.nwc
+	c.mu.Unlock()
+	if nwc != nil {
+		connections, nwcErr := nwc.ListConnections()
+		scrapeErrors.add(boolValue(nwcErr != nil), label{"source", "nwc_list_connections"})
+		if nwcErr == nil {
+			conns := &family{name: ns + "nwc_connections", typ: "gauge",
+				help: "Configured NWC connections."}
+			conns.add(float64(len(connections)))
+			paid := &family{name: ns + "nwc_connection_paid_sat", typ: "gauge",
+				help: "Amount paid through each NWC connection, in sats."}
+			names := make([]string, 0, len(connections))
+			for name := range connections {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				paid.add(float64(connections[name].PaidAmountSat), label{"connection", name})
+			}
+			families = append(families, conns, paid)
+		}
+	}
+
+	return append(families,
+		c.events.family(),
+		c.nwcEvents.family(),
+		c.nwcPayments.family(),
+		c.nwcFees.family(),
+		c.calls.family(),
+		c.callErrors.family(),
+		c.callDuration.family(),
+		scrapeErrors,
+	)
+}
+
+func (c *Collector) invalidatePayments() {
+	c.mu.Lock()
+	c.payments = nil
+	c.mu.Unlock()
+}
+
+func (c *Collector) paymentTotals() (map[paymentKey]paymentTotals, error) {
+	c.mu.Lock()
+	if c.payments != nil && time.Since(c.paymentsAt) < c.opts.PaymentsTTL {
+		defer c.mu.Unlock()
+		return c.payments, nil
+	}
+	c.mu.Unlock()
+
+	payments, err := sdkutil.ListAllPayments(c.sdk, breez_sdk_liquid.ListPaymentsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	totals := map[paymentKey]paymentTotals{}
+	for _, p := range payments {
+		key := paymentKey{
+			paymentType: sdkutil.PaymentTypeName(p.PaymentType),
+			state:       sdkutil.PaymentStateName(p.Status),
+			method:      sdkutil.PaymentDetailsName(p.Details),
+		}
+		t := totals[key]
+		t.count++
+		t.amountSat += p.AmountSat
+		t.feesSat += p.FeesSat
+		totals[key] = t
+	}
+	c.mu.Lock()
+	c.payments, c.paymentsAt = totals, time.Now()
+	c.mu.Unlock()
+	return totals, nil
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+func scrape(t *testing.T, c *Collector) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	return w.Body.String()
+}
+
+func assertLines(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, "\n"+line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestScrape(t *testing.T) {
+	fake := &sdktest.Fake{BalanceSat: 1000, FeesSat: 10}
+	fake.Pay("lq1", 500)
+	c := New(fake, Options{})
+	sdk := Instrument(fake, c)
+	if _, err := sdk.GetInfo(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertLines(t, scrape(t, c),
+		"breez_liquid_wallet_balance_sat 1000",
+		`breez_liquid_payments{type="send",state="complete",method="liquid"} 1`,
+		`breez_liquid_payments_fees_sat{type="send",state="complete",method="liquid"} 10`,
+		`breez_liquid_method_calls_total{method="GetInfo"} 1`,
+		`breez_liquid_scrape_error{source="get_info"} 0`,
+		`breez_liquid_scrape_error{source="list_payments"} 0`,
+	)
+}
+
+func TestScrapeErrors(t *testing.T) {
+	var c *Collector
+	sdk := intercept.WrapLiquidSdk(&sdktest.Fake{BalanceSat: 1000}, func(call *intercept.Call, next intercept.Handler) (any, error) {
+		return c.Interceptor()(call, next)
+	}, func(call *intercept.Call, next intercept.Handler) (any, error) {
+		if call.Method == "ListPayments" {
+			return nil, breez_sdk_liquid.NewPaymentErrorPersistError()
+		}
+		return next(call)
+	})
+	c = New(sdk, Options{Namespace: "test"})
+
+	body := scrape(t, c)
+	assertLines(t, body,
+		"test_wallet_balance_sat 1000",
+		`test_scrape_error{source="list_payments"} 1`,
+		`test_method_calls_total{method="ListPayments"} 1`,
+	)
+	if strings.Contains(body, "test_payments{") || !strings.Contains(body, `test_method_errors_total{method="ListPayments",error=`) {
+		t.Fatalf("scraped:\n%s", body)
+	}
+}
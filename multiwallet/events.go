@@ -0,0 +1,67 @@
+package multiwallet
+
+import "sync"
+
+// Subscription receives the events of all wallets.
+type Subscription struct {
+	m *Manager
+	c chan WalletEvent
+
+	mu      sync.Mutex
+	dropped uint64
+}
+
+// Subscribe returns a subscription buffering up to buffer events. Events
+// are dropped while the buffer is full.
+func (m *Manager) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{m: m, c: make(chan WalletEvent, buffer)}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		close(sub.c)
+		return sub
+	}
+	m.subs[sub] = struct{}{}
+	return sub
+}
+
+// C returns the channel of events, closed when the subscription or the
+// Manager is closed.
+func (s *Subscription) C() <-chan WalletEvent {
+	return s.c
+}
+
+// Dropped returns the number of events dropped because the buffer was full.
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.subs[s]; ok {
+		delete(s.m.subs, s)
+		close(s.c)
+	}
+}
+
+// dispatch sends an event to OnEvent and the subscriptions.
+func (m *Manager) dispatch(e WalletEvent) {
+	if m.opts.OnEvent != nil {
+		m.opts.OnEvent(e)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sub := range m.subs {
+		select {
+		case sub.c <- e:
+		default:
+			sub.mu.Lock()
+			sub.dropped++
+			sub.mu.Unlock()
+		}
+	}
+}
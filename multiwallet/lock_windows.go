@@ -0,0 +1,35 @@
+//go:build windows
+
+package multiwallet
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION, missing from syscall.
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens path without sharing it, so that no other process can
+// open it until the returned file is closed or the process exits.
+func lockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0, // no sharing
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0)
+	if err != nil {
+		if errors.Is(err, errorSharingViolation) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
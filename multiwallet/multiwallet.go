@@ -0,0 +1,483 @@
+// Package multiwallet manages many SDK instances in one process, one per
+// wallet id, each with its own working directory.
+//
+// Wallets are connected lazily on first use and disconnected when idle, or
+// when more than MaxOpen wallets are connected, least recently used first.
+// Each working directory is locked while its wallet is connected, so that
+// two processes never open the same wallet. The events of all wallets are
+// routed to a single stream, tagged with the wallet id.
+package multiwallet
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultMaxOpen     = 32
+	DefaultIdleTimeout = 15 * time.Minute
+)
+
+// LockFileName is the file locked in each wallet's working directory.
+const LockFileName = ".multiwallet.lock"
+
+var (
+	// ErrLocked is returned when a wallet's working directory is in use
+	// by another process.
+	ErrLocked = errors.New("multiwallet: working directory in use by another process")
+	// ErrClosed is returned once the Manager is closed.
+	ErrClosed = errors.New("multiwallet: manager closed")
+	// ErrInvalidId is returned for wallet ids which cannot be used as a
+	// directory name.
+	ErrInvalidId = errors.New("multiwallet: invalid wallet id")
+)
+
+// Credentials are the secrets a wallet is connected with. Either Mnemonic
+// or Seed must be set.
+type Credentials struct {
+	Mnemonic   string
+	Passphrase string
+	Seed       []byte
+}
+
+// ConnectFunc connects an SDK instance.
+type ConnectFunc func(req breez_sdk_liquid.ConnectRequest) (breez_sdk_liquid.BindingLiquidSdkInterface, error)
+
+// Options configures a Manager.
+type Options struct {
+	// Config is the configuration of every wallet. Its WorkingDir is the
+	// root under which each wallet gets the directory named after its id.
+	// Required.
+	Config breez_sdk_liquid.Config
+	// Credentials returns the secrets of a wallet. Required.
+	Credentials func(walletId string) (Credentials, error)
+	// MaxOpen is the number of wallets kept connected. Wallets in use are
+	// never disconnected, so the limit may be exceeded temporarily.
+	// Defaults to DefaultMaxOpen.
+	MaxOpen int
+	// IdleTimeout is how long an unused wallet stays connected. Defaults
+	// to DefaultIdleTimeout.
+	IdleTimeout time.Duration
+	// Connect defaults to breez_sdk_liquid.Connect.
+	Connect ConnectFunc
+	// OnEvent, if set, is called with the events of all wallets.
+	OnEvent func(WalletEvent)
+	// OnDisconnect, if set, is called when a wallet is disconnected, with
+	// the error of the disconnection, if any.
+	OnDisconnect func(walletId string, err error)
+}
+
+// WalletEvent is an event of one of the wallets.
+type WalletEvent struct {
+	WalletId string
+	Event    breez_sdk_liquid.SdkEvent
+}
+
+// WalletInfo describes a connected wallet.
+type WalletInfo struct {
+	WalletId    string    `json:"wallet_id"`
+	WorkingDir  string    `json:"working_dir"`
+	ConnectedAt time.Time `json:"connected_at"`
+	LastUsed    time.Time `json:"last_used"`
+	// InUse is the number of unreleased Acquire calls.
+	InUse int `json:"in_use"`
+}
+
+// entry is a wallet connected, being connected or being disconnected.
+type entry struct {
+	id  string
+	dir string
+
+	// ready is closed once connecting is done, sdk or err being set.
+	ready chan struct{}
+	sdk   breez_sdk_liquid.BindingLiquidSdkInterface
+	err   error
+	// closed is set when disconnecting starts, and closed when done.
+	closed chan struct{}
+
+	lock        *os.File
+	listenerId  string
+	connectedAt time.Time
+	lastUsed    time.Time
+	refs        int
+	elem        *list.Element
+}
+
+// Manager is a pool of wallets keyed by wallet id.
+type Manager struct {
+	opts Options
+
+	mu      sync.Mutex
+	wallets map[string]*entry
+	// lru holds the connected wallets, most recently used first.
+	lru    *list.List
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// New creates a Manager.
+func New(opts Options) (*Manager, error) {
+	if opts.Config.WorkingDir == "" {
+		return nil, errors.New("multiwallet: Config.WorkingDir is required")
+	}
+	if opts.Credentials == nil {
+		return nil, errors.New("multiwallet: Credentials is required")
+	}
+	if opts.MaxOpen <= 0 {
+		opts.MaxOpen = DefaultMaxOpen
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	if opts.Connect == nil {
+		opts.Connect = connect
+	}
+	return &Manager{
+		opts:    opts,
+		wallets: map[string]*entry{},
+		lru:     list.New(),
+		subs:    map[*Subscription]struct{}{},
+	}, nil
+}
+
+func connect(req breez_sdk_liquid.ConnectRequest) (breez_sdk_liquid.BindingLiquidSdkInterface, error) {
+	sdk, err := breez_sdk_liquid.Connect(req)
+	if err != nil {
+		return nil, err
+	}
+	return sdk, nil
+}
+
+// WorkingDir returns the working directory of a wallet.
+func (m *Manager) WorkingDir(walletId string) string {
+	return filepath.Join(m.opts.Config.WorkingDir, walletId)
+}
+
+// Wallet is a wallet acquired from the Manager. It is not disconnected
+// until released.
+type Wallet struct {
+	Id  string
+	Sdk breez_sdk_liquid.BindingLiquidSdkInterface
+
+	m    *Manager
+	e    *entry
+	once sync.Once
+}
+
+// Release allows the wallet to be disconnected again. It may be called
+// more than once.
+func (w *Wallet) Release() {
+	w.once.Do(func() { w.m.release(w.e) })
+}
+
+// Acquire returns a wallet, connecting it if needed. The wallet must be
+// released once no longer used.
+func (m *Manager) Acquire(walletId string) (*Wallet, error) {
+	if err := validId(walletId); err != nil {
+		return nil, err
+	}
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return nil, ErrClosed
+		}
+		e, ok := m.wallets[walletId]
+		if ok && e.closed != nil {
+			// Wait for the previous connection to be released.
+			closed := e.closed
+			m.mu.Unlock()
+			<-closed
+			continue
+		}
+		if !ok {
+			e = &entry{id: walletId, dir: m.WorkingDir(walletId), ready: make(chan struct{})}
+			m.wallets[walletId] = e
+			e.refs++
+			m.mu.Unlock()
+			m.open(e)
+		} else {
+			e.refs++
+			m.mu.Unlock()
+			<-e.ready
+		}
+		if e.err != nil {
+			return nil, e.err
+		}
+		m.evict()
+		return &Wallet{Id: walletId, Sdk: e.sdk, m: m, e: e}, nil
+	}
+}
+
+// Use calls fn with the SDK of a wallet, connecting it if needed.
+func (m *Manager) Use(walletId string, fn func(sdk breez_sdk_liquid.BindingLiquidSdkInterface) error) error {
+	w, err := m.Acquire(walletId)
+	if err != nil {
+		return err
+	}
+	defer w.Release()
+	return fn(w.Sdk)
+}
+
+// open connects a new entry, removing it on failure.
+func (m *Manager) open(e *entry) {
+	err := m.connect(e)
+
+	m.mu.Lock()
+	if err != nil {
+		e.err = err
+		delete(m.wallets, e.id)
+	} else {
+		e.connectedAt = time.Now()
+		e.lastUsed = e.connectedAt
+		e.elem = m.lru.PushFront(e)
+	}
+	m.mu.Unlock()
+	close(e.ready)
+}
+
+func (m *Manager) connect(e *entry) error {
+	creds, err := m.opts.Credentials(e.id)
+	if err != nil {
+		return fmt.Errorf("multiwallet: credentials of %s: %w", e.id, err)
+	}
+	if creds.Mnemonic == "" && len(creds.Seed) == 0 {
+		return fmt.Errorf("multiwallet: no mnemonic or seed for %s", e.id)
+	}
+	if err := os.MkdirAll(e.dir, 0o700); err != nil {
+		return err
+	}
+	lock, err := lockFile(filepath.Join(e.dir, LockFileName))
+	if err != nil {
+		return err
+	}
+
+	config := m.opts.Config
+	config.WorkingDir = e.dir
+	req := breez_sdk_liquid.ConnectRequest{Config: config}
+	if creds.Mnemonic != "" {
+		req.Mnemonic = &creds.Mnemonic
+	} else {
+		req.Seed = &creds.Seed
+	}
+	if creds.Passphrase != "" {
+		req.Passphrase = &creds.Passphrase
+	}
+	sdk, err := m.opts.Connect(req)
+	if err != nil {
+		lock.Close()
+		return err
+	}
+
+	walletId := e.id
+	listenerId, sdkErr := sdk.AddEventListener(sdkutil.EventListenerFunc(func(ev breez_sdk_liquid.SdkEvent) {
+		m.dispatch(WalletEvent{WalletId: walletId, Event: ev})
+	}))
+	if sdkErr != nil {
+		_ = sdk.Disconnect()
+		lock.Close()
+		return sdkErr
+	}
+	e.sdk, e.lock, e.listenerId = sdk, lock, listenerId
+	return nil
+}
+
+func (m *Manager) release(e *entry) {
+	m.mu.Lock()
+	e.refs--
+	e.lastUsed = time.Now()
+	if e.elem != nil {
+		m.lru.MoveToFront(e.elem)
+	}
+	m.mu.Unlock()
+	m.evict()
+}
+
+// evict disconnects the least recently used idle wallets above MaxOpen.
+func (m *Manager) evict() {
+	m.mu.Lock()
+	var victims []*entry
+	excess := m.lru.Len() - m.opts.MaxOpen
+	for el := m.lru.Back(); el != nil && excess > 0; {
+		e := el.Value.(*entry)
+		el = el.Prev()
+		if e.refs == 0 {
+			m.detach(e)
+			victims = append(victims, e)
+			excess--
+		}
+	}
+	m.mu.Unlock()
+	m.close(victims)
+}
+
+// Run disconnects idle wallets until ctx is done.
+func (m *Manager) Run(ctx context.Context) error {
+	interval := m.opts.IdleTimeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.reap()
+		}
+	}
+}
+
+// reap disconnects the wallets unused for IdleTimeout.
+func (m *Manager) reap() {
+	m.mu.Lock()
+	var victims []*entry
+	for el := m.lru.Back(); el != nil; {
+		e := el.Value.(*entry)
+		el = el.Prev()
+		if e.refs == 0 && time.Since(e.lastUsed) >= m.opts.IdleTimeout {
+			m.detach(e)
+			victims = append(victims, e)
+		}
+	}
+	m.mu.Unlock()
+	m.close(victims)
+}
+
+// Disconnect disconnects a wallet, waiting for it to be released if in
+// use. It does nothing if the wallet is not connected.
+func (m *Manager) Disconnect(walletId string) {
+	for {
+		m.mu.Lock()
+		e, ok := m.wallets[walletId]
+		if !ok {
+			m.mu.Unlock()
+			return
+		}
+		if e.closed != nil {
+			closed := e.closed
+			m.mu.Unlock()
+			<-closed
+			return
+		}
+		if e.elem == nil || e.refs > 0 {
+			// Connecting or in use.
+			m.mu.Unlock()
+			time.Sleep(50 * time.Millisecond)
+			continue
+		}
+		m.detach(e)
+		m.mu.Unlock()
+		m.close([]*entry{e})
+		return
+	}
+}
+
+// Close disconnects all wallets, waiting for those in use to be released.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	ids := make([]string, 0, len(m.wallets))
+	for id := range m.wallets {
+		ids = append(ids, id)
+	}
+	for sub := range m.subs {
+		delete(m.subs, sub)
+		close(sub.c)
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			m.Disconnect(id)
+		}(id)
+	}
+	wg.Wait()
+}
+
+// Wallets returns the connected wallets, most recently used first.
+func (m *Manager) Wallets() []WalletInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var infos []WalletInfo
+	for el := m.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*entry)
+		infos = append(infos, WalletInfo{
+			WalletId:    e.id,
+			WorkingDir:  e.dir,
+			ConnectedAt: e.connectedAt,
+			LastUsed:    e.lastUsed,
+			InUse:       e.refs,
+		})
+	}
+	return infos
+}
+
+// Known returns the ids of the wallets with a working directory, connected
+// or not.
+func (m *Manager) Known() ([]string, error) {
+	entries, err := os.ReadDir(m.opts.Config.WorkingDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, de := range entries {
+		if de.IsDir() && validId(de.Name()) == nil {
+			ids = append(ids, de.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// detach marks a connected wallet as disconnecting. m.mu must be held.
+func (m *Manager) detach(e *entry) {
+	e.closed = make(chan struct{})
+	m.lru.Remove(e.elem)
+	e.elem = nil
+}
+
+// close disconnects detached wallets.
+func (m *Manager) close(entries []*entry) {
+	for _, e := range entries {
+		_ = e.sdk.RemoveEventListener(e.listenerId)
+		err := e.sdk.Disconnect().AsError()
+		e.lock.Close()
+
+		m.mu.Lock()
+		delete(m.wallets, e.id)
+		m.mu.Unlock()
+		close(e.closed)
+
+		if m.opts.OnDisconnect != nil {
+			m.opts.OnDisconnect(e.id, err)
+		}
+	}
+}
+
+func validId(walletId string) error {
+	if walletId == "" || walletId == "." || walletId == ".." ||
+		strings.ContainsAny(walletId, `/\:`) || strings.HasPrefix(walletId, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidId, walletId)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package multiwallet
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeConnect connects SDKs keeping their event listener by working
+// directory.
+type fakeConnect struct {
+	mu        sync.Mutex
+	listeners map[string]breez_sdk_liquid.EventListener
+	connects  int
+}
+
+func (f *fakeConnect) connect(req breez_sdk_liquid.ConnectRequest) (breez_sdk_liquid.BindingLiquidSdkInterface, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.connects++
+	dir := req.Config.WorkingDir
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		if call.Method == "AddEventListener" {
+			f.mu.Lock()
+			f.listeners[dir] = call.Request.(breez_sdk_liquid.EventListener)
+			f.mu.Unlock()
+			return "listener", nil
+		}
+		return nil, nil
+	}), nil
+}
+
+func newManager(t *testing.T, f *fakeConnect, opts Options) *Manager {
+	t.Helper()
+	f.listeners = map[string]breez_sdk_liquid.EventListener{}
+	opts.Config.WorkingDir = t.TempDir()
+	opts.Connect = f.connect
+	if opts.Credentials == nil {
+		opts.Credentials = func(string) (Credentials, error) { return Credentials{Mnemonic: "abandon"}, nil }
+	}
+	m, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestAcquireEvict(t *testing.T) {
+	f := &fakeConnect{}
+	var disconnected []string
+	m := newManager(t, f, Options{MaxOpen: 1, OnDisconnect: func(walletId string, err error) {
+		disconnected = append(disconnected, walletId)
+	}})
+	sub := m.Subscribe(1)
+
+	a, err := m.Acquire("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Connected once while in use.
+	if err := m.Use("a", func(breez_sdk_liquid.BindingLiquidSdkInterface) error { return nil }); err != nil || f.connects != 1 {
+		t.Fatalf("got %v after %d connects", err, f.connects)
+	}
+	f.listeners[m.WorkingDir("a")].OnEvent(breez_sdk_liquid.SdkEventSynced{})
+	if e := <-sub.C(); e.WalletId != "a" {
+		t.Fatalf("event %+v", e)
+	}
+	a.Release()
+
+	// The least recently used wallet is disconnected above MaxOpen.
+	if err := m.Use("b", func(breez_sdk_liquid.BindingLiquidSdkInterface) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(disconnected) != 1 || disconnected[0] != "a" {
+		t.Fatalf("disconnected %v", disconnected)
+	}
+	if wallets := m.Wallets(); len(wallets) != 1 || wallets[0].WalletId != "b" {
+		t.Fatalf("wallets %+v", wallets)
+	}
+	if known, err := m.Known(); err != nil || len(known) != 2 {
+		t.Fatalf("known %v, %v", known, err)
+	}
+}
+
+func TestAcquireFailures(t *testing.T) {
+	f := &fakeConnect{}
+	errNoSecret := errors.New("no secret")
+	m := newManager(t, f, Options{Credentials: func(walletId string) (Credentials, error) {
+		if walletId == "missing" {
+			return Credentials{}, errNoSecret
+		}
+		return Credentials{Mnemonic: "abandon"}, nil
+	}})
+
+	if _, err := m.Acquire("../a"); !errors.Is(err, ErrInvalidId) {
+		t.Fatalf("got %v, want ErrInvalidId", err)
+	}
+	if _, err := m.Acquire("missing"); !errors.Is(err, errNoSecret) || len(m.Wallets()) != 0 {
+		t.Fatalf("got %v, want the credentials error", err)
+	}
+
+	// The working directory is locked while the wallet is connected.
+	other := newManager(t, &fakeConnect{}, Options{})
+	other.opts.Config.WorkingDir = m.opts.Config.WorkingDir
+	w, err := m.Acquire("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Acquire("a"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got %v, want ErrLocked", err)
+	}
+	w.Release()
+
+	m.Close()
+	if _, err := m.Acquire("a"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v, want ErrClosed", err)
+	}
+}
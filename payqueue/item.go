@@ -0,0 +1,180 @@
+package payqueue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/idempotency"
+	"github.com/breez/breez-sdk-liquid-go/internal/payflow"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// State of a queued payment.
+type State string
+
+const (
+	// StateQueued items are executed once NextAttemptAt is reached.
+	StateQueued State = "queued"
+	// StateProcessing items are being parsed, prepared and sent. Items
+	// left processing by a crash are queued again on startup, and their
+	// payment is never sent twice.
+	StateProcessing State = "processing"
+	// StateWaiting items were sent and wait for the payment to complete.
+	StateWaiting State = "waiting"
+	// StateSucceeded items were paid.
+	StateSucceeded State = "succeeded"
+	// StateDead items failed permanently or exhausted their attempts.
+	StateDead State = "dead"
+	// StateUnknown items were sent, but their payment was not found after
+	// exhausting their rechecks. They wait for Recheck, or for Forget once
+	// the payment is known not to have gone through.
+	StateUnknown State = "unknown"
+	// StateCancelled items were cancelled before being sent.
+	StateCancelled State = "cancelled"
+)
+
+// Stage of the pipeline an item failed at.
+type Stage string
+
+const (
+	StageParse    Stage = "parse"
+	StagePrepare  Stage = "prepare"
+	StageFeeCheck Stage = "fee_check"
+	StageSend     Stage = "send"
+	StageWait     Stage = "wait"
+)
+
+// RetryPolicy decides how failed attempts are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of failed attempts after which an item is
+	// dead-lettered, and the number of rechecks of a send whose outcome is
+	// unknown after which the item is left unknown.
+	MaxAttempts int `json:"max_attempts"`
+	// InitialBackoff is the delay before the first retry. It doubles on
+	// each retry, up to MaxBackoff.
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+}
+
+// DefaultRetryPolicy is used when Options.Retry is not set.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     30 * time.Minute,
+}
+
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < failures && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// Request is a payment to enqueue.
+type Request struct {
+	// Id identifies the item. Enqueuing an existing id returns the
+	// existing item. Generated if empty.
+	Id string `json:"id,omitempty"`
+	// Destination is any string accepted by Parse: a Bolt11 invoice, a
+	// Bolt12 offer, an LNURL, a Lightning address, a Liquid or a Bitcoin
+	// address or BIP21 URI.
+	Destination string `json:"destination"`
+	// AmountSat is the amount to pay. Zero uses the amount set by the
+	// destination.
+	AmountSat uint64 `json:"amount_sat,omitempty"`
+	// Comment is sent to LNURL services allowing one.
+	Comment string `json:"comment,omitempty"`
+	// MaxFeesSat overrides Options.MaxFeesSat for this item.
+	MaxFeesSat *uint64 `json:"max_fees_sat,omitempty"`
+	// Retry overrides Options.Retry for this item.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+}
+
+// Item is a queued payment and its progress.
+type Item struct {
+	Request
+	State     State     `json:"state"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Kind of the parsed destination, e.g. "bolt11".
+	Kind string `json:"kind,omitempty"`
+	// FeesSat are the fees of the last prepared attempt.
+	FeesSat uint64 `json:"fees_sat,omitempty"`
+	// Failures is the number of failed attempts.
+	Failures int `json:"failures"`
+	// Attempt numbers the idempotency key the payment is sent under. It
+	// is advanced only once the payment sent under the current key failed
+	// for sure, so that a send whose outcome is unknown is never repeated
+	// under another key.
+	Attempt int `json:"attempt"`
+	// Rechecks is the number of times the payment sent under the current
+	// key was looked for, its outcome being unknown.
+	Rechecks      int       `json:"rechecks,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	LastStage     Stage     `json:"last_stage,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	// PaymentId, TxId and PaymentState describe the payment sent by the
+	// last attempt.
+	PaymentId    string `json:"payment_id,omitempty"`
+	TxId         string `json:"tx_id,omitempty"`
+	PaymentState string `json:"payment_state,omitempty"`
+}
+
+// attemptKey is the idempotency key of the current attempt.
+func (it *Item) attemptKey() string {
+	return fmt.Sprintf("payqueue/%s/%d", it.Id, it.Attempt)
+}
+
+// FeeError is returned when the prepared fees exceed the configured cap.
+type FeeError struct {
+	FeesSat    uint64
+	MaxFeesSat uint64
+}
+
+func (e *FeeError) Error() string {
+	return fmt.Sprintf("fees of %d sat exceed cap of %d sat", e.FeesSat, e.MaxFeesSat)
+}
+
+// PaymentFailedError is returned when a sent payment failed.
+type PaymentFailedError struct {
+	PaymentId string
+	State     breez_sdk_liquid.PaymentState
+}
+
+func (e *PaymentFailedError) Error() string {
+	return fmt.Sprintf("payment %s ended %s", e.PaymentId, sdkutil.PaymentStateName(e.State))
+}
+
+// permanent reports whether an attempt failing with err can never succeed.
+func permanent(err error) bool {
+	for _, target := range []error{
+		payflow.ErrUnsupported,
+		payflow.ErrAmountRequired,
+		payflow.ErrAmountMismatch,
+		idempotency.ErrKeyConflict,
+		breez_sdk_liquid.ErrPaymentErrorAlreadyClaimed,
+		breez_sdk_liquid.ErrPaymentErrorAlreadyPaid,
+		breez_sdk_liquid.ErrPaymentErrorAmountMissing,
+		breez_sdk_liquid.ErrPaymentErrorAmountOutOfRange,
+		breez_sdk_liquid.ErrPaymentErrorInvalidInvoice,
+		breez_sdk_liquid.ErrPaymentErrorInvalidNetwork,
+		breez_sdk_liquid.ErrPaymentErrorSelfTransferNotSupported,
+		breez_sdk_liquid.ErrLnUrlPayErrorAlreadyPaid,
+		breez_sdk_liquid.ErrLnUrlPayErrorInvalidAmount,
+		breez_sdk_liquid.ErrLnUrlPayErrorInvalidInvoice,
+		breez_sdk_liquid.ErrLnUrlPayErrorInvalidNetwork,
+		breez_sdk_liquid.ErrLnUrlPayErrorInvalidUri,
+		breez_sdk_liquid.ErrLnUrlPayErrorInvoiceExpired,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,633 @@
+// Package payqueue is a durable queue of outbound payments executed by a
+// pool of workers.
+//
+// Each item goes through parse, prepare, fee check, send and wait for the
+// payment to reach a final state. Failed attempts are retried with backoff
+// according to a retry policy, and items which cannot succeed are
+// dead-lettered. Items whose send has an unknown outcome are checked again
+// under the same key, and set aside once their payment is still not found
+// after as many rechecks as the policy allows attempts. Items are
+// persisted, and sends go through an idempotency executor so that a
+// payment is never sent twice, even across restarts.
+package payqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/idempotency"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/payflow"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultConcurrency     = 4
+	DefaultPollInterval    = 15 * time.Second
+	DefaultRecheckInterval = time.Minute
+)
+
+var (
+	// ErrNotFound is returned for unknown item ids.
+	ErrNotFound = errors.New("payqueue: item not found")
+	// ErrConflict is returned when enqueuing an existing id with a
+	// different destination or amount.
+	ErrConflict = errors.New("payqueue: item id already used for a different payment")
+	// ErrInvalidState is returned when an item cannot be cancelled,
+	// requeued, rechecked or forgotten in its current state.
+	ErrInvalidState = errors.New("payqueue: invalid item state")
+)
+
+// Options configures a Queue.
+type Options struct {
+	// StatePath is the file the items are persisted to. Required.
+	StatePath string
+	// Executor sends the payments at most once. Defaults to an executor
+	// persisted to StatePath + ".keys".
+	Executor *idempotency.Executor
+	// Concurrency is the number of items processed at once. Defaults to
+	// DefaultConcurrency.
+	Concurrency int
+	// MaxFeesSat caps the fees of each payment, unless overridden by the
+	// item. Zero disables the cap.
+	MaxFeesSat uint64
+	// MaxFeeRate caps the fees relative to the amount, e.g. 0.01 for 1%.
+	// Zero disables the cap.
+	MaxFeeRate float64
+	// Retry is the retry policy of items without one. Defaults to
+	// DefaultRetryPolicy.
+	Retry *RetryPolicy
+	// PollInterval between scans for due items and payment updates.
+	// Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// RecheckInterval is how long to wait before checking again a send
+	// whose outcome is unknown. Defaults to DefaultRecheckInterval.
+	RecheckInterval time.Duration
+	// OnUpdate, if set, is called whenever an item changes.
+	OnUpdate func(Item)
+	// OnDeadLetter, if set, is called when an item is dead-lettered.
+	OnDeadLetter func(Item)
+}
+
+// Queue is a durable queue of outbound payments.
+type Queue struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	trigger chan struct{}
+
+	mu       sync.Mutex
+	items    map[string]*Item
+	inFlight map[string]bool
+}
+
+// New creates a Queue and loads its persisted items. Items left processing
+// by a previous run are queued again.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Queue, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("payqueue: StatePath is required")
+	}
+	if opts.Executor == nil {
+		exec, err := idempotency.New(sdk, idempotency.Options{StatePath: opts.StatePath + ".keys"})
+		if err != nil {
+			return nil, err
+		}
+		opts.Executor = exec
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Retry == nil {
+		opts.Retry = &DefaultRetryPolicy
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.RecheckInterval <= 0 {
+		opts.RecheckInterval = DefaultRecheckInterval
+	}
+
+	q := &Queue{
+		sdk:      sdk,
+		opts:     opts,
+		trigger:  make(chan struct{}, 1),
+		items:    map[string]*Item{},
+		inFlight: map[string]bool{},
+	}
+	if err := filestore.Load(opts.StatePath, &q.items); err != nil {
+		return nil, err
+	}
+	for _, it := range q.items {
+		if it.State == StateProcessing {
+			it.State = StateQueued
+		}
+	}
+	return q, nil
+}
+
+// Enqueue adds a payment to the queue.
+func (q *Queue) Enqueue(req Request) (Item, error) {
+	if req.Destination == "" {
+		return Item{}, errors.New("payqueue: destination is required")
+	}
+	if req.Id == "" {
+		id, err := newId()
+		if err != nil {
+			return Item{}, err
+		}
+		req.Id = id
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if it, ok := q.items[req.Id]; ok {
+		if it.Destination != req.Destination || it.AmountSat != req.AmountSat {
+			return Item{}, ErrConflict
+		}
+		return *it, nil
+	}
+	now := time.Now().UTC()
+	it := &Item{Request: req, State: StateQueued, Attempt: 1, CreatedAt: now, UpdatedAt: now}
+	q.items[req.Id] = it
+	if err := q.saveLocked(); err != nil {
+		delete(q.items, req.Id)
+		return Item{}, err
+	}
+	q.Trigger()
+	q.notify(*it)
+	return *it, nil
+}
+
+// Get returns an item.
+func (q *Queue) Get(id string) (Item, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	it, ok := q.items[id]
+	if !ok {
+		return Item{}, false
+	}
+	return *it, true
+}
+
+// List returns the items in the given states, or all items if none is
+// given, ordered by creation time.
+func (q *Queue) List(states ...State) []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var items []Item
+	for _, it := range q.items {
+		if len(states) == 0 || hasState(states, it.State) {
+			items = append(items, *it)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		}
+		return items[i].Id < items[j].Id
+	})
+	return items
+}
+
+// DeadLetters returns the dead-lettered items.
+func (q *Queue) DeadLetters() []Item {
+	return q.List(StateDead)
+}
+
+// Stats returns the number of items in each state.
+func (q *Queue) Stats() map[State]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := map[State]int{}
+	for _, it := range q.items {
+		stats[it.State]++
+	}
+	return stats
+}
+
+// Cancel cancels a queued item.
+func (q *Queue) Cancel(id string) (Item, error) {
+	return q.transition(id, StateQueued, func(it *Item) {
+		it.State = StateCancelled
+	})
+}
+
+// Requeue queues a dead-lettered item again, resetting its failures.
+func (q *Queue) Requeue(id string) (Item, error) {
+	item, err := q.transition(id, StateDead, func(it *Item) {
+		it.State, it.NextAttemptAt, it.Failures = StateQueued, time.Time{}, 0
+	})
+	if err == nil {
+		q.Trigger()
+	}
+	return item, err
+}
+
+// Recheck queues again an item whose outcome is unknown, to look for its
+// payment for another round of rechecks.
+func (q *Queue) Recheck(id string) (Item, error) {
+	item, err := q.transition(id, StateUnknown, func(it *Item) {
+		it.State, it.NextAttemptAt, it.Rechecks = StateQueued, time.Time{}, 0
+	})
+	if err == nil {
+		q.Trigger()
+	}
+	return item, err
+}
+
+// Forget queues again an item whose outcome is unknown, once its payment
+// is known not to have gone through, forgetting the key it was sent under
+// so that it is sent again.
+func (q *Queue) Forget(id string) (Item, error) {
+	q.mu.Lock()
+	it, ok := q.items[id]
+	if !ok {
+		q.mu.Unlock()
+		return Item{}, ErrNotFound
+	}
+	if it.State != StateUnknown || q.inFlight[id] {
+		q.mu.Unlock()
+		return Item{}, ErrInvalidState
+	}
+	key := it.attemptKey()
+	q.mu.Unlock()
+	if err := q.opts.Executor.Forget(key); err != nil {
+		return Item{}, err
+	}
+	return q.Recheck(id)
+}
+
+func (q *Queue) transition(id string, from State, update func(it *Item)) (Item, error) {
+	q.mu.Lock()
+	it, ok := q.items[id]
+	if !ok {
+		q.mu.Unlock()
+		return Item{}, ErrNotFound
+	}
+	if it.State != from || q.inFlight[id] {
+		q.mu.Unlock()
+		return Item{}, ErrInvalidState
+	}
+	update(it)
+	it.UpdatedAt = time.Now().UTC()
+	item := *it
+	err := q.saveLocked()
+	q.mu.Unlock()
+	q.notify(item)
+	return item, err
+}
+
+// Trigger makes Run scan the queue immediately.
+func (q *Queue) Trigger() {
+	select {
+	case q.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes the queue until ctx is done, then waits for the items
+// being processed.
+func (q *Queue) Run(ctx context.Context) error {
+	listenerId, err := q.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		if _, ok := sdkutil.EventPayment(e); ok {
+			q.Trigger()
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = q.sdk.RemoveEventListener(listenerId) }()
+
+	slots := make(chan struct{}, q.opts.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		q.refreshWaiting()
+		q.dispatch(slots, &wg)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-q.trigger:
+		}
+	}
+}
+
+// dispatch starts processing the due items while workers are available.
+func (q *Queue) dispatch(slots chan struct{}, wg *sync.WaitGroup) {
+	for _, it := range q.due() {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return
+		}
+		if !q.claim(it.Id) {
+			<-slots
+			continue
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			q.process(id)
+		}(it.Id)
+	}
+}
+
+func (q *Queue) due() []Item {
+	now := time.Now()
+	var due []Item
+	for _, it := range q.List(StateQueued) {
+		if !it.NextAttemptAt.After(now) {
+			due = append(due, it)
+		}
+	}
+	return due
+}
+
+// claim marks a queued item processing.
+func (q *Queue) claim(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	it, ok := q.items[id]
+	if !ok || it.State != StateQueued || q.inFlight[id] {
+		return false
+	}
+	q.inFlight[id] = true
+	it.State, it.UpdatedAt = StateProcessing, time.Now().UTC()
+	return q.saveLocked() == nil
+}
+
+func (q *Queue) process(id string) {
+	defer func() {
+		q.mu.Lock()
+		delete(q.inFlight, id)
+		q.mu.Unlock()
+	}()
+	it, ok := q.Get(id)
+	if !ok {
+		return
+	}
+
+	// The current key may have been sent by an attempt whose outcome was
+	// unknown: resolve it before preparing the payment again.
+	key := it.attemptKey()
+	if payment, found, err := q.opts.Executor.Replay(key); found {
+		q.sent(id, key, payment, err)
+		return
+	}
+
+	input, sdkErr := q.sdk.Parse(it.Destination)
+	if sdkErr != nil {
+		q.fail(id, StageParse, sdkErr.AsError())
+		return
+	}
+	prepared, err := payflow.Prepare(q.sdk, it.Destination, input, it.AmountSat, it.Comment)
+	q.update(id, func(it *Item) {
+		it.Kind, it.FeesSat = payflow.Kind(input), prepared.FeesSat
+	})
+	if err != nil {
+		if payment, ok := q.paid(input, err); ok {
+			q.update(id, func(it *Item) {
+				it.State = StateWaiting
+				setPayment(it, payment)
+			})
+			q.settle(id, payment)
+			return
+		}
+		q.fail(id, StagePrepare, err)
+		return
+	}
+	if err := q.checkFees(it, prepared); err != nil {
+		q.fail(id, StageFeeCheck, err)
+		return
+	}
+
+	payment, err := prepared.ExecuteOnce(q.opts.Executor, key)
+	q.sent(id, key, payment, err)
+}
+
+// sent records the result of sending, or replaying, the payment of an item
+// under key.
+func (q *Queue) sent(id, key string, payment breez_sdk_liquid.Payment, err error) {
+	switch {
+	case err != nil && q.outcomeUnknown(key, err):
+		// The payment may have been sent: check again later with the
+		// same key, without counting a failure, until the rechecks are
+		// exhausted.
+		q.update(id, func(it *Item) {
+			it.Rechecks++
+			it.LastStage, it.LastError = StageSend, err.Error()
+			if it.Rechecks >= q.policy(it).MaxAttempts {
+				it.State, it.NextAttemptAt = StateUnknown, time.Time{}
+				return
+			}
+			it.State = StateQueued
+			it.NextAttemptAt = time.Now().Add(q.opts.RecheckInterval).UTC()
+		})
+	case err != nil:
+		q.fail(id, StageSend, err)
+	default:
+		q.update(id, func(it *Item) {
+			it.State = StateWaiting
+			setPayment(it, payment)
+		})
+		q.settle(id, payment)
+	}
+}
+
+// outcomeUnknown reports whether a send under key which failed with err may
+// have paid: the executor returns the error of the SDK when the send fails
+// ambiguously, and an UnknownOutcomeError when the key is sent again.
+func (q *Queue) outcomeUnknown(key string, err error) bool {
+	var unknown *idempotency.UnknownOutcomeError
+	if errors.As(err, &unknown) || errors.Is(err, idempotency.ErrInProgress) {
+		return true
+	}
+	if errors.Is(err, idempotency.ErrKeyConflict) {
+		return false
+	}
+	r, ok := q.opts.Executor.Record(key)
+	return ok && (r.Status == idempotency.StatusUnknown || r.Status == idempotency.StatusPending)
+}
+
+// paid returns the payment of a Bolt11 invoice the SDK refused to prepare
+// because it was already paid, possibly by a send of the item whose outcome
+// was unknown.
+func (q *Queue) paid(input breez_sdk_liquid.InputType, err error) (breez_sdk_liquid.Payment, bool) {
+	invoice, ok := input.(breez_sdk_liquid.InputTypeBolt11)
+	if !ok || !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorAlreadyPaid) {
+		return breez_sdk_liquid.Payment{}, false
+	}
+	payment, sdkErr := q.sdk.GetPayment(breez_sdk_liquid.GetPaymentRequestPaymentHash{PaymentHash: invoice.Invoice.PaymentHash})
+	if sdkErr != nil || payment == nil || payment.PaymentType != breez_sdk_liquid.PaymentTypeSend {
+		return breez_sdk_liquid.Payment{}, false
+	}
+	return *payment, true
+}
+
+func (q *Queue) checkFees(it Item, prepared payflow.Prepared) error {
+	maxFees := q.opts.MaxFeesSat
+	if it.MaxFeesSat != nil {
+		maxFees = *it.MaxFeesSat
+	}
+	if maxFees > 0 && prepared.FeesSat > maxFees {
+		return &FeeError{FeesSat: prepared.FeesSat, MaxFeesSat: maxFees}
+	}
+	if q.opts.MaxFeeRate > 0 && prepared.AmountSat > 0 {
+		capSat := uint64(float64(prepared.AmountSat) * q.opts.MaxFeeRate)
+		if prepared.FeesSat > capSat {
+			return &FeeError{FeesSat: prepared.FeesSat, MaxFeesSat: capSat}
+		}
+	}
+	return nil
+}
+
+// refreshWaiting updates the items waiting for their payment to complete.
+func (q *Queue) refreshWaiting() {
+	waiting := q.List(StateWaiting)
+	if len(waiting) == 0 {
+		return
+	}
+	from := waiting[0].CreatedAt.Add(-idempotency.DefaultClockSkew).Unix()
+	payments, err := sdkutil.ListAllPayments(q.sdk, breez_sdk_liquid.ListPaymentsRequest{
+		Filters:       &[]breez_sdk_liquid.PaymentType{breez_sdk_liquid.PaymentTypeSend},
+		FromTimestamp: &from,
+	})
+	if err != nil {
+		return
+	}
+	byId := map[string]breez_sdk_liquid.Payment{}
+	for _, p := range payments {
+		byId[sdkutil.PaymentId(p)] = p
+	}
+	for _, it := range waiting {
+		if p, ok := byId[it.PaymentId]; ok {
+			q.settle(it.Id, p)
+		}
+	}
+}
+
+// settle records the state of the payment of a waiting item.
+func (q *Queue) settle(id string, payment breez_sdk_liquid.Payment) {
+	switch payment.Status {
+	case breez_sdk_liquid.PaymentStateComplete:
+		q.update(id, func(it *Item) {
+			if it.State == StateWaiting {
+				it.State = StateSucceeded
+				setPayment(it, payment)
+			}
+		})
+	case breez_sdk_liquid.PaymentStateFailed, breez_sdk_liquid.PaymentStateTimedOut:
+		q.mu.Lock()
+		waiting := q.items[id] != nil && q.items[id].State == StateWaiting
+		q.mu.Unlock()
+		if waiting {
+			q.fail(id, StageWait, &PaymentFailedError{PaymentId: sdkutil.PaymentId(payment), State: payment.Status})
+		}
+	default:
+		q.update(id, func(it *Item) {
+			if it.State == StateWaiting {
+				setPayment(it, payment)
+			}
+		})
+	}
+}
+
+// fail records a failed attempt, scheduling a retry or dead-lettering the
+// item.
+func (q *Queue) fail(id string, stage Stage, err error) {
+	var dead bool
+	item := q.update(id, func(it *Item) {
+		it.Failures++
+		// Only a send rejected by the SDK, or a payment which failed, rules
+		// out that the current key paid: failures before the send leave a
+		// key whose outcome may be unknown in use.
+		if stage == StageWait || stage == StageSend && !errors.Is(err, idempotency.ErrKeyConflict) {
+			it.Attempt, it.Rechecks = it.Attempt+1, 0
+		}
+		it.LastStage, it.LastError = stage, err.Error()
+		policy := q.policy(it)
+		if permanent(err) || it.Failures >= policy.MaxAttempts {
+			it.State, it.NextAttemptAt, dead = StateDead, time.Time{}, true
+			return
+		}
+		it.State = StateQueued
+		it.NextAttemptAt = time.Now().Add(policy.backoff(it.Failures)).UTC()
+	})
+	if dead && q.opts.OnDeadLetter != nil {
+		q.opts.OnDeadLetter(item)
+	}
+}
+
+func (q *Queue) policy(it *Item) *RetryPolicy {
+	if it.Retry != nil {
+		return it.Retry
+	}
+	return q.opts.Retry
+}
+
+// update applies a change to an item, persists and notifies it.
+func (q *Queue) update(id string, change func(it *Item)) Item {
+	q.mu.Lock()
+	it, ok := q.items[id]
+	if !ok {
+		q.mu.Unlock()
+		return Item{}
+	}
+	before := *it
+	change(it)
+	if *it == before {
+		q.mu.Unlock()
+		return before
+	}
+	it.UpdatedAt = time.Now().UTC()
+	item := *it
+	_ = q.saveLocked()
+	q.mu.Unlock()
+	q.notify(item)
+	return item
+}
+
+func (q *Queue) saveLocked() error {
+	return filestore.Save(q.opts.StatePath, q.items)
+}
+
+func (q *Queue) notify(it Item) {
+	if q.opts.OnUpdate != nil {
+		q.opts.OnUpdate(it)
+	}
+}
+
+func setPayment(it *Item, p breez_sdk_liquid.Payment) {
+	it.PaymentId = sdkutil.PaymentId(p)
+	it.TxId = sdkutil.StringValue(p.TxId)
+	it.PaymentState = sdkutil.PaymentStateName(p.Status)
+	it.FeesSat = p.FeesSat
+}
+
+func hasState(states []State, s State) bool {
+	for _, state := range states {
+		if state == s {
+			return true
+		}
+	}
+	return false
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
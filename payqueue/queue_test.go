@@ -0,0 +1,201 @@
+package payqueue
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/idempotency"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+var errTimeout = breez_sdk_liquid.NewPaymentErrorPaymentTimeout()
+
+func newQueue(t *testing.T, sdk *sdktest.Fake, retry *RetryPolicy) *Queue {
+	t.Helper()
+	q, err := New(sdk, Options{StatePath: filepath.Join(t.TempDir(), "payqueue.json"), Retry: retry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// attempt processes an item as a worker would, regardless of its backoff.
+func attempt(t *testing.T, q *Queue, id string) Item {
+	t.Helper()
+	if !q.claim(id) {
+		t.Fatalf("item %s not claimed", id)
+	}
+	q.process(id)
+	it, _ := q.Get(id)
+	return it
+}
+
+func TestUnknownOutcomeKeepsKey(t *testing.T) {
+	sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{errTimeout}, PaidOnError: true}
+	q := newQueue(t, sdk, nil)
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The send timed out: its outcome is unknown, and the payment went
+	// through.
+	it := attempt(t, q, "a")
+	if it.State != StateQueued || it.Attempt != 1 || it.Failures != 0 {
+		t.Fatalf("after timeout: %+v", it)
+	}
+	if r, ok := q.opts.Executor.Record("payqueue/a/1"); !ok || r.Status != idempotency.StatusUnknown {
+		t.Fatalf("record %+v", r)
+	}
+
+	// The recheck finds the payment under the same key.
+	it = attempt(t, q, "a")
+	if it.State != StateSucceeded || it.PaymentId != "tx1" {
+		t.Fatalf("after recheck: %+v", it)
+	}
+	if sdk.Sends != 1 {
+		t.Fatalf("%d sends, want 1", sdk.Sends)
+	}
+}
+
+func TestUnknownOutcomeResolvedBeforePrepare(t *testing.T) {
+	sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorSendError()}}
+	// A send error is ambiguous, but no payment was made.
+	q := newQueue(t, sdk, nil)
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if it := attempt(t, q, "a"); it.State != StateQueued || it.Attempt != 1 || it.Rechecks != 1 {
+		t.Fatalf("after send error: %+v", it)
+	}
+
+	// The recheck looks for the payment of the key without preparing it
+	// again, so a failing prepare neither counts a failure nor advances
+	// the key.
+	sdk.PrepareErr = breez_sdk_liquid.NewPaymentErrorGeneric()
+	it := attempt(t, q, "a")
+	if it.State != StateQueued || it.LastStage != StageSend || it.Failures != 0 || it.Attempt != 1 || it.Rechecks != 2 {
+		t.Fatalf("after recheck: %+v", it)
+	}
+	if sdk.Prepares != 1 || sdk.Sends != 1 {
+		t.Fatalf("%d prepares and %d sends, want 1 each", sdk.Prepares, sdk.Sends)
+	}
+}
+
+func TestUnknownOutcomeBounded(t *testing.T) {
+	sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{errTimeout}}
+	q := newQueue(t, sdk, &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: time.Minute})
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	attempt(t, q, "a")
+	if it := attempt(t, q, "a"); it.State != StateUnknown || it.Rechecks != 2 {
+		t.Fatalf("after two rechecks: %+v", it)
+	}
+	if _, err := q.Requeue("a"); err != ErrInvalidState {
+		t.Fatalf("Requeue: got %v, want ErrInvalidState", err)
+	}
+
+	// Rechecking still finds no payment.
+	if it, err := q.Recheck("a"); err != nil || it.State != StateQueued || it.Rechecks != 0 {
+		t.Fatalf("Recheck: %+v, %v", it, err)
+	}
+	attempt(t, q, "a")
+	if it := attempt(t, q, "a"); it.State != StateUnknown || sdk.Sends != 1 {
+		t.Fatalf("after rechecking: %+v, %d sends", it, sdk.Sends)
+	}
+
+	// Once forgotten, the key is sent again.
+	if _, err := q.Forget("a"); err != nil {
+		t.Fatal(err)
+	}
+	if it := attempt(t, q, "a"); it.State != StateSucceeded || it.Attempt != 1 || sdk.Sends != 2 {
+		t.Fatalf("after Forget: %+v, %d sends", it, sdk.Sends)
+	}
+	if _, err := q.Forget("a"); err != ErrInvalidState {
+		t.Fatalf("Forget of a succeeded item: got %v, want ErrInvalidState", err)
+	}
+}
+
+func TestDefiniteFailureAdvancesKey(t *testing.T) {
+	sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorInsufficientFunds()}}
+	q := newQueue(t, sdk, nil)
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	it := attempt(t, q, "a")
+	if it.State != StateQueued || it.Attempt != 2 || it.Failures != 1 || it.LastStage != StageSend {
+		t.Fatalf("after failure: %+v", it)
+	}
+	if !it.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("retry not delayed: %+v", it)
+	}
+
+	it = attempt(t, q, "a")
+	if it.State != StateSucceeded || sdk.Sends != 2 {
+		t.Fatalf("after retry: %+v, %d sends", it, sdk.Sends)
+	}
+	if r, ok := q.opts.Executor.Record("payqueue/a/2"); !ok || r.Status != idempotency.StatusSucceeded {
+		t.Fatalf("record %+v", r)
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	insufficient := breez_sdk_liquid.NewPaymentErrorInsufficientFunds()
+	sdk := &sdktest.Fake{SendErrs: []*breez_sdk_liquid.PaymentError{insufficient, insufficient}}
+	q := newQueue(t, sdk, &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Second, MaxBackoff: time.Minute})
+	var dead []Item
+	q.opts.OnDeadLetter = func(it Item) { dead = append(dead, it) }
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	attempt(t, q, "a")
+	if it := attempt(t, q, "a"); it.State != StateDead || it.Failures != 2 {
+		t.Fatalf("after two failures: %+v", it)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("%d dead letters reported, want 1", len(dead))
+	}
+
+	it, err := q.Requeue("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.State != StateQueued || it.Failures != 0 || it.Attempt != 3 {
+		t.Fatalf("requeued: %+v", it)
+	}
+	if it := attempt(t, q, "a"); it.State != StateSucceeded {
+		t.Fatalf("after requeue: %+v", it)
+	}
+}
+
+func TestPermanentFailure(t *testing.T) {
+	sdk := &sdktest.Fake{PrepareErr: breez_sdk_liquid.NewPaymentErrorAlreadyPaid()}
+	q := newQueue(t, sdk, nil)
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if it := attempt(t, q, "a"); it.State != StateDead || it.Failures != 1 {
+		t.Fatalf("after already paid: %+v", it)
+	}
+}
+
+func TestRestartRequeuesProcessing(t *testing.T) {
+	sdk := &sdktest.Fake{}
+	q := newQueue(t, sdk, nil)
+	if _, err := q.Enqueue(Request{Id: "a", Destination: "lq1", AmountSat: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if !q.claim("a") {
+		t.Fatal("not claimed")
+	}
+
+	q, err := New(sdk, Options{StatePath: q.opts.StatePath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it, _ := q.Get("a"); it.State != StateQueued || it.Attempt != 1 {
+		t.Fatalf("after restart: %+v", it)
+	}
+}
@@ -0,0 +1,368 @@
+// Package reconcile recomputes the balances reported in WalletInfo from the
+// payment history and reports any mismatch together with the payments that
+// may be responsible for it.
+//
+// Balances are recomputed the same way the SDK derives them: completed
+// receives add to the balance, completed sends subtract their amount and
+// fees, and sends that are Pending or RefundPending are counted as pending
+// send and already deducted from the balance. Receives that are Pending or
+// RefundPending are counted as pending receive. Payments in any other state
+// do not affect the balances.
+package reconcile
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// DefaultInterval is the interval between runs used by Run when
+// Options.Interval is not set.
+const DefaultInterval = 10 * time.Minute
+
+// maxSnapshotAttempts bounds how often a run is retried when the wallet
+// info changes while the payments are being listed.
+const maxSnapshotAttempts = 3
+
+// Options configures a Reconciler.
+type Options struct {
+	// Network selects the L-BTC asset id. Defaults to mainnet.
+	Network breez_sdk_liquid.LiquidNetwork
+	// Interval between runs started by Run. Defaults to DefaultInterval.
+	Interval time.Duration
+	// ToleranceSat is the absolute difference below which values are
+	// considered equal.
+	ToleranceSat uint64
+	// OnReport, if set, is called with every report produced by Run.
+	OnReport func(Report)
+}
+
+// Reconciler compares WalletInfo with the payment history.
+type Reconciler struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+	lbtc string
+
+	mu     sync.Mutex
+	latest *Report
+}
+
+// New creates a Reconciler for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Reconciler {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	return &Reconciler{
+		sdk:  sdk,
+		opts: opts,
+		lbtc: sdkutil.LbtcAssetId(opts.Network),
+	}
+}
+
+// Latest returns the most recent report, if any run has completed.
+func (r *Reconciler) Latest() (Report, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.latest == nil {
+		return Report{}, false
+	}
+	return *r.latest, true
+}
+
+// Run reconciles immediately and then every Options.Interval until ctx is
+// cancelled. Failed runs are skipped; the next run is attempted on schedule.
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if report, err := r.Reconcile(); err == nil && r.opts.OnReport != nil {
+			r.opts.OnReport(report)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile runs a single reconciliation and stores the resulting report.
+func (r *Reconciler) Reconcile() (Report, error) {
+	var (
+		info     breez_sdk_liquid.GetInfoResponse
+		payments []breez_sdk_liquid.Payment
+	)
+	for attempt := 0; attempt < maxSnapshotAttempts; attempt++ {
+		before, sdkErr := r.sdk.GetInfo()
+		if sdkErr != nil {
+			return Report{}, sdkErr
+		}
+		var err error
+		payments, err = sdkutil.ListAllPayments(r.sdk, breez_sdk_liquid.ListPaymentsRequest{})
+		if err != nil {
+			return Report{}, err
+		}
+		after, sdkErr := r.sdk.GetInfo()
+		if sdkErr != nil {
+			return Report{}, sdkErr
+		}
+		info = after
+		if reflect.DeepEqual(before.WalletInfo, after.WalletInfo) {
+			break
+		}
+	}
+
+	report := r.build(info.WalletInfo, payments)
+	r.mu.Lock()
+	r.latest = &report
+	r.mu.Unlock()
+	return report, nil
+}
+
+type assetTotals struct {
+	received       uint64
+	sent           uint64
+	pendingSend    uint64
+	pendingReceive uint64
+}
+
+func (t *assetTotals) expected() int64 {
+	return int64(t.received) - int64(t.sent) - int64(t.pendingSend)
+}
+
+type stateKey struct {
+	assetId     string
+	paymentType breez_sdk_liquid.PaymentType
+	state       breez_sdk_liquid.PaymentState
+}
+
+func (r *Reconciler) build(wallet breez_sdk_liquid.WalletInfo, payments []breez_sdk_liquid.Payment) Report {
+	totals := map[string]*assetTotals{r.lbtc: {}}
+	byState := map[stateKey]*StateTotals{}
+	total := func(assetId string) *assetTotals {
+		t, ok := totals[assetId]
+		if !ok {
+			t = &assetTotals{}
+			totals[assetId] = t
+		}
+		return t
+	}
+
+	for _, payment := range payments {
+		assetId := sdkutil.AssetId(payment, r.lbtc)
+		key := stateKey{assetId, payment.PaymentType, payment.Status}
+		st, ok := byState[key]
+		if !ok {
+			st = &StateTotals{
+				AssetId:     assetId,
+				PaymentType: sdkutil.PaymentTypeName(payment.PaymentType),
+				State:       sdkutil.PaymentStateName(payment.Status),
+			}
+			byState[key] = st
+		}
+		st.Count++
+		st.AmountSat += payment.AmountSat
+		st.FeesSat += payment.FeesSat
+
+		switch payment.Status {
+		case breez_sdk_liquid.PaymentStateComplete:
+			if payment.PaymentType == breez_sdk_liquid.PaymentTypeReceive {
+				total(assetId).received += payment.AmountSat
+				continue
+			}
+			total(assetId).sent += payment.AmountSat
+			feeAsset, fees := r.fees(payment, assetId)
+			total(feeAsset).sent += fees
+		case breez_sdk_liquid.PaymentStatePending, breez_sdk_liquid.PaymentStateRefundPending:
+			if payment.PaymentType == breez_sdk_liquid.PaymentTypeReceive {
+				total(assetId).pendingReceive += payment.AmountSat
+				continue
+			}
+			total(assetId).pendingSend += payment.AmountSat
+			feeAsset, fees := r.fees(payment, assetId)
+			total(feeAsset).pendingSend += fees
+		}
+	}
+
+	lbtc := totals[r.lbtc]
+	report := Report{
+		GeneratedAt:  time.Now().UTC(),
+		PaymentCount: len(payments),
+		Expected: Balances{
+			BalanceSat:        clamp(lbtc.expected()),
+			PendingSendSat:    lbtc.pendingSend,
+			PendingReceiveSat: lbtc.pendingReceive,
+		},
+		Actual: Balances{
+			BalanceSat:        wallet.BalanceSat,
+			PendingSendSat:    wallet.PendingSendSat,
+			PendingReceiveSat: wallet.PendingReceiveSat,
+		},
+	}
+
+	r.check(&report, FieldBalance, r.lbtc, lbtc.expected(), wallet.BalanceSat, payments)
+	r.check(&report, FieldPendingSend, r.lbtc, int64(lbtc.pendingSend), wallet.PendingSendSat, payments)
+	r.check(&report, FieldPendingReceive, r.lbtc, int64(lbtc.pendingReceive), wallet.PendingReceiveSat, payments)
+
+	reported := map[string]breez_sdk_liquid.AssetBalance{}
+	for _, balance := range wallet.AssetBalances {
+		reported[balance.AssetId] = balance
+		total(balance.AssetId)
+	}
+	assetIds := make([]string, 0, len(totals))
+	for assetId := range totals {
+		assetIds = append(assetIds, assetId)
+	}
+	sort.Strings(assetIds)
+	for _, assetId := range assetIds {
+		t := totals[assetId]
+		balance, ok := reported[assetId]
+		asset := AssetTotals{
+			AssetId:     assetId,
+			Ticker:      sdkutil.StringValue(balance.Ticker),
+			ReceivedSat: t.received,
+			SentSat:     t.sent,
+			PendingSat:  t.pendingSend,
+			ExpectedSat: t.expected(),
+			ActualSat:   balance.BalanceSat,
+			Reported:    ok,
+		}
+		report.Assets = append(report.Assets, asset)
+		// L-BTC is only checked here when it is listed among the asset
+		// balances, it was already checked against WalletInfo.BalanceSat.
+		if assetId != r.lbtc || ok {
+			r.check(&report, FieldAssetBalance, assetId, asset.ExpectedSat, asset.ActualSat, payments)
+		}
+	}
+
+	for _, st := range byState {
+		report.ByState = append(report.ByState, *st)
+	}
+	sort.Slice(report.ByState, func(i, j int) bool {
+		a, b := report.ByState[i], report.ByState[j]
+		if a.AssetId != b.AssetId {
+			return a.AssetId < b.AssetId
+		}
+		if a.PaymentType != b.PaymentType {
+			return a.PaymentType < b.PaymentType
+		}
+		return a.State < b.State
+	})
+	return report
+}
+
+// fees returns the asset in which the fees of a send were paid, and their
+// amount in that asset's base units.
+func (r *Reconciler) fees(payment breez_sdk_liquid.Payment, assetId string) (string, uint64) {
+	details, ok := payment.Details.(breez_sdk_liquid.PaymentDetailsLiquid)
+	if !ok || assetId == r.lbtc || details.AssetInfo == nil || details.AssetInfo.Fees == nil || details.AssetInfo.Amount <= 0 {
+		return r.lbtc, payment.FeesSat
+	}
+	// Fees paid in the asset are only known in asset units, convert them
+	// using the ratio between the payment amount in base and asset units.
+	scale := float64(payment.AmountSat) / details.AssetInfo.Amount
+	return assetId, uint64(*details.AssetInfo.Fees*scale + 0.5)
+}
+
+func (r *Reconciler) check(report *Report, field Field, assetId string, expected int64, actual uint64, payments []breez_sdk_liquid.Payment) {
+	diff := int64(actual) - expected
+	if abs(diff) <= int64(r.opts.ToleranceSat) {
+		return
+	}
+	report.Discrepancies = append(report.Discrepancies, Discrepancy{
+		Field:       field,
+		AssetId:     assetId,
+		ExpectedSat: expected,
+		ActualSat:   actual,
+		DiffSat:     diff,
+		Payments:    r.suspects(field, assetId, abs(diff), payments),
+	})
+}
+
+// suspects lists the payments of the asset that may explain a difference of
+// diff on field: payments whose state is ambiguous for that field, and any
+// payment whose contribution alone equals the difference.
+func (r *Reconciler) suspects(field Field, assetId string, diff int64, payments []breez_sdk_liquid.Payment) []PaymentRef {
+	var refs []PaymentRef
+	for _, payment := range payments {
+		if sdkutil.AssetId(payment, r.lbtc) != assetId {
+			continue
+		}
+		contribution := payment.AmountSat
+		if payment.PaymentType == breez_sdk_liquid.PaymentTypeSend && assetId == r.lbtc {
+			contribution += payment.FeesSat
+		}
+		exact := int64(contribution) == diff || int64(payment.AmountSat) == diff
+		reason := suspectReason(field, payment)
+		if reason == "" && !exact {
+			continue
+		}
+		if reason == "" {
+			reason = "amount equals the difference"
+		}
+		refs = append(refs, PaymentRef{
+			Id:          sdkutil.PaymentId(payment),
+			TxId:        sdkutil.StringValue(payment.TxId),
+			Destination: sdkutil.StringValue(payment.Destination),
+			Timestamp:   payment.Timestamp,
+			PaymentType: sdkutil.PaymentTypeName(payment.PaymentType),
+			State:       sdkutil.PaymentStateName(payment.Status),
+			AmountSat:   payment.AmountSat,
+			FeesSat:     payment.FeesSat,
+			ExactMatch:  exact,
+			Reason:      reason,
+		})
+	}
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].ExactMatch != refs[j].ExactMatch {
+			return refs[i].ExactMatch
+		}
+		return refs[i].Timestamp > refs[j].Timestamp
+	})
+	return refs
+}
+
+func suspectReason(field Field, payment breez_sdk_liquid.Payment) string {
+	send := payment.PaymentType == breez_sdk_liquid.PaymentTypeSend
+	switch payment.Status {
+	case breez_sdk_liquid.PaymentStatePending, breez_sdk_liquid.PaymentStateRefundPending:
+		if send && field != FieldPendingReceive {
+			return "in-flight send is counted as pending and deducted from the balance"
+		}
+		if !send && field != FieldPendingSend {
+			return "in-flight receive is counted as pending and not yet in the balance"
+		}
+	case breez_sdk_liquid.PaymentStateCreated:
+		return "created payment is not counted until it becomes pending"
+	case breez_sdk_liquid.PaymentStateWaitingFeeAcceptance:
+		if !send {
+			return "receive waiting for fee acceptance is not counted"
+		}
+	case breez_sdk_liquid.PaymentStateRefundable:
+		return "refundable swap is not counted"
+	case breez_sdk_liquid.PaymentStateFailed, breez_sdk_liquid.PaymentStateTimedOut:
+		if send && field != FieldPendingReceive {
+			return "failed send is not counted, its funds may still be locked or refunded"
+		}
+	}
+	return ""
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func clamp(v int64) uint64 {
+	if v < 0 {
+		return 0
+	}
+	return uint64(v)
+}
@@ -0,0 +1,71 @@
+package reconcile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+// history returns an SDK with a receive of 10000 sat and a send of 3000 sat
+// paying 100 sat of fees, reporting balanceSat.
+func history(balanceSat uint64) *sdktest.Fake {
+	f := &sdktest.Fake{BalanceSat: balanceSat, FeesSat: 100}
+	f.Pay("lq1", 3000)
+	txId := "rx1"
+	f.Payments = append(f.Payments, breez_sdk_liquid.Payment{
+		TxId:        &txId,
+		AmountSat:   10000,
+		PaymentType: breez_sdk_liquid.PaymentTypeReceive,
+		Status:      breez_sdk_liquid.PaymentStateComplete,
+		Details:     breez_sdk_liquid.PaymentDetailsLiquid{Destination: "lq2"},
+	})
+	return f
+}
+
+func TestBalanced(t *testing.T) {
+	r := New(history(6900), Options{})
+	report, err := r.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Balanced() || report.PaymentCount != 2 || report.Expected.BalanceSat != 6900 {
+		t.Fatalf("report %+v", report)
+	}
+	if latest, ok := r.Latest(); !ok || latest.PaymentCount != 2 {
+		t.Fatalf("latest %+v", latest)
+	}
+}
+
+func TestDiscrepancy(t *testing.T) {
+	// The send, with its fees, is missing from the balance.
+	report, err := New(history(10000), Options{ToleranceSat: 10}).Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Discrepancies) != 1 {
+		t.Fatalf("discrepancies %+v", report.Discrepancies)
+	}
+	d := report.Discrepancies[0]
+	if d.Field != FieldBalance || d.DiffSat != 3100 || len(d.Payments) != 1 || !d.Payments[0].ExactMatch || d.Payments[0].TxId != "tx1" {
+		t.Fatalf("discrepancy %+v", d)
+	}
+}
+
+func TestSdkFailure(t *testing.T) {
+	sdk := intercept.WrapLiquidSdk(history(6900), func(call *intercept.Call, next intercept.Handler) (any, error) {
+		if call.Method == "ListPayments" {
+			return nil, breez_sdk_liquid.NewPaymentErrorPersistError()
+		}
+		return next(call)
+	})
+	r := New(sdk, Options{})
+	if _, err := r.Reconcile(); !errors.Is(err, breez_sdk_liquid.ErrPaymentErrorPersistError) {
+		t.Fatalf("got %v, want the ListPayments error", err)
+	}
+	if _, ok := r.Latest(); ok {
+		t.Fatal("report stored after a failure")
+	}
+}
@@ -0,0 +1,87 @@
+package reconcile
+
+import (
+	"time"
+)
+
+// Field names the WalletInfo value a Discrepancy refers to.
+type Field string
+
+const (
+	FieldBalance        Field = "balance_sat"
+	FieldPendingSend    Field = "pending_send_sat"
+	FieldPendingReceive Field = "pending_receive_sat"
+	FieldAssetBalance   Field = "asset_balance_sat"
+)
+
+// Report is the outcome of a single reconciliation run.
+type Report struct {
+	GeneratedAt   time.Time     `json:"generated_at"`
+	PaymentCount  int           `json:"payment_count"`
+	Expected      Balances      `json:"expected"`
+	Actual        Balances      `json:"actual"`
+	Assets        []AssetTotals `json:"assets"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	ByState       []StateTotals `json:"by_state"`
+}
+
+// Balanced reports whether no discrepancy was found.
+func (r Report) Balanced() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// Balances mirrors the L-BTC amounts of WalletInfo.
+type Balances struct {
+	BalanceSat        uint64 `json:"balance_sat"`
+	PendingSendSat    uint64 `json:"pending_send_sat"`
+	PendingReceiveSat uint64 `json:"pending_receive_sat"`
+}
+
+// AssetTotals compares the expected and reported balance of one asset.
+type AssetTotals struct {
+	AssetId     string `json:"asset_id"`
+	Ticker      string `json:"ticker,omitempty"`
+	ReceivedSat uint64 `json:"received_sat"`
+	SentSat     uint64 `json:"sent_sat"`
+	PendingSat  uint64 `json:"pending_send_sat"`
+	ExpectedSat int64  `json:"expected_sat"`
+	ActualSat   uint64 `json:"actual_sat"`
+	Reported    bool   `json:"reported"`
+}
+
+// StateTotals sums the payments of one PaymentType and PaymentState.
+type StateTotals struct {
+	AssetId     string `json:"asset_id"`
+	PaymentType string `json:"payment_type"`
+	State       string `json:"state"`
+	Count       int    `json:"count"`
+	AmountSat   uint64 `json:"amount_sat"`
+	FeesSat     uint64 `json:"fees_sat"`
+}
+
+// Discrepancy describes a WalletInfo value that does not match the value
+// recomputed from the payment history.
+type Discrepancy struct {
+	Field       Field        `json:"field"`
+	AssetId     string       `json:"asset_id"`
+	ExpectedSat int64        `json:"expected_sat"`
+	ActualSat   uint64       `json:"actual_sat"`
+	DiffSat     int64        `json:"diff_sat"`
+	Payments    []PaymentRef `json:"payments"`
+}
+
+// PaymentRef identifies a payment that may be responsible for a Discrepancy.
+type PaymentRef struct {
+	Id          string `json:"id"`
+	TxId        string `json:"tx_id,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Timestamp   uint32 `json:"timestamp"`
+	PaymentType string `json:"payment_type"`
+	State       string `json:"state"`
+	AmountSat   uint64 `json:"amount_sat"`
+	FeesSat     uint64 `json:"fees_sat"`
+	// ExactMatch is set when the payment alone accounts for the whole
+	// difference.
+	ExactMatch bool   `json:"exact_match"`
+	Reason     string `json:"reason"`
+}
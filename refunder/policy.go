@@ -0,0 +1,122 @@
+package refunder
+
+import (
+	"errors"
+	"math"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+)
+
+// AddressProvider returns the Bitcoin address a refundable swap is refunded
+// to. It is called once per swap; the address is persisted and reused for
+// every later broadcast of the same refund.
+type AddressProvider interface {
+	RefundAddress(swap breez_sdk_liquid.RefundableSwap) (string, error)
+}
+
+// AddressProviderFunc adapts a function to the AddressProvider interface.
+type AddressProviderFunc func(swap breez_sdk_liquid.RefundableSwap) (string, error)
+
+func (f AddressProviderFunc) RefundAddress(swap breez_sdk_liquid.RefundableSwap) (string, error) {
+	return f(swap)
+}
+
+// StaticAddress refunds every swap to the same address.
+func StaticAddress(address string) AddressProvider {
+	return AddressProviderFunc(func(breez_sdk_liquid.RefundableSwap) (string, error) {
+		if address == "" {
+			return "", errors.New("no refund address configured")
+		}
+		return address, nil
+	})
+}
+
+// Priority selects one of the RecommendedFees rates.
+type Priority string
+
+const (
+	PriorityFastest  Priority = "fastest"
+	PriorityHalfHour Priority = "half_hour"
+	PriorityHour     Priority = "hour"
+	PriorityEconomy  Priority = "economy"
+	PriorityMinimum  Priority = "minimum"
+)
+
+func (p Priority) rate(fees breez_sdk_liquid.RecommendedFees) uint64 {
+	switch p {
+	case PriorityFastest:
+		return fees.FastestFee
+	case PriorityHalfHour:
+		return fees.HalfHourFee
+	case PriorityEconomy:
+		return fees.EconomyFee
+	case PriorityMinimum:
+		return fees.MinimumFee
+	default:
+		return fees.HourFee
+	}
+}
+
+// FeePolicy chooses the fee rate of refund transactions.
+type FeePolicy struct {
+	// Priority of the initial refund. Defaults to PriorityHour.
+	Priority Priority
+	// BumpPriority is the minimum priority used when re-broadcasting.
+	// Defaults to PriorityHalfHour.
+	BumpPriority Priority
+	// BumpFactor multiplies the previous fee rate when re-broadcasting.
+	// Defaults to 1.5.
+	BumpFactor float64
+	// MinFeeRate and MaxFeeRate bound the chosen rate, in sat/vbyte.
+	// MaxFeeRate defaults to 100 sat/vbyte.
+	MinFeeRate uint32
+	MaxFeeRate uint32
+}
+
+func (p *FeePolicy) setDefaults() {
+	if p.Priority == "" {
+		p.Priority = PriorityHour
+	}
+	if p.BumpPriority == "" {
+		p.BumpPriority = PriorityHalfHour
+	}
+	if p.BumpFactor <= 1 {
+		p.BumpFactor = 1.5
+	}
+	if p.MaxFeeRate == 0 {
+		p.MaxFeeRate = 100
+	}
+}
+
+// initialRate returns the rate of the first refund transaction.
+func (p FeePolicy) initialRate(fees breez_sdk_liquid.RecommendedFees) uint32 {
+	return p.clamp(p.Priority.rate(fees))
+}
+
+// bumpedRate returns the rate of a replacement for a refund broadcast at
+// previous sat/vbyte. It is always strictly higher than previous unless
+// MaxFeeRate is reached, in which case ok is false.
+func (p FeePolicy) bumpedRate(fees breez_sdk_liquid.RecommendedFees, previous uint32) (uint32, bool) {
+	rate := uint64(math.Ceil(float64(previous) * p.BumpFactor))
+	if recommended := p.BumpPriority.rate(fees); recommended > rate {
+		rate = recommended
+	}
+	if rate <= uint64(previous) {
+		rate = uint64(previous) + 1
+	}
+	bumped := p.clamp(rate)
+	return bumped, bumped > previous
+}
+
+func (p FeePolicy) clamp(rate uint64) uint32 {
+	if rate < uint64(p.MinFeeRate) {
+		rate = uint64(p.MinFeeRate)
+	}
+	if rate > uint64(p.MaxFeeRate) {
+		rate = uint64(p.MaxFeeRate)
+	}
+	if rate == 0 {
+		rate = 1
+	}
+	return uint32(rate)
+}
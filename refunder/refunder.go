@@ -0,0 +1,425 @@
+// Package refunder automatically refunds failed Bitcoin swaps. It watches the
+// refundable swaps, refunds them to an address chosen by an AddressProvider at
+// a fee rate chosen from the recommended fees, and re-broadcasts the refund
+// with a higher fee rate while it stays unconfirmed. Progress is persisted so
+// that a restart never refunds a swap to a different address or acts twice on
+// the same attempt.
+package refunder
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+const (
+	// DefaultInterval is the polling interval used when Options.Interval
+	// is not set.
+	DefaultInterval = 10 * time.Minute
+	// DefaultRebroadcastAfter is how long a refund may stay unconfirmed
+	// before it is replaced, used when Options.RebroadcastAfter is not set.
+	DefaultRebroadcastAfter = 6 * time.Hour
+)
+
+// Status of a tracked swap.
+type Status string
+
+const (
+	// StatusPending means a refund is about to be broadcast, or that its
+	// broadcast failed with an error which does not rule out that it went
+	// through. A swap in this status is reconciled with LastRefundTxId on
+	// the next run, and otherwise refunded again at a higher fee rate.
+	StatusPending Status = "pending"
+	// StatusBroadcast means a refund was broadcast and awaits confirmation.
+	StatusBroadcast Status = "broadcast"
+	// StatusFailed means the last attempt failed; it is retried on the
+	// next run.
+	StatusFailed Status = "failed"
+	// StatusDone means the swap is no longer refundable.
+	StatusDone Status = "done"
+)
+
+// Attempt is a single refund broadcast.
+type Attempt struct {
+	At                 time.Time `json:"at"`
+	FeeRateSatPerVbyte uint32    `json:"fee_rate_sat_per_vbyte"`
+	TxFeeSat           uint64    `json:"tx_fee_sat"`
+	TxId               string    `json:"tx_id,omitempty"`
+	Error              string    `json:"error,omitempty"`
+}
+
+// Swap is the persisted progress of a refundable swap.
+type Swap struct {
+	SwapAddress   string    `json:"swap_address"`
+	AmountSat     uint64    `json:"amount_sat"`
+	RefundAddress string    `json:"refund_address"`
+	Status        Status    `json:"status"`
+	Attempts      []Attempt `json:"attempts"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// broadcast reports whether txId is the transaction of a recorded attempt.
+func (s *Swap) broadcast(txId string) bool {
+	for _, a := range s.Attempts {
+		if a.TxId == txId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Swap) last() *Attempt {
+	if len(s.Attempts) == 0 {
+		return nil
+	}
+	return &s.Attempts[len(s.Attempts)-1]
+}
+
+// Options configures a Manager.
+type Options struct {
+	// StatePath is the file the progress is persisted to. Required.
+	StatePath string
+	// Addresses provides refund addresses. Required.
+	Addresses AddressProvider
+	// FeePolicy chooses the refund fee rates.
+	FeePolicy FeePolicy
+	// Interval between scans of the refundable swaps. Defaults to
+	// DefaultInterval.
+	Interval time.Duration
+	// RebroadcastAfter is how long a refund may stay unconfirmed before
+	// it is replaced. Defaults to DefaultRebroadcastAfter.
+	RebroadcastAfter time.Duration
+	// OnUpdate, if set, is called whenever the progress of a swap changes.
+	OnUpdate func(Swap)
+}
+
+// Manager refunds refundable swaps.
+type Manager struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	trigger chan struct{}
+
+	mu    sync.Mutex
+	swaps map[string]*Swap
+	dirty bool
+	// inFlight holds the swaps being refunded, so that concurrent scans
+	// never refund the same swap twice.
+	inFlight map[string]bool
+}
+
+// New creates a Manager and loads its persisted progress.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Manager, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("refunder: StatePath is required")
+	}
+	if opts.Addresses == nil {
+		return nil, errors.New("refunder: Addresses is required")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.RebroadcastAfter <= 0 {
+		opts.RebroadcastAfter = DefaultRebroadcastAfter
+	}
+	opts.FeePolicy.setDefaults()
+
+	m := &Manager{
+		sdk:      sdk,
+		opts:     opts,
+		trigger:  make(chan struct{}, 1),
+		swaps:    map[string]*Swap{},
+		inFlight: map[string]bool{},
+	}
+	if err := filestore.Load(opts.StatePath, &m.swaps); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Swaps returns the tracked swaps ordered by swap address.
+func (m *Manager) Swaps() []Swap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	swaps := make([]Swap, 0, len(m.swaps))
+	for _, swap := range m.swaps {
+		swaps = append(swaps, *swap)
+	}
+	sort.Slice(swaps, func(i, j int) bool {
+		return swaps[i].SwapAddress < swaps[j].SwapAddress
+	})
+	return swaps
+}
+
+// Trigger requests a scan without waiting for the next interval.
+func (m *Manager) Trigger() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run scans the refundable swaps immediately, then on every interval, on
+// every refund related event and on Trigger, until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context) error {
+	listenerId, err := m.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		switch e.(type) {
+		case breez_sdk_liquid.SdkEventPaymentRefundable, breez_sdk_liquid.SdkEventPaymentRefunded:
+			m.Trigger()
+		}
+	}))
+	if err != nil {
+		return err
+	}
+	defer m.sdk.RemoveEventListener(listenerId)
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		_ = m.Scan()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-m.trigger:
+		}
+	}
+}
+
+// Scan performs a single pass over the refundable swaps. Errors refunding
+// individual swaps are recorded in their progress and do not abort the scan:
+// the first one is returned once the other swaps are handled. Swaps being
+// refunded by a concurrent scan are skipped.
+func (m *Manager) Scan() error {
+	refundables, err := m.sdk.ListRefundables()
+	if err != nil {
+		return err
+	}
+	listed := map[string]bool{}
+	for _, refundable := range refundables {
+		listed[refundable.SwapAddress] = true
+	}
+
+	// Swaps that are no longer refundable have been refunded.
+	m.mu.Lock()
+	var done []Swap
+	for address, swap := range m.swaps {
+		if !listed[address] && swap.Status != StatusDone {
+			swap.Status = StatusDone
+			swap.UpdatedAt = time.Now().UTC()
+			done = append(done, *swap)
+		}
+	}
+	m.mu.Unlock()
+	if len(done) > 0 {
+		if err := m.save(); err != nil {
+			return err
+		}
+		for _, swap := range done {
+			m.notify(swap)
+		}
+	}
+
+	var fees *breez_sdk_liquid.RecommendedFees
+	var firstErr error
+	for _, refundable := range refundables {
+		if !m.claim(refundable) {
+			continue
+		}
+		if fees == nil {
+			recommended, err := m.sdk.RecommendedFees()
+			if err != nil {
+				m.release(refundable.SwapAddress)
+				return err
+			}
+			fees = &recommended
+		}
+		err := m.refund(refundable, *fees)
+		m.release(refundable.SwapAddress)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	m.mu.Lock()
+	dirty := m.dirty
+	m.mu.Unlock()
+	if dirty {
+		if err := m.save(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// claim marks the swap in flight if it is due and not already in flight.
+func (m *Manager) claim(refundable breez_sdk_liquid.RefundableSwap) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.inFlight[refundable.SwapAddress] || !m.dueLocked(refundable) {
+		return false
+	}
+	m.inFlight[refundable.SwapAddress] = true
+	return true
+}
+
+func (m *Manager) release(address string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.inFlight, address)
+}
+
+// dueLocked reports whether the swap needs a (new) refund broadcast.
+func (m *Manager) dueLocked(refundable breez_sdk_liquid.RefundableSwap) bool {
+	swap, ok := m.swaps[refundable.SwapAddress]
+	if !ok {
+		return true
+	}
+	last := swap.last()
+	switch swap.Status {
+	case StatusPending:
+		// Interrupted before the outcome was recorded. If the SDK knows
+		// of a refund transaction other than those already recorded, it
+		// is the outcome: adopt it instead of broadcasting again.
+		if refundable.LastRefundTxId != nil && last != nil && !swap.broadcast(*refundable.LastRefundTxId) {
+			last.TxId = *refundable.LastRefundTxId
+			swap.Status = StatusBroadcast
+			swap.UpdatedAt = time.Now().UTC()
+			m.dirty = true
+			return false
+		}
+		if last != nil && last.Error != "" {
+			// The broadcast may have gone through: wait as for a
+			// broadcast refund.
+			return time.Since(last.At) >= m.opts.RebroadcastAfter
+		}
+		return true
+	case StatusBroadcast:
+		return last == nil || time.Since(last.At) >= m.opts.RebroadcastAfter
+	default:
+		return true
+	}
+}
+
+func (m *Manager) refund(refundable breez_sdk_liquid.RefundableSwap, fees breez_sdk_liquid.RecommendedFees) error {
+	m.mu.Lock()
+	swap, ok := m.swaps[refundable.SwapAddress]
+	// Any replacement must pay more than the last broadcast refund, which
+	// may be the last attempt of a pending swap.
+	var previous *Attempt
+	if ok {
+		for i := len(swap.Attempts) - 1; i >= 0; i-- {
+			if swap.Attempts[i].TxId != "" || swap.Status == StatusPending && i == len(swap.Attempts)-1 {
+				copied := swap.Attempts[i]
+				previous = &copied
+				break
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		address, err := m.opts.Addresses.RefundAddress(refundable)
+		if err != nil {
+			return err
+		}
+		swap = &Swap{
+			SwapAddress:   refundable.SwapAddress,
+			AmountSat:     refundable.AmountSat,
+			RefundAddress: address,
+		}
+		m.mu.Lock()
+		m.swaps[refundable.SwapAddress] = swap
+		m.mu.Unlock()
+	}
+
+	rate := m.opts.FeePolicy.initialRate(fees)
+	if previous != nil {
+		bumped, ok := m.opts.FeePolicy.bumpedRate(fees, previous.FeeRateSatPerVbyte)
+		if !ok {
+			// Already at the maximum fee rate, keep waiting.
+			return nil
+		}
+		rate = bumped
+	}
+
+	prepared, err := m.sdk.PrepareRefund(breez_sdk_liquid.PrepareRefundRequest{
+		SwapAddress:        refundable.SwapAddress,
+		RefundAddress:      swap.RefundAddress,
+		FeeRateSatPerVbyte: rate,
+	})
+	if err != nil {
+		return m.update(swap, StatusFailed, Attempt{At: time.Now().UTC(), FeeRateSatPerVbyte: rate, Error: err.Error()})
+	}
+
+	// Persist the intent before broadcasting so that an interrupted
+	// refund is detected on restart.
+	attempt := Attempt{At: time.Now().UTC(), FeeRateSatPerVbyte: rate, TxFeeSat: prepared.TxFeeSat}
+	if err := m.update(swap, StatusPending, attempt); err != nil {
+		return err
+	}
+	res, refundErr := m.sdk.Refund(breez_sdk_liquid.RefundRequest{
+		SwapAddress:        refundable.SwapAddress,
+		RefundAddress:      swap.RefundAddress,
+		FeeRateSatPerVbyte: rate,
+	})
+	m.mu.Lock()
+	last := swap.last()
+	status := StatusBroadcast
+	if refundErr != nil {
+		last.Error = refundErr.Error()
+		status = StatusFailed
+		if sdkutil.Ambiguous(refundErr.AsError()) {
+			status = StatusPending
+		}
+	} else {
+		last.TxId = res.RefundTxId
+	}
+	swap.Status = status
+	swap.UpdatedAt = time.Now().UTC()
+	updated := *swap
+	m.mu.Unlock()
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.notify(updated)
+	return nil
+}
+
+// update appends an attempt to the swap, sets its status and persists it.
+func (m *Manager) update(swap *Swap, status Status, attempt Attempt) error {
+	m.mu.Lock()
+	swap.Attempts = append(swap.Attempts, attempt)
+	swap.Status = status
+	swap.UpdatedAt = time.Now().UTC()
+	updated := *swap
+	m.mu.Unlock()
+	if err := m.save(); err != nil {
+		return err
+	}
+	m.notify(updated)
+	return nil
+}
+
+func (m *Manager) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := filestore.Save(m.opts.StatePath, m.swaps); err != nil {
+		return err
+	}
+	m.dirty = false
+	return nil
+}
+
+func (m *Manager) notify(swap Swap) {
+	if m.opts.OnUpdate != nil {
+		swap.Attempts = append([]Attempt(nil), swap.Attempts...)
+		m.opts.OnUpdate(swap)
+	}
+}
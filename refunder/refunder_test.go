@@ -0,0 +1,168 @@
+package refunder
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk lists refundables and records the refunds broadcast.
+type fakeSdk struct {
+	refundables []breez_sdk_liquid.RefundableSwap
+	// refundErrs fail the next refunds, one each.
+	refundErrs []*breez_sdk_liquid.PaymentError
+	refunds    []breez_sdk_liquid.RefundRequest
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		switch call.Method {
+		case "ListRefundables":
+			return f.refundables, nil
+		case "RecommendedFees":
+			return breez_sdk_liquid.RecommendedFees{FastestFee: 20, HalfHourFee: 10, HourFee: 5, EconomyFee: 2, MinimumFee: 1}, nil
+		case "PrepareRefund":
+			return breez_sdk_liquid.PrepareRefundResponse{TxFeeSat: 200}, nil
+		case "Refund":
+			f.refunds = append(f.refunds, call.Request.(breez_sdk_liquid.RefundRequest))
+			if len(f.refundErrs) > 0 {
+				err := f.refundErrs[0]
+				f.refundErrs = f.refundErrs[1:]
+				return nil, err
+			}
+			return breez_sdk_liquid.RefundResponse{RefundTxId: fmt.Sprintf("tx%d", len(f.refunds))}, nil
+		}
+		return nil, nil
+	})
+}
+
+func newManager(t *testing.T, f *fakeSdk) *Manager {
+	t.Helper()
+	m, err := New(f.sdk(), Options{
+		StatePath: filepath.Join(t.TempDir(), "refunder.json"),
+		Addresses: StaticAddress("bc1refund"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// age makes the last attempt of a swap older than RebroadcastAfter.
+func age(m *Manager, address string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.swaps[address].last().At = time.Now().Add(-m.opts.RebroadcastAfter)
+}
+
+func TestRefund(t *testing.T) {
+	f := &fakeSdk{refundables: []breez_sdk_liquid.RefundableSwap{{SwapAddress: "bc1swap", AmountSat: 10000}}}
+	m := newManager(t, f)
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	swaps := m.Swaps()
+	if len(swaps) != 1 || swaps[0].Status != StatusBroadcast || swaps[0].last().TxId != "tx1" {
+		t.Fatalf("after refund: %+v", swaps)
+	}
+	if len(f.refunds) != 1 || f.refunds[0].RefundAddress != "bc1refund" || f.refunds[0].FeeRateSatPerVbyte != 5 {
+		t.Fatalf("refunds %+v", f.refunds)
+	}
+
+	// Not replaced before RebroadcastAfter, then at a higher rate.
+	if err := m.Scan(); err != nil || len(f.refunds) != 1 {
+		t.Fatalf("rescan: %v, %d refunds", err, len(f.refunds))
+	}
+	age(m, "bc1swap")
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.refunds) != 2 || f.refunds[1].FeeRateSatPerVbyte != 10 {
+		t.Fatalf("replacement %+v", f.refunds)
+	}
+
+	// Refunded once no longer refundable.
+	f.refundables = nil
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if swaps := m.Swaps(); swaps[0].Status != StatusDone {
+		t.Fatalf("after confirmation: %+v", swaps)
+	}
+}
+
+func TestRefundFailure(t *testing.T) {
+	f := &fakeSdk{
+		refundables: []breez_sdk_liquid.RefundableSwap{{SwapAddress: "bc1swap", AmountSat: 10000}},
+		refundErrs:  []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorInvalidNetwork()},
+	}
+	m := newManager(t, f)
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if swaps := m.Swaps(); swaps[0].Status != StatusFailed || swaps[0].last().Error == "" {
+		t.Fatalf("after failure: %+v", swaps)
+	}
+
+	// A refund which failed for sure is retried on the next scan.
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if swaps := m.Swaps(); swaps[0].Status != StatusBroadcast || len(f.refunds) != 2 || f.refunds[1].FeeRateSatPerVbyte != 5 {
+		t.Fatalf("after retry: %+v, refunds %+v", swaps, f.refunds)
+	}
+}
+
+func TestAmbiguousRefundStaysPending(t *testing.T) {
+	f := &fakeSdk{
+		refundables: []breez_sdk_liquid.RefundableSwap{{SwapAddress: "bc1swap", AmountSat: 10000}},
+		refundErrs:  []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorSendError()},
+	}
+	m := newManager(t, f)
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if swaps := m.Swaps(); swaps[0].Status != StatusPending {
+		t.Fatalf("after ambiguous error: %+v", swaps)
+	}
+
+	// The refund may have been broadcast: it is not broadcast again right
+	// away.
+	if err := m.Scan(); err != nil || len(f.refunds) != 1 {
+		t.Fatalf("rescan: %v, %d refunds", err, len(f.refunds))
+	}
+
+	// A refund transaction reported by the SDK is adopted.
+	txId := "tx-sdk"
+	f.refundables[0].LastRefundTxId = &txId
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if swaps := m.Swaps(); swaps[0].Status != StatusBroadcast || swaps[0].last().TxId != txId || len(f.refunds) != 1 {
+		t.Fatalf("after adoption: %+v, %d refunds", swaps, len(f.refunds))
+	}
+}
+
+func TestAmbiguousRefundReplaced(t *testing.T) {
+	f := &fakeSdk{
+		refundables: []breez_sdk_liquid.RefundableSwap{{SwapAddress: "bc1swap", AmountSat: 10000}},
+		refundErrs:  []*breez_sdk_liquid.PaymentError{breez_sdk_liquid.NewPaymentErrorSendError()},
+	}
+	m := newManager(t, f)
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	// Never reported by the SDK, the refund is replaced after
+	// RebroadcastAfter at a higher rate.
+	age(m, "bc1swap")
+	if err := m.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.refunds) != 2 || f.refunds[1].FeeRateSatPerVbyte <= f.refunds[0].FeeRateSatPerVbyte {
+		t.Fatalf("refunds %+v", f.refunds)
+	}
+}
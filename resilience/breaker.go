@@ -0,0 +1,185 @@
+package resilience
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset BreakerOptions.
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 30 * time.Second
+)
+
+// State of a circuit breaker.
+type State string
+
+const (
+	// StateClosed lets calls through.
+	StateClosed State = "closed"
+	// StateOpen fails calls fast.
+	StateOpen State = "open"
+	// StateHalfOpen lets a single probe call through to test whether the
+	// service recovered.
+	StateHalfOpen State = "half_open"
+)
+
+// BreakerOptions configures a Breaker.
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive transient failures
+	// opening the breaker. Defaults to DefaultFailureThreshold.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before letting a probe
+	// through. Defaults to DefaultOpenTimeout.
+	OpenTimeout time.Duration
+	// OnStateChange is called, outside of the breaker's lock, when the
+	// state changes.
+	OnStateChange func(from, to State)
+}
+
+// BreakerStats is a snapshot of a Breaker, for monitoring.
+type BreakerStats struct {
+	State               State     `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenedAt            time.Time `json:"opened_at,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	Rejected            uint64    `json:"rejected"`
+}
+
+// OpenError is returned by calls rejected while the breaker is open.
+//
+// It converts to the ServiceConnectivity variant of the SDK error types
+// which have one, and to the Generic variant otherwise, so that callers of
+// a wrapped SDK see it as a connectivity failure, and still reach the
+// OpenError with errors.As.
+type OpenError struct {
+	// RetryAt is when the breaker will let a probe through.
+	RetryAt time.Time
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open until %s", e.RetryAt.UTC().Format(time.RFC3339))
+}
+
+// As converts the error to the SDK error types.
+func (e *OpenError) As(target any) bool {
+	switch target := target.(type) {
+	case **breez_sdk_liquid.SdkError:
+		*target = sdkutil.WithCause(sdkutil.SdkErrorWithMessage(breez_sdk_liquid.NewSdkErrorServiceConnectivity(), e.Error()), e)
+	case **breez_sdk_liquid.PaymentError:
+		*target = sdkutil.WithCause(sdkutil.PaymentErrorWithMessage(breez_sdk_liquid.NewPaymentErrorGeneric(), e.Error()), e)
+	case **breez_sdk_liquid.LnUrlPayError:
+		*target = sdkutil.WithCause(breez_sdk_liquid.NewLnUrlPayErrorServiceConnectivity(e.Error()), e)
+	case **breez_sdk_liquid.LnUrlAuthError:
+		*target = sdkutil.WithCause(breez_sdk_liquid.NewLnUrlAuthErrorServiceConnectivity(e.Error()), e)
+	case **breez_sdk_liquid.LnUrlWithdrawError:
+		*target = sdkutil.WithCause(breez_sdk_liquid.NewLnUrlWithdrawErrorServiceConnectivity(e.Error()), e)
+	case **breez_sdk_liquid.NwcError:
+		*target = sdkutil.WithCause(breez_sdk_liquid.NewNwcErrorGeneric(e.Error()), e)
+	default:
+		return false
+	}
+	return true
+}
+
+// Breaker is a circuit breaker opened by consecutive transient failures.
+type Breaker struct {
+	opts BreakerOptions
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+	lastErr  string
+	rejected uint64
+}
+
+// NewBreaker creates a closed Breaker.
+func NewBreaker(opts BreakerOptions) *Breaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = DefaultOpenTimeout
+	}
+	return &Breaker{opts: opts, state: StateClosed}
+}
+
+// Allow returns an *OpenError if a call must fail fast. Otherwise the
+// outcome of the call must be reported with Done.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case StateOpen:
+		retryAt := b.openedAt.Add(b.opts.OpenTimeout)
+		if time.Now().Before(retryAt) {
+			b.rejected++
+			b.mu.Unlock()
+			return &OpenError{RetryAt: retryAt}
+		}
+		b.state, b.probing = StateHalfOpen, true
+	case StateHalfOpen:
+		if b.probing {
+			b.rejected++
+			retryAt := time.Now().Add(b.opts.OpenTimeout)
+			b.mu.Unlock()
+			return &OpenError{RetryAt: retryAt}
+		}
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+	return nil
+}
+
+// Done reports the outcome of an allowed call. Only transient errors count
+// as failures: other errors show that the service answered.
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	from := b.state
+	b.probing = false
+	if err != nil && IsTransient(err) {
+		b.failures++
+		b.lastErr = err.Error()
+		if b.state == StateHalfOpen || b.failures >= b.opts.FailureThreshold {
+			b.state, b.openedAt = StateOpen, time.Now()
+		}
+	} else {
+		b.failures = 0
+		b.state = StateClosed
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// State returns the current state.
+func (b *Breaker) State() State {
+	return b.Stats().State
+}
+
+// Stats returns a snapshot of the breaker.
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStats{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		OpenedAt:            b.openedAt,
+		LastError:           b.lastErr,
+		Rejected:            b.rejected,
+	}
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,254 @@
+// Package resilience retries transient SDK failures and fails fast while the
+// swapper or the chain explorers are unreachable.
+//
+// Retries are per-method: by default only reads and prepare calls, which
+// have no side effect, are retried. Calls executing payments are never
+// retried, as a failed attempt may still have reached the swapper. All calls
+// reaching the network go through a circuit breaker opened by consecutive
+// connectivity failures.
+package resilience
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// Policy is the retry policy of a method.
+type Policy struct {
+	// MaxAttempts is the number of attempts, including the first. Values
+	// below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles on
+	// each retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter randomizes each delay by up to this fraction of it, in [0, 1].
+	Jitter float64
+	// Retryable reports whether an error is worth retrying. Defaults to
+	// IsTransient.
+	Retryable func(err error) bool
+	// BypassBreaker exempts the method from the circuit breaker, for calls
+	// which do not reach the network.
+	BypassBreaker bool
+}
+
+// RetryPolicy is the default policy of idempotent methods.
+var RetryPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.2,
+}
+
+// NoRetryPolicy is the default policy of methods with side effects.
+var NoRetryPolicy = Policy{MaxAttempts: 1}
+
+// LocalPolicy is the default policy of methods not reaching the network.
+var LocalPolicy = Policy{MaxAttempts: 1, BypassBreaker: true}
+
+// DefaultPolicies returns the default policies, keyed by method name.
+// Methods not listed use NoRetryPolicy.
+func DefaultPolicies() map[string]Policy {
+	policies := map[string]Policy{}
+	for _, method := range []string{
+		"FetchFiatRates",
+		"FetchLightningLimits",
+		"FetchOnchainLimits",
+		"FetchPaymentProposedFees",
+		"GetInfo",
+		"GetPayment",
+		"ListFiatCurrencies",
+		"ListPayments",
+		"ListRefundables",
+		"Parse",
+		"PrepareBuyBitcoin",
+		"PrepareLnurlPay",
+		"PreparePayOnchain",
+		"PrepareReceivePayment",
+		"PrepareRefund",
+		"PrepareSendPayment",
+		"RecommendedFees",
+		"RescanOnchainSwaps",
+		"Sync",
+	} {
+		policies[method] = RetryPolicy
+	}
+	for _, method := range []string{
+		"AddEventListener",
+		"CheckMessage",
+		"Disconnect",
+		"RemoveEventListener",
+		"SignMessage",
+		"BindingNwcService.AddEventListener",
+		"BindingNwcService.GetInfo",
+		"BindingNwcService.IsZap",
+		"BindingNwcService.RemoveEventListener",
+		"BindingNwcService.Stop",
+	} {
+		policies[method] = LocalPolicy
+	}
+	return policies
+}
+
+// IsTransient reports whether err is a connectivity failure, or was
+// returned by an open breaker.
+func IsTransient(err error) bool {
+	var open *OpenError
+	return errors.As(err, &open) ||
+		errors.Is(err, breez_sdk_liquid.ErrSdkErrorServiceConnectivity) ||
+		errors.Is(err, breez_sdk_liquid.ErrPaymentErrorPairsNotFound) ||
+		errors.Is(err, breez_sdk_liquid.ErrLnUrlPayErrorServiceConnectivity) ||
+		errors.Is(err, breez_sdk_liquid.ErrLnUrlAuthErrorServiceConnectivity) ||
+		errors.Is(err, breez_sdk_liquid.ErrLnUrlWithdrawErrorServiceConnectivity)
+}
+
+// Options configures a Layer.
+type Options struct {
+	// Policies are keyed by method name. Policies specific to the NWC
+	// service are keyed by "BindingNwcService.<method>" and take
+	// precedence. Defaults to DefaultPolicies().
+	Policies map[string]Policy
+	// Default is the policy of methods without one. Defaults to
+	// NoRetryPolicy.
+	Default *Policy
+	// Breaker configures the circuit breaker.
+	Breaker BreakerOptions
+	// OnRetry is called before waiting ahead of a retry.
+	OnRetry func(call *intercept.Call, attempt int, delay time.Duration, err error)
+}
+
+// Layer applies the retry policies and the circuit breaker to the calls
+// going through its interceptor.
+type Layer struct {
+	opts    Options
+	breaker *Breaker
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a Layer.
+func New(opts Options) *Layer {
+	if opts.Policies == nil {
+		opts.Policies = DefaultPolicies()
+	}
+	if opts.Default == nil {
+		opts.Default = &NoRetryPolicy
+	}
+	return &Layer{opts: opts, breaker: NewBreaker(opts.Breaker), done: make(chan struct{})}
+}
+
+// Close stops the retries: the calls waiting to be retried return their
+// last error at once, and later calls are attempted only once.
+func (l *Layer) Close() {
+	l.closeOnce.Do(func() { close(l.done) })
+}
+
+// Breaker returns the circuit breaker, for monitoring.
+func (l *Layer) Breaker() *Breaker {
+	return l.breaker
+}
+
+// Wrap returns sdk with the retry policies and the circuit breaker applied.
+func (l *Layer) Wrap(sdk breez_sdk_liquid.BindingLiquidSdkInterface) breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(sdk, l.Interceptor())
+}
+
+// WrapNwc returns nwc with the retry policies and the circuit breaker
+// applied.
+func (l *Layer) WrapNwc(nwc breez_sdk_liquid.BindingNwcServiceInterface) breez_sdk_liquid.BindingNwcServiceInterface {
+	return intercept.WrapNwcService(nwc, l.Interceptor())
+}
+
+// Interceptor returns the interceptor applying the policies.
+func (l *Layer) Interceptor() intercept.Interceptor {
+	return func(call *intercept.Call, next intercept.Handler) (any, error) {
+		policy := l.policy(call)
+		retryable := policy.Retryable
+		if retryable == nil {
+			retryable = IsTransient
+		}
+		for attempt := 1; ; attempt++ {
+			res, err := l.attempt(call, next, policy)
+			var open *OpenError
+			if err == nil || attempt >= policy.MaxAttempts || errors.As(err, &open) || !retryable(err) || l.closed() {
+				return res, err
+			}
+			delay := backoff(policy, attempt)
+			if l.opts.OnRetry != nil {
+				l.opts.OnRetry(call, attempt, delay, err)
+			}
+			if !l.wait(delay) {
+				return res, err
+			}
+		}
+	}
+}
+
+func (l *Layer) closed() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait waits for delay and reports whether the layer is still open.
+func (l *Layer) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *Layer) attempt(call *intercept.Call, next intercept.Handler, policy Policy) (any, error) {
+	if policy.BypassBreaker {
+		return next(call)
+	}
+	if err := l.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	res, err := next(call)
+	l.breaker.Done(err)
+	return res, err
+}
+
+func (l *Layer) policy(call *intercept.Call) Policy {
+	if call.Service != "BindingLiquidSdk" {
+		if p, ok := l.opts.Policies[call.Service+"."+call.Method]; ok {
+			return p
+		}
+	}
+	if p, ok := l.opts.Policies[call.Method]; ok {
+		return p
+	}
+	return *l.opts.Default
+}
+
+// backoff returns the delay before the retry following the given attempt.
+func backoff(p Policy, attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(delay))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
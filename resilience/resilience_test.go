@@ -0,0 +1,101 @@
+package resilience
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+// failing returns an SDK failing the first n calls of each method with err,
+// and the number of calls made.
+func failing(l *Layer, n int, err error) (breez_sdk_liquid.BindingLiquidSdkInterface, map[string]int) {
+	calls := map[string]int{}
+	sdk := intercept.WrapLiquidSdk(&sdktest.Fake{BalanceSat: 1000}, l.Interceptor(), func(call *intercept.Call, next intercept.Handler) (any, error) {
+		calls[call.Method]++
+		if calls[call.Method] <= n {
+			return nil, err
+		}
+		return next(call)
+	})
+	return sdk, calls
+}
+
+var fastRetry = Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+func TestRetry(t *testing.T) {
+	var retries int
+	l := New(Options{
+		Policies: map[string]Policy{"GetInfo": fastRetry},
+		OnRetry:  func(*intercept.Call, int, time.Duration, error) { retries++ },
+	})
+	sdk, calls := failing(l, 2, breez_sdk_liquid.NewSdkErrorServiceConnectivity())
+	info, err := sdk.GetInfo()
+	if err != nil || info.WalletInfo.BalanceSat != 1000 {
+		t.Fatalf("got %+v, %v", info, err)
+	}
+	if calls["GetInfo"] != 3 || retries != 2 {
+		t.Fatalf("%d calls, %d retries", calls["GetInfo"], retries)
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	l := New(Options{Policies: map[string]Policy{"GetInfo": fastRetry}})
+	// Errors which are not transient.
+	sdk, calls := failing(l, 1, breez_sdk_liquid.NewSdkErrorGeneric())
+	if _, err := sdk.GetInfo(); !errors.Is(err, breez_sdk_liquid.ErrSdkErrorGeneric) || calls["GetInfo"] != 1 {
+		t.Fatalf("got %v after %d calls", err, calls["GetInfo"])
+	}
+	// Methods with side effects.
+	sdk, calls = failing(l, 1, breez_sdk_liquid.NewPaymentErrorPairsNotFound())
+	if _, err := sdk.SendPayment(sdktest.SendRequest("lq1", 1000, 0)); err == nil || calls["SendPayment"] != 1 {
+		t.Fatalf("got %v after %d calls", err, calls["SendPayment"])
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	l := New(Options{Policies: map[string]Policy{}, Breaker: BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Hour}})
+	sdk, calls := failing(l, 2, breez_sdk_liquid.NewSdkErrorServiceConnectivity())
+	for i := 0; i < 2; i++ {
+		if err := sdk.Sync(); err == nil {
+			t.Fatal("no error")
+		}
+	}
+	if l.Breaker().State() != StateOpen {
+		t.Fatalf("breaker %s", l.Breaker().State())
+	}
+	// Failing fast, as a connectivity failure.
+	err := sdk.Sync()
+	var open *OpenError
+	if !errors.As(err, &open) || !errors.Is(err, breez_sdk_liquid.ErrSdkErrorServiceConnectivity) || calls["Sync"] != 2 {
+		t.Fatalf("got %v after %d calls", err, calls["Sync"])
+	}
+}
+
+func TestCloseStopsBackoff(t *testing.T) {
+	l := New(Options{Policies: map[string]Policy{"GetInfo": {MaxAttempts: 3, InitialBackoff: time.Hour}}})
+	sdk, calls := failing(l, 3, breez_sdk_liquid.NewSdkErrorServiceConnectivity())
+	done := make(chan error)
+	go func() {
+		_, err := sdk.GetInfo()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, breez_sdk_liquid.ErrSdkErrorServiceConnectivity) {
+			t.Fatalf("got %v, want the last error", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("retry still waiting after Close")
+	}
+
+	// Later calls are not retried.
+	if _, err := sdk.GetInfo(); err == nil || calls["GetInfo"] != 2 {
+		t.Fatalf("got %v after %d calls", err, calls["GetInfo"])
+	}
+}
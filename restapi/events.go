@@ -0,0 +1,73 @@
+package restapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// serveEvents streams the SDK events as Server-Sent Events, named after
+// the event variant, such as "payment_succeeded". Events are dropped while
+// the client's buffer is full; a "dropped" event reports how many.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, sdkjson.Error{Type: "method_not_allowed", Message: "GET required"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, sdkjson.Error{Type: "error", Message: "streaming unsupported"})
+		return
+	}
+
+	events := make(chan breez_sdk_liquid.SdkEvent, s.opts.EventBuffer)
+	var dropped atomic.Int64
+	listenerId, sdkErr := s.sdk.AddEventListener(sdkutil.EventListenerFunc(func(e breez_sdk_liquid.SdkEvent) {
+		select {
+		case events <- e:
+		default:
+			dropped.Add(1)
+		}
+	}))
+	if sdkErr != nil {
+		status, body := errorResponse(sdkErr)
+		writeError(w, status, body)
+		return
+	}
+	defer func() { _ = s.sdk.RemoveEventListener(listenerId) }()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(s.opts.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e := <-events:
+			if lost := dropped.Swap(0); lost > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", lost)
+			}
+			data, err := json.Marshal(sdkjson.Encode(e))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", sdkjson.VariantTag(e), data)
+		}
+		flusher.Flush()
+	}
+}
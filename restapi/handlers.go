@@ -0,0 +1,223 @@
+package restapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+)
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(OpenAPI)
+	})
+	s.mux.HandleFunc("/v1/events", s.serveEvents)
+
+	s.handle(http.MethodGet, "/v1/info", func(*http.Request) (any, error) {
+		res, err := s.sdk.GetInfo()
+		return res, err.AsError()
+	})
+	s.handle(http.MethodPost, "/v1/sync", func(*http.Request) (any, error) {
+		return struct{}{}, s.sdk.Sync().AsError()
+	})
+	post(s, "/v1/parse", func(req struct{ Input string }) (any, error) {
+		res, err := s.sdk.Parse(req.Input)
+		return res, err.AsError()
+	})
+
+	// Sends.
+	post(s, "/v1/send/prepare", func(req breez_sdk_liquid.PrepareSendRequest) (any, error) {
+		res, err := s.sdk.PrepareSendPayment(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/send", func(req breez_sdk_liquid.SendPaymentRequest) (any, error) {
+		res, err := s.sdk.SendPayment(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/onchain/prepare", func(req breez_sdk_liquid.PreparePayOnchainRequest) (any, error) {
+		res, err := s.sdk.PreparePayOnchain(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/onchain/pay", func(req breez_sdk_liquid.PayOnchainRequest) (any, error) {
+		res, err := s.sdk.PayOnchain(req)
+		return res, err.AsError()
+	})
+
+	// Receives.
+	post(s, "/v1/receive/prepare", func(req breez_sdk_liquid.PrepareReceiveRequest) (any, error) {
+		res, err := s.sdk.PrepareReceivePayment(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/receive", func(req breez_sdk_liquid.ReceivePaymentRequest) (any, error) {
+		res, err := s.sdk.ReceivePayment(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/receive/proposed-fees", func(req breez_sdk_liquid.FetchPaymentProposedFeesRequest) (any, error) {
+		res, err := s.sdk.FetchPaymentProposedFees(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/receive/accept-fees", func(req breez_sdk_liquid.AcceptPaymentProposedFeesRequest) (any, error) {
+		return struct{}{}, s.sdk.AcceptPaymentProposedFees(req).AsError()
+	})
+
+	// Payments.
+	s.handle(http.MethodGet, "/v1/payments", s.listPayments)
+	s.handle(http.MethodGet, "/v1/payments/", s.getPayment)
+
+	// Limits and fees.
+	s.handle(http.MethodGet, "/v1/limits/lightning", func(*http.Request) (any, error) {
+		res, err := s.sdk.FetchLightningLimits()
+		return res, err.AsError()
+	})
+	s.handle(http.MethodGet, "/v1/limits/onchain", func(*http.Request) (any, error) {
+		res, err := s.sdk.FetchOnchainLimits()
+		return res, err.AsError()
+	})
+	s.handle(http.MethodGet, "/v1/fees/recommended", func(*http.Request) (any, error) {
+		res, err := s.sdk.RecommendedFees()
+		return res, err.AsError()
+	})
+
+	// Refunds.
+	s.handle(http.MethodGet, "/v1/refunds", func(*http.Request) (any, error) {
+		res, err := s.sdk.ListRefundables()
+		return res, err.AsError()
+	})
+	post(s, "/v1/refunds/prepare", func(req breez_sdk_liquid.PrepareRefundRequest) (any, error) {
+		res, err := s.sdk.PrepareRefund(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/refunds", func(req breez_sdk_liquid.RefundRequest) (any, error) {
+		res, err := s.sdk.Refund(req)
+		return res, err.AsError()
+	})
+	s.handle(http.MethodPost, "/v1/refunds/rescan", func(*http.Request) (any, error) {
+		return struct{}{}, s.sdk.RescanOnchainSwaps().AsError()
+	})
+
+	// LNURL.
+	post(s, "/v1/lnurl/pay/prepare", func(req breez_sdk_liquid.PrepareLnUrlPayRequest) (any, error) {
+		res, err := s.sdk.PrepareLnurlPay(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/lnurl/pay", func(req breez_sdk_liquid.LnUrlPayRequest) (any, error) {
+		res, err := s.sdk.LnurlPay(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/lnurl/withdraw", func(req breez_sdk_liquid.LnUrlWithdrawRequest) (any, error) {
+		res, err := s.sdk.LnurlWithdraw(req)
+		return res, err.AsError()
+	})
+	post(s, "/v1/lnurl/auth", func(req breez_sdk_liquid.LnUrlAuthRequestData) (any, error) {
+		res, err := s.sdk.LnurlAuth(req)
+		return res, err.AsError()
+	})
+
+	// Fiat.
+	s.handle(http.MethodGet, "/v1/fiat/rates", func(*http.Request) (any, error) {
+		res, err := s.sdk.FetchFiatRates()
+		return res, err.AsError()
+	})
+	s.handle(http.MethodGet, "/v1/fiat/currencies", func(*http.Request) (any, error) {
+		res, err := s.sdk.ListFiatCurrencies()
+		return res, err.AsError()
+	})
+}
+
+// listPayments serves GET /v1/payments. The query parameters types and
+// states take comma-separated names, from_timestamp and to_timestamp Unix
+// times.
+func (s *Server) listPayments(r *http.Request) (any, error) {
+	q := r.URL.Query()
+	var req breez_sdk_liquid.ListPaymentsRequest
+	var err error
+	if v := q.Get("types"); v != "" {
+		var types []breez_sdk_liquid.PaymentType
+		if err := sdkjson.Decode(splitList(v), &types); err != nil {
+			return nil, &badRequestError{msg: "types: " + err.Error()}
+		}
+		req.Filters = &types
+	}
+	if v := q.Get("states"); v != "" {
+		var states []breez_sdk_liquid.PaymentState
+		if err := sdkjson.Decode(splitList(v), &states); err != nil {
+			return nil, &badRequestError{msg: "states: " + err.Error()}
+		}
+		req.States = &states
+	}
+	if req.FromTimestamp, err = queryInt[int64](q.Get("from_timestamp"), "from_timestamp"); err != nil {
+		return nil, err
+	}
+	if req.ToTimestamp, err = queryInt[int64](q.Get("to_timestamp"), "to_timestamp"); err != nil {
+		return nil, err
+	}
+	if req.Offset, err = queryInt[uint32](q.Get("offset"), "offset"); err != nil {
+		return nil, err
+	}
+	if req.Limit, err = queryInt[uint32](q.Get("limit"), "limit"); err != nil {
+		return nil, err
+	}
+	if v := q.Get("sort_ascending"); v != "" {
+		asc, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, &badRequestError{msg: "sort_ascending: expected boolean"}
+		}
+		req.SortAscending = &asc
+	}
+	res, sdkErr := s.sdk.ListPayments(req)
+	if sdkErr != nil {
+		return nil, sdkErr
+	}
+	if res == nil {
+		res = []breez_sdk_liquid.Payment{}
+	}
+	return res, nil
+}
+
+// getPayment serves GET /v1/payments/{id}, id being a payment hash or,
+// with ?by=swap_id, a swap id.
+func (s *Server) getPayment(r *http.Request) (any, error) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/payments/")
+	if id == "" || strings.Contains(id, "/") {
+		return nil, &notFoundError{msg: "no such endpoint"}
+	}
+	var req breez_sdk_liquid.GetPaymentRequest
+	switch by := r.URL.Query().Get("by"); by {
+	case "", "payment_hash":
+		req = breez_sdk_liquid.GetPaymentRequestPaymentHash{PaymentHash: id}
+	case "swap_id":
+		req = breez_sdk_liquid.GetPaymentRequestSwapId{SwapId: id}
+	default:
+		return nil, &badRequestError{msg: "by: expected payment_hash or swap_id"}
+	}
+	res, err := s.sdk.GetPayment(req)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, &notFoundError{msg: "payment not found"}
+	}
+	return res, nil
+}
+
+func splitList(v string) []any {
+	var items []any
+	for _, item := range strings.Split(v, ",") {
+		items = append(items, strings.TrimSpace(item))
+	}
+	return items
+}
+
+func queryInt[T int64 | uint32](v, name string) (*T, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var n T
+	if err := sdkjson.Unmarshal([]byte(v), &n); err != nil {
+		return nil, &badRequestError{msg: name + ": expected integer"}
+	}
+	return &n, nil
+}
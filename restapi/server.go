@@ -0,0 +1,222 @@
+// Package restapi exposes the SDK over HTTP as JSON endpoints.
+//
+// Request and response bodies are SDK types encoded as described in
+// OpenAPI, the document served at /v1/openapi.json: snake_case members,
+// sum type variants tagged with a "type" member, enums as snake_case
+// strings. Prepare responses are sent back as is in the prepare_response
+// member of the matching send, receive, pay or refund request.
+//
+// Errors are reported with a 4xx or 5xx status and a body such as
+//
+//	{"error": {"type": "payment_error", "variant": "insufficient_funds", "message": "..."}}
+package restapi
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkjson"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultMaxBodySize = 1 << 20
+	DefaultHeartbeat   = 15 * time.Second
+	DefaultEventBuffer = 64
+)
+
+// OpenAPI is the OpenAPI document describing the endpoints.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// Options configures a Server.
+type Options struct {
+	// Token, if set, must be sent by clients as a bearer token.
+	Token string
+	// MaxBodySize caps request bodies. Defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+	// Heartbeat is the interval between comments sent on idle event
+	// streams. Defaults to DefaultHeartbeat.
+	Heartbeat time.Duration
+	// EventBuffer is the number of events buffered per event stream
+	// client; events are dropped while it is full. Defaults to
+	// DefaultEventBuffer.
+	EventBuffer int
+}
+
+// Server is an http.Handler serving the endpoints under /v1/.
+type Server struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+	mux  *http.ServeMux
+	// endpoints are keyed by path and method.
+	endpoints map[string]map[string]endpoint
+}
+
+// New creates a Server.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Server {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = DefaultHeartbeat
+	}
+	if opts.EventBuffer <= 0 {
+		opts.EventBuffer = DefaultEventBuffer
+	}
+	s := &Server{sdk: sdk, opts: opts, mux: http.NewServeMux(), endpoints: map[string]map[string]endpoint{}}
+	s.routes()
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Token != "" && r.URL.Path != "/v1/openapi.json" && !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="breez"`)
+		writeError(w, http.StatusUnauthorized, sdkjson.Error{Type: "unauthorized", Message: "missing or invalid bearer token"})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1
+}
+
+// endpoint handles a request, returning the value to encode as the
+// response body.
+type endpoint func(r *http.Request) (any, error)
+
+// handle registers an endpoint for a method and path. A path may have an
+// endpoint for each method.
+func (s *Server) handle(method, path string, fn endpoint) {
+	if methods, ok := s.endpoints[path]; ok {
+		methods[method] = fn
+		return
+	}
+	methods := map[string]endpoint{method: fn}
+	s.endpoints[path] = methods
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		fn, ok := methods[r.Method]
+		if !ok {
+			allowed := make([]string, 0, len(methods))
+			for method := range methods {
+				allowed = append(allowed, method)
+			}
+			sort.Strings(allowed)
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, sdkjson.Error{Type: "method_not_allowed", Message: strings.Join(allowed, " or ") + " required"})
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.opts.MaxBodySize)
+		res, err := fn(r)
+		if err != nil {
+			status, body := errorResponse(err)
+			writeError(w, status, body)
+			return
+		}
+		writeJSON(w, http.StatusOK, sdkjson.Encode(res))
+	})
+}
+
+// post registers an endpoint decoding its body into a Req.
+func post[Req any](s *Server, path string, fn func(req Req) (any, error)) {
+	s.handle(http.MethodPost, path, func(r *http.Request) (any, error) {
+		var req Req
+		if err := decodeBody(r, &req); err != nil {
+			return nil, err
+		}
+		return fn(req)
+	})
+}
+
+// badRequestError is returned for requests which cannot be decoded.
+type badRequestError struct {
+	msg string
+}
+
+func (e *badRequestError) Error() string {
+	return e.msg
+}
+
+// notFoundError is returned for missing resources.
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string {
+	return e.msg
+}
+
+func decodeBody(r *http.Request, v any) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return &badRequestError{msg: err.Error()}
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		data = []byte("{}")
+	}
+	if err := sdkjson.Unmarshal(data, v); err != nil {
+		return &badRequestError{msg: err.Error()}
+	}
+	return nil
+}
+
+// errorResponse returns the status and body reporting err.
+func errorResponse(err error) (int, sdkjson.Error) {
+	var badRequest *badRequestError
+	if errors.As(err, &badRequest) {
+		return http.StatusBadRequest, sdkjson.Error{Type: "bad_request", Message: badRequest.msg}
+	}
+	var notFound *notFoundError
+	if errors.As(err, &notFound) {
+		return http.StatusNotFound, sdkjson.Error{Type: "not_found", Message: notFound.msg}
+	}
+	body := sdkjson.EncodeError(err)
+	return variantStatus(body.Variant), body
+}
+
+// variantStatus returns the status reporting an SDK error variant.
+func variantStatus(variant string) int {
+	switch variant {
+	case "insufficient_funds", "insufficient_balance", "max_budget_exceeded":
+		return http.StatusUnprocessableEntity
+	case "already_paid", "already_claimed", "payment_in_progress", "already_started", "already_replied", "connection_exists":
+		return http.StatusConflict
+	case "connection_not_found", "pubkey_not_found", "pairs_not_found":
+		return http.StatusNotFound
+	case "service_connectivity", "not_started", "network":
+		return http.StatusServiceUnavailable
+	case "payment_timeout":
+		return http.StatusGatewayTimeout
+	case "route_not_found", "route_too_expensive", "payment_failed", "send_error", "receive_error", "refunded":
+		return http.StatusBadGateway
+	case "amount_out_of_range", "amount_missing", "asset_error", "self_transfer_not_supported", "network_not_supported",
+		"invoice_expired", "invoice_without_amount", "invoice_no_routing_hints", "event_expired":
+		return http.StatusBadRequest
+	}
+	if strings.HasPrefix(variant, "invalid_") {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, body sdkjson.Error) {
+	writeJSON(w, status, map[string]sdkjson.Error{"error": body})
+}
@@ -0,0 +1,138 @@
+package restapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// newServer returns a server on an SDK recording the methods called and
+// returning zero responses, or err.
+func newServer(opts Options, err error) (*Server, *[]string) {
+	var calls []string
+	sdk := intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		calls = append(calls, call.Method)
+		return nil, err
+	})
+	return New(sdk, opts), &calls
+}
+
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestRoutes(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		// call is the SDK method called.
+		call string
+	}{
+		{"GET", "/v1/info", "", "GetInfo"},
+		{"POST", "/v1/sync", "", "Sync"},
+		{"POST", "/v1/parse", `{"input":"lq1"}`, "Parse"},
+		{"POST", "/v1/send/prepare", `{"destination":"lq1"}`, "PrepareSendPayment"},
+		{"POST", "/v1/send", `{"prepare_response":{"destination":{"type":"liquid_address","address_data":{"address":"lq1","network":"bitcoin"}}}}`, "SendPayment"},
+		{"POST", "/v1/onchain/prepare", `{"amount":{"type":"drain"}}`, "PreparePayOnchain"},
+		{"POST", "/v1/onchain/pay", `{"address":"bc1","prepare_response":{}}`, "PayOnchain"},
+		{"POST", "/v1/receive/prepare", `{"payment_method":"liquid_address"}`, "PrepareReceivePayment"},
+		{"POST", "/v1/receive", `{"prepare_response":{"payment_method":"liquid_address"}}`, "ReceivePayment"},
+		{"POST", "/v1/receive/proposed-fees", `{"swap_id":"s"}`, "FetchPaymentProposedFees"},
+		{"POST", "/v1/receive/accept-fees", `{"response":{"swap_id":"s"}}`, "AcceptPaymentProposedFees"},
+		{"GET", "/v1/payments?types=send&states=complete,failed&limit=10", "", "ListPayments"},
+		{"GET", "/v1/limits/lightning", "", "FetchLightningLimits"},
+		{"GET", "/v1/limits/onchain", "", "FetchOnchainLimits"},
+		{"GET", "/v1/fees/recommended", "", "RecommendedFees"},
+		{"GET", "/v1/refunds", "", "ListRefundables"},
+		{"POST", "/v1/refunds/prepare", `{"swap_address":"bc1","refund_address":"bc1","fee_rate_sat_per_vbyte":2}`, "PrepareRefund"},
+		{"POST", "/v1/refunds", `{"swap_address":"bc1","refund_address":"bc1","fee_rate_sat_per_vbyte":2}`, "Refund"},
+		{"POST", "/v1/refunds/rescan", "", "RescanOnchainSwaps"},
+		{"POST", "/v1/lnurl/pay/prepare", `{"data":{},"amount":{"type":"bitcoin","receiver_amount_sat":1}}`, "PrepareLnurlPay"},
+		{"POST", "/v1/lnurl/pay", `{"prepare_response":{}}`, "LnurlPay"},
+		{"POST", "/v1/lnurl/withdraw", `{"data":{},"amount_msat":1000}`, "LnurlWithdraw"},
+		{"POST", "/v1/lnurl/auth", `{}`, "LnurlAuth"},
+		{"GET", "/v1/fiat/rates", "", "FetchFiatRates"},
+		{"GET", "/v1/fiat/currencies", "", "ListFiatCurrencies"},
+	}
+	s, calls := newServer(Options{}, nil)
+	for _, tt := range tests {
+		*calls = nil
+		w := serve(s, tt.method, tt.path, tt.body)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s %s: status %d: %s", tt.method, tt.path, w.Code, w.Body)
+			continue
+		}
+		if len(*calls) != 1 || (*calls)[0] != tt.call {
+			t.Errorf("%s %s called %v, want %s", tt.method, tt.path, *calls, tt.call)
+		}
+	}
+
+	// The SDK finds no payment.
+	if w := serve(s, "GET", "/v1/payments/abc?by=swap_id", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /v1/payments/abc: status %d", w.Code)
+	}
+	if w := serve(s, "GET", "/v1/openapi.json", ""); w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("GET /v1/openapi.json: status %d", w.Code)
+	}
+
+	// The event stream of a client gone after connecting.
+	*calls = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/v1/events", nil).WithContext(ctx))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), ": connected") {
+		t.Errorf("GET /v1/events: status %d: %s", w.Code, w.Body)
+	}
+	if strings.Join(*calls, ",") != "AddEventListener,RemoveEventListener" {
+		t.Errorf("GET /v1/events called %v", *calls)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	s, calls := newServer(Options{}, nil)
+	w := serve(s, "DELETE", "/v1/refunds", "")
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, POST" {
+		t.Fatalf("status %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+	if len(*calls) != 0 {
+		t.Fatalf("called %v", *calls)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s, _ := newServer(Options{Token: "secret"}, breez_sdk_liquid.NewPaymentErrorInsufficientFunds())
+	if w := serve(s, "GET", "/v1/info", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("without token: status %d", w.Code)
+	}
+
+	r := httptest.NewRequest("POST", "/v1/send/prepare", strings.NewReader(`{"destination":"lq1"}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	var body struct {
+		Error struct{ Type, Variant string }
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || body.Error.Type != "payment_error" || body.Error.Variant != "insufficient_funds" {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+
+	r = httptest.NewRequest("POST", "/v1/parse", strings.NewReader(`{"input":1}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body: status %d", w.Code)
+	}
+}
@@ -0,0 +1,767 @@
+// Package spendguard enforces spending limits on outgoing payments.
+//
+// The guard wraps SendPayment, PayOnchain and LnurlPay: spends exceeding the
+// per-transaction, rolling daily and weekly, or per-destination limits are
+// rejected with a *LimitError before reaching the SDK. Limits are set in
+// sats or in a fiat currency. A drain counts the whole balance. Asset
+// payments, which cannot be priced in sats, are rejected while a limit
+// applies, and only count their fees otherwise.
+//
+// Spends made through NWC do not go through the Go bindings: they are
+// counted once reported by the NWC service, and when they exhaust a limit
+// the guard makes the NWC connections receive-only until ResumeNwc is
+// called.
+//
+// Counters, overrides and the audit log are persisted. Temporary overrides
+// replace the limits until they expire or are revoked, and every override
+// and rejection is recorded in the audit log.
+package spendguard
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/payflow"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultPerDestinationWindow = 24 * time.Hour
+	DefaultAuditSize            = 1000
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// Sources of spends.
+const (
+	SourceSdk = "sdk"
+	SourceNwc = "nwc"
+)
+
+// Spend is a counted outgoing payment.
+type Spend struct {
+	Id          string    `json:"id"`
+	At          time.Time `json:"at"`
+	AmountSat   uint64    `json:"amount_sat"`
+	Destination string    `json:"destination,omitempty"`
+	Source      string    `json:"source"`
+	// Connection is the NWC connection of NWC spends.
+	Connection string `json:"connection,omitempty"`
+	PaymentId  string `json:"payment_id,omitempty"`
+}
+
+// Override temporarily replaces the limits.
+type Override struct {
+	Id     string `json:"id"`
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+	// Limits applied while the override is active, nil for no limit.
+	Limits    *Limits    `json:"limits,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (o Override) active(now time.Time) bool {
+	return o.RevokedAt == nil && now.Before(o.ExpiresAt)
+}
+
+// Action recorded in the audit log.
+type Action string
+
+const (
+	ActionSpendRejected   Action = "spend_rejected"
+	ActionOverrideUsed    Action = "override_used"
+	ActionOverrideGranted Action = "override_granted"
+	ActionOverrideRevoked Action = "override_revoked"
+	ActionNwcSuspended    Action = "nwc_suspended"
+	ActionNwcResumed      Action = "nwc_resumed"
+)
+
+// AuditEntry is an entry of the audit log.
+type AuditEntry struct {
+	At          time.Time `json:"at"`
+	Action      Action    `json:"action"`
+	Actor       string    `json:"actor,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	OverrideId  string    `json:"override_id,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	AmountSat   uint64    `json:"amount_sat,omitempty"`
+	Limit       string    `json:"limit,omitempty"`
+}
+
+// Usage is the amount spent within each window.
+type Usage struct {
+	DailySat  uint64 `json:"daily_sat"`
+	WeeklySat uint64 `json:"weekly_sat"`
+}
+
+// Options configures a Guard.
+type Options struct {
+	// StatePath is the file the counters and audit log are persisted to.
+	// Required.
+	StatePath string
+	Limits    Limits
+	// RatesTTL is how long fiat rates are cached. Defaults to
+	// DefaultRatesTTL.
+	RatesTTL time.Duration
+	// AuditSize is the number of audit entries kept. Defaults to
+	// DefaultAuditSize.
+	AuditSize int
+	// OnAudit, if set, is called for every audit entry.
+	OnAudit func(AuditEntry)
+}
+
+type state struct {
+	Spends       []Spend      `json:"spends"`
+	Overrides    []Override   `json:"overrides"`
+	Audit        []AuditEntry `json:"audit"`
+	SuspendedNwc []string     `json:"suspended_nwc,omitempty"`
+}
+
+// Guard enforces the spending limits.
+type Guard struct {
+	sdk   breez_sdk_liquid.BindingLiquidSdkInterface
+	opts  Options
+	rates *rates
+
+	mu            sync.Mutex
+	state         state
+	nwc           breez_sdk_liquid.BindingNwcServiceInterface
+	nwcListenerId string
+}
+
+// New creates a Guard and loads its persisted state.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Guard, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("spendguard: StatePath is required")
+	}
+	if opts.RatesTTL <= 0 {
+		opts.RatesTTL = DefaultRatesTTL
+	}
+	if opts.AuditSize <= 0 {
+		opts.AuditSize = DefaultAuditSize
+	}
+	g := &Guard{sdk: sdk, opts: opts, rates: &rates{sdk: sdk, ttl: opts.RatesTTL}}
+	if err := filestore.Load(opts.StatePath, &g.state); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Wrap returns sdk with the limits enforced on its spends.
+func (g *Guard) Wrap(sdk breez_sdk_liquid.BindingLiquidSdkInterface) breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(sdk, g.Interceptor())
+}
+
+// Interceptor returns the interceptor enforcing the limits on SendPayment,
+// PayOnchain and LnurlPay.
+func (g *Guard) Interceptor() intercept.Interceptor {
+	return func(call *intercept.Call, next intercept.Handler) (any, error) {
+		spend, ok, err := g.spendOf(call)
+		if !ok {
+			return next(call)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if spend.asset {
+			if err := g.checkAsset(spend.destination); err != nil {
+				return nil, err
+			}
+		}
+		id, err := g.Reserve(spend.destination, spend.amountSat)
+		if err != nil {
+			return nil, err
+		}
+		res, err := next(call)
+		switch {
+		case err == nil:
+			g.bind(id, res)
+		case !sdkutil.Ambiguous(err):
+			g.Release(id)
+		}
+		return res, err
+	}
+}
+
+// spend is the spend of a spending call.
+type spend struct {
+	destination string
+	// amountSat includes the fees.
+	amountSat uint64
+	// asset is set for asset payments, whose amount is not priced in sats:
+	// only their fees are counted.
+	asset bool
+}
+
+// spendOf returns the spend of the spending calls. Drains are priced at the
+// balance.
+func (g *Guard) spendOf(call *intercept.Call) (spend, bool, error) {
+	var s spend
+	var amount breez_sdk_liquid.PayAmount
+	switch req := call.Request.(type) {
+	case breez_sdk_liquid.SendPaymentRequest:
+		res := req.PrepareResponse
+		s.destination = payflow.SendDestination(res.Destination)
+		s.amountSat = sdkutil.Uint64Value(res.FeesSat)
+		if res.Amount != nil {
+			amount = *res.Amount
+		}
+		if _, ok := amount.(breez_sdk_liquid.PayAmountBitcoin); ok || amount == nil {
+			s.amountSat += payflow.SendAmountSat(res, 0)
+		}
+	case breez_sdk_liquid.PayOnchainRequest:
+		// The prepare response of a drain has its amount.
+		return spend{destination: req.Address, amountSat: req.PrepareResponse.ReceiverAmountSat + req.PrepareResponse.TotalFeesSat}, true, nil
+	case breez_sdk_liquid.LnUrlPayRequest:
+		res := req.PrepareResponse
+		s.destination = sdkutil.StringValue(res.Data.LnAddress)
+		if s.destination == "" {
+			s.destination = res.Data.Domain
+		}
+		s.amountSat, amount = res.FeesSat, res.Amount
+		if a, ok := amount.(breez_sdk_liquid.PayAmountBitcoin); ok {
+			s.amountSat += a.ReceiverAmountSat
+		}
+	default:
+		return s, false, nil
+	}
+	switch amount.(type) {
+	case breez_sdk_liquid.PayAmountDrain:
+		info, err := g.sdk.GetInfo()
+		if err != nil {
+			return s, true, err.AsError()
+		}
+		// The fees are paid out of the drained balance.
+		s.amountSat = info.WalletInfo.BalanceSat
+	case breez_sdk_liquid.PayAmountAsset:
+		s.asset = true
+	}
+	return s, true, nil
+}
+
+// checkAsset returns ErrAssetPayment, as an SDK error, unless no limit
+// applies.
+func (g *Guard) checkAsset(destination string) error {
+	limits, _, err := g.effectiveLimits()
+	if err != nil {
+		return err
+	}
+	if limits == nil || limits.none() {
+		return nil
+	}
+	now := time.Now().UTC()
+	entry := AuditEntry{At: now, Action: ActionSpendRejected, Source: SourceSdk, Destination: destination, Reason: ErrAssetPayment.Error()}
+	g.mu.Lock()
+	g.auditLocked(entry)
+	_ = g.saveLocked()
+	g.mu.Unlock()
+	g.notify(entry)
+	return assetPaymentError{}
+}
+
+// Check returns a *LimitError if spending amountSat to destination would
+// exceed a limit, without counting it.
+func (g *Guard) Check(destination string, amountSat uint64) error {
+	limits, override, err := g.effectiveLimits()
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if override != nil && limits == nil {
+		return nil
+	}
+	return g.checkLocked(*limits, destination, amountSat, time.Now())
+}
+
+// Reserve counts a spend if it is within the limits, returning its id. A
+// spend which did not happen must be released.
+func (g *Guard) Reserve(destination string, amountSat uint64) (string, error) {
+	limits, override, err := g.effectiveLimits()
+	if err != nil {
+		return "", err
+	}
+	id, err := newId()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	g.mu.Lock()
+	var entries []AuditEntry
+	if limits != nil {
+		if err := g.checkLocked(*limits, destination, amountSat, now); err != nil {
+			var limitErr *LimitError
+			errors.As(err, &limitErr)
+			entry := AuditEntry{At: now, Action: ActionSpendRejected, Source: SourceSdk, Destination: destination, AmountSat: amountSat}
+			if limitErr != nil {
+				entry.Limit = limitErr.Limit
+			}
+			g.auditLocked(entry)
+			_ = g.saveLocked()
+			g.mu.Unlock()
+			g.notify(entry)
+			return "", err
+		}
+	}
+	if override != nil {
+		entry := AuditEntry{At: now, Action: ActionOverrideUsed, OverrideId: override.Id, Actor: override.Actor,
+			Source: SourceSdk, Destination: destination, AmountSat: amountSat}
+		g.auditLocked(entry)
+		entries = append(entries, entry)
+	}
+	g.state.Spends = append(g.state.Spends, Spend{Id: id, At: now, AmountSat: amountSat, Destination: destination, Source: SourceSdk})
+	g.pruneLocked(now)
+	err = g.saveLocked()
+	g.mu.Unlock()
+	for _, entry := range entries {
+		g.notify(entry)
+	}
+	if err != nil {
+		g.Release(id)
+		return "", err
+	}
+	return id, nil
+}
+
+// Release removes a reserved spend.
+func (g *Guard) Release(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, s := range g.state.Spends {
+		if s.Id == id {
+			g.state.Spends = append(g.state.Spends[:i], g.state.Spends[i+1:]...)
+			_ = g.saveLocked()
+			return
+		}
+	}
+}
+
+// bind records the payment of a reserved spend.
+func (g *Guard) bind(id string, res any) {
+	var payment breez_sdk_liquid.Payment
+	switch res := res.(type) {
+	case breez_sdk_liquid.SendPaymentResponse:
+		payment = res.Payment
+	case breez_sdk_liquid.LnUrlPayResultEndpointSuccess:
+		payment = res.Data.Payment
+	default:
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := range g.state.Spends {
+		if g.state.Spends[i].Id == id {
+			g.state.Spends[i].PaymentId = sdkutil.PaymentId(payment)
+			_ = g.saveLocked()
+			return
+		}
+	}
+}
+
+// effectiveLimits returns the limits in force converted to sats, and the
+// active override if any. Nil limits with an override mean no limit.
+func (g *Guard) effectiveLimits() (*sats, *Override, error) {
+	g.mu.Lock()
+	limits := g.opts.Limits
+	var override *Override
+	now := time.Now()
+	for i := len(g.state.Overrides) - 1; i >= 0; i-- {
+		if o := g.state.Overrides[i]; o.active(now) {
+			override = &o
+			break
+		}
+	}
+	g.mu.Unlock()
+	if override != nil {
+		if override.Limits == nil {
+			return nil, override, nil
+		}
+		limits = *override.Limits
+	}
+	converted, err := g.convert(limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	return converted, override, nil
+}
+
+// sats are limits converted to sats, zero meaning no limit.
+type sats struct {
+	limits               Limits
+	perTransaction       uint64
+	daily                uint64
+	weekly               uint64
+	perDestination       uint64
+	perDestinationWindow time.Duration
+}
+
+// none reports whether no limit is set.
+func (s *sats) none() bool {
+	return s.perTransaction == 0 && s.daily == 0 && s.weekly == 0 && s.perDestination == 0
+}
+
+func (g *Guard) convert(l Limits) (*sats, error) {
+	s := &sats{limits: l, perDestinationWindow: l.PerDestinationWindow}
+	if s.perDestinationWindow <= 0 {
+		s.perDestinationWindow = DefaultPerDestinationWindow
+	}
+	var err error
+	for _, c := range []struct {
+		amount Amount
+		dst    *uint64
+	}{
+		{l.PerTransaction, &s.perTransaction},
+		{l.Daily, &s.daily},
+		{l.Weekly, &s.weekly},
+		{l.PerDestination, &s.perDestination},
+	} {
+		if *c.dst, err = g.rates.sats(c.amount); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (g *Guard) checkLocked(s sats, destination string, amountSat uint64, now time.Time) error {
+	exceeded := func(limit string, configured Amount, limitSat, spentSat uint64) error {
+		if limitSat == 0 || spentSat+amountSat <= limitSat {
+			return nil
+		}
+		return &LimitError{
+			Limit:       limit,
+			Destination: destination,
+			AmountSat:   amountSat,
+			SpentSat:    spentSat,
+			LimitSat:    limitSat,
+			Configured:  configured.String(),
+		}
+	}
+	if err := exceeded(LimitPerTransaction, s.limits.PerTransaction, s.perTransaction, 0); err != nil {
+		return err
+	}
+	if err := exceeded(LimitDaily, s.limits.Daily, s.daily, g.spentLocked(now.Add(-day), "")); err != nil {
+		return err
+	}
+	if err := exceeded(LimitWeekly, s.limits.Weekly, s.weekly, g.spentLocked(now.Add(-week), "")); err != nil {
+		return err
+	}
+	if destination != "" {
+		spent := g.spentLocked(now.Add(-s.perDestinationWindow), destination)
+		if err := exceeded(LimitPerDestination, s.limits.PerDestination, s.perDestination, spent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// spentLocked sums the spends since the given time, to destination if set.
+func (g *Guard) spentLocked(since time.Time, destination string) uint64 {
+	var total uint64
+	for _, s := range g.state.Spends {
+		if s.At.After(since) && (destination == "" || s.Destination == destination) {
+			total += s.AmountSat
+		}
+	}
+	return total
+}
+
+// Usage returns the amounts spent within the rolling windows.
+func (g *Guard) Usage() Usage {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Usage{
+		DailySat:  g.spentLocked(now.Add(-day), ""),
+		WeeklySat: g.spentLocked(now.Add(-week), ""),
+	}
+}
+
+// Grant activates an override for the given duration. Nil limits lift all
+// limits.
+func (g *Guard) Grant(actor, reason string, limits *Limits, duration time.Duration) (Override, error) {
+	if actor == "" || reason == "" {
+		return Override{}, errors.New("spendguard: overrides require an actor and a reason")
+	}
+	id, err := newId()
+	if err != nil {
+		return Override{}, err
+	}
+	now := time.Now().UTC()
+	o := Override{Id: id, Actor: actor, Reason: reason, Limits: limits, CreatedAt: now, ExpiresAt: now.Add(duration)}
+	entry := AuditEntry{At: now, Action: ActionOverrideGranted, Actor: actor, Reason: reason, OverrideId: id}
+	g.mu.Lock()
+	g.state.Overrides = append(g.state.Overrides, o)
+	g.auditLocked(entry)
+	err = g.saveLocked()
+	g.mu.Unlock()
+	g.notify(entry)
+	return o, err
+}
+
+// Revoke ends an override before it expires.
+func (g *Guard) Revoke(id, actor, reason string) error {
+	now := time.Now().UTC()
+	g.mu.Lock()
+	for i := range g.state.Overrides {
+		o := &g.state.Overrides[i]
+		if o.Id != id {
+			continue
+		}
+		if !o.active(now) {
+			g.mu.Unlock()
+			return errors.New("spendguard: override not active")
+		}
+		o.RevokedAt = &now
+		entry := AuditEntry{At: now, Action: ActionOverrideRevoked, Actor: actor, Reason: reason, OverrideId: id}
+		g.auditLocked(entry)
+		err := g.saveLocked()
+		g.mu.Unlock()
+		g.notify(entry)
+		return err
+	}
+	g.mu.Unlock()
+	return errors.New("spendguard: override not found")
+}
+
+// Overrides returns the active overrides.
+func (g *Guard) Overrides() []Override {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var active []Override
+	for _, o := range g.state.Overrides {
+		if o.active(now) {
+			active = append(active, o)
+		}
+	}
+	return active
+}
+
+// Audit returns the audit log, oldest first.
+func (g *Guard) Audit() []AuditEntry {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]AuditEntry(nil), g.state.Audit...)
+}
+
+// AttachNwc counts the spends made through the NWC service.
+func (g *Guard) AttachNwc(nwc breez_sdk_liquid.BindingNwcServiceInterface) {
+	listenerId := nwc.AddEventListener(sdkutil.NwcEventListenerFunc(func(e breez_sdk_liquid.NwcEvent) {
+		details, ok := e.Details.(breez_sdk_liquid.NwcEventDetailsPayInvoice)
+		if ok && details.Success && e.ConnectionName != nil {
+			go g.countNwc(*e.ConnectionName)
+		}
+	}))
+	g.mu.Lock()
+	g.nwc, g.nwcListenerId = nwc, listenerId
+	g.mu.Unlock()
+}
+
+// Stop removes the NWC event listener.
+func (g *Guard) Stop() {
+	g.mu.Lock()
+	nwc, listenerId := g.nwc, g.nwcListenerId
+	g.nwc, g.nwcListenerId = nil, ""
+	g.mu.Unlock()
+	if nwc != nil {
+		nwc.RemoveEventListener(listenerId)
+	}
+}
+
+// countNwc counts the payments of an NWC connection not counted yet, and
+// suspends NWC spending if they exhaust a limit.
+func (g *Guard) countNwc(connection string) {
+	g.mu.Lock()
+	nwc := g.nwc
+	g.mu.Unlock()
+	if nwc == nil {
+		return
+	}
+	payments, nwcErr := nwc.ListConnectionPayments(connection)
+	if nwcErr != nil {
+		return
+	}
+	limits, override, err := g.effectiveLimits()
+
+	now := time.Now().UTC()
+	g.mu.Lock()
+	counted := map[string]bool{}
+	for _, s := range g.state.Spends {
+		if s.PaymentId != "" {
+			counted[s.PaymentId] = true
+		}
+	}
+	var breach string
+	for _, p := range payments {
+		at := time.Unix(int64(p.Timestamp), 0).UTC()
+		id := sdkutil.PaymentId(p)
+		if p.PaymentType != breez_sdk_liquid.PaymentTypeSend || counted[id] || at.Before(now.Add(-week)) ||
+			p.Status == breez_sdk_liquid.PaymentStateFailed || p.Status == breez_sdk_liquid.PaymentStateTimedOut {
+			continue
+		}
+		amountSat := p.AmountSat + p.FeesSat
+		if limits != nil && breach == "" {
+			var limitErr *LimitError
+			if errors.As(g.checkLocked(*limits, "", amountSat, now), &limitErr) {
+				breach = limitErr.Limit
+			}
+		}
+		g.state.Spends = append(g.state.Spends, Spend{
+			Id: id, At: at, AmountSat: amountSat, Source: SourceNwc, Connection: connection, PaymentId: id,
+		})
+	}
+	// Suspend once a limit is exhausted, even if not exceeded, as NWC
+	// spends cannot be rejected beforehand.
+	if limits != nil && breach == "" {
+		var limitErr *LimitError
+		if errors.As(g.checkLocked(*limits, "", 1, now), &limitErr) {
+			breach = limitErr.Limit
+		}
+	}
+	_ = g.saveLocked()
+	g.mu.Unlock()
+
+	if err == nil && override == nil && breach != "" {
+		g.suspendNwc(breach)
+	}
+}
+
+// suspendNwc makes the NWC connections able to spend receive-only.
+func (g *Guard) suspendNwc(limit string) {
+	g.mu.Lock()
+	nwc := g.nwc
+	g.mu.Unlock()
+	if nwc == nil {
+		return
+	}
+	connections, err := nwc.ListConnections()
+	if err != nil {
+		return
+	}
+	var suspended []string
+	for name, conn := range connections {
+		if conn.ReceiveOnly {
+			continue
+		}
+		receiveOnly := true
+		if _, err := nwc.EditConnection(breez_sdk_liquid.EditConnectionRequest{Name: name, ReceiveOnly: &receiveOnly}); err == nil {
+			suspended = append(suspended, name)
+		}
+	}
+	if len(suspended) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	g.mu.Lock()
+	g.state.SuspendedNwc = append(g.state.SuspendedNwc, suspended...)
+	var entries []AuditEntry
+	for _, name := range suspended {
+		entry := AuditEntry{At: now, Action: ActionNwcSuspended, Source: SourceNwc + ":" + name, Limit: limit}
+		g.auditLocked(entry)
+		entries = append(entries, entry)
+	}
+	_ = g.saveLocked()
+	g.mu.Unlock()
+	for _, entry := range entries {
+		g.notify(entry)
+	}
+}
+
+// ResumeNwc restores spending on the NWC connections suspended by the
+// guard.
+func (g *Guard) ResumeNwc(actor, reason string) error {
+	g.mu.Lock()
+	nwc, suspended := g.nwc, g.state.SuspendedNwc
+	g.mu.Unlock()
+	if nwc == nil {
+		return errors.New("spendguard: no NWC service attached")
+	}
+	var remaining []string
+	var entries []AuditEntry
+	var firstErr error
+	now := time.Now().UTC()
+	for _, name := range suspended {
+		receiveOnly := false
+		if _, err := nwc.EditConnection(breez_sdk_liquid.EditConnectionRequest{Name: name, ReceiveOnly: &receiveOnly}); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			remaining = append(remaining, name)
+			continue
+		}
+		entries = append(entries, AuditEntry{At: now, Action: ActionNwcResumed, Actor: actor, Reason: reason, Source: SourceNwc + ":" + name})
+	}
+	g.mu.Lock()
+	g.state.SuspendedNwc = remaining
+	for _, entry := range entries {
+		g.auditLocked(entry)
+	}
+	err := g.saveLocked()
+	g.mu.Unlock()
+	for _, entry := range entries {
+		g.notify(entry)
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return err
+}
+
+func (g *Guard) auditLocked(entry AuditEntry) {
+	g.state.Audit = append(g.state.Audit, entry)
+	if n := len(g.state.Audit) - g.opts.AuditSize; n > 0 {
+		g.state.Audit = append([]AuditEntry(nil), g.state.Audit[n:]...)
+	}
+}
+
+// pruneLocked drops the spends older than every window, including the
+// per-destination windows of the active overrides.
+func (g *Guard) pruneLocked(now time.Time) {
+	window := week
+	if w := g.opts.Limits.PerDestinationWindow; w > window {
+		window = w
+	}
+	for _, o := range g.state.Overrides {
+		if o.active(now) && o.Limits != nil && o.Limits.PerDestinationWindow > window {
+			window = o.Limits.PerDestinationWindow
+		}
+	}
+	kept := g.state.Spends[:0]
+	for _, s := range g.state.Spends {
+		if s.At.After(now.Add(-window)) {
+			kept = append(kept, s)
+		}
+	}
+	g.state.Spends = kept
+}
+
+func (g *Guard) saveLocked() error {
+	return filestore.Save(g.opts.StatePath, g.state)
+}
+
+func (g *Guard) notify(entry AuditEntry) {
+	if g.opts.OnAudit != nil {
+		g.opts.OnAudit(entry)
+	}
+}
+
+func newId() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,203 @@
+package spendguard
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdktest"
+)
+
+func newGuard(t *testing.T, sdk *sdktest.Fake, limits Limits) *Guard {
+	t.Helper()
+	g, err := New(sdk, Options{StatePath: filepath.Join(t.TempDir(), "spendguard.json"), Limits: limits})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestReserveRelease(t *testing.T) {
+	g := newGuard(t, &sdktest.Fake{}, Limits{Daily: Sats(1000)})
+	id, err := g.Reserve("lq1", 600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Reserve("lq2", 600)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got %v, want a LimitError", err)
+	}
+	if limitErr.Limit != LimitDaily || limitErr.SpentSat != 600 || limitErr.LimitSat != 1000 {
+		t.Fatalf("unexpected %+v", limitErr)
+	}
+	if audit := g.Audit(); len(audit) != 1 || audit[0].Action != ActionSpendRejected || audit[0].Limit != LimitDaily {
+		t.Fatalf("audit %+v", audit)
+	}
+
+	g.Release(id)
+	if usage := g.Usage(); usage.DailySat != 0 {
+		t.Fatalf("usage %+v after release", usage)
+	}
+	if _, err := g.Reserve("lq2", 600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The counters survive a restart.
+	g, err = New(&sdktest.Fake{}, g.opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := g.Usage(); usage.DailySat != 600 || usage.WeeklySat != 600 {
+		t.Fatalf("usage %+v after restart", usage)
+	}
+}
+
+func TestFiatLimit(t *testing.T) {
+	sdk := &sdktest.Fake{Rates: []breez_sdk_liquid.Rate{{Coin: "USD", Value: 100000}}}
+	g := newGuard(t, sdk, Limits{PerTransaction: Fiat(10, "usd")})
+	if err := g.Check("lq1", 10000); err != nil {
+		t.Fatal(err)
+	}
+	err := g.Check("lq1", 10001)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Configured != "10.00 USD" || limitErr.LimitSat != 10000 {
+		t.Fatalf("got %v, want the per-transaction limit of 10.00 USD", err)
+	}
+}
+
+func TestWrappedSdk(t *testing.T) {
+	tests := []struct {
+		name    string
+		sendErr *breez_sdk_liquid.PaymentError
+		// kept reports whether the spend still counts after the call.
+		kept bool
+	}{
+		{name: "sent", kept: true},
+		{name: "timeout", sendErr: breez_sdk_liquid.NewPaymentErrorPaymentTimeout(), kept: true},
+		{name: "rejected", sendErr: breez_sdk_liquid.NewPaymentErrorAmountOutOfRange()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sdk := &sdktest.Fake{}
+			if tt.sendErr != nil {
+				sdk.SendErrs = []*breez_sdk_liquid.PaymentError{tt.sendErr}
+			}
+			g := newGuard(t, sdk, Limits{Daily: Sats(1000)})
+			_, payErr := g.Wrap(sdk).SendPayment(sdktest.SendRequest("lq1", 500, 10))
+			if (payErr != nil) != (tt.sendErr != nil) {
+				t.Fatalf("got %v, want %v", payErr, tt.sendErr)
+			}
+			want := uint64(0)
+			if tt.kept {
+				want = 510
+			}
+			if usage := g.Usage(); usage.DailySat != want {
+				t.Fatalf("%d sat counted, want %d", usage.DailySat, want)
+			}
+			if tt.sendErr == nil && g.state.Spends[0].PaymentId != "tx1" {
+				t.Fatalf("spend %+v not bound to its payment", g.state.Spends[0])
+			}
+		})
+	}
+}
+
+func TestWrappedSdkLimitError(t *testing.T) {
+	sdk := &sdktest.Fake{}
+	g := newGuard(t, sdk, Limits{PerTransaction: Sats(100)})
+	_, payErr := g.Wrap(sdk).SendPayment(sdktest.SendRequest("lq1", 500, 10))
+	if payErr == nil {
+		t.Fatal("spend over the limit sent")
+	}
+	if sdk.Sends != 0 {
+		t.Fatalf("%d sends, want 0", sdk.Sends)
+	}
+	// The variant tells that the payment was not sent.
+	if !errors.Is(payErr, breez_sdk_liquid.ErrPaymentErrorInsufficientFunds) {
+		t.Fatalf("got %v, want an InsufficientFunds PaymentError", payErr)
+	}
+	var limitErr *LimitError
+	if !errors.As(payErr, &limitErr) || !errors.Is(payErr, ErrLimitExceeded) || limitErr.Limit != LimitPerTransaction {
+		t.Fatalf("got %v, want the LimitError through it", payErr)
+	}
+	if errors.Is(breez_sdk_liquid.NewPaymentErrorInsufficientFunds(), ErrLimitExceeded) {
+		t.Fatal("ErrLimitExceeded matches a plain InsufficientFunds")
+	}
+}
+
+// withAmount returns a request sending amount to lq1 with 10 sat of fees.
+func withAmount(amount breez_sdk_liquid.PayAmount) breez_sdk_liquid.SendPaymentRequest {
+	req := sdktest.SendRequest("lq1", 0, 10)
+	req.PrepareResponse.Amount = &amount
+	return req
+}
+
+func TestDrainCountsBalance(t *testing.T) {
+	sdk := &sdktest.Fake{BalanceSat: 5000, FeesSat: 10}
+	g := newGuard(t, sdk, Limits{Daily: Sats(4000)})
+	_, payErr := g.Wrap(sdk).SendPayment(withAmount(breez_sdk_liquid.PayAmountDrain{}))
+	var limitErr *LimitError
+	if !errors.As(payErr, &limitErr) || limitErr.AmountSat != 5000 {
+		t.Fatalf("got %v, want the daily limit exceeded by 5000 sat", payErr)
+	}
+	if sdk.Sends != 0 {
+		t.Fatalf("%d sends, want 0", sdk.Sends)
+	}
+
+	g = newGuard(t, sdk, Limits{Daily: Sats(6000)})
+	if _, payErr := g.Wrap(sdk).SendPayment(withAmount(breez_sdk_liquid.PayAmountDrain{})); payErr != nil {
+		t.Fatal(payErr)
+	}
+	if usage := g.Usage(); usage.DailySat != 5000 {
+		t.Fatalf("%d sat counted, want 5000", usage.DailySat)
+	}
+}
+
+func TestAssetPayments(t *testing.T) {
+	asset := breez_sdk_liquid.PayAmountAsset{ToAsset: "usdt", ReceiverAmount: 10}
+	sdk := &sdktest.Fake{FeesSat: 10}
+	g := newGuard(t, sdk, Limits{Weekly: Sats(100000)})
+	_, payErr := g.Wrap(sdk).SendPayment(withAmount(asset))
+	if !errors.Is(payErr, ErrAssetPayment) || !errors.Is(payErr, breez_sdk_liquid.ErrPaymentErrorAssetError) {
+		t.Fatalf("got %v, want ErrAssetPayment as an AssetError", payErr)
+	}
+	if audit := g.Audit(); sdk.Sends != 0 || len(audit) != 1 || audit[0].Action != ActionSpendRejected {
+		t.Fatalf("%d sends, audit %+v", sdk.Sends, audit)
+	}
+
+	// Asset payments go through, counting their fees, once no limit
+	// applies.
+	if _, err := g.Grant("ops", "asset payout", nil, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, payErr := g.Wrap(sdk).SendPayment(withAmount(asset)); payErr != nil {
+		t.Fatal(payErr)
+	}
+	if usage := g.Usage(); usage.DailySat != 10 {
+		t.Fatalf("%d sat counted, want the 10 sat of fees", usage.DailySat)
+	}
+
+	g = newGuard(t, sdk, Limits{})
+	if _, payErr := g.Wrap(sdk).SendPayment(withAmount(asset)); payErr != nil {
+		t.Fatalf("without limits: %v", payErr)
+	}
+}
+
+func TestPruneKeepsOverrideWindow(t *testing.T) {
+	g := newGuard(t, &sdktest.Fake{}, Limits{})
+	limits := &Limits{PerDestination: Sats(1000), PerDestinationWindow: 30 * day}
+	if _, err := g.Grant("ops", "monthly cap", limits, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	g.state.Spends = append(g.state.Spends, Spend{Id: "old", At: time.Now().Add(-10 * day), AmountSat: 800, Destination: "lq1", Source: SourceSdk})
+
+	// The spend older than a week still counts within the override window.
+	if _, err := g.Reserve("lq2", 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Reserve("lq1", 300); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got %v, want the per-destination limit", err)
+	}
+}
@@ -0,0 +1,186 @@
+package spendguard
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Amount is a limit expressed in sats, or in a fiat currency converted with
+// the SDK's fiat rates when checked. A zero Amount disables the limit.
+type Amount struct {
+	Sat uint64 `json:"sat,omitempty"`
+	// Fiat is an amount of Currency, e.g. 100 USD.
+	Fiat     float64 `json:"fiat,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// Sats returns a limit of sat sats.
+func Sats(sat uint64) Amount {
+	return Amount{Sat: sat}
+}
+
+// Fiat returns a limit of amount units of currency.
+func Fiat(amount float64, currency string) Amount {
+	return Amount{Fiat: amount, Currency: currency}
+}
+
+// IsZero reports whether the limit is disabled.
+func (a Amount) IsZero() bool {
+	return a.Sat == 0 && a.Fiat == 0
+}
+
+func (a Amount) String() string {
+	if a.Fiat > 0 {
+		return fmt.Sprintf("%.2f %s", a.Fiat, strings.ToUpper(a.Currency))
+	}
+	return fmt.Sprintf("%d sat", a.Sat)
+}
+
+// Limits are the spending limits. Each spend counts its amount plus fees.
+type Limits struct {
+	// PerTransaction caps a single spend.
+	PerTransaction Amount `json:"per_transaction"`
+	// Daily caps the spends of the last 24 hours.
+	Daily Amount `json:"daily"`
+	// Weekly caps the spends of the last 7 days.
+	Weekly Amount `json:"weekly"`
+	// PerDestination caps the spends to a single destination within
+	// PerDestinationWindow.
+	PerDestination Amount `json:"per_destination"`
+	// PerDestinationWindow defaults to 24 hours.
+	PerDestinationWindow time.Duration `json:"per_destination_window,omitempty"`
+}
+
+// Limit names, reported in LimitError.
+const (
+	LimitPerTransaction = "per_transaction"
+	LimitDaily          = "daily"
+	LimitWeekly         = "weekly"
+	LimitPerDestination = "per_destination"
+)
+
+// ErrLimitExceeded is matched by LimitError with errors.Is, including
+// through the SDK errors returned by a wrapped SDK.
+var ErrLimitExceeded = errors.New("spending limit exceeded")
+
+// ErrAssetPayment is matched by the errors returned for asset payments
+// while a limit applies, as their amount cannot be priced in sats.
+var ErrAssetPayment = errors.New("spendguard: asset payments are not allowed while spending limits apply")
+
+// assetPaymentError is returned for asset payments while a limit applies.
+type assetPaymentError struct{}
+
+func (assetPaymentError) Error() string {
+	return ErrAssetPayment.Error()
+}
+
+func (assetPaymentError) Is(target error) bool {
+	return target == ErrAssetPayment
+}
+
+// As converts the error to the SDK error types, as LimitError.As.
+func (e assetPaymentError) As(target any) bool {
+	switch target := target.(type) {
+	case **breez_sdk_liquid.PaymentError:
+		*target = sdkutil.WithCause(sdkutil.PaymentErrorWithMessage(breez_sdk_liquid.NewPaymentErrorAssetError(), e.Error()), e)
+	case **breez_sdk_liquid.LnUrlPayError:
+		*target = sdkutil.WithCause(breez_sdk_liquid.NewLnUrlPayErrorInvalidAmount(e.Error()), e)
+	default:
+		return false
+	}
+	return true
+}
+
+// LimitError is returned for spends exceeding a limit.
+type LimitError struct {
+	Limit       string `json:"limit"`
+	Destination string `json:"destination,omitempty"`
+	AmountSat   uint64 `json:"amount_sat"`
+	// SpentSat were already spent within the limit's window.
+	SpentSat uint64 `json:"spent_sat"`
+	LimitSat uint64 `json:"limit_sat"`
+	// Configured is the limit as configured, e.g. "100.00 USD".
+	Configured string `json:"configured"`
+}
+
+func (e *LimitError) Error() string {
+	if e.SpentSat > 0 {
+		return fmt.Sprintf("%v: %s limit of %s, %d sat spent, %d sat requested", ErrLimitExceeded, e.Limit, e.Configured, e.SpentSat, e.AmountSat)
+	}
+	return fmt.Sprintf("%v: %s limit of %s, %d sat requested", ErrLimitExceeded, e.Limit, e.Configured, e.AmountSat)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// As converts the error to the SDK error types, so that callers of a
+// wrapped SDK receive an error of the method's type, through which
+// errors.Is and errors.As still reach the LimitError. The variants used
+// tell that the payment was not sent.
+func (e *LimitError) As(target any) bool {
+	switch target := target.(type) {
+	case **breez_sdk_liquid.PaymentError:
+		*target = sdkutil.WithCause(sdkutil.PaymentErrorWithMessage(breez_sdk_liquid.NewPaymentErrorInsufficientFunds(), e.Error()), e)
+	case **breez_sdk_liquid.LnUrlPayError:
+		*target = sdkutil.WithCause(breez_sdk_liquid.NewLnUrlPayErrorInsufficientBalance(e.Error()), e)
+	default:
+		return false
+	}
+	return true
+}
+
+// DefaultRatesTTL is how long fiat rates are cached.
+const DefaultRatesTTL = 10 * time.Minute
+
+// rates caches the fiat rates of the SDK.
+type rates struct {
+	sdk breez_sdk_liquid.BindingLiquidSdkInterface
+	ttl time.Duration
+
+	mu      sync.Mutex
+	values  map[string]float64
+	fetched time.Time
+}
+
+// sats converts a limit to sats.
+func (r *rates) sats(a Amount) (uint64, error) {
+	if a.Fiat == 0 {
+		return a.Sat, nil
+	}
+	rate, err := r.rate(a.Currency)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(math.Floor(a.Fiat / rate * 1e8)), nil
+}
+
+// rate returns the price of a bitcoin in currency.
+func (r *rates) rate(currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values == nil || time.Since(r.fetched) > r.ttl {
+		list, err := r.sdk.FetchFiatRates()
+		if err != nil {
+			return 0, fmt.Errorf("fetching fiat rates: %w", err)
+		}
+		values := map[string]float64{}
+		for _, rate := range list {
+			values[strings.ToUpper(rate.Coin)] = rate.Value
+		}
+		r.values, r.fetched = values, time.Now()
+	}
+	rate, ok := r.values[currency]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no fiat rate for %s", currency)
+	}
+	return rate, nil
+}
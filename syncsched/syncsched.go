@@ -0,0 +1,257 @@
+// Package syncsched calls Sync on a schedule. Syncs run on an interval with
+// jitter, back off exponentially after failures, can be forced by external
+// triggers, and concurrent requests share a single in-flight Sync. The
+// scheduler also tracks sync health from both its own calls and the sync
+// events emitted by the SDK. Failures are counted from the scheduler's own
+// calls only, so the failure count and the backoff always agree.
+package syncsched
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultInterval       = time.Minute
+	DefaultJitter         = 0.1
+	DefaultInitialBackoff = 5 * time.Second
+	DefaultMaxBackoff     = 10 * time.Minute
+)
+
+// Options configures a Scheduler.
+type Options struct {
+	// Interval between successful syncs. Defaults to DefaultInterval.
+	Interval time.Duration
+	// Jitter randomizes every delay by up to this fraction in either
+	// direction. Defaults to DefaultJitter; set a negative value to
+	// disable.
+	Jitter float64
+	// InitialBackoff is the delay after the first failure, doubled after
+	// each consecutive failure up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// OnSync, if set, is called after every Sync call made by the
+	// scheduler.
+	OnSync func(Health, error)
+}
+
+// Health describes the sync state.
+type Health struct {
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
+	LastError   string    `json:"last_error,omitempty"`
+	// ConsecutiveFailures, Syncs and Failures count the Sync calls made by
+	// the scheduler; ConsecutiveFailures drives the backoff.
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Syncs               uint64 `json:"syncs"`
+	Failures            uint64 `json:"failures"`
+	// LastSyncedEvent is when SdkEventSynced was last received, and
+	// LastSyncFailedEvent when SdkEventSyncFailed was.
+	LastSyncedEvent     time.Time `json:"last_synced_event"`
+	LastSyncFailedEvent time.Time `json:"last_sync_failed_event"`
+	// DataSyncedEvents counts SdkEventDataSynced events, and
+	// PulledNewRecords those of them with DidPullNewRecords set.
+	DataSyncedEvents uint64    `json:"data_synced_events"`
+	PulledNewRecords uint64    `json:"pulled_new_records"`
+	LastNewRecords   time.Time `json:"last_new_records"`
+	// NextSync is when the next scheduled sync is due.
+	NextSync time.Time `json:"next_sync"`
+}
+
+// Healthy reports whether a sync succeeded within maxAge and no sync failed
+// since.
+func (h Health) Healthy(maxAge time.Duration) bool {
+	last := h.LastSuccess
+	if h.LastSyncedEvent.After(last) {
+		last = h.LastSyncedEvent
+	}
+	return h.ConsecutiveFailures == 0 && !last.IsZero() && time.Since(last) <= maxAge &&
+		!h.LastSyncFailedEvent.After(last)
+}
+
+type call struct {
+	done chan struct{}
+	err  error
+}
+
+// Scheduler runs Sync on a schedule.
+type Scheduler struct {
+	sdk  breez_sdk_liquid.BindingLiquidSdkInterface
+	opts Options
+
+	trigger chan struct{}
+
+	mu       sync.Mutex
+	inFlight *call
+	health   Health
+	rand     *rand.Rand
+}
+
+// New creates a Scheduler for the given SDK instance.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) *Scheduler {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Jitter == 0 {
+		opts.Jitter = DefaultJitter
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	return &Scheduler{
+		sdk:     sdk,
+		opts:    opts,
+		trigger: make(chan struct{}, 1),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Health returns a snapshot of the sync health.
+func (s *Scheduler) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.health
+}
+
+// Trigger requests an immediate sync from Run without waiting for it.
+func (s *Scheduler) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// SyncNow syncs and waits for the result. If a sync is already in flight,
+// its result is shared instead of starting another one.
+func (s *Scheduler) SyncNow(ctx context.Context) error {
+	s.mu.Lock()
+	c := s.inFlight
+	if c == nil {
+		c = &call{done: make(chan struct{})}
+		s.inFlight = c
+		s.health.LastAttempt = time.Now().UTC()
+		s.mu.Unlock()
+		go s.sync(c)
+	} else {
+		s.mu.Unlock()
+	}
+
+	select {
+	case <-c.done:
+		return c.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) sync(c *call) {
+	c.err = s.sdk.Sync().AsError()
+
+	s.mu.Lock()
+	now := time.Now().UTC()
+	s.health.Syncs++
+	if c.err != nil {
+		s.health.Failures++
+		s.health.ConsecutiveFailures++
+		s.health.LastFailure = now
+		s.health.LastError = c.err.Error()
+	} else {
+		s.health.ConsecutiveFailures = 0
+		s.health.LastSuccess = now
+		s.health.LastError = ""
+	}
+	s.inFlight = nil
+	health := s.health
+	s.mu.Unlock()
+
+	close(c.done)
+	if s.opts.OnSync != nil {
+		s.opts.OnSync(health, c.err)
+	}
+}
+
+// Run syncs immediately and then on schedule until ctx is cancelled. It also
+// tracks the sync events emitted by the SDK.
+func (s *Scheduler) Run(ctx context.Context) error {
+	listenerId, err := s.sdk.AddEventListener(sdkutil.EventListenerFunc(s.onEvent))
+	if err != nil {
+		return err
+	}
+	defer s.sdk.RemoveEventListener(listenerId)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		case <-s.trigger:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		syncErr := s.SyncNow(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delay := s.delay(syncErr)
+		s.mu.Lock()
+		s.health.NextSync = time.Now().UTC().Add(delay)
+		s.mu.Unlock()
+		timer.Reset(delay)
+	}
+}
+
+// delay returns the time to wait before the next sync.
+func (s *Scheduler) delay(syncErr error) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delay := s.opts.Interval
+	if syncErr != nil {
+		delay = s.opts.InitialBackoff
+		for i := 1; i < s.health.ConsecutiveFailures && delay < s.opts.MaxBackoff; i++ {
+			delay *= 2
+		}
+		if delay > s.opts.MaxBackoff {
+			delay = s.opts.MaxBackoff
+		}
+	}
+	if s.opts.Jitter > 0 {
+		delay += time.Duration((s.rand.Float64()*2 - 1) * s.opts.Jitter * float64(delay))
+	}
+	return delay
+}
+
+func (s *Scheduler) onEvent(e breez_sdk_liquid.SdkEvent) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch e := e.(type) {
+	case breez_sdk_liquid.SdkEventSynced:
+		s.health.LastSyncedEvent = now
+	case breez_sdk_liquid.SdkEventSyncFailed:
+		// A failed Sync call of the scheduler is also reported by this
+		// event: it is recorded but not counted again.
+		s.health.LastSyncFailedEvent = now
+		s.health.LastFailure = now
+		s.health.LastError = e.Error
+	case breez_sdk_liquid.SdkEventDataSynced:
+		s.health.DataSyncedEvents++
+		if e.DidPullNewRecords {
+			s.health.PulledNewRecords++
+			s.health.LastNewRecords = now
+		}
+	}
+}
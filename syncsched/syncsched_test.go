@@ -0,0 +1,97 @@
+package syncsched
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk fails Sync with syncErr and, like the SDK, reports every sync
+// with an event.
+type fakeSdk struct {
+	mu       sync.Mutex
+	syncErr  *breez_sdk_liquid.SdkError
+	syncs    int
+	release  chan struct{}
+	listener breez_sdk_liquid.EventListener
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		switch call.Method {
+		case "AddEventListener":
+			f.mu.Lock()
+			f.listener = call.Request.(breez_sdk_liquid.EventListener)
+			f.mu.Unlock()
+			return "listener", nil
+		case "Sync":
+			if f.release != nil {
+				<-f.release
+			}
+			f.mu.Lock()
+			f.syncs++
+			listener, syncErr := f.listener, f.syncErr
+			f.mu.Unlock()
+			if syncErr != nil {
+				if listener != nil {
+					listener.OnEvent(breez_sdk_liquid.SdkEventSyncFailed{Error: syncErr.Error()})
+				}
+				return nil, syncErr
+			}
+			if listener != nil {
+				listener.OnEvent(breez_sdk_liquid.SdkEventSynced{})
+			}
+		}
+		return nil, nil
+	})
+}
+
+func TestSyncNowShared(t *testing.T) {
+	f := &fakeSdk{release: make(chan struct{})}
+	s := New(f.sdk(), Options{})
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = s.SyncNow(context.Background())
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(f.release)
+	wg.Wait()
+	if errs[0] != nil || errs[1] != nil || f.syncs != 1 {
+		t.Fatalf("errors %v after %d syncs", errs, f.syncs)
+	}
+	h := s.Health()
+	if h.Syncs != 1 || h.Failures != 0 || h.LastSuccess.IsZero() || !h.Healthy(time.Minute) {
+		t.Fatalf("health %+v", h)
+	}
+}
+
+func TestFailureCountedOnce(t *testing.T) {
+	f := &fakeSdk{syncErr: breez_sdk_liquid.NewSdkErrorServiceConnectivity()}
+	s := New(f.sdk(), Options{Jitter: -1, InitialBackoff: time.Second, MaxBackoff: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	for s.Health().NextSync.IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	h := s.Health()
+	if h.Failures != 1 || h.ConsecutiveFailures != 1 || h.LastError == "" || h.Healthy(time.Minute) {
+		t.Fatalf("health %+v", h)
+	}
+	// The backoff is that of a single failure.
+	if d := s.delay(f.syncErr); d != time.Second {
+		t.Fatalf("delay %s, want 1s", d)
+	}
+}
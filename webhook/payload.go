@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Templates of the notifications sent by Breez.
+const (
+	// TemplateSwapUpdated reports a swap status change.
+	TemplateSwapUpdated = "swap_updated"
+	// TemplateInvoiceRequest asks for an invoice paying a BOLT12 offer of
+	// the wallet.
+	TemplateInvoiceRequest = "invoice_request"
+)
+
+// Notification is a webhook payload.
+type Notification struct {
+	Template string          `json:"template"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// SwapUpdated is the data of a swap_updated notification.
+type SwapUpdated struct {
+	// Id is the swap id, or its SHA-256 hash in hex.
+	Id     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// InvoiceRequest is the data of an invoice_request notification. The
+// invoice is posted to ReplyUrl.
+type InvoiceRequest struct {
+	Offer          string `json:"offer"`
+	InvoiceRequest string `json:"invoice_request"`
+	ReplyUrl       string `json:"reply_url"`
+}
+
+// Delivery is a validated notification.
+type Delivery struct {
+	// Key identifies the notification among redeliveries.
+	Key            string
+	Template       string
+	SwapUpdated    *SwapUpdated
+	InvoiceRequest *InvoiceRequest
+}
+
+// ValidationError reports an invalid payload.
+type ValidationError struct {
+	Msg string
+}
+
+func (e *ValidationError) Error() string {
+	return "webhook: invalid payload: " + e.Msg
+}
+
+func invalid(format string, a ...any) error {
+	return &ValidationError{Msg: fmt.Sprintf(format, a...)}
+}
+
+// Parse parses and validates a webhook payload. Unknown members are
+// ignored.
+func Parse(body []byte) (Delivery, error) {
+	var n Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return Delivery{}, invalid("%v", err)
+	}
+	if len(n.Data) == 0 || n.Data[0] != '{' {
+		return Delivery{}, invalid("data must be an object")
+	}
+
+	d := Delivery{Template: n.Template}
+	var canonical any
+	switch n.Template {
+	case TemplateSwapUpdated:
+		var data SwapUpdated
+		if err := json.Unmarshal(n.Data, &data); err != nil {
+			return Delivery{}, invalid("data: %v", err)
+		}
+		if !isToken(data.Id) {
+			return Delivery{}, invalid("data.id must be a non-empty alphanumeric string")
+		}
+		if !isToken(data.Status) {
+			return Delivery{}, invalid("data.status must be a non-empty status name")
+		}
+		d.SwapUpdated, canonical = &data, data
+	case TemplateInvoiceRequest:
+		var data InvoiceRequest
+		if err := json.Unmarshal(n.Data, &data); err != nil {
+			return Delivery{}, invalid("data: %v", err)
+		}
+		if data.Offer == "" || data.InvoiceRequest == "" {
+			return Delivery{}, invalid("data.offer and data.invoice_request are required")
+		}
+		if err := checkReplyUrl(data.ReplyUrl); err != nil {
+			return Delivery{}, err
+		}
+		d.InvoiceRequest, canonical = &data, data
+	case "":
+		return Delivery{}, invalid("template is required")
+	default:
+		return Delivery{}, invalid("unknown template %q", n.Template)
+	}
+
+	encoded, err := json.Marshal(canonical)
+	if err != nil {
+		return Delivery{}, err
+	}
+	sum := sha256.Sum256(append([]byte(n.Template+"\n"), encoded...))
+	d.Key = hex.EncodeToString(sum[:])
+	return d, nil
+}
+
+// isToken reports whether s is a non-empty identifier such as a swap id or
+// a status name.
+func isToken(s string) bool {
+	if s == "" || len(s) > 128 {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func checkReplyUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return invalid("data.reply_url must be an http(s) URL")
+	}
+	return nil
+}
+
+// checkWebhookUrl validates a URL given to Register.
+func checkWebhookUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return errors.New("webhook: URL must be an absolute http(s) URL")
+	}
+	return nil
+}
+
+// hashSwapId returns the SHA-256 hash of a swap id in hex, the form some
+// notifications carry instead of the id.
+func hashSwapId(swapId string) string {
+	sum := sha256.Sum256([]byte(swapId))
+	return hex.EncodeToString(sum[:])
+}
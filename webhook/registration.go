@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+)
+
+// Register registers url with RegisterWebhook and records it. Registering
+// again on every start is harmless and renews the registration of the
+// pending swaps.
+func (r *Receiver) Register(url string) error {
+	if err := checkWebhookUrl(url); err != nil {
+		return err
+	}
+	if err := r.sdk.RegisterWebhook(url).AsError(); err != nil {
+		return fmt.Errorf("webhook: registering: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.WebhookUrl, r.state.RegisteredAt = url, time.Now().UTC()
+	if err := filestore.Save(r.opts.StatePath, r.state); err != nil {
+		return fmt.Errorf("webhook: saving state: %w", err)
+	}
+	return nil
+}
+
+// Unregister unregisters the webhook and forgets the recorded URL.
+func (r *Receiver) Unregister() error {
+	if err := r.sdk.UnregisterWebhook().AsError(); err != nil {
+		return fmt.Errorf("webhook: unregistering: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.WebhookUrl, r.state.RegisteredAt = "", time.Time{}
+	if err := filestore.Save(r.opts.StatePath, r.state); err != nil {
+		return fmt.Errorf("webhook: saving state: %w", err)
+	}
+	return nil
+}
+
+// Registration returns the recorded URL, empty if none, and when it was
+// last registered.
+func (r *Receiver) Registration() (string, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state.WebhookUrl, r.state.RegisteredAt
+}
+
+// Configure applies the configured webhook URL at startup: it registers
+// url, or if url is empty, unregisters the URL recorded by a previous run.
+func (r *Receiver) Configure(url string) error {
+	if url != "" {
+		return r.Register(url)
+	}
+	if registered, _ := r.Registration(); registered != "" {
+		return r.Unregister()
+	}
+	return nil
+}
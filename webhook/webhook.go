@@ -0,0 +1,388 @@
+// Package webhook receives the notifications Breez sends to the URL given
+// to RegisterWebhook, so that swaps progress while the app is not polling.
+//
+// Receiver is the http.Handler of that URL. It validates the payloads,
+// drops redeliveries, and queues the notifications for Run, which syncs the
+// wallet once per batch and reports the resulting state of each notified
+// swap to Options.OnUpdate. Invoice requests for BOLT12 offers are answered
+// with an invoice posted to their reply URL; as the receiver then posts to
+// a URL chosen by the sender, they are only accepted with Options.Token
+// set, and can be restricted to Options.ReplyHosts.
+//
+// A delivery is remembered once processed, so that redeliveries are
+// dropped; one whose processing failed is processed again when redelivered.
+// Redeliveries are recognized across restarts through the state file,
+// which also records the registered URL: see Register, Unregister and
+// Configure.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/internal/filestore"
+	"github.com/breez/breez-sdk-liquid-go/internal/sdkutil"
+	"github.com/breez/breez-sdk-liquid-go/syncsched"
+)
+
+// Defaults applied to unset Options.
+const (
+	DefaultDedupWindow  = 24 * time.Hour
+	DefaultQueueSize    = 256
+	DefaultMaxBodySize  = 16 << 10
+	DefaultReplyTimeout = 10 * time.Second
+	// DefaultLookback is how far back payments are searched for a swap
+	// notified by the hash of its id.
+	DefaultLookback = 7 * 24 * time.Hour
+)
+
+// Options configures a Receiver.
+type Options struct {
+	// StatePath is the file the registration and the recent deliveries
+	// are persisted to. Required.
+	StatePath string
+	// Token, if set, must be sent by Breez as the token query parameter:
+	// register a URL such as https://example.com/webhook?token=....
+	// Invoice requests are refused without it.
+	Token string
+	// ReplyHosts, if set, are the only hosts invoices are posted to.
+	ReplyHosts []string
+	// Scheduler, if set, runs the syncs, sharing them with its own.
+	Scheduler *syncsched.Scheduler
+	// OnUpdate is called with the state of each notified swap after the
+	// sync.
+	OnUpdate func(Update)
+	// OnError, if set, is called with the errors answering invoice
+	// requests.
+	OnError func(error)
+	// DedupWindow is how long deliveries are remembered. Defaults to
+	// DefaultDedupWindow.
+	DedupWindow time.Duration
+	// QueueSize caps the notifications waiting for Run; deliveries are
+	// refused with 503 while it is full. Defaults to DefaultQueueSize.
+	QueueSize int
+	// MaxBodySize caps payloads. Defaults to DefaultMaxBodySize.
+	MaxBodySize int64
+	// ReplyTimeout bounds the post of an invoice to a reply URL. Defaults
+	// to DefaultReplyTimeout.
+	ReplyTimeout time.Duration
+	// Lookback is how far back payments are searched for a swap notified
+	// by the hash of its id. Defaults to DefaultLookback.
+	Lookback time.Duration
+	// Client posts the invoices. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Update reports the state of a notified swap.
+type Update struct {
+	SwapId     string    `json:"swap_id"`
+	Status     string    `json:"status"`
+	ReceivedAt time.Time `json:"received_at"`
+	// Payment is the payment of the swap after the sync, nil if it is not
+	// found.
+	Payment *breez_sdk_liquid.Payment `json:"-"`
+	// Err is set if the sync or the payment lookup failed.
+	Err error `json:"-"`
+}
+
+// state is the persisted state.
+type state struct {
+	WebhookUrl   string               `json:"webhook_url,omitempty"`
+	RegisteredAt time.Time            `json:"registered_at,omitempty"`
+	Deliveries   map[string]time.Time `json:"deliveries"`
+}
+
+// queued is a delivery waiting for Run.
+type queued struct {
+	Delivery
+	receivedAt time.Time
+}
+
+// Receiver handles the webhook deliveries.
+type Receiver struct {
+	sdk   breez_sdk_liquid.BindingLiquidSdkInterface
+	opts  Options
+	queue chan queued
+
+	mu    sync.Mutex
+	state state
+	// pending holds the keys of the queued deliveries, not yet processed.
+	pending map[string]bool
+}
+
+// New creates a Receiver and loads its persisted state.
+func New(sdk breez_sdk_liquid.BindingLiquidSdkInterface, opts Options) (*Receiver, error) {
+	if opts.StatePath == "" {
+		return nil, errors.New("webhook: StatePath is required")
+	}
+	if opts.DedupWindow <= 0 {
+		opts.DedupWindow = DefaultDedupWindow
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.ReplyTimeout <= 0 {
+		opts.ReplyTimeout = DefaultReplyTimeout
+	}
+	if opts.Lookback <= 0 {
+		opts.Lookback = DefaultLookback
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	r := &Receiver{sdk: sdk, opts: opts, queue: make(chan queued, opts.QueueSize), pending: map[string]bool{}}
+	if err := filestore.Load(opts.StatePath, &r.state); err != nil {
+		return nil, fmt.Errorf("webhook: loading state: %w", err)
+	}
+	if r.state.Deliveries == nil {
+		r.state.Deliveries = map[string]time.Time{}
+	}
+	return r, nil
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.opts.Token != "" && subtle.ConstantTimeCompare([]byte(req.URL.Query().Get("token")), []byte(r.opts.Token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.opts.MaxBodySize))
+	if err != nil {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	d, err := Parse(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if d.InvoiceRequest != nil && !r.replyAllowed(d.InvoiceRequest.ReplyUrl) {
+		http.Error(w, "invoice requests not allowed", http.StatusForbidden)
+		return
+	}
+
+	switch err := r.accept(d); {
+	case errors.Is(err, errDuplicate):
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, errQueueFull):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+var (
+	errDuplicate = errors.New("webhook: duplicate delivery")
+	errQueueFull = errors.New("webhook: queue full")
+)
+
+// replyAllowed reports whether invoices may be posted to the reply URL.
+func (r *Receiver) replyAllowed(replyUrl string) bool {
+	if r.opts.Token == "" {
+		return false
+	}
+	if len(r.opts.ReplyHosts) == 0 {
+		return true
+	}
+	u, err := url.Parse(replyUrl)
+	if err != nil {
+		return false
+	}
+	for _, host := range r.opts.ReplyHosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+	return false
+}
+
+// accept queues a delivery, or reports it as a duplicate of a queued or
+// processed one.
+func (r *Receiver) accept(d Delivery) error {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[d.Key] {
+		return errDuplicate
+	}
+	if at, ok := r.state.Deliveries[d.Key]; ok && now.Sub(at) < r.opts.DedupWindow {
+		return errDuplicate
+	}
+	select {
+	case r.queue <- queued{Delivery: d, receivedAt: now}:
+	default:
+		return errQueueFull
+	}
+	r.pending[d.Key] = true
+	return nil
+}
+
+// done records the end of the processing of a delivery. A processed
+// delivery is remembered so that its redeliveries are dropped; a failed
+// one is processed again if redelivered.
+func (r *Receiver) done(key string, processed bool) {
+	now := time.Now().UTC()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, key)
+	if !processed {
+		return
+	}
+	r.state.Deliveries[key] = now
+	for k, at := range r.state.Deliveries {
+		if now.Sub(at) >= r.opts.DedupWindow {
+			delete(r.state.Deliveries, k)
+		}
+	}
+	// A failed save only loses the deduplication of the delivery after a
+	// restart.
+	_ = filestore.Save(r.opts.StatePath, r.state)
+}
+
+// Run processes the queued deliveries until ctx is done.
+func (r *Receiver) Run(ctx context.Context) error {
+	for {
+		var batch []queued
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case q := <-r.queue:
+			batch = append(batch, q)
+		}
+	drain:
+		for {
+			select {
+			case q := <-r.queue:
+				batch = append(batch, q)
+			default:
+				break drain
+			}
+		}
+
+		var swaps []queued
+		for _, q := range batch {
+			if q.InvoiceRequest != nil {
+				go r.replyInvoice(ctx, q)
+			} else {
+				swaps = append(swaps, q)
+			}
+		}
+		if len(swaps) > 0 {
+			r.processSwaps(ctx, swaps)
+		}
+	}
+}
+
+// processSwaps syncs once and reports the state of each notified swap.
+func (r *Receiver) processSwaps(ctx context.Context, swaps []queued) {
+	syncErr := r.sync(ctx)
+	var recent []breez_sdk_liquid.Payment
+	var recentErr error
+	listed := false
+	for _, q := range swaps {
+		u := Update{SwapId: q.SwapUpdated.Id, Status: q.SwapUpdated.Status, ReceivedAt: q.receivedAt, Err: syncErr}
+		payment, err := r.lookup(q.SwapUpdated.Id)
+		if err == nil && payment == nil {
+			if !listed {
+				recent, recentErr = r.recentPayments()
+				listed = true
+			}
+			err = recentErr
+			payment = findHashed(recent, q.SwapUpdated.Id)
+		}
+		u.Payment = payment
+		if u.Err == nil {
+			u.Err = err
+		}
+		if r.opts.OnUpdate != nil {
+			r.opts.OnUpdate(u)
+		}
+		r.done(q.Key, u.Err == nil)
+	}
+}
+
+func (r *Receiver) sync(ctx context.Context) error {
+	if r.opts.Scheduler != nil {
+		return r.opts.Scheduler.SyncNow(ctx)
+	}
+	return r.sdk.Sync().AsError()
+}
+
+// lookup returns the payment of a swap id.
+func (r *Receiver) lookup(swapId string) (*breez_sdk_liquid.Payment, error) {
+	payment, payErr := r.sdk.GetPayment(breez_sdk_liquid.GetPaymentRequestSwapId{SwapId: swapId})
+	if payErr != nil {
+		return nil, payErr
+	}
+	return payment, nil
+}
+
+func (r *Receiver) recentPayments() ([]breez_sdk_liquid.Payment, error) {
+	from := time.Now().Add(-r.opts.Lookback).Unix()
+	return sdkutil.ListAllPayments(r.sdk, breez_sdk_liquid.ListPaymentsRequest{FromTimestamp: &from})
+}
+
+// findHashed returns the payment whose swap id hashes to hash.
+func findHashed(payments []breez_sdk_liquid.Payment, hash string) *breez_sdk_liquid.Payment {
+	for i := range payments {
+		if swapId := sdkutil.SwapId(payments[i]); swapId != "" && hashSwapId(swapId) == hash {
+			return &payments[i]
+		}
+	}
+	return nil
+}
+
+// replyInvoice creates the invoice of a BOLT12 invoice request and posts
+// it to the reply URL.
+func (r *Receiver) replyInvoice(ctx context.Context, q queued) {
+	err := r.postInvoice(ctx, *q.InvoiceRequest)
+	r.done(q.Key, err == nil)
+	if err != nil && r.opts.OnError != nil {
+		r.opts.OnError(err)
+	}
+}
+
+func (r *Receiver) postInvoice(ctx context.Context, req InvoiceRequest) error {
+	res, payErr := r.sdk.CreateBolt12Invoice(breez_sdk_liquid.CreateBolt12InvoiceRequest{Offer: req.Offer, InvoiceRequest: req.InvoiceRequest})
+	if payErr != nil {
+		return fmt.Errorf("webhook: creating BOLT12 invoice: %w", payErr)
+	}
+	body, err := json.Marshal(map[string]string{"invoice": res.Invoice})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.opts.ReplyTimeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.ReplyUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpRes, err := r.opts.Client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("webhook: posting BOLT12 invoice: %w", err)
+	}
+	httpRes.Body.Close()
+	if httpRes.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook: posting BOLT12 invoice: %s", httpRes.Status)
+	}
+	return nil
+}
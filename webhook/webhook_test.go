@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/breez/breez-sdk-liquid-go/breez_sdk_liquid"
+	"github.com/breez/breez-sdk-liquid-go/intercept"
+)
+
+// fakeSdk syncs, failing with syncErr, and knows the payment of swap s1.
+type fakeSdk struct {
+	mu      sync.Mutex
+	syncErr *breez_sdk_liquid.SdkError
+	syncs   int
+}
+
+func (f *fakeSdk) sdk() breez_sdk_liquid.BindingLiquidSdkInterface {
+	return intercept.WrapLiquidSdk(nil, func(call *intercept.Call, _ intercept.Handler) (any, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch call.Method {
+		case "Sync":
+			f.syncs++
+			if f.syncErr != nil {
+				return nil, f.syncErr
+			}
+		case "GetPayment":
+			if call.Request.(breez_sdk_liquid.GetPaymentRequestSwapId).SwapId == "s1" {
+				return &breez_sdk_liquid.Payment{AmountSat: 1000, Status: breez_sdk_liquid.PaymentStateComplete}, nil
+			}
+		}
+		return nil, nil
+	})
+}
+
+// start runs a Receiver, returning its updates.
+func start(t *testing.T, f *fakeSdk, opts Options) (*Receiver, chan Update) {
+	t.Helper()
+	updates := make(chan Update, 8)
+	opts.StatePath = filepath.Join(t.TempDir(), "webhook.json")
+	opts.OnUpdate = func(u Update) { updates <- u }
+	r, err := New(f.sdk(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = r.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return r, updates
+}
+
+// waitProcessed waits for the deliveries reported to OnUpdate to be
+// recorded.
+func waitProcessed(r *Receiver) {
+	for {
+		r.mu.Lock()
+		n := len(r.pending)
+		r.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func deliver(r *Receiver, target, body string) int {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	return w.Code
+}
+
+const swapUpdated = `{"template":"swap_updated","data":{"id":"s1","status":"transaction.claimed"}}`
+
+func TestDelivery(t *testing.T) {
+	f := &fakeSdk{}
+	r, updates := start(t, f, Options{Token: "secret"})
+	if code := deliver(r, "/webhook?token=secret", swapUpdated); code != http.StatusAccepted {
+		t.Fatalf("status %d", code)
+	}
+	u := <-updates
+	if u.Err != nil || u.SwapId != "s1" || u.Payment == nil || u.Payment.AmountSat != 1000 {
+		t.Fatalf("update %+v", u)
+	}
+	// Redeliveries are dropped.
+	if code := deliver(r, "/webhook?token=secret", swapUpdated); code != http.StatusOK {
+		t.Fatalf("redelivery: status %d", code)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.syncs != 1 {
+		t.Fatalf("%d syncs, want 1", f.syncs)
+	}
+}
+
+func TestDeliveryFailures(t *testing.T) {
+	f := &fakeSdk{syncErr: breez_sdk_liquid.NewSdkErrorServiceConnectivity()}
+	r, updates := start(t, f, Options{Token: "secret"})
+	tests := []struct {
+		target, body string
+		code         int
+	}{
+		{"/webhook", swapUpdated, http.StatusUnauthorized},
+		{"/webhook?token=secret", `{"template":"swap_updated","data":{"id":""}}`, http.StatusBadRequest},
+		{"/webhook?token=secret", `{"template":"other","data":{}}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code := deliver(r, tt.target, tt.body); code != tt.code {
+			t.Fatalf("%s %s: status %d, want %d", tt.target, tt.body, code, tt.code)
+		}
+	}
+
+	// A delivery whose sync failed is processed again when redelivered.
+	if code := deliver(r, "/webhook?token=secret", swapUpdated); code != http.StatusAccepted {
+		t.Fatalf("status %d", code)
+	}
+	if u := <-updates; u.Err == nil {
+		t.Fatalf("update %+v, want the sync error", u)
+	}
+	waitProcessed(r)
+	if code := deliver(r, "/webhook?token=secret", swapUpdated); code != http.StatusAccepted {
+		t.Fatalf("redelivery: status %d", code)
+	}
+	<-updates
+}
+
+func TestInvoiceRequestNeedsToken(t *testing.T) {
+	r, _ := start(t, &fakeSdk{}, Options{})
+	body := `{"template":"invoice_request","data":{"offer":"lno1","invoice_request":"lnr1","reply_url":"https://example.com/reply"}}`
+	if code := deliver(r, "/webhook", body); code != http.StatusForbidden {
+		t.Fatalf("status %d", code)
+	}
+}